@@ -0,0 +1,514 @@
+package financial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// Every change to the financial tables is captured by a row trigger into the
+// append-only financial_audit_log. Handlers attribute their changes by running
+// them in a transaction that sets app.actor (see beginAudited); anything else
+// (migrations, the price updater) is recorded as "system".
+
+// auditedTables maps each audited table to the columns that identify a row
+var auditedTables = []struct {
+	table string
+	keys  []string
+}{
+	{"assets", []string{"id"}},
+	{"asset_entries", []string{"id"}},
+	{"asset_prices", []string{"id"}},
+	{"debts", []string{"id"}},
+	{"debt_entries", []string{"id"}},
+	{"currency_rates", []string{"currency"}},
+	{"asset_categories", []string{"id"}},
+	{"owners", []string{"id"}},
+	{"asset_owners", []string{"asset_id", "owner_id"}},
+	{"debt_owners", []string{"debt_id", "owner_id"}},
+	{"savings_goals", []string{"id"}},
+	{"savings_goal_assets", []string{"goal_id", "asset_id"}},
+}
+
+// auditMigrations returns the statements installing the audit log and its triggers
+func auditMigrations() []string {
+	migrations := []string{
+		// Audit log: one row per inserted, updated or deleted row
+		`CREATE TABLE IF NOT EXISTS financial_audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+			actor VARCHAR(100) NOT NULL,
+			table_name VARCHAR(63) NOT NULL,
+			row_id TEXT NOT NULL,
+			action VARCHAR(6) NOT NULL,
+			old_values JSONB,
+			new_values JSONB
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_financial_audit_row ON financial_audit_log(table_name, row_id, changed_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_financial_audit_changed_at ON financial_audit_log(changed_at)`,
+
+		// Trigger function; arguments are the key columns of the audited table
+		`CREATE OR REPLACE FUNCTION financial_audit() RETURNS trigger AS $$
+		DECLARE
+			old_row JSONB;
+			new_row JSONB;
+			key_parts TEXT[] := '{}';
+			col TEXT;
+		BEGIN
+			IF TG_OP <> 'INSERT' THEN old_row := to_jsonb(OLD); END IF;
+			IF TG_OP <> 'DELETE' THEN new_row := to_jsonb(NEW); END IF;
+			IF TG_OP = 'UPDATE' AND old_row = new_row THEN RETURN NULL; END IF;
+			FOREACH col IN ARRAY TG_ARGV LOOP
+				key_parts := key_parts || (COALESCE(new_row, old_row) ->> col);
+			END LOOP;
+			INSERT INTO financial_audit_log (actor, table_name, row_id, action, old_values, new_values)
+			VALUES (
+				COALESCE(NULLIF(current_setting('app.actor', true), ''), 'system'),
+				TG_TABLE_NAME, array_to_string(key_parts, '/'), TG_OP, old_row, new_row
+			);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+
+		// The log itself is append-only
+		`CREATE OR REPLACE FUNCTION financial_audit_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'financial_audit_log is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS financial_audit_log_append_only ON financial_audit_log`,
+		`CREATE TRIGGER financial_audit_log_append_only
+			BEFORE UPDATE OR DELETE OR TRUNCATE ON financial_audit_log
+			FOR EACH STATEMENT EXECUTE FUNCTION financial_audit_append_only()`,
+	}
+
+	for _, t := range auditedTables {
+		migrations = append(migrations,
+			`DROP TRIGGER IF EXISTS `+t.table+`_audit ON `+t.table,
+			`CREATE TRIGGER `+t.table+`_audit
+				AFTER INSERT OR UPDATE OR DELETE ON `+t.table+`
+				FOR EACH ROW EXECUTE FUNCTION financial_audit('`+strings.Join(t.keys, "', '")+`')`,
+		)
+	}
+	return migrations
+}
+
+// actorFromRequest identifies who made a change (X-Actor header, defaulting to "api")
+func actorFromRequest(r *http.Request) string {
+	actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+	if actor == "" {
+		return "api"
+	}
+	if len(actor) > 100 {
+		actor = actor[:100]
+	}
+	return actor
+}
+
+// beginAudited starts a transaction whose changes are attributed to the request's actor
+func (h *Handler) beginAudited(r *http.Request) (pgx.Tx, error) {
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(r.Context(), `SELECT set_config('app.actor', $1, true)`, actorFromRequest(r)); err != nil {
+		tx.Rollback(r.Context())
+		return nil, err
+	}
+	return tx, nil
+}
+
+// audited runs fn in an audited transaction and commits it
+func (h *Handler) audited(r *http.Request, fn func(tx pgx.Tx) error) error {
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(r.Context())
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(r.Context())
+}
+
+// parseAuditTime accepts RFC3339 timestamps or dates; a date means the end of that day
+func parseAuditTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (use YYYY-MM-DD or RFC3339)", s)
+	}
+	return d.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// changedColumns lists the columns that differ between two row images
+func changedColumns(oldValues, newValues json.RawMessage) []string {
+	var before, after map[string]json.RawMessage
+	json.Unmarshal(oldValues, &before)
+	json.Unmarshal(newValues, &after)
+
+	var changed []string
+	for col, v := range after {
+		if !bytes.Equal(before[col], v) {
+			changed = append(changed, col)
+		}
+	}
+	for col := range before {
+		if _, ok := after[col]; !ok {
+			changed = append(changed, col)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// ListAuditLog returns audit entries, newest first.
+// Filters: table, row_id, actor, action, from, to, before_id (paging) and limit.
+func (h *Handler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+
+	if v := q.Get("table"); v != "" {
+		add("table_name = $%d", v)
+	}
+	if v := q.Get("row_id"); v != "" {
+		add("row_id = $%d", v)
+	}
+	if v := q.Get("actor"); v != "" {
+		add("actor = $%d", v)
+	}
+	if v := q.Get("action"); v != "" {
+		add("action = $%d", strings.ToUpper(v))
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, v); err != nil {
+				core.WriteError(w, http.StatusBadRequest, "from must be YYYY-MM-DD or RFC3339")
+				return
+			}
+		}
+		add("changed_at >= $%d", t)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := parseAuditTime(v)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		add("changed_at <= $%d", t)
+	}
+	if v := q.Get("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "invalid before_id")
+			return
+		}
+		add("id < $%d", id)
+	}
+
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			core.WriteError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	args = append(args, limit)
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, changed_at, actor, table_name, row_id, action, old_values, new_values
+		FROM financial_audit_log
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY id DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var oldValues, newValues []byte
+		if err := rows.Scan(&e.ID, &e.ChangedAt, &e.Actor, &e.Table, &e.RowID, &e.Action, &oldValues, &newValues); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if oldValues != nil {
+			e.OldValues = json.RawMessage(oldValues)
+		}
+		if newValues != nil {
+			e.NewValues = json.RawMessage(newValues)
+		}
+		if e.Action == "UPDATE" {
+			e.Changed = changedColumns(e.OldValues, e.NewValues)
+		}
+		entries = append(entries, e)
+	}
+
+	core.WriteJSON(w, http.StatusOK, entries)
+}
+
+// --- Reconstruction ---
+
+// rowsAsKnownAt returns the rows of a table as they were at the given time, by
+// rolling the current rows back through every audited change made after it
+func (h *Handler) rowsAsKnownAt(ctx context.Context, table string, keys []string, at time.Time) ([]json.RawMessage, error) {
+	state := make(map[string]json.RawMessage)
+
+	rows, err := h.db.Query(ctx, `SELECT concat_ws('/', `+strings.Join(keys, ", ")+`), to_jsonb(t) FROM `+table+` t`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		var row []byte
+		if err := rows.Scan(&key, &row); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		state[key] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The first change after the cutoff tells us what each row looked like before it
+	rows, err = h.db.Query(ctx, `
+		SELECT DISTINCT ON (row_id) row_id, old_values
+		FROM financial_audit_log
+		WHERE table_name = $1 AND changed_at > $2
+		ORDER BY row_id, changed_at, id
+	`, table, at)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		var old []byte
+		if err := rows.Scan(&key, &old); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if old == nil {
+			delete(state, key) // inserted after the cutoff
+		} else {
+			state[key] = old
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]json.RawMessage, 0, len(state))
+	for _, row := range state {
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// snapshotRow holds the columns of the reconstructed tables used for valuation
+type snapshotRow struct {
+	ID        int64     `json:"id"`
+	AssetID   int64     `json:"asset_id"`
+	DebtID    int64     `json:"debt_id"`
+	OwnerID   int64     `json:"owner_id"`
+	Currency  string    `json:"currency"`
+	EntryDate string    `json:"entry_date"`
+	PriceDate string    `json:"price_date"`
+	Units     float64   `json:"units"`
+	UnitValue float64   `json:"unit_value"`
+	Principal float64   `json:"principal"`
+	SEKRate   float64   `json:"sek_rate"`
+	Share     float64   `json:"share"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// financialSnapshot is the valuation-relevant state of the financial tables at one point in time
+type financialSnapshot struct {
+	assets      []snapshotRow
+	entries     []snapshotRow
+	prices      []snapshotRow
+	debts       []snapshotRow
+	debtEntries []snapshotRow
+	rates       map[string]float64
+	assetShares map[int64]map[int64]float64 // asset -> owner -> percent
+	debtShares  map[int64]map[int64]float64 // debt -> owner -> percent
+}
+
+// snapshotAt reconstructs the financial tables as they were known at the given time
+func (h *Handler) snapshotAt(ctx context.Context, at time.Time) (*financialSnapshot, error) {
+	load := func(table string, keys ...string) ([]snapshotRow, error) {
+		raw, err := h.rowsAsKnownAt(ctx, table, keys, at)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]snapshotRow, 0, len(raw))
+		for _, r := range raw {
+			var row snapshotRow
+			if err := json.Unmarshal(r, &row); err != nil {
+				return nil, fmt.Errorf("%s: %w", table, err)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	s := &financialSnapshot{
+		rates:       make(map[string]float64),
+		assetShares: make(map[int64]map[int64]float64),
+		debtShares:  make(map[int64]map[int64]float64),
+	}
+	var err error
+	if s.assets, err = load("assets", "id"); err != nil {
+		return nil, err
+	}
+	if s.entries, err = load("asset_entries", "id"); err != nil {
+		return nil, err
+	}
+	if s.prices, err = load("asset_prices", "id"); err != nil {
+		return nil, err
+	}
+	if s.debts, err = load("debts", "id"); err != nil {
+		return nil, err
+	}
+	if s.debtEntries, err = load("debt_entries", "id"); err != nil {
+		return nil, err
+	}
+
+	rates, err := load("currency_rates", "currency")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rates {
+		s.rates[r.Currency] = r.SEKRate
+	}
+
+	assetShares, err := load("asset_owners", "asset_id", "owner_id")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range assetShares {
+		if s.assetShares[r.AssetID] == nil {
+			s.assetShares[r.AssetID] = make(map[int64]float64)
+		}
+		s.assetShares[r.AssetID][r.OwnerID] = r.Share
+	}
+	debtShares, err := load("debt_owners", "debt_id", "owner_id")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range debtShares {
+		if s.debtShares[r.DebtID] == nil {
+			s.debtShares[r.DebtID] = make(map[int64]float64)
+		}
+		s.debtShares[r.DebtID][r.OwnerID] = r.Share
+	}
+
+	return s, nil
+}
+
+// latestOn returns the row for the item with the latest date on or before the given date
+func latestOn(rows []snapshotRow, item func(snapshotRow) int64, itemID int64, date func(snapshotRow) string, asOf string) *snapshotRow {
+	var best *snapshotRow
+	for i := range rows {
+		r := &rows[i]
+		d := date(*r)
+		if item(*r) != itemID || d > asOf {
+			continue
+		}
+		if best == nil || d > date(*best) || (d == date(*best) && r.CreatedAt.After(best.CreatedAt)) {
+			best = r
+		}
+	}
+	return best
+}
+
+// history values the snapshot on every date it has data for, mirroring GetHistory
+func (s *financialSnapshot) history(owner *int64) []NetWorthDataPoint {
+	share := func(shares map[int64]map[int64]float64, id int64) float64 {
+		if owner == nil {
+			return 1
+		}
+		return shares[id][*owner] / 100
+	}
+	rate := func(currency string) float64 {
+		if r, ok := s.rates[currency]; ok {
+			return r
+		}
+		return 1
+	}
+	byAsset := func(r snapshotRow) int64 { return r.AssetID }
+	byDebt := func(r snapshotRow) int64 { return r.DebtID }
+	entryDate := func(r snapshotRow) string { return r.EntryDate }
+	priceDate := func(r snapshotRow) string { return r.PriceDate }
+
+	dateSet := make(map[string]bool)
+	for _, e := range s.entries {
+		dateSet[e.EntryDate] = true
+	}
+	for _, e := range s.debtEntries {
+		dateSet[e.EntryDate] = true
+	}
+	for _, p := range s.prices {
+		dateSet[p.PriceDate] = true
+	}
+	dates := make([]string, 0, len(dateSet))
+	for d := range dateSet {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	history := []NetWorthDataPoint{}
+	for _, date := range dates {
+		point := NetWorthDataPoint{Date: date}
+		for _, a := range s.assets {
+			entry := latestOn(s.entries, byAsset, a.ID, entryDate, date)
+			if entry == nil {
+				continue
+			}
+			unitValue := 0.0
+			if price := latestOn(s.prices, byAsset, a.ID, priceDate, date); price != nil {
+				unitValue = price.UnitValue
+			}
+			point.TotalAssets += entry.Units * unitValue * rate(a.Currency) * share(s.assetShares, a.ID)
+		}
+		for _, d := range s.debts {
+			entry := latestOn(s.debtEntries, byDebt, d.ID, entryDate, date)
+			if entry == nil {
+				continue
+			}
+			point.TotalDebt += entry.Principal * rate(d.Currency) * share(s.debtShares, d.ID)
+		}
+		point.NetWorth = point.TotalAssets - point.TotalDebt
+		history = append(history, point)
+	}
+	return history
+}
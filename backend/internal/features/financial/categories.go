@@ -0,0 +1,617 @@
+package financial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// validLiquidity lists the supported liquidity classes, most liquid first
+var validLiquidity = map[string]bool{
+	"cash":        true,
+	"liquid":      true,
+	"semi_liquid": true,
+	"illiquid":    true,
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// errUnknownCategory is returned when an asset references a category that does not exist
+var errUnknownCategory = errors.New("unknown category; create it first via /asset-categories")
+
+// --- Category tree ---
+
+// categoryTree indexes categories by ID and parent for path and roll-up calculations
+type categoryTree struct {
+	byID     map[int64]*AssetCategory
+	children map[int64][]int64 // parent ID (0 for roots) -> child IDs
+	order    []int64
+}
+
+// buildCategoryTree indexes the categories and fills in their Path and Depth
+func buildCategoryTree(categories []AssetCategory) *categoryTree {
+	t := &categoryTree{
+		byID:     make(map[int64]*AssetCategory),
+		children: make(map[int64][]int64),
+	}
+	for i := range categories {
+		c := &categories[i]
+		t.byID[c.ID] = c
+		t.order = append(t.order, c.ID)
+	}
+	for _, id := range t.order {
+		c := t.byID[id]
+		parent := int64(0)
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		t.children[parent] = append(t.children[parent], id)
+
+		names := []string{}
+		for _, ancestor := range t.ancestors(id) {
+			names = append([]string{t.byID[ancestor].Name}, names...)
+		}
+		c.Path = strings.Join(names, " > ")
+		c.Depth = len(names) - 1
+	}
+	return t
+}
+
+// ancestors returns the category followed by its parents up to the root
+func (t *categoryTree) ancestors(id int64) []int64 {
+	var chain []int64
+	seen := make(map[int64]bool)
+	for {
+		c, ok := t.byID[id]
+		if !ok || seen[id] {
+			return chain
+		}
+		seen[id] = true
+		chain = append(chain, id)
+		if c.ParentID == nil {
+			return chain
+		}
+		id = *c.ParentID
+	}
+}
+
+// ancestorAt returns the ancestor of id at the given depth (or id itself if it is shallower)
+func (t *categoryTree) ancestorAt(id int64, depth int) int64 {
+	chain := t.ancestors(id)
+	if len(chain) == 0 || depth < 0 || depth >= len(chain)-1 {
+		return id
+	}
+	// chain is leaf -> root, so the root is last
+	return chain[len(chain)-1-depth]
+}
+
+// isDescendant reports whether candidate is id itself or one of its descendants
+func (t *categoryTree) isDescendant(candidate, id int64) bool {
+	for _, a := range t.ancestors(candidate) {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// breakdown rolls direct values up the tree; maxDepth < 0 means unlimited
+func (t *categoryTree) breakdown(direct map[int64]float64, total float64, maxDepth int) []CategoryBreakdown {
+	var build func(parent int64) ([]CategoryBreakdown, float64)
+	build = func(parent int64) ([]CategoryBreakdown, float64) {
+		var nodes []CategoryBreakdown
+		var sum float64
+		for _, id := range t.children[parent] {
+			c := t.byID[id]
+			children, childSum := build(id)
+			node := CategoryBreakdown{
+				ID:           c.ID,
+				Name:         c.Name,
+				Path:         c.Path,
+				Color:        c.Color,
+				Liquidity:    c.Liquidity,
+				Depth:        c.Depth,
+				DirectValue:  direct[id],
+				Value:        direct[id] + childSum,
+				TargetWeight: c.TargetWeight,
+			}
+			if maxDepth < 0 || c.Depth < maxDepth {
+				node.Children = children
+			}
+			if total > 0 {
+				node.Weight = node.Value / total * 100
+			}
+			if c.TargetWeight != nil {
+				drift := node.Weight - *c.TargetWeight
+				node.Drift = &drift
+			}
+			nodes = append(nodes, node)
+			sum += node.Value
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Value > nodes[j].Value })
+		return nodes, sum
+	}
+
+	nodes, _ := build(0)
+	if nodes == nil {
+		nodes = []CategoryBreakdown{}
+	}
+	return nodes
+}
+
+// loadCategories returns all categories ordered by name
+func loadCategories(ctx context.Context, q querier) ([]AssetCategory, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, name, parent_id, color, liquidity, target_weight, created_at
+		FROM asset_categories
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []AssetCategory{}
+	for rows.Next() {
+		var c AssetCategory
+		if err := rows.Scan(&c.ID, &c.Name, &c.ParentID, &c.Color, &c.Liquidity, &c.TargetWeight, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// resolveCategory finds the category an asset should be filed under, by ID or
+// (case-insensitively) by name, and returns its ID and canonical name
+func resolveCategory(ctx context.Context, q querier, id *int64, name string) (int64, string, error) {
+	var categoryID int64
+	var categoryName string
+
+	if id != nil {
+		err := q.QueryRow(ctx, `SELECT id, name FROM asset_categories WHERE id = $1`, *id).Scan(&categoryID, &categoryName)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, "", errUnknownCategory
+		}
+		return categoryID, categoryName, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, "", errors.New("category or category_id is required")
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT id, name FROM asset_categories
+		WHERE LOWER(name) = LOWER($1)
+		ORDER BY parent_id NULLS FIRST, id
+	`, name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	matches := 0
+	for rows.Next() {
+		var mid int64
+		var mname string
+		if err := rows.Scan(&mid, &mname); err != nil {
+			return 0, "", err
+		}
+		if matches == 0 {
+			categoryID, categoryName = mid, mname
+		}
+		matches++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+
+	switch {
+	case matches == 0:
+		return 0, "", errUnknownCategory
+	case matches > 1:
+		return 0, "", fmt.Errorf("category name %q is ambiguous; use category_id", name)
+	}
+	return categoryID, categoryName, nil
+}
+
+// ensureRootCategory returns the root category with the given name, creating it if needed
+func ensureRootCategory(ctx context.Context, q querier, name string) (int64, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Other"
+	}
+
+	var id int64
+	var canonical string
+	err := q.QueryRow(ctx, `
+		SELECT id, name FROM asset_categories
+		WHERE parent_id IS NULL AND LOWER(name) = LOWER($1)
+	`, name).Scan(&id, &canonical)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = q.QueryRow(ctx, `
+			INSERT INTO asset_categories (name) VALUES ($1)
+			RETURNING id, name
+		`, name).Scan(&id, &canonical)
+	}
+	return id, canonical, err
+}
+
+// categoryValues returns SEK asset values as of a date, per category (0 = uncategorized),
+// scaled to the owner's share (nil for the whole household)
+func (h *Handler) categoryValues(ctx context.Context, asOfDate string, owner *int64) (map[int64]float64, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT COALESCE(a.category_id, 0), COALESCE(SUM(e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1) * `+assetShareSQL(2)+`), 0) as total
+		FROM assets a
+		JOIN LATERAL (
+			SELECT units FROM asset_entries
+			WHERE asset_id = a.id AND entry_date <= $1
+			ORDER BY entry_date DESC, created_at DESC
+			LIMIT 1
+		) e ON true
+		LEFT JOIN LATERAL (
+			SELECT unit_value FROM asset_prices
+			WHERE asset_id = a.id AND price_date <= $1
+			ORDER BY price_date DESC, created_at DESC
+			LIMIT 1
+		) p ON true
+		LEFT JOIN currency_rates cr ON cr.currency = a.currency
+		GROUP BY COALESCE(a.category_id, 0)
+	`, asOfDate, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[int64]float64)
+	for rows.Next() {
+		var id int64
+		var total float64
+		if err := rows.Scan(&id, &total); err != nil {
+			return nil, err
+		}
+		values[id] = total
+	}
+	return values, rows.Err()
+}
+
+// --- Handlers ---
+
+// ListAssetCategories returns all categories with their computed paths
+func (h *Handler) ListAssetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := loadCategories(r.Context(), h.db)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	buildCategoryTree(categories)
+	sort.SliceStable(categories, func(i, j int) bool { return categories[i].Path < categories[j].Path })
+
+	core.WriteJSON(w, http.StatusOK, categories)
+}
+
+// validateCategoryInput normalizes and validates a category request body
+func validateCategoryInput(input *AssetCategoryInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.Contains(input.Name, ">") {
+		return errors.New("name must not contain '>'")
+	}
+	if input.Color == "" {
+		input.Color = "#6366f1"
+	}
+	if !hexColor.MatchString(input.Color) {
+		return errors.New("color must be a hex color like #6366f1")
+	}
+	if input.Liquidity == "" {
+		input.Liquidity = "semi_liquid"
+	}
+	if !validLiquidity[input.Liquidity] {
+		return errors.New("liquidity must be cash, liquid, semi_liquid or illiquid")
+	}
+	if input.TargetWeight != nil && (*input.TargetWeight < 0 || *input.TargetWeight > 100) {
+		return errors.New("target_weight must be between 0 and 100")
+	}
+	return nil
+}
+
+// CreateAssetCategory adds a category, optionally under a parent
+func (h *Handler) CreateAssetCategory(w http.ResponseWriter, r *http.Request) {
+	var input AssetCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateCategoryInput(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.ParentID != nil {
+		var exists bool
+		h.db.QueryRow(r.Context(), `SELECT EXISTS (SELECT 1 FROM asset_categories WHERE id = $1)`, *input.ParentID).Scan(&exists)
+		if !exists {
+			core.WriteError(w, http.StatusBadRequest, "parent_id must be an existing category")
+			return
+		}
+	}
+
+	var c AssetCategory
+	err := h.audited(r, func(tx pgx.Tx) error {
+		return tx.QueryRow(r.Context(), `
+			INSERT INTO asset_categories (name, parent_id, color, liquidity, target_weight)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, name, parent_id, color, liquidity, target_weight, created_at
+		`, input.Name, input.ParentID, input.Color, input.Liquidity, input.TargetWeight).Scan(
+			&c.ID, &c.Name, &c.ParentID, &c.Color, &c.Liquidity, &c.TargetWeight, &c.CreatedAt,
+		)
+	})
+	if err != nil {
+		core.WriteError(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, c)
+}
+
+// UpdateAssetCategory renames, re-parents or re-targets a category
+func (h *Handler) UpdateAssetCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input AssetCategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateCategoryInput(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Refuse to move a category underneath itself
+	if input.ParentID != nil {
+		categories, err := loadCategories(ctx, tx)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		tree := buildCategoryTree(categories)
+		if _, ok := tree.byID[*input.ParentID]; !ok {
+			core.WriteError(w, http.StatusBadRequest, "parent_id must be an existing category")
+			return
+		}
+		if tree.isDescendant(*input.ParentID, id) {
+			core.WriteError(w, http.StatusBadRequest, "a category cannot be moved under itself or its descendants")
+			return
+		}
+	}
+
+	var c AssetCategory
+	err = tx.QueryRow(ctx, `
+		UPDATE asset_categories
+		SET name = $1, parent_id = $2, color = $3, liquidity = $4, target_weight = $5
+		WHERE id = $6
+		RETURNING id, name, parent_id, color, liquidity, target_weight, created_at
+	`, input.Name, input.ParentID, input.Color, input.Liquidity, input.TargetWeight, id).Scan(
+		&c.ID, &c.Name, &c.ParentID, &c.Color, &c.Liquidity, &c.TargetWeight, &c.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		core.WriteError(w, http.StatusNotFound, "category not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	// Keep the denormalized name on assets in sync
+	if _, err := tx.Exec(ctx, `UPDATE assets SET category = $1 WHERE category_id = $2`, c.Name, c.ID); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, c)
+}
+
+// DeleteAssetCategory removes a category that has no assets or subcategories
+func (h *Handler) DeleteAssetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var result pgconn.CommandTag
+	err = h.audited(r, func(tx pgx.Tx) error {
+		result, err = tx.Exec(r.Context(), `DELETE FROM asset_categories WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		if status := categoryErrorStatus(err); status == http.StatusConflict {
+			core.WriteError(w, status, "category still has assets or subcategories; move or merge them first")
+			return
+		}
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "category not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeAssetCategory moves all assets and subcategories into another category and deletes this one
+func (h *Handler) MergeAssetCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req struct {
+		IntoID int64 `json:"into_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	categories, err := loadCategories(ctx, tx)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tree := buildCategoryTree(categories)
+	if _, ok := tree.byID[id]; !ok {
+		core.WriteError(w, http.StatusNotFound, "category not found")
+		return
+	}
+	target, ok := tree.byID[req.IntoID]
+	if !ok {
+		core.WriteError(w, http.StatusBadRequest, "into_id must be an existing category")
+		return
+	}
+	if tree.isDescendant(req.IntoID, id) {
+		core.WriteError(w, http.StatusBadRequest, "cannot merge a category into itself or its descendants")
+		return
+	}
+
+	moved, err := tx.Exec(ctx, `UPDATE assets SET category_id = $1, category = $2 WHERE category_id = $3`, target.ID, target.Name, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if _, err := tx.Exec(ctx, `UPDATE asset_categories SET parent_id = $1 WHERE parent_id = $2`, target.ID, id); err != nil {
+		core.WriteError(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM asset_categories WHERE id = $1`, id); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"into_id":      target.ID,
+		"assets_moved": moved.RowsAffected(),
+	})
+}
+
+// GetCategoryBreakdown returns the category tree with values rolled up as of a date
+func (h *Handler) GetCategoryBreakdown(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	asOfDate := r.URL.Query().Get("as_of")
+	if asOfDate == "" {
+		asOfDate = time.Now().Format("2006-01-02")
+	}
+	maxDepth := -1
+	if l := r.URL.Query().Get("level"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 {
+			core.WriteError(w, http.StatusBadRequest, "level must be a non-negative integer")
+			return
+		}
+		maxDepth = n
+	}
+
+	owner, err := h.ownerFromRequest(r)
+	if err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+
+	categories, err := loadCategories(ctx, h.db)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	values, err := h.categoryValues(ctx, asOfDate, ownerID(owner))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+
+	tree := buildCategoryTree(categories)
+	response := CategoryBreakdownResponse{
+		AsOfDate:      asOfDate,
+		Owner:         ownerLabel(owner),
+		TotalAssets:   total,
+		Categories:    tree.breakdown(values, total, maxDepth),
+		Uncategorized: values[0],
+	}
+
+	core.WriteJSON(w, http.StatusOK, response)
+}
+
+// categoryErrorStatus maps constraint violations to client errors
+func categoryErrorStatus(err error) int {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505": // unique_violation
+			return http.StatusConflict
+		case "23503": // foreign_key_violation
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,1269 @@
+package financial
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// archiveVersion is bumped whenever the archive layout changes incompatibly
+const archiveVersion = 1
+
+// exportTable is one table of an archive in tabular form (a CSV file or an xlsx sheet)
+type exportTable struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// numericColumns are written as numbers (rather than text) in xlsx exports
+var numericColumns = map[string]bool{
+	"id": true, "asset_id": true, "debt_id": true, "parent_id": true, "category_id": true,
+	"units": true, "unit_value": true, "principal": true, "monthly_payment": true,
+	"interest_rate": true, "sek_rate": true, "target_weight": true,
+	"owner_id": true, "person_id": true, "share": true,
+	"goal_id": true, "target_amount": true,
+}
+
+func (t exportTable) isNumeric(col int) bool {
+	return col < len(t.Header) && numericColumns[t.Header[col]]
+}
+
+// --- Handlers ---
+
+// ExportData exports all financial data as csv (zip), xlsx or json
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "csv" && format != "xlsx" && format != "json" {
+		core.WriteError(w, http.StatusBadRequest, "format must be csv, xlsx or json")
+		return
+	}
+
+	archive, err := h.loadArchive(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Render into memory first so a failure can still be reported as a JSON error
+	var buf bytes.Buffer
+	var contentType, ext string
+	switch format {
+	case "csv":
+		contentType, ext = "application/zip", "zip"
+		err = writeCSVZip(&buf, archiveTables(archive))
+	case "xlsx":
+		contentType, ext = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"
+		err = writeXLSX(&buf, archiveTables(archive))
+	default:
+		contentType, ext = "application/json", "json"
+		err = json.NewEncoder(&buf).Encode(archive)
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("financial-export-%s.%s", archive.ExportedAt.Format("2006-01-02"), ext)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// RestoreData validates an export archive and imports it into an empty database
+func (h *Handler) RestoreData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse multipart form (max 50MB)
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "file too large or not a multipart upload")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "no file provided")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		switch strings.ToLower(path.Ext(header.Filename)) {
+		case ".zip", ".csv":
+			format = "csv"
+		case ".xlsx":
+			format = "xlsx"
+		default:
+			format = "json"
+		}
+	}
+
+	var archive *FinancialArchive
+	var problems []string
+	switch format {
+	case "csv":
+		var tables []exportTable
+		tables, err = readCSVZip(data)
+		if err == nil {
+			archive, problems = archiveFromTables(tables)
+		}
+	case "xlsx":
+		var tables []exportTable
+		tables, err = readXLSX(data)
+		if err == nil {
+			archive, problems = archiveFromTables(tables)
+		}
+	case "json":
+		archive = &FinancialArchive{}
+		err = json.Unmarshal(data, archive)
+	default:
+		core.WriteError(w, http.StatusBadRequest, "format must be csv, xlsx or json")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(problems) == 0 {
+		problems = validateArchive(archive)
+	}
+	if len(problems) > 0 {
+		core.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":    "archive failed validation",
+			"problems": problems,
+		})
+		return
+	}
+
+	// Restore only into an empty database so IDs and history stay exactly as exported
+	var hasData bool
+	err = h.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM assets) OR EXISTS (SELECT 1 FROM debts) OR EXISTS (SELECT 1 FROM savings_goals)
+	`).Scan(&hasData)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if hasData {
+		core.WriteError(w, http.StatusConflict, "database already contains financial data; restore requires an empty database")
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := restoreArchive(ctx, tx, archive)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
+
+// --- Loading and restoring ---
+
+// loadArchive reads all financial tables into an archive
+func (h *Handler) loadArchive(ctx context.Context) (*FinancialArchive, error) {
+	categories, err := loadCategories(ctx, h.db)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &FinancialArchive{
+		Version:         archiveVersion,
+		ExportedAt:      time.Now(),
+		AssetCategories: categories,
+		Assets:          []Asset{},
+		AssetEntries:    []ArchivedAssetEntry{},
+		AssetPrices:     []AssetPrice{},
+		Debts:           []Debt{},
+		DebtEntries:     []DebtEntry{},
+		CurrencyRates:   []CurrencyRate{},
+	}
+
+	rows, err := h.db.Query(ctx, `
+		SELECT id, category, category_id, asset_type, name, ticker, currency, created_at
+		FROM assets ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a Asset
+		if err := rows.Scan(&a.ID, &a.Category, &a.CategoryID, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.Assets = append(archive.Assets, a)
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(ctx, `
+		SELECT id, asset_id, entry_date, units, COALESCE(notes, ''), created_at
+		FROM asset_entries ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e ArchivedAssetEntry
+		var entryDate time.Time
+		if err := rows.Scan(&e.ID, &e.AssetID, &entryDate, &e.Units, &e.Notes, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.EntryDate = entryDate.Format("2006-01-02")
+		archive.AssetEntries = append(archive.AssetEntries, e)
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(ctx, `
+		SELECT id, asset_id, price_date, unit_value, source, created_at
+		FROM asset_prices ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p AssetPrice
+		var priceDate time.Time
+		if err := rows.Scan(&p.ID, &p.AssetID, &priceDate, &p.UnitValue, &p.Source, &p.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.PriceDate = priceDate.Format("2006-01-02")
+		archive.AssetPrices = append(archive.AssetPrices, p)
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(ctx, `
+		SELECT id, name, currency, interest_rate, created_at
+		FROM debts ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d Debt
+		if err := rows.Scan(&d.ID, &d.Name, &d.Currency, &d.InterestRate, &d.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.Debts = append(archive.Debts, d)
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(ctx, `
+		SELECT id, debt_id, entry_date, principal, monthly_payment, COALESCE(notes, ''), created_at
+		FROM debt_entries ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e DebtEntry
+		var entryDate time.Time
+		if err := rows.Scan(&e.ID, &e.DebtID, &entryDate, &e.Principal, &e.MonthlyPayment, &e.Notes, &e.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.EntryDate = entryDate.Format("2006-01-02")
+		archive.DebtEntries = append(archive.DebtEntries, e)
+	}
+	rows.Close()
+
+	rows, err = h.db.Query(ctx, `
+		SELECT currency, sek_rate, updated_at
+		FROM currency_rates ORDER BY currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c CurrencyRate
+		if err := rows.Scan(&c.Currency, &c.SEKRate, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		archive.CurrencyRates = append(archive.CurrencyRates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if archive.Owners, err = loadOwners(ctx, h.db); err != nil {
+		return nil, err
+	}
+	if archive.AssetOwners, err = loadArchivedOwnership(ctx, h.db, assetOwnership); err != nil {
+		return nil, err
+	}
+	if archive.DebtOwners, err = loadArchivedOwnership(ctx, h.db, debtOwnership); err != nil {
+		return nil, err
+	}
+	if err := loadArchivedSavingsGoals(ctx, h.db, archive); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// loadArchivedSavingsGoals reads the savings goals and their linked assets
+func loadArchivedSavingsGoals(ctx context.Context, q querier, archive *FinancialArchive) error {
+	rows, err := q.Query(ctx, `
+		SELECT id, name, target_amount, target_date, notes, created_at
+		FROM savings_goals ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var g ArchivedSavingsGoal
+		var targetDate *time.Time
+		if err := rows.Scan(&g.ID, &g.Name, &g.TargetAmount, &targetDate, &g.Notes, &g.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if targetDate != nil {
+			d := targetDate.Format("2006-01-02")
+			g.TargetDate = &d
+		}
+		archive.SavingsGoals = append(archive.SavingsGoals, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT goal_id, asset_id, share
+		FROM savings_goal_assets ORDER BY goal_id, asset_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l ArchivedSavingsGoalAsset
+		if err := rows.Scan(&l.GoalID, &l.AssetID, &l.Share); err != nil {
+			return err
+		}
+		archive.SavingsGoalAssets = append(archive.SavingsGoalAssets, l)
+	}
+	return rows.Err()
+}
+
+// loadArchivedOwnership reads every ownership share of assets or debts
+func loadArchivedOwnership(ctx context.Context, q querier, t ownershipTable) ([]ArchivedOwnership, error) {
+	rows, err := q.Query(ctx, `SELECT `+t.column+`, owner_id, share FROM `+t.table+` ORDER BY `+t.column+`, owner_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []ArchivedOwnership{}
+	for rows.Next() {
+		var s ArchivedOwnership
+		if err := rows.Scan(&s.ItemID, &s.OwnerID, &s.Share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+// restoreArchive inserts an archive with its original IDs and resets the ID sequences
+func restoreArchive(ctx context.Context, tx pgx.Tx, a *FinancialArchive) (*RestoreResult, error) {
+	result := &RestoreResult{}
+
+	// Archived categories replace the ones seeded by migrations; parents are
+	// linked in a second pass so rows can be inserted in any order
+	if len(a.AssetCategories) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM asset_categories`); err != nil {
+			return nil, fmt.Errorf("clear asset categories: %w", err)
+		}
+		for _, c := range a.AssetCategories {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO asset_categories (id, name, color, liquidity, target_weight, created_at)
+				VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+			`, c.ID, c.Name, c.Color, c.Liquidity, c.TargetWeight, nullTime(c.CreatedAt))
+			if err != nil {
+				return nil, fmt.Errorf("asset category %d: %w", c.ID, err)
+			}
+			result.AssetCategories++
+		}
+		for _, c := range a.AssetCategories {
+			if c.ParentID == nil {
+				continue
+			}
+			if _, err := tx.Exec(ctx, `UPDATE asset_categories SET parent_id = $1 WHERE id = $2`, *c.ParentID, c.ID); err != nil {
+				return nil, fmt.Errorf("asset category %d: %w", c.ID, err)
+			}
+		}
+	}
+	categoryNames := make(map[int64]string)
+	for _, c := range a.AssetCategories {
+		categoryNames[c.ID] = c.Name
+	}
+
+	for _, asset := range a.Assets {
+		// Older archives only carry the category name
+		var categoryID int64
+		var err error
+		if asset.CategoryID != nil {
+			categoryID, asset.Category = *asset.CategoryID, categoryNames[*asset.CategoryID]
+		} else if categoryID, asset.Category, err = ensureRootCategory(ctx, tx, asset.Category); err != nil {
+			return nil, fmt.Errorf("asset %d: %w", asset.ID, err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO assets (id, category, category_id, asset_type, name, ticker, currency, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, NOW()))
+		`, asset.ID, asset.Category, categoryID, asset.AssetType, asset.Name, asset.Ticker, asset.Currency, nullTime(asset.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("asset %d: %w", asset.ID, err)
+		}
+		result.Assets++
+	}
+
+	for _, e := range a.AssetEntries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO asset_entries (id, asset_id, entry_date, units, notes, created_at)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+		`, e.ID, e.AssetID, e.EntryDate, e.Units, e.Notes, nullTime(e.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("asset entry %d: %w", e.ID, err)
+		}
+		result.AssetEntries++
+	}
+
+	for _, p := range a.AssetPrices {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO asset_prices (id, asset_id, price_date, unit_value, source, created_at)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+		`, p.ID, p.AssetID, p.PriceDate, p.UnitValue, p.Source, nullTime(p.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("asset price %d: %w", p.ID, err)
+		}
+		result.AssetPrices++
+	}
+
+	for _, d := range a.Debts {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO debts (id, name, currency, interest_rate, created_at)
+			VALUES ($1, $2, $3, $4, COALESCE($5, NOW()))
+		`, d.ID, d.Name, d.Currency, d.InterestRate, nullTime(d.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("debt %d: %w", d.ID, err)
+		}
+		result.Debts++
+	}
+
+	for _, e := range a.DebtEntries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO debt_entries (id, debt_id, entry_date, principal, monthly_payment, notes, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, NOW()))
+		`, e.ID, e.DebtID, e.EntryDate, e.Principal, e.MonthlyPayment, e.Notes, nullTime(e.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("debt entry %d: %w", e.ID, err)
+		}
+		result.DebtEntries++
+	}
+
+	// Currency rates are seeded by migrations, so restored rates overwrite them
+	for _, c := range a.CurrencyRates {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO currency_rates (currency, sek_rate, updated_at)
+			VALUES ($1, $2, COALESCE($3, NOW()))
+			ON CONFLICT (currency) DO UPDATE SET sek_rate = EXCLUDED.sek_rate, updated_at = EXCLUDED.updated_at
+		`, c.Currency, c.SEKRate, nullTime(c.UpdatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("currency rate %s: %w", c.Currency, err)
+		}
+		result.CurrencyRates++
+	}
+
+	// Archived owners replace the seeded default owner
+	if len(a.Owners) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM owners`); err != nil {
+			return nil, fmt.Errorf("clear owners: %w", err)
+		}
+		for _, o := range a.Owners {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO owners (id, name, person_id, is_default, created_at)
+				VALUES ($1, $2, $3, $4, COALESCE($5, NOW()))
+			`, o.ID, o.Name, o.PersonID, o.IsDefault, nullTime(o.CreatedAt))
+			if err != nil {
+				return nil, fmt.Errorf("owner %d: %w", o.ID, err)
+			}
+			result.Owners++
+		}
+	}
+	for _, set := range []struct {
+		t      ownershipTable
+		shares []ArchivedOwnership
+	}{{assetOwnership, a.AssetOwners}, {debtOwnership, a.DebtOwners}} {
+		for _, s := range set.shares {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO `+set.t.table+` (`+set.t.column+`, owner_id, share)
+				VALUES ($1, $2, $3)
+			`, s.ItemID, s.OwnerID, s.Share)
+			if err != nil {
+				return nil, fmt.Errorf("%s %d/%d: %w", set.t.table, s.ItemID, s.OwnerID, err)
+			}
+		}
+	}
+	// Older archives have no owners; everything goes to the default owner
+	for _, backfill := range []string{backfillAssetOwners, backfillDebtOwners} {
+		if _, err := tx.Exec(ctx, backfill); err != nil {
+			return nil, fmt.Errorf("assign default owner: %w", err)
+		}
+	}
+
+	for _, g := range a.SavingsGoals {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO savings_goals (id, name, target_amount, target_date, notes, created_at)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()))
+		`, g.ID, g.Name, g.TargetAmount, g.TargetDate, g.Notes, nullTime(g.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("savings goal %d: %w", g.ID, err)
+		}
+		result.SavingsGoals++
+	}
+	for _, l := range a.SavingsGoalAssets {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO savings_goal_assets (goal_id, asset_id, share)
+			VALUES ($1, $2, $3)
+		`, l.GoalID, l.AssetID, l.Share)
+		if err != nil {
+			return nil, fmt.Errorf("savings_goal_assets %d/%d: %w", l.GoalID, l.AssetID, err)
+		}
+	}
+
+	for _, table := range []string{"owners", "asset_categories", "assets", "asset_entries", "asset_prices", "debts", "debt_entries", "savings_goals"} {
+		_, err := tx.Exec(ctx, `
+			SELECT setval(pg_get_serial_sequence('`+table+`', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL)
+			FROM `+table)
+		if err != nil {
+			return nil, fmt.Errorf("reset %s sequence: %w", table, err)
+		}
+	}
+
+	return result, nil
+}
+
+// nullTime maps the zero time to NULL so the column default applies
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// validateArchive checks an archive for consistency before anything is written
+func validateArchive(a *FinancialArchive) []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	validDate := func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	}
+
+	if a.Version != archiveVersion {
+		addf("unsupported archive version %d (expected %d)", a.Version, archiveVersion)
+		return problems
+	}
+
+	categories := make(map[int64]*AssetCategory)
+	for i, c := range a.AssetCategories {
+		if c.ID <= 0 || categories[c.ID] != nil {
+			addf("asset_categories: invalid or duplicate id %d", c.ID)
+		}
+		categories[c.ID] = &a.AssetCategories[i]
+		if strings.TrimSpace(c.Name) == "" {
+			addf("asset_categories %d: name is required", c.ID)
+		}
+		if !hexColor.MatchString(c.Color) {
+			addf("asset_categories %d: invalid color %q", c.ID, c.Color)
+		}
+		if !validLiquidity[c.Liquidity] {
+			addf("asset_categories %d: invalid liquidity %q", c.ID, c.Liquidity)
+		}
+	}
+	tree := buildCategoryTree(a.AssetCategories)
+	for _, c := range a.AssetCategories {
+		if c.ParentID == nil {
+			continue
+		}
+		if categories[*c.ParentID] == nil {
+			addf("asset_categories %d: unknown parent_id %d", c.ID, *c.ParentID)
+		} else if tree.isDescendant(*c.ParentID, c.ID) {
+			addf("asset_categories %d: parent_id %d creates a cycle", c.ID, *c.ParentID)
+		}
+	}
+
+	assetIDs := make(map[int64]bool)
+	for _, asset := range a.Assets {
+		if asset.ID <= 0 || assetIDs[asset.ID] {
+			addf("assets: invalid or duplicate id %d", asset.ID)
+		}
+		assetIDs[asset.ID] = true
+		if strings.TrimSpace(asset.Name) == "" {
+			addf("assets %d: name is required", asset.ID)
+		}
+		if asset.CategoryID != nil {
+			if categories[*asset.CategoryID] == nil {
+				addf("assets %d: unknown category_id %d", asset.ID, *asset.CategoryID)
+			}
+		} else if strings.TrimSpace(asset.Category) == "" {
+			addf("assets %d: category is required", asset.ID)
+		}
+		if asset.AssetType != "stock" && asset.AssetType != "manual" {
+			addf("assets %d: asset_type must be 'stock' or 'manual'", asset.ID)
+		}
+		if len(asset.Currency) != 3 {
+			addf("assets %d: currency must be a 3-letter code", asset.ID)
+		}
+	}
+
+	entryIDs := make(map[int64]bool)
+	for _, e := range a.AssetEntries {
+		if e.ID <= 0 || entryIDs[e.ID] {
+			addf("asset_entries: invalid or duplicate id %d", e.ID)
+		}
+		entryIDs[e.ID] = true
+		if !assetIDs[e.AssetID] {
+			addf("asset_entries %d: unknown asset_id %d", e.ID, e.AssetID)
+		}
+		if !validDate(e.EntryDate) {
+			addf("asset_entries %d: invalid entry_date %q", e.ID, e.EntryDate)
+		}
+	}
+
+	priceIDs := make(map[int64]bool)
+	priceKeys := make(map[string]bool)
+	for _, p := range a.AssetPrices {
+		if p.ID <= 0 || priceIDs[p.ID] {
+			addf("asset_prices: invalid or duplicate id %d", p.ID)
+		}
+		priceIDs[p.ID] = true
+		if !assetIDs[p.AssetID] {
+			addf("asset_prices %d: unknown asset_id %d", p.ID, p.AssetID)
+		}
+		if !validDate(p.PriceDate) {
+			addf("asset_prices %d: invalid price_date %q", p.ID, p.PriceDate)
+		}
+		if p.UnitValue < 0 {
+			addf("asset_prices %d: unit_value must not be negative", p.ID)
+		}
+		key := fmt.Sprintf("%d/%s", p.AssetID, p.PriceDate)
+		if priceKeys[key] {
+			addf("asset_prices %d: duplicate price for asset %d on %s", p.ID, p.AssetID, p.PriceDate)
+		}
+		priceKeys[key] = true
+	}
+
+	debtIDs := make(map[int64]bool)
+	for _, d := range a.Debts {
+		if d.ID <= 0 || debtIDs[d.ID] {
+			addf("debts: invalid or duplicate id %d", d.ID)
+		}
+		debtIDs[d.ID] = true
+		if strings.TrimSpace(d.Name) == "" {
+			addf("debts %d: name is required", d.ID)
+		}
+		if len(d.Currency) != 3 {
+			addf("debts %d: currency must be a 3-letter code", d.ID)
+		}
+	}
+
+	debtEntryIDs := make(map[int64]bool)
+	for _, e := range a.DebtEntries {
+		if e.ID <= 0 || debtEntryIDs[e.ID] {
+			addf("debt_entries: invalid or duplicate id %d", e.ID)
+		}
+		debtEntryIDs[e.ID] = true
+		if !debtIDs[e.DebtID] {
+			addf("debt_entries %d: unknown debt_id %d", e.ID, e.DebtID)
+		}
+		if !validDate(e.EntryDate) {
+			addf("debt_entries %d: invalid entry_date %q", e.ID, e.EntryDate)
+		}
+	}
+
+	ownerIDs := make(map[int64]bool)
+	defaults := 0
+	for _, o := range a.Owners {
+		if o.ID <= 0 || ownerIDs[o.ID] {
+			addf("owners: invalid or duplicate id %d", o.ID)
+		}
+		ownerIDs[o.ID] = true
+		if strings.TrimSpace(o.Name) == "" {
+			addf("owners %d: name is required", o.ID)
+		}
+		if o.IsDefault {
+			defaults++
+		}
+	}
+	if len(a.Owners) > 0 && defaults != 1 {
+		addf("owners: exactly one owner must be the default (found %d)", defaults)
+	}
+	checkShares := func(table string, shares []ArchivedOwnership, itemIDs map[int64]bool) {
+		totals := make(map[int64]float64)
+		seen := make(map[string]bool)
+		for _, s := range shares {
+			key := fmt.Sprintf("%d/%d", s.ItemID, s.OwnerID)
+			if seen[key] {
+				addf("%s: duplicate share %s", table, key)
+			}
+			seen[key] = true
+			if !itemIDs[s.ItemID] {
+				addf("%s: unknown item id %d", table, s.ItemID)
+			}
+			if !ownerIDs[s.OwnerID] {
+				addf("%s %d: unknown owner_id %d", table, s.ItemID, s.OwnerID)
+			}
+			if s.Share <= 0 || s.Share > 100 {
+				addf("%s %d: share must be greater than 0 and at most 100", table, s.ItemID)
+			}
+			totals[s.ItemID] += s.Share
+		}
+		for id, total := range totals {
+			if math.Abs(total-100) > 0.01 {
+				addf("%s %d: shares add up to %.2f, not 100", table, id, total)
+			}
+		}
+	}
+	checkShares("asset_owners", a.AssetOwners, assetIDs)
+	checkShares("debt_owners", a.DebtOwners, debtIDs)
+
+	goalIDs := make(map[int64]bool)
+	for _, g := range a.SavingsGoals {
+		if g.ID <= 0 || goalIDs[g.ID] {
+			addf("savings_goals: invalid or duplicate id %d", g.ID)
+		}
+		goalIDs[g.ID] = true
+		if strings.TrimSpace(g.Name) == "" {
+			addf("savings_goals %d: name is required", g.ID)
+		}
+		if g.TargetAmount <= 0 {
+			addf("savings_goals %d: target_amount must be positive", g.ID)
+		}
+		if g.TargetDate != nil && !validDate(*g.TargetDate) {
+			addf("savings_goals %d: invalid target_date %q", g.ID, *g.TargetDate)
+		}
+	}
+	allocated := make(map[int64]float64)
+	links := make(map[string]bool)
+	for _, l := range a.SavingsGoalAssets {
+		key := fmt.Sprintf("%d/%d", l.GoalID, l.AssetID)
+		if links[key] {
+			addf("savings_goal_assets: duplicate link %s", key)
+		}
+		links[key] = true
+		if !goalIDs[l.GoalID] {
+			addf("savings_goal_assets %s: unknown goal_id %d", key, l.GoalID)
+		}
+		if !assetIDs[l.AssetID] {
+			addf("savings_goal_assets %s: unknown asset_id %d", key, l.AssetID)
+		}
+		if l.Share <= 0 || l.Share > 100 {
+			addf("savings_goal_assets %s: share must be greater than 0 and at most 100", key)
+		}
+		allocated[l.AssetID] += l.Share
+	}
+	for assetID, total := range allocated {
+		if total > 100.01 {
+			addf("savings_goal_assets: asset %d is allocated %.2f%% across goals", assetID, total)
+		}
+	}
+
+	currencies := make(map[string]bool)
+	for _, c := range a.CurrencyRates {
+		if len(c.Currency) != 3 || currencies[c.Currency] {
+			addf("currency_rates: invalid or duplicate currency %q", c.Currency)
+		}
+		currencies[c.Currency] = true
+		if c.SEKRate <= 0 {
+			addf("currency_rates %s: sek_rate must be positive", c.Currency)
+		}
+	}
+
+	return problems
+}
+
+// --- Tabular conversion (CSV and xlsx) ---
+
+// archiveTables flattens an archive into one table per financial table
+func archiveTables(a *FinancialArchive) []exportTable {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	id := func(v int64) string { return strconv.FormatInt(v, 10) }
+	ts := func(t time.Time) string { return t.Format(time.RFC3339Nano) }
+
+	meta := exportTable{Name: "meta", Header: []string{"key", "value"}, Rows: [][]string{
+		{"version", strconv.Itoa(a.Version)},
+		{"exported_at", ts(a.ExportedAt)},
+	}}
+
+	categories := exportTable{Name: "asset_categories", Header: []string{"id", "name", "parent_id", "color", "liquidity", "target_weight", "created_at"}}
+	for _, c := range a.AssetCategories {
+		parent, target := "", ""
+		if c.ParentID != nil {
+			parent = id(*c.ParentID)
+		}
+		if c.TargetWeight != nil {
+			target = f(*c.TargetWeight)
+		}
+		categories.Rows = append(categories.Rows, []string{id(c.ID), c.Name, parent, c.Color, c.Liquidity, target, ts(c.CreatedAt)})
+	}
+
+	assets := exportTable{Name: "assets", Header: []string{"id", "category", "category_id", "asset_type", "name", "ticker", "currency", "created_at"}}
+	for _, asset := range a.Assets {
+		ticker, categoryID := "", ""
+		if asset.Ticker != nil {
+			ticker = *asset.Ticker
+		}
+		if asset.CategoryID != nil {
+			categoryID = id(*asset.CategoryID)
+		}
+		assets.Rows = append(assets.Rows, []string{id(asset.ID), asset.Category, categoryID, asset.AssetType, asset.Name, ticker, asset.Currency, ts(asset.CreatedAt)})
+	}
+
+	entries := exportTable{Name: "asset_entries", Header: []string{"id", "asset_id", "entry_date", "units", "notes", "created_at"}}
+	for _, e := range a.AssetEntries {
+		entries.Rows = append(entries.Rows, []string{id(e.ID), id(e.AssetID), e.EntryDate, f(e.Units), e.Notes, ts(e.CreatedAt)})
+	}
+
+	prices := exportTable{Name: "asset_prices", Header: []string{"id", "asset_id", "price_date", "unit_value", "source", "created_at"}}
+	for _, p := range a.AssetPrices {
+		prices.Rows = append(prices.Rows, []string{id(p.ID), id(p.AssetID), p.PriceDate, f(p.UnitValue), p.Source, ts(p.CreatedAt)})
+	}
+
+	debts := exportTable{Name: "debts", Header: []string{"id", "name", "currency", "interest_rate", "created_at"}}
+	for _, d := range a.Debts {
+		debts.Rows = append(debts.Rows, []string{id(d.ID), d.Name, d.Currency, f(d.InterestRate), ts(d.CreatedAt)})
+	}
+
+	debtEntries := exportTable{Name: "debt_entries", Header: []string{"id", "debt_id", "entry_date", "principal", "monthly_payment", "notes", "created_at"}}
+	for _, e := range a.DebtEntries {
+		debtEntries.Rows = append(debtEntries.Rows, []string{id(e.ID), id(e.DebtID), e.EntryDate, f(e.Principal), f(e.MonthlyPayment), e.Notes, ts(e.CreatedAt)})
+	}
+
+	rates := exportTable{Name: "currency_rates", Header: []string{"currency", "sek_rate", "updated_at"}}
+	for _, c := range a.CurrencyRates {
+		rates.Rows = append(rates.Rows, []string{c.Currency, f(c.SEKRate), ts(c.UpdatedAt)})
+	}
+
+	owners := exportTable{Name: "owners", Header: []string{"id", "name", "person_id", "is_default", "created_at"}}
+	for _, o := range a.Owners {
+		person := ""
+		if o.PersonID != nil {
+			person = id(*o.PersonID)
+		}
+		owners.Rows = append(owners.Rows, []string{id(o.ID), o.Name, person, strconv.FormatBool(o.IsDefault), ts(o.CreatedAt)})
+	}
+
+	assetOwners := exportTable{Name: "asset_owners", Header: []string{"asset_id", "owner_id", "share"}}
+	for _, s := range a.AssetOwners {
+		assetOwners.Rows = append(assetOwners.Rows, []string{id(s.ItemID), id(s.OwnerID), f(s.Share)})
+	}
+
+	debtOwners := exportTable{Name: "debt_owners", Header: []string{"debt_id", "owner_id", "share"}}
+	for _, s := range a.DebtOwners {
+		debtOwners.Rows = append(debtOwners.Rows, []string{id(s.ItemID), id(s.OwnerID), f(s.Share)})
+	}
+
+	goals := exportTable{Name: "savings_goals", Header: []string{"id", "name", "target_amount", "target_date", "notes", "created_at"}}
+	for _, g := range a.SavingsGoals {
+		targetDate := ""
+		if g.TargetDate != nil {
+			targetDate = *g.TargetDate
+		}
+		goals.Rows = append(goals.Rows, []string{id(g.ID), g.Name, f(g.TargetAmount), targetDate, g.Notes, ts(g.CreatedAt)})
+	}
+
+	goalAssets := exportTable{Name: "savings_goal_assets", Header: []string{"goal_id", "asset_id", "share"}}
+	for _, l := range a.SavingsGoalAssets {
+		goalAssets.Rows = append(goalAssets.Rows, []string{id(l.GoalID), id(l.AssetID), f(l.Share)})
+	}
+
+	return []exportTable{meta, categories, assets, entries, prices, debts, debtEntries, rates, owners, assetOwners, debtOwners, goals, goalAssets}
+}
+
+// tableRow reads typed values from one row of an exportTable, collecting parse problems
+type tableRow struct {
+	table    string
+	line     int
+	cols     map[string]int
+	values   []string
+	problems *[]string
+}
+
+func (r tableRow) str(col string) string {
+	if i, ok := r.cols[col]; ok && i < len(r.values) {
+		return strings.TrimSpace(r.values[i])
+	}
+	return ""
+}
+
+func (r tableRow) fail(col, value string) {
+	*r.problems = append(*r.problems, fmt.Sprintf("%s row %d: invalid %s %q", r.table, r.line, col, value))
+}
+
+func (r tableRow) int64(col string) int64 {
+	s := r.str(col)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		// Spreadsheets may turn integers into floats ("12.0")
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil || f != float64(int64(f)) {
+			r.fail(col, s)
+			return 0
+		}
+		n = int64(f)
+	}
+	return n
+}
+
+// optionalInt64 returns nil for an empty cell
+func (r tableRow) optionalInt64(col string) *int64 {
+	if r.str(col) == "" {
+		return nil
+	}
+	n := r.int64(col)
+	return &n
+}
+
+// optionalFloat returns nil for an empty cell
+func (r tableRow) optionalFloat(col string) *float64 {
+	if r.str(col) == "" {
+		return nil
+	}
+	f := r.float(col)
+	return &f
+}
+
+func (r tableRow) float(col string) float64 {
+	s := r.str(col)
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		r.fail(col, s)
+	}
+	return f
+}
+
+func (r tableRow) bool(col string) bool {
+	s := r.str(col)
+	if s == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		r.fail(col, s)
+	}
+	return b
+}
+
+func (r tableRow) timestamp(col string) time.Time {
+	s := r.str(col)
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		r.fail(col, s)
+	}
+	return t
+}
+
+// optionalTables were added after the first archives were written
+var optionalTables = map[string]bool{
+	"asset_categories": true,
+	"owners":           true,
+	"asset_owners":     true,
+	"debt_owners":      true,
+
+	"savings_goals":       true,
+	"savings_goal_assets": true,
+}
+
+// archiveFromTables rebuilds an archive from its tabular form
+func archiveFromTables(tables []exportTable) (*FinancialArchive, []string) {
+	var problems []string
+	byName := make(map[string]exportTable)
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+
+	// each calls fn for every non-empty row of the named table
+	each := func(name string, required []string, fn func(tableRow)) {
+		t, ok := byName[name]
+		if !ok && optionalTables[name] {
+			return
+		}
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing table %s", name))
+			return
+		}
+		cols := make(map[string]int)
+		for i, h := range t.Header {
+			cols[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		for _, col := range required {
+			if _, ok := cols[col]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing column %s", name, col))
+				return
+			}
+		}
+		for i, values := range t.Rows {
+			if strings.TrimSpace(strings.Join(values, "")) == "" {
+				continue
+			}
+			fn(tableRow{table: name, line: i + 2, cols: cols, values: values, problems: &problems})
+		}
+	}
+
+	a := &FinancialArchive{}
+
+	each("meta", []string{"key", "value"}, func(r tableRow) {
+		switch r.str("key") {
+		case "version":
+			a.Version = int(r.int64("value"))
+		case "exported_at":
+			a.ExportedAt = r.timestamp("value")
+		}
+	})
+
+	each("asset_categories", []string{"id", "name"}, func(r tableRow) {
+		c := AssetCategory{
+			ID:           r.int64("id"),
+			Name:         r.str("name"),
+			ParentID:     r.optionalInt64("parent_id"),
+			Color:        r.str("color"),
+			Liquidity:    r.str("liquidity"),
+			TargetWeight: r.optionalFloat("target_weight"),
+			CreatedAt:    r.timestamp("created_at"),
+		}
+		if c.Color == "" {
+			c.Color = "#6366f1"
+		}
+		if c.Liquidity == "" {
+			c.Liquidity = "semi_liquid"
+		}
+		a.AssetCategories = append(a.AssetCategories, c)
+	})
+
+	each("assets", []string{"id", "category", "asset_type", "name", "currency"}, func(r tableRow) {
+		asset := Asset{
+			ID:         r.int64("id"),
+			Category:   r.str("category"),
+			CategoryID: r.optionalInt64("category_id"),
+			AssetType:  r.str("asset_type"),
+			Name:       r.str("name"),
+			Currency:   r.str("currency"),
+			CreatedAt:  r.timestamp("created_at"),
+		}
+		if ticker := r.str("ticker"); ticker != "" {
+			asset.Ticker = &ticker
+		}
+		a.Assets = append(a.Assets, asset)
+	})
+
+	each("asset_entries", []string{"id", "asset_id", "entry_date", "units"}, func(r tableRow) {
+		a.AssetEntries = append(a.AssetEntries, ArchivedAssetEntry{
+			ID:        r.int64("id"),
+			AssetID:   r.int64("asset_id"),
+			EntryDate: r.str("entry_date"),
+			Units:     r.float("units"),
+			Notes:     r.str("notes"),
+			CreatedAt: r.timestamp("created_at"),
+		})
+	})
+
+	each("asset_prices", []string{"id", "asset_id", "price_date", "unit_value"}, func(r tableRow) {
+		source := r.str("source")
+		if source == "" {
+			source = "manual"
+		}
+		a.AssetPrices = append(a.AssetPrices, AssetPrice{
+			ID:        r.int64("id"),
+			AssetID:   r.int64("asset_id"),
+			PriceDate: r.str("price_date"),
+			UnitValue: r.float("unit_value"),
+			Source:    source,
+			CreatedAt: r.timestamp("created_at"),
+		})
+	})
+
+	each("debts", []string{"id", "name", "currency"}, func(r tableRow) {
+		a.Debts = append(a.Debts, Debt{
+			ID:           r.int64("id"),
+			Name:         r.str("name"),
+			Currency:     r.str("currency"),
+			InterestRate: r.float("interest_rate"),
+			CreatedAt:    r.timestamp("created_at"),
+		})
+	})
+
+	each("debt_entries", []string{"id", "debt_id", "entry_date", "principal"}, func(r tableRow) {
+		a.DebtEntries = append(a.DebtEntries, DebtEntry{
+			ID:             r.int64("id"),
+			DebtID:         r.int64("debt_id"),
+			EntryDate:      r.str("entry_date"),
+			Principal:      r.float("principal"),
+			MonthlyPayment: r.float("monthly_payment"),
+			Notes:          r.str("notes"),
+			CreatedAt:      r.timestamp("created_at"),
+		})
+	})
+
+	each("currency_rates", []string{"currency", "sek_rate"}, func(r tableRow) {
+		a.CurrencyRates = append(a.CurrencyRates, CurrencyRate{
+			Currency:  r.str("currency"),
+			SEKRate:   r.float("sek_rate"),
+			UpdatedAt: r.timestamp("updated_at"),
+		})
+	})
+
+	each("owners", []string{"id", "name"}, func(r tableRow) {
+		a.Owners = append(a.Owners, Owner{
+			ID:        r.int64("id"),
+			Name:      r.str("name"),
+			PersonID:  r.optionalInt64("person_id"),
+			IsDefault: r.bool("is_default"),
+			CreatedAt: r.timestamp("created_at"),
+		})
+	})
+
+	each("asset_owners", []string{"asset_id", "owner_id", "share"}, func(r tableRow) {
+		a.AssetOwners = append(a.AssetOwners, ArchivedOwnership{
+			ItemID:  r.int64("asset_id"),
+			OwnerID: r.int64("owner_id"),
+			Share:   r.float("share"),
+		})
+	})
+
+	each("debt_owners", []string{"debt_id", "owner_id", "share"}, func(r tableRow) {
+		a.DebtOwners = append(a.DebtOwners, ArchivedOwnership{
+			ItemID:  r.int64("debt_id"),
+			OwnerID: r.int64("owner_id"),
+			Share:   r.float("share"),
+		})
+	})
+
+	each("savings_goals", []string{"id", "name", "target_amount"}, func(r tableRow) {
+		g := ArchivedSavingsGoal{
+			ID:           r.int64("id"),
+			Name:         r.str("name"),
+			TargetAmount: r.float("target_amount"),
+			Notes:        r.str("notes"),
+			CreatedAt:    r.timestamp("created_at"),
+		}
+		if targetDate := r.str("target_date"); targetDate != "" {
+			g.TargetDate = &targetDate
+		}
+		a.SavingsGoals = append(a.SavingsGoals, g)
+	})
+
+	each("savings_goal_assets", []string{"goal_id", "asset_id", "share"}, func(r tableRow) {
+		a.SavingsGoalAssets = append(a.SavingsGoalAssets, ArchivedSavingsGoalAsset{
+			GoalID:  r.int64("goal_id"),
+			AssetID: r.int64("asset_id"),
+			Share:   r.float("share"),
+		})
+	})
+
+	return a, problems
+}
+
+// writeCSVZip writes one CSV file per table into a zip archive
+func writeCSVZip(w io.Writer, tables []exportTable) error {
+	zw := zip.NewWriter(w)
+	for _, t := range tables {
+		fw, err := zw.Create(t.Name + ".csv")
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(fw)
+		if err := cw.Write(t.Header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(t.Rows); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// readCSVZip reads every CSV file in a zip archive back into tables
+func readCSVZip(data []byte) ([]exportTable, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid zip file: %w", err)
+	}
+
+	var tables []exportTable
+	for _, f := range zr.File {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		records, err := csv.NewReader(rc).ReadAll()
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+
+		t := exportTable{Name: strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))}
+		if len(records) > 0 {
+			t.Header = records[0]
+			t.Rows = records[1:]
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, nil
+}
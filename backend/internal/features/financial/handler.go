@@ -8,6 +8,8 @@ import (
 
 	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,7 +31,7 @@ func (h *Handler) ListAssets(w http.ResponseWriter, r *http.Request) {
 	// Get all assets with their latest entry and latest price
 	rows, err := h.db.Query(r.Context(), `
 		SELECT 
-			a.id, a.category, a.asset_type, a.name, a.ticker, a.currency, a.created_at,
+			a.id, a.category, a.category_id, a.asset_type, a.name, a.ticker, a.currency, a.created_at,
 			e.id, e.entry_date, e.units, e.notes, e.created_at,
 			p.unit_value
 		FROM assets a
@@ -64,7 +66,7 @@ func (h *Handler) ListAssets(w http.ResponseWriter, r *http.Request) {
 		var latestPrice *float64
 
 		err := rows.Scan(
-			&a.ID, &a.Category, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt,
+			&a.ID, &a.Category, &a.CategoryID, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt,
 			&entryID, &entryDate, &entryUnits, &entryNotes, &entryCreatedAt,
 			&latestPrice,
 		)
@@ -98,11 +100,14 @@ func (h *Handler) ListAssets(w http.ResponseWriter, r *http.Request) {
 }
 
 type CreateAssetRequest struct {
-	Category  string  `json:"category"`
-	AssetType string  `json:"asset_type"`
-	Name      string  `json:"name"`
-	Ticker    *string `json:"ticker,omitempty"`
-	Currency  string  `json:"currency"` // ISO 4217 code, defaults to SEK
+	Category   string  `json:"category"` // category name, used when CategoryID is not set
+	CategoryID *int64  `json:"category_id,omitempty"`
+	AssetType  string  `json:"asset_type"`
+	Name       string  `json:"name"`
+	Ticker     *string `json:"ticker,omitempty"`
+	Currency   string  `json:"currency"` // ISO 4217 code, defaults to SEK
+	// Ownership shares; defaults to 100% for the default owner
+	Owners []Ownership `json:"owners,omitempty"`
 	// Initial entry
 	EntryDate string  `json:"entry_date"`
 	Units     float64 `json:"units"`
@@ -126,28 +131,43 @@ func (h *Handler) CreateAsset(w http.ResponseWriter, r *http.Request) {
 	if req.EntryDate == "" {
 		req.EntryDate = time.Now().Format("2006-01-02")
 	}
+	if err := validateShares(req.Owners); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	tx, err := h.db.Begin(r.Context())
+	tx, err := h.beginAudited(r)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	defer tx.Rollback(r.Context())
 
+	categoryID, categoryName, err := resolveCategory(r.Context(), tx, req.CategoryID, req.Category)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Insert asset
 	var asset Asset
 	err = tx.QueryRow(r.Context(), `
-		INSERT INTO assets (category, asset_type, name, ticker, currency)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, category, asset_type, name, ticker, currency, created_at
-	`, req.Category, req.AssetType, req.Name, req.Ticker, req.Currency).Scan(
-		&asset.ID, &asset.Category, &asset.AssetType, &asset.Name, &asset.Ticker, &asset.Currency, &asset.CreatedAt,
+		INSERT INTO assets (category, category_id, asset_type, name, ticker, currency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, category, category_id, asset_type, name, ticker, currency, created_at
+	`, categoryName, categoryID, req.AssetType, req.Name, req.Ticker, req.Currency).Scan(
+		&asset.ID, &asset.Category, &asset.CategoryID, &asset.AssetType, &asset.Name, &asset.Ticker, &asset.Currency, &asset.CreatedAt,
 	)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if err := assetOwnership.replace(r.Context(), tx, asset.ID, req.Owners); err != nil {
+		core.WriteError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+
 	// Insert initial entry (units only)
 	var entry AssetEntry
 	var entryDate time.Time
@@ -201,9 +221,9 @@ func (h *Handler) GetAsset(w http.ResponseWriter, r *http.Request) {
 
 	var a Asset
 	err = h.db.QueryRow(r.Context(), `
-		SELECT id, category, asset_type, name, ticker, currency, created_at
+		SELECT id, category, category_id, asset_type, name, ticker, currency, created_at
 		FROM assets WHERE id = $1
-	`, id).Scan(&a.ID, &a.Category, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt)
+	`, id).Scan(&a.ID, &a.Category, &a.CategoryID, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt)
 	if err != nil {
 		core.WriteError(w, http.StatusNotFound, "asset not found")
 		return
@@ -212,6 +232,71 @@ func (h *Handler) GetAsset(w http.ResponseWriter, r *http.Request) {
 	core.WriteJSON(w, http.StatusOK, a)
 }
 
+type UpdateAssetRequest struct {
+	Category   string  `json:"category"`
+	CategoryID *int64  `json:"category_id,omitempty"`
+	AssetType  string  `json:"asset_type"`
+	Name       string  `json:"name"`
+	Ticker     *string `json:"ticker,omitempty"`
+	Currency   string  `json:"currency"`
+}
+
+// UpdateAsset edits asset metadata, including moving it to another category
+func (h *Handler) UpdateAsset(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req UpdateAssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.AssetType == "" {
+		req.AssetType = "manual"
+	}
+	if req.Currency == "" {
+		req.Currency = "SEK"
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	categoryID, categoryName, err := resolveCategory(r.Context(), tx, req.CategoryID, req.Category)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var a Asset
+	err = tx.QueryRow(r.Context(), `
+		UPDATE assets
+		SET category = $1, category_id = $2, asset_type = $3, name = $4, ticker = $5, currency = $6
+		WHERE id = $7
+		RETURNING id, category, category_id, asset_type, name, ticker, currency, created_at
+	`, categoryName, categoryID, req.AssetType, req.Name, req.Ticker, req.Currency, id).Scan(
+		&a.ID, &a.Category, &a.CategoryID, &a.AssetType, &a.Name, &a.Ticker, &a.Currency, &a.CreatedAt,
+	)
+	if err != nil {
+		core.WriteError(w, http.StatusNotFound, "asset not found")
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, a)
+}
+
 func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
@@ -219,7 +304,10 @@ func (h *Handler) DeleteAsset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.db.Exec(r.Context(), `DELETE FROM assets WHERE id = $1`, id)
+	err = h.audited(r, func(tx pgx.Tx) error {
+		_, err := tx.Exec(r.Context(), `DELETE FROM assets WHERE id = $1`, id)
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -290,7 +378,7 @@ func (h *Handler) CreateAssetEntry(w http.ResponseWriter, r *http.Request) {
 		req.EntryDate = time.Now().Format("2006-01-02")
 	}
 
-	tx, err := h.db.Begin(r.Context())
+	tx, err := h.beginAudited(r)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -352,7 +440,7 @@ func (h *Handler) UpdateAssetEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := h.db.Begin(r.Context())
+	tx, err := h.beginAudited(r)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -409,7 +497,11 @@ func (h *Handler) DeleteAssetEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.db.Exec(r.Context(), `DELETE FROM asset_entries WHERE id = $1 AND asset_id = $2`, entryID, assetID)
+	var result pgconn.CommandTag
+	err = h.audited(r, func(tx pgx.Tx) error {
+		result, err = tx.Exec(r.Context(), `DELETE FROM asset_entries WHERE id = $1 AND asset_id = $2`, entryID, assetID)
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -484,6 +576,8 @@ type CreateDebtRequest struct {
 	Name         string  `json:"name"`
 	Currency     string  `json:"currency"` // ISO 4217 code, defaults to SEK
 	InterestRate float64 `json:"interest_rate"`
+	// Ownership shares; defaults to 100% for the default owner
+	Owners []Ownership `json:"owners,omitempty"`
 	// Initial entry
 	EntryDate      string  `json:"entry_date"`
 	Principal      float64 `json:"principal"`
@@ -497,6 +591,10 @@ func (h *Handler) CreateDebt(w http.ResponseWriter, r *http.Request) {
 		core.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if err := validateShares(req.Owners); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	if req.Currency == "" {
 		req.Currency = "SEK"
@@ -505,7 +603,7 @@ func (h *Handler) CreateDebt(w http.ResponseWriter, r *http.Request) {
 		req.EntryDate = time.Now().Format("2006-01-02")
 	}
 
-	tx, err := h.db.Begin(r.Context())
+	tx, err := h.beginAudited(r)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -523,6 +621,11 @@ func (h *Handler) CreateDebt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := debtOwnership.replace(r.Context(), tx, debt.ID, req.Owners); err != nil {
+		core.WriteError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+
 	var entry DebtEntry
 	var entryDate time.Time
 	err = tx.QueryRow(r.Context(), `
@@ -578,7 +681,10 @@ func (h *Handler) DeleteDebt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.db.Exec(r.Context(), `DELETE FROM debts WHERE id = $1`, id)
+	err = h.audited(r, func(tx pgx.Tx) error {
+		_, err := tx.Exec(r.Context(), `DELETE FROM debts WHERE id = $1`, id)
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -649,13 +755,15 @@ func (h *Handler) CreateDebtEntry(w http.ResponseWriter, r *http.Request) {
 
 	var entry DebtEntry
 	var entryDate time.Time
-	err = h.db.QueryRow(r.Context(), `
-		INSERT INTO debt_entries (debt_id, entry_date, principal, monthly_payment, notes)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, debt_id, entry_date, principal, monthly_payment, notes, created_at
-	`, debtID, req.EntryDate, req.Principal, req.MonthlyPayment, req.Notes).Scan(
-		&entry.ID, &entry.DebtID, &entryDate, &entry.Principal, &entry.MonthlyPayment, &entry.Notes, &entry.CreatedAt,
-	)
+	err = h.audited(r, func(tx pgx.Tx) error {
+		return tx.QueryRow(r.Context(), `
+			INSERT INTO debt_entries (debt_id, entry_date, principal, monthly_payment, notes)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, debt_id, entry_date, principal, monthly_payment, notes, created_at
+		`, debtID, req.EntryDate, req.Principal, req.MonthlyPayment, req.Notes).Scan(
+			&entry.ID, &entry.DebtID, &entryDate, &entry.Principal, &entry.MonthlyPayment, &entry.Notes, &entry.CreatedAt,
+		)
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -685,14 +793,16 @@ func (h *Handler) UpdateDebtEntry(w http.ResponseWriter, r *http.Request) {
 
 	var entry DebtEntry
 	var entryDate time.Time
-	err = h.db.QueryRow(r.Context(), `
-		UPDATE debt_entries 
-		SET entry_date = $1, principal = $2, monthly_payment = $3, notes = $4
-		WHERE id = $5 AND debt_id = $6
-		RETURNING id, debt_id, entry_date, principal, monthly_payment, notes, created_at
-	`, req.EntryDate, req.Principal, req.MonthlyPayment, req.Notes, entryID, debtID).Scan(
-		&entry.ID, &entry.DebtID, &entryDate, &entry.Principal, &entry.MonthlyPayment, &entry.Notes, &entry.CreatedAt,
-	)
+	err = h.audited(r, func(tx pgx.Tx) error {
+		return tx.QueryRow(r.Context(), `
+			UPDATE debt_entries
+			SET entry_date = $1, principal = $2, monthly_payment = $3, notes = $4
+			WHERE id = $5 AND debt_id = $6
+			RETURNING id, debt_id, entry_date, principal, monthly_payment, notes, created_at
+		`, req.EntryDate, req.Principal, req.MonthlyPayment, req.Notes, entryID, debtID).Scan(
+			&entry.ID, &entry.DebtID, &entryDate, &entry.Principal, &entry.MonthlyPayment, &entry.Notes, &entry.CreatedAt,
+		)
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusNotFound, "entry not found")
 		return
@@ -714,7 +824,11 @@ func (h *Handler) DeleteDebtEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.db.Exec(r.Context(), `DELETE FROM debt_entries WHERE id = $1 AND debt_id = $2`, entryID, debtID)
+	var result pgconn.CommandTag
+	err = h.audited(r, func(tx pgx.Tx) error {
+		result, err = tx.Exec(r.Context(), `DELETE FROM debt_entries WHERE id = $1 AND debt_id = $2`, entryID, debtID)
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -735,82 +849,57 @@ func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 		asOfDate = time.Now().Format("2006-01-02")
 	}
 
-	// Get total assets as of date (converted to SEK)
-	var totalAssets float64
-	err := h.db.QueryRow(r.Context(), `
-		SELECT COALESCE(SUM(e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1)), 0)
-		FROM assets a
-		JOIN LATERAL (
-			SELECT units FROM asset_entries 
-			WHERE asset_id = a.id AND entry_date <= $1
-			ORDER BY entry_date DESC, created_at DESC 
-			LIMIT 1
-		) e ON true
-		LEFT JOIN LATERAL (
-			SELECT unit_value FROM asset_prices 
-			WHERE asset_id = a.id AND price_date <= $1
-			ORDER BY price_date DESC, created_at DESC 
-			LIMIT 1
-		) p ON true
-		LEFT JOIN currency_rates cr ON cr.currency = a.currency
-	`, asOfDate).Scan(&totalAssets)
+	// ?owner= scales every value to that owner's share; without it the household totals are reported
+	owner, err := h.ownerFromRequest(r)
 	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		writeOwnerError(w, err)
 		return
 	}
 
-	// Get total debt as of date (converted to SEK)
-	var totalDebt float64
-	err = h.db.QueryRow(r.Context(), `
-		SELECT COALESCE(SUM(e.principal * COALESCE(cr.sek_rate, 1)), 0)
-		FROM debts d
-		JOIN LATERAL (
-			SELECT principal FROM debt_entries 
-			WHERE debt_id = d.id AND entry_date <= $1
-			ORDER BY entry_date DESC, created_at DESC 
-			LIMIT 1
-		) e ON true
-		LEFT JOIN currency_rates cr ON cr.currency = d.currency
-	`, asOfDate).Scan(&totalDebt)
+	// Get totals as of date (converted to SEK)
+	totalAssets, totalDebt, err := h.totalsAsOf(r.Context(), asOfDate, ownerID(owner))
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Get breakdown by category (converted to SEK)
-	rows, err := h.db.Query(r.Context(), `
-		SELECT a.category, COALESCE(SUM(e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1)), 0) as total
-		FROM assets a
-		JOIN LATERAL (
-			SELECT units FROM asset_entries 
-			WHERE asset_id = a.id AND entry_date <= $1
-			ORDER BY entry_date DESC, created_at DESC 
-			LIMIT 1
-		) e ON true
-		LEFT JOIN LATERAL (
-			SELECT unit_value FROM asset_prices 
-			WHERE asset_id = a.id AND price_date <= $1
-			ORDER BY price_date DESC, created_at DESC 
-			LIMIT 1
-		) p ON true
-		LEFT JOIN currency_rates cr ON cr.currency = a.currency
-		GROUP BY a.category
-	`, asOfDate)
+	// Get breakdown by category (converted to SEK), rolled up to ?category_level (default: leaf categories)
+	level := -1
+	if l := r.URL.Query().Get("category_level"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 {
+			core.WriteError(w, http.StatusBadRequest, "category_level must be a non-negative integer")
+			return
+		}
+		level = n
+	}
+
+	categories, err := loadCategories(r.Context(), h.db)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer rows.Close()
+	values, err := h.categoryValues(r.Context(), asOfDate, ownerID(owner))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tree := buildCategoryTree(categories)
 
 	byCategory := make(map[string]float64)
-	for rows.Next() {
-		var cat string
-		var total float64
-		if err := rows.Scan(&cat, &total); err != nil {
-			core.WriteError(w, http.StatusInternalServerError, err.Error())
-			return
+	byLiquidity := make(map[string]float64)
+	for id, total := range values {
+		c, ok := tree.byID[id]
+		if !ok {
+			byCategory["Uncategorized"] += total
+			byLiquidity["semi_liquid"] += total
+			continue
+		}
+		byLiquidity[c.Liquidity] += total
+		if level >= 0 {
+			c = tree.byID[tree.ancestorAt(id, level)]
 		}
-		byCategory[cat] = total
+		byCategory[c.Path] += total
 	}
 
 	dashboard := Dashboard{
@@ -819,7 +908,18 @@ func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 		NetWorth:        totalAssets - totalDebt,
 		AsOfDate:        asOfDate,
 		ByCategory:      byCategory,
+		ByLiquidity:     byLiquidity,
 		DisplayCurrency: "SEK",
+		Owner:           ownerLabel(owner),
+	}
+
+	// Household view also shows how the totals split between owners
+	if owner == nil {
+		dashboard.ByOwner, err = h.ownerTotals(r.Context(), asOfDate)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	}
 
 	core.WriteJSON(w, http.StatusOK, dashboard)
@@ -827,6 +927,28 @@ func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 
 // GetHistory returns net worth history over time
 func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.ownerFromRequest(r)
+	if err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+
+	// ?known_at= reconstructs the history as it looked at that time, before later edits
+	if knownAt := r.URL.Query().Get("known_at"); knownAt != "" {
+		at, err := parseAuditTime(knownAt)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		snapshot, err := h.snapshotAt(r.Context(), at)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		core.WriteJSON(w, http.StatusOK, snapshot.history(ownerID(owner)))
+		return
+	}
+
 	// Get all unique dates from entries and prices
 	rows, err := h.db.Query(r.Context(), `
 		SELECT DISTINCT entry_date FROM (
@@ -857,37 +979,11 @@ func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	var history []NetWorthDataPoint
 	for _, date := range dates {
 		// Calculate totals as of each date (converted to SEK)
-		var totalAssets float64
-		h.db.QueryRow(r.Context(), `
-			SELECT COALESCE(SUM(e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1)), 0)
-			FROM assets a
-			JOIN LATERAL (
-				SELECT units FROM asset_entries 
-				WHERE asset_id = a.id AND entry_date <= $1
-				ORDER BY entry_date DESC, created_at DESC 
-				LIMIT 1
-			) e ON true
-			LEFT JOIN LATERAL (
-				SELECT unit_value FROM asset_prices 
-				WHERE asset_id = a.id AND price_date <= $1
-				ORDER BY price_date DESC, created_at DESC 
-				LIMIT 1
-			) p ON true
-			LEFT JOIN currency_rates cr ON cr.currency = a.currency
-		`, date).Scan(&totalAssets)
-
-		var totalDebt float64
-		h.db.QueryRow(r.Context(), `
-			SELECT COALESCE(SUM(e.principal * COALESCE(cr.sek_rate, 1)), 0)
-			FROM debts d
-			JOIN LATERAL (
-				SELECT principal FROM debt_entries 
-				WHERE debt_id = d.id AND entry_date <= $1
-				ORDER BY entry_date DESC, created_at DESC 
-				LIMIT 1
-			) e ON true
-			LEFT JOIN currency_rates cr ON cr.currency = d.currency
-		`, date).Scan(&totalDebt)
+		totalAssets, totalDebt, err := h.totalsAsOf(r.Context(), date, ownerID(owner))
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 
 		history = append(history, NetWorthDataPoint{
 			Date:        date,
@@ -902,6 +998,12 @@ func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 // GetDetailedHistory returns net worth history with per-item breakdown
 func (h *Handler) GetDetailedHistory(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.ownerFromRequest(r)
+	if err != nil {
+		writeOwnerError(w, err)
+		return
+	}
+
 	// Get all unique dates from entries and prices
 	rows, err := h.db.Query(r.Context(), `
 		SELECT DISTINCT entry_date FROM (
@@ -929,13 +1031,13 @@ func (h *Handler) GetDetailedHistory(w http.ResponseWriter, r *http.Request) {
 		dates = append(dates, d.Format("2006-01-02"))
 	}
 
-	// Get all assets with currency info
+	// Get all assets with currency info and the owner's share
 	assetRows, err := h.db.Query(r.Context(), `
-		SELECT a.id, a.name, COALESCE(cr.sek_rate, 1) as sek_rate
+		SELECT a.id, a.name, COALESCE(cr.sek_rate, 1) * `+assetShareSQL(1)+` as sek_rate
 		FROM assets a
 		LEFT JOIN currency_rates cr ON cr.currency = a.currency
 		ORDER BY a.name
-	`)
+	`, ownerID(owner))
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -955,17 +1057,20 @@ func (h *Handler) GetDetailedHistory(w http.ResponseWriter, r *http.Request) {
 			core.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		if a.SEKRate == 0 {
+			continue // not held by the requested owner
+		}
 		assets = append(assets, a)
 		assetNames = append(assetNames, a.Name)
 	}
 
-	// Get all debts with currency info
+	// Get all debts with currency info and the owner's share
 	debtRows, err := h.db.Query(r.Context(), `
-		SELECT d.id, d.name, COALESCE(cr.sek_rate, 1) as sek_rate
+		SELECT d.id, d.name, COALESCE(cr.sek_rate, 1) * `+debtShareSQL(1)+` as sek_rate
 		FROM debts d
 		LEFT JOIN currency_rates cr ON cr.currency = d.currency
 		ORDER BY d.name
-	`)
+	`, ownerID(owner))
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -985,6 +1090,9 @@ func (h *Handler) GetDetailedHistory(w http.ResponseWriter, r *http.Request) {
 			core.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		if d.SEKRate == 0 {
+			continue // not held by the requested owner
+		}
 		debts = append(debts, d)
 		debtNames = append(debtNames, d.Name)
 	}
@@ -1099,12 +1207,14 @@ func (h *Handler) UpsertCurrencyRate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var rate CurrencyRate
-	err := h.db.QueryRow(r.Context(), `
-		INSERT INTO currency_rates (currency, sek_rate, updated_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (currency) DO UPDATE SET sek_rate = $2, updated_at = NOW()
-		RETURNING currency, sek_rate, updated_at
-	`, req.Currency, req.SEKRate).Scan(&rate.Currency, &rate.SEKRate, &rate.UpdatedAt)
+	err := h.audited(r, func(tx pgx.Tx) error {
+		return tx.QueryRow(r.Context(), `
+			INSERT INTO currency_rates (currency, sek_rate, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (currency) DO UPDATE SET sek_rate = $2, updated_at = NOW()
+			RETURNING currency, sek_rate, updated_at
+		`, req.Currency, req.SEKRate).Scan(&rate.Currency, &rate.SEKRate, &rate.UpdatedAt)
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1125,7 +1235,12 @@ func (h *Handler) DeleteCurrencyRate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.db.Exec(r.Context(), `DELETE FROM currency_rates WHERE currency = $1`, currency)
+	var result pgconn.CommandTag
+	err := h.audited(r, func(tx pgx.Tx) error {
+		var err error
+		result, err = tx.Exec(r.Context(), `DELETE FROM currency_rates WHERE currency = $1`, currency)
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1192,7 +1307,11 @@ func (h *Handler) CreateAssetPrice(w http.ResponseWriter, r *http.Request) {
 		req.PriceDate = time.Now().Format("2006-01-02")
 	}
 
-	price, err := h.prices.UpdateAssetPrice(r.Context(), assetID, req.PriceDate, req.UnitValue, "manual")
+	var price *AssetPrice
+	err = h.audited(r, func(tx pgx.Tx) error {
+		price, err = storeAssetPrice(r.Context(), tx, assetID, req.PriceDate, req.UnitValue, "manual")
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1234,7 +1353,11 @@ func (h *Handler) FetchAssetPrice(w http.ResponseWriter, r *http.Request) {
 
 	// Store the price
 	today := time.Now().Format("2006-01-02")
-	assetPrice, err := h.prices.UpdateAssetPrice(r.Context(), assetID, today, price, "yahoo")
+	var assetPrice *AssetPrice
+	err = h.audited(r, func(tx pgx.Tx) error {
+		assetPrice, err = storeAssetPrice(r.Context(), tx, assetID, today, price, "yahoo")
+		return err
+	})
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -102,8 +102,101 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
 
 		// Drop unit_value column from asset_entries (no longer needed)
 		`ALTER TABLE asset_entries DROP COLUMN IF EXISTS unit_value`,
+
+		// Asset categories: hierarchy replacing the free-text assets.category
+		`CREATE TABLE IF NOT EXISTS asset_categories (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			parent_id BIGINT REFERENCES asset_categories(id) ON DELETE RESTRICT,
+			color VARCHAR(7) NOT NULL DEFAULT '#6366f1',
+			liquidity VARCHAR(20) NOT NULL DEFAULT 'semi_liquid',
+			target_weight DECIMAL(5, 2),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_asset_categories_name ON asset_categories (COALESCE(parent_id, 0), LOWER(name))`,
+
+		// Seed the categories the frontend has always offered
+		`INSERT INTO asset_categories (name, liquidity)
+		SELECT v.name, v.liquidity
+		FROM (VALUES
+			('Real Estate', 'illiquid'),
+			('Equity - Public', 'liquid'),
+			('Equity - Private', 'illiquid'),
+			('Cash', 'cash'),
+			('Other', 'semi_liquid')
+		) AS v(name, liquidity)
+		WHERE NOT EXISTS (SELECT 1 FROM asset_categories)`,
+
+		// Migrate existing category strings (case-insensitive, blank -> Other) into root categories
+		`INSERT INTO asset_categories (name)
+		SELECT DISTINCT ON (LOWER(cat)) cat
+		FROM (SELECT COALESCE(NULLIF(TRIM(category), ''), 'Other') AS cat FROM assets) a
+		WHERE NOT EXISTS (
+			SELECT 1 FROM asset_categories c
+			WHERE c.parent_id IS NULL AND LOWER(c.name) = LOWER(a.cat)
+		)
+		ORDER BY LOWER(cat), cat`,
+		`ALTER TABLE assets ADD COLUMN IF NOT EXISTS category_id BIGINT REFERENCES asset_categories(id) ON DELETE RESTRICT`,
+		`UPDATE assets a SET category_id = c.id, category = c.name
+		FROM asset_categories c
+		WHERE a.category_id IS NULL AND c.parent_id IS NULL
+			AND LOWER(c.name) = LOWER(COALESCE(NULLIF(TRIM(a.category), ''), 'Other'))`,
+		`CREATE INDEX IF NOT EXISTS idx_assets_category_id ON assets(category_id)`,
+
+		// Owners: people in the household who hold shares of assets and debts
+		`CREATE TABLE IF NOT EXISTS owners (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			person_id BIGINT,
+			is_default BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_name ON owners (LOWER(name))`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_default ON owners (is_default) WHERE is_default`,
+		`INSERT INTO owners (name, is_default)
+		SELECT 'Me', TRUE
+		WHERE NOT EXISTS (SELECT 1 FROM owners)`,
+
+		// Ownership shares (percent) per asset and debt
+		`CREATE TABLE IF NOT EXISTS asset_owners (
+			asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+			owner_id BIGINT NOT NULL REFERENCES owners(id) ON DELETE RESTRICT,
+			share DECIMAL(5, 2) NOT NULL CHECK (share > 0 AND share <= 100),
+			PRIMARY KEY (asset_id, owner_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_asset_owners_owner ON asset_owners(owner_id)`,
+		`CREATE TABLE IF NOT EXISTS debt_owners (
+			debt_id BIGINT NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+			owner_id BIGINT NOT NULL REFERENCES owners(id) ON DELETE RESTRICT,
+			share DECIMAL(5, 2) NOT NULL CHECK (share > 0 AND share <= 100),
+			PRIMARY KEY (debt_id, owner_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_debt_owners_owner ON debt_owners(owner_id)`,
+
+		// Existing assets and debts belong fully to the default owner
+		backfillAssetOwners,
+		backfillDebtOwners,
+
+		// Savings goals, funded by shares of linked assets
+		`CREATE TABLE IF NOT EXISTS savings_goals (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			target_amount DECIMAL(15, 2) NOT NULL,
+			target_date DATE,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS savings_goal_assets (
+			goal_id BIGINT NOT NULL REFERENCES savings_goals(id) ON DELETE CASCADE,
+			asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+			share DECIMAL(5, 2) NOT NULL DEFAULT 100 CHECK (share > 0 AND share <= 100),
+			PRIMARY KEY (goal_id, asset_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_savings_goal_assets_asset ON savings_goal_assets(asset_id)`,
 	}
 
+	migrations = append(migrations, auditMigrations()...)
+
 	for _, migration := range migrations {
 		if _, err := pool.Exec(ctx, migration); err != nil {
 			return err
@@ -1,16 +1,20 @@
 package financial
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Asset represents a financial asset (metadata only)
 type Asset struct {
-	ID        int64     `json:"id"`
-	Category  string    `json:"category"`
-	AssetType string    `json:"asset_type"` // 'stock' or 'manual'
-	Name      string    `json:"name"`
-	Ticker    *string   `json:"ticker,omitempty"` // for stocks
-	Currency  string    `json:"currency"`         // ISO 4217 code (SEK, USD, EUR, etc.)
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64     `json:"id"`
+	Category   string    `json:"category"`              // name of the asset category (kept in sync with CategoryID)
+	CategoryID *int64    `json:"category_id,omitempty"` // references asset_categories
+	AssetType  string    `json:"asset_type"`            // 'stock' or 'manual'
+	Name       string    `json:"name"`
+	Ticker     *string   `json:"ticker,omitempty"` // for stocks
+	Currency   string    `json:"currency"`         // ISO 4217 code (SEK, USD, EUR, etc.)
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // AssetEntry represents a point-in-time value for an asset
@@ -86,13 +90,16 @@ type CurrencyRate struct {
 
 // Dashboard represents the net worth dashboard summary
 type Dashboard struct {
-	TotalAssets    float64            `json:"total_assets"`     // in SEK
-	TotalDebt      float64            `json:"total_debt"`       // in SEK
-	NetWorth       float64            `json:"net_worth"`        // in SEK
-	AsOfDate       string             `json:"as_of_date"`
-	ByCategory     map[string]float64 `json:"by_category"`      // in SEK
-	DisplayCurrency string            `json:"display_currency"` // always "SEK"
-	History        []NetWorthDataPoint `json:"history,omitempty"`
+	TotalAssets     float64             `json:"total_assets"` // in SEK
+	TotalDebt       float64             `json:"total_debt"`   // in SEK
+	NetWorth        float64             `json:"net_worth"`    // in SEK
+	AsOfDate        string              `json:"as_of_date"`
+	ByCategory      map[string]float64  `json:"by_category"`        // in SEK, keyed by category path
+	ByLiquidity     map[string]float64  `json:"by_liquidity"`       // in SEK, keyed by liquidity class
+	DisplayCurrency string              `json:"display_currency"`   // always "SEK"
+	Owner           string              `json:"owner"`              // owner name, or "household" for totals
+	ByOwner         []OwnerTotals       `json:"by_owner,omitempty"` // household view only
+	History         []NetWorthDataPoint `json:"history,omitempty"`
 }
 
 // NetWorthDataPoint represents a single point in the net worth history
@@ -109,8 +116,8 @@ type DetailedHistoryDataPoint struct {
 	TotalAssets float64            `json:"total_assets"`
 	TotalDebt   float64            `json:"total_debt"`
 	NetWorth    float64            `json:"net_worth"`
-	Assets      map[string]float64 `json:"assets"`      // asset name -> value
-	Debts       map[string]float64 `json:"debts"`       // debt name -> value (positive number, displayed as negative)
+	Assets      map[string]float64 `json:"assets"` // asset name -> value
+	Debts       map[string]float64 `json:"debts"`  // debt name -> value (positive number, displayed as negative)
 }
 
 // DetailedHistoryResponse contains history with item names for legend
@@ -119,3 +126,210 @@ type DetailedHistoryResponse struct {
 	AssetNames []string                   `json:"asset_names"`
 	DebtNames  []string                   `json:"debt_names"`
 }
+
+// --- Asset Categories ---
+
+// AssetCategory is a node in the asset category tree (e.g. "Equities > Global index")
+type AssetCategory struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	ParentID     *int64    `json:"parent_id,omitempty"`
+	Color        string    `json:"color"`                   // hex, e.g. #6366f1
+	Liquidity    string    `json:"liquidity"`               // cash, liquid, semi_liquid, illiquid
+	TargetWeight *float64  `json:"target_weight,omitempty"` // target share of total assets, in percent
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Computed fields (not stored)
+	Path  string `json:"path,omitempty"` // "Equities > Global index"
+	Depth int    `json:"depth"`          // 0 for root categories
+}
+
+// AssetCategoryInput is the request body for creating/updating a category
+type AssetCategoryInput struct {
+	Name         string   `json:"name"`
+	ParentID     *int64   `json:"parent_id,omitempty"`
+	Color        string   `json:"color"`
+	Liquidity    string   `json:"liquidity"`
+	TargetWeight *float64 `json:"target_weight,omitempty"`
+}
+
+// CategoryBreakdown is a category's value rolled up over its subtree
+type CategoryBreakdown struct {
+	ID           int64               `json:"id"`
+	Name         string              `json:"name"`
+	Path         string              `json:"path"`
+	Color        string              `json:"color"`
+	Liquidity    string              `json:"liquidity"`
+	Depth        int                 `json:"depth"`
+	Value        float64             `json:"value"`        // in SEK, including subcategories
+	DirectValue  float64             `json:"direct_value"` // in SEK, assets directly in this category
+	Weight       float64             `json:"weight"`       // percent of total assets
+	TargetWeight *float64            `json:"target_weight,omitempty"`
+	Drift        *float64            `json:"drift,omitempty"` // weight - target_weight, in percentage points
+	Children     []CategoryBreakdown `json:"children,omitempty"`
+}
+
+// CategoryBreakdownResponse is the category tree with values as of a date
+type CategoryBreakdownResponse struct {
+	AsOfDate      string              `json:"as_of_date"`
+	Owner         string              `json:"owner"`        // owner name, or "household"
+	TotalAssets   float64             `json:"total_assets"` // in SEK
+	Categories    []CategoryBreakdown `json:"categories"`
+	Uncategorized float64             `json:"uncategorized"` // in SEK
+}
+
+// --- Owners ---
+
+// Owner is a person who holds a share of assets and debts in the household
+type Owner struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	PersonID  *int64    `json:"person_id,omitempty"` // optional link to a contact in the people feature
+	IsDefault bool      `json:"is_default"`          // receives 100% of new assets and debts without explicit owners
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OwnerInput is the request body for creating/updating an owner
+type OwnerInput struct {
+	Name      string `json:"name"`
+	PersonID  *int64 `json:"person_id,omitempty"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// Ownership is one owner's share of an asset or debt
+type Ownership struct {
+	OwnerID   int64   `json:"owner_id"`
+	OwnerName string  `json:"owner_name,omitempty"`
+	Share     float64 `json:"share"` // percent, 0-100
+}
+
+// ArchivedOwnership is an ownership row as stored in an export archive
+type ArchivedOwnership struct {
+	ItemID  int64   `json:"item_id"` // asset or debt ID
+	OwnerID int64   `json:"owner_id"`
+	Share   float64 `json:"share"`
+}
+
+// OwnerTotals is one owner's share of the household totals
+type OwnerTotals struct {
+	OwnerID     int64   `json:"owner_id"`
+	Name        string  `json:"name"`
+	TotalAssets float64 `json:"total_assets"` // in SEK
+	TotalDebt   float64 `json:"total_debt"`   // in SEK
+	NetWorth    float64 `json:"net_worth"`    // in SEK
+}
+
+// --- Savings Goals ---
+
+// SavingsGoal is something we save toward, funded by (shares of) linked assets
+type SavingsGoal struct {
+	ID           int64              `json:"id"`
+	Name         string             `json:"name"`
+	TargetAmount float64            `json:"target_amount"`         // in SEK
+	TargetDate   *string            `json:"target_date,omitempty"` // YYYY-MM-DD
+	Notes        string             `json:"notes,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	Assets       []SavingsGoalAsset `json:"assets"`
+
+	// Computed fields (not stored)
+	CurrentAmount   float64  `json:"current_amount"`             // in SEK
+	Progress        float64  `json:"progress"`                   // percent of target
+	Remaining       float64  `json:"remaining"`                  // in SEK
+	RequiredMonthly *float64 `json:"required_monthly,omitempty"` // contribution needed to reach the target by target_date
+	MonthlyGrowth   *float64 `json:"monthly_growth,omitempty"`   // average over up to the last 6 months; unset without enough history
+	ProjectedDate   *string  `json:"projected_date,omitempty"`   // at the current growth rate
+	OnTrack         *bool    `json:"on_track,omitempty"`         // projected_date <= target_date
+}
+
+// SavingsGoalAsset links an asset (or a share of it) to a goal
+type SavingsGoalAsset struct {
+	AssetID   int64   `json:"asset_id"`
+	AssetName string  `json:"asset_name,omitempty"`
+	Share     float64 `json:"share"` // percent of the asset counted toward the goal
+	Value     float64 `json:"value"` // current SEK value of the share
+}
+
+// SavingsGoalInput is the request body for creating/updating a savings goal
+type SavingsGoalInput struct {
+	Name         string             `json:"name"`
+	TargetAmount float64            `json:"target_amount"`
+	TargetDate   *string            `json:"target_date,omitempty"`
+	Notes        string             `json:"notes"`
+	Assets       []SavingsGoalAsset `json:"assets"` // asset_id and share (defaults to 100)
+}
+
+// ArchivedSavingsGoal is a savings goal as stored in an export archive (no computed fields)
+type ArchivedSavingsGoal struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	TargetAmount float64   `json:"target_amount"`
+	TargetDate   *string   `json:"target_date,omitempty"` // YYYY-MM-DD
+	Notes        string    `json:"notes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ArchivedSavingsGoalAsset is a goal-asset link as stored in an export archive
+type ArchivedSavingsGoalAsset struct {
+	GoalID  int64   `json:"goal_id"`
+	AssetID int64   `json:"asset_id"`
+	Share   float64 `json:"share"`
+}
+
+// --- Audit ---
+
+// AuditEntry is one recorded change to a financial table
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ChangedAt time.Time       `json:"changed_at"`
+	Actor     string          `json:"actor"`
+	Table     string          `json:"table"`
+	RowID     string          `json:"row_id"` // key columns joined by "/", e.g. "12" or "3/1"
+	Action    string          `json:"action"` // INSERT, UPDATE or DELETE
+	OldValues json.RawMessage `json:"old_values,omitempty"`
+	NewValues json.RawMessage `json:"new_values,omitempty"`
+	Changed   []string        `json:"changed,omitempty"` // columns modified by an UPDATE
+}
+
+// --- Export / Restore ---
+
+// ArchivedAssetEntry is an asset entry as stored in an export archive (units only, prices live in AssetPrices)
+type ArchivedAssetEntry struct {
+	ID        int64     `json:"id"`
+	AssetID   int64     `json:"asset_id"`
+	EntryDate string    `json:"entry_date"` // YYYY-MM-DD
+	Units     float64   `json:"units"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FinancialArchive is a complete snapshot of the financial tables, used by export and restore
+type FinancialArchive struct {
+	Version         int                  `json:"version"`
+	ExportedAt      time.Time            `json:"exported_at"`
+	AssetCategories []AssetCategory      `json:"asset_categories,omitempty"`
+	Assets          []Asset              `json:"assets"`
+	AssetEntries    []ArchivedAssetEntry `json:"asset_entries"`
+	AssetPrices     []AssetPrice         `json:"asset_prices"`
+	Debts           []Debt               `json:"debts"`
+	DebtEntries     []DebtEntry          `json:"debt_entries"`
+	CurrencyRates   []CurrencyRate       `json:"currency_rates"`
+	Owners          []Owner              `json:"owners,omitempty"`
+	AssetOwners     []ArchivedOwnership  `json:"asset_owners,omitempty"`
+	DebtOwners      []ArchivedOwnership  `json:"debt_owners,omitempty"`
+
+	SavingsGoals      []ArchivedSavingsGoal      `json:"savings_goals,omitempty"`
+	SavingsGoalAssets []ArchivedSavingsGoalAsset `json:"savings_goal_assets,omitempty"`
+}
+
+// RestoreResult summarizes what a restore imported
+type RestoreResult struct {
+	AssetCategories int `json:"asset_categories"`
+	Assets          int `json:"assets"`
+	AssetEntries    int `json:"asset_entries"`
+	AssetPrices     int `json:"asset_prices"`
+	Debts           int `json:"debts"`
+	DebtEntries     int `json:"debt_entries"`
+	CurrencyRates   int `json:"currency_rates"`
+	Owners          int `json:"owners"`
+	SavingsGoals    int `json:"savings_goals"`
+}
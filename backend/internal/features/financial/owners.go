@@ -0,0 +1,537 @@
+package financial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// Assets and debts without ownership rows are given fully to the default owner
+const (
+	backfillAssetOwners = `INSERT INTO asset_owners (asset_id, owner_id, share)
+		SELECT a.id, o.id, 100
+		FROM assets a
+		JOIN owners o ON o.is_default
+		WHERE NOT EXISTS (SELECT 1 FROM asset_owners ao WHERE ao.asset_id = a.id)`
+	backfillDebtOwners = `INSERT INTO debt_owners (debt_id, owner_id, share)
+		SELECT d.id, o.id, 100
+		FROM debts d
+		JOIN owners o ON o.is_default
+		WHERE NOT EXISTS (SELECT 1 FROM debt_owners dow WHERE dow.debt_id = d.id)`
+)
+
+// errOwnerNotFound is returned when ?owner= does not match any owner
+var errOwnerNotFound = errors.New("owner not found")
+
+// assetShareSQL returns the fraction of asset "a" held by the owner in the given
+// query parameter, or 1 when the parameter is NULL (household view)
+func assetShareSQL(param int) string {
+	return fmt.Sprintf(`CASE WHEN $%[1]d::BIGINT IS NULL THEN 1 ELSE COALESCE((
+		SELECT ao.share FROM asset_owners ao WHERE ao.asset_id = a.id AND ao.owner_id = $%[1]d
+	), 0) / 100.0 END`, param)
+}
+
+// debtShareSQL is the debt counterpart of assetShareSQL for debt "d"
+func debtShareSQL(param int) string {
+	return fmt.Sprintf(`CASE WHEN $%[1]d::BIGINT IS NULL THEN 1 ELSE COALESCE((
+		SELECT dow.share FROM debt_owners dow WHERE dow.debt_id = d.id AND dow.owner_id = $%[1]d
+	), 0) / 100.0 END`, param)
+}
+
+// ownerFromRequest resolves ?owner= (ID or name); nil means the household view
+func (h *Handler) ownerFromRequest(r *http.Request) (*Owner, error) {
+	param := strings.TrimSpace(r.URL.Query().Get("owner"))
+	if param == "" || strings.EqualFold(param, "household") {
+		return nil, nil
+	}
+
+	var o Owner
+	var err error
+	if id, convErr := strconv.ParseInt(param, 10, 64); convErr == nil {
+		err = h.db.QueryRow(r.Context(), `
+			SELECT id, name, person_id, is_default, created_at FROM owners WHERE id = $1
+		`, id).Scan(&o.ID, &o.Name, &o.PersonID, &o.IsDefault, &o.CreatedAt)
+	} else {
+		err = h.db.QueryRow(r.Context(), `
+			SELECT id, name, person_id, is_default, created_at FROM owners WHERE LOWER(name) = LOWER($1)
+		`, param).Scan(&o.ID, &o.Name, &o.PersonID, &o.IsDefault, &o.CreatedAt)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errOwnerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// writeOwnerError reports a failed ?owner= lookup
+func writeOwnerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errOwnerNotFound) {
+		core.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	core.WriteError(w, http.StatusInternalServerError, err.Error())
+}
+
+// ownerID returns the owner's ID, or nil for the household view
+func ownerID(o *Owner) *int64 {
+	if o == nil {
+		return nil
+	}
+	return &o.ID
+}
+
+// ownerLabel returns the owner's name, or "household" for the household view
+func ownerLabel(o *Owner) string {
+	if o == nil {
+		return "household"
+	}
+	return o.Name
+}
+
+// totalsAsOf returns SEK totals of assets and debt as of a date, scaled to the owner's share
+func (h *Handler) totalsAsOf(ctx context.Context, date string, owner *int64) (float64, float64, error) {
+	var totalAssets float64
+	err := h.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1) * `+assetShareSQL(2)+`), 0)
+		FROM assets a
+		JOIN LATERAL (
+			SELECT units FROM asset_entries
+			WHERE asset_id = a.id AND entry_date <= $1
+			ORDER BY entry_date DESC, created_at DESC
+			LIMIT 1
+		) e ON true
+		LEFT JOIN LATERAL (
+			SELECT unit_value FROM asset_prices
+			WHERE asset_id = a.id AND price_date <= $1
+			ORDER BY price_date DESC, created_at DESC
+			LIMIT 1
+		) p ON true
+		LEFT JOIN currency_rates cr ON cr.currency = a.currency
+	`, date, owner).Scan(&totalAssets)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalDebt float64
+	err = h.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(e.principal * COALESCE(cr.sek_rate, 1) * `+debtShareSQL(2)+`), 0)
+		FROM debts d
+		JOIN LATERAL (
+			SELECT principal FROM debt_entries
+			WHERE debt_id = d.id AND entry_date <= $1
+			ORDER BY entry_date DESC, created_at DESC
+			LIMIT 1
+		) e ON true
+		LEFT JOIN currency_rates cr ON cr.currency = d.currency
+	`, date, owner).Scan(&totalDebt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return totalAssets, totalDebt, nil
+}
+
+// ownerTotals splits the household totals as of a date per owner
+func (h *Handler) ownerTotals(ctx context.Context, date string) ([]OwnerTotals, error) {
+	owners, err := loadOwners(ctx, h.db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []OwnerTotals{}
+	for _, o := range owners {
+		id := o.ID
+		assets, debt, err := h.totalsAsOf(ctx, date, &id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, OwnerTotals{
+			OwnerID:     o.ID,
+			Name:        o.Name,
+			TotalAssets: assets,
+			TotalDebt:   debt,
+			NetWorth:    assets - debt,
+		})
+	}
+	return result, nil
+}
+
+// loadOwners returns all owners, default owner first
+func loadOwners(ctx context.Context, q querier) ([]Owner, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, name, person_id, is_default, created_at
+		FROM owners
+		ORDER BY is_default DESC, name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []Owner{}
+	for rows.Next() {
+		var o Owner
+		if err := rows.Scan(&o.ID, &o.Name, &o.PersonID, &o.IsDefault, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// --- Ownership shares ---
+
+// ownershipTable describes where the shares of assets or debts are stored
+type ownershipTable struct {
+	table  string // asset_owners or debt_owners
+	column string // asset_id or debt_id
+}
+
+var (
+	assetOwnership = ownershipTable{table: "asset_owners", column: "asset_id"}
+	debtOwnership  = ownershipTable{table: "debt_owners", column: "debt_id"}
+)
+
+// validateShares checks that shares reference distinct owners and add up to 100%
+func validateShares(shares []Ownership) error {
+	seen := make(map[int64]bool)
+	var total float64
+	for _, s := range shares {
+		if seen[s.OwnerID] {
+			return fmt.Errorf("owner %d is listed more than once", s.OwnerID)
+		}
+		seen[s.OwnerID] = true
+		if s.Share <= 0 || s.Share > 100 {
+			return errors.New("share must be greater than 0 and at most 100")
+		}
+		total += s.Share
+	}
+	if len(shares) > 0 && math.Abs(total-100) > 0.01 {
+		return fmt.Errorf("shares must add up to 100 (got %.2f)", total)
+	}
+	return nil
+}
+
+// load returns the ownership shares of one asset or debt
+func (t ownershipTable) load(ctx context.Context, q querier, itemID int64) ([]Ownership, error) {
+	rows, err := q.Query(ctx, `
+		SELECT s.owner_id, o.name, s.share
+		FROM `+t.table+` s
+		JOIN owners o ON o.id = s.owner_id
+		WHERE s.`+t.column+` = $1
+		ORDER BY s.share DESC, o.name
+	`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []Ownership{}
+	for rows.Next() {
+		var s Ownership
+		if err := rows.Scan(&s.OwnerID, &s.OwnerName, &s.Share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+// replace sets the ownership shares of one asset or debt; no shares means 100% to the default owner.
+// Shares must already be validated.
+func (t ownershipTable) replace(ctx context.Context, q querier, itemID int64, shares []Ownership) error {
+	if _, err := q.Exec(ctx, `DELETE FROM `+t.table+` WHERE `+t.column+` = $1`, itemID); err != nil {
+		return err
+	}
+
+	if len(shares) == 0 {
+		_, err := q.Exec(ctx, `
+			INSERT INTO `+t.table+` (`+t.column+`, owner_id, share)
+			SELECT $1, id, 100 FROM owners WHERE is_default
+		`, itemID)
+		return err
+	}
+
+	for _, s := range shares {
+		var exists bool
+		if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM owners WHERE id = $1)`, s.OwnerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: %d", errOwnerNotFound, s.OwnerID)
+		}
+		_, err := q.Exec(ctx, `
+			INSERT INTO `+t.table+` (`+t.column+`, owner_id, share)
+			VALUES ($1, $2, $3)
+		`, itemID, s.OwnerID, s.Share)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shareErrorStatus maps ownership errors to a status code
+func shareErrorStatus(err error) int {
+	if errors.Is(err, errOwnerNotFound) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// --- Handlers ---
+
+func (h *Handler) ListOwners(w http.ResponseWriter, r *http.Request) {
+	owners, err := loadOwners(r.Context(), h.db)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, owners)
+}
+
+func (h *Handler) CreateOwner(w http.ResponseWriter, r *http.Request) {
+	var input OwnerInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		core.WriteError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// Only one owner can be the default
+	if input.IsDefault {
+		if _, err := tx.Exec(r.Context(), `UPDATE owners SET is_default = FALSE WHERE is_default`); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	var o Owner
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO owners (name, person_id, is_default)
+		VALUES ($1, $2, $3)
+		RETURNING id, name, person_id, is_default, created_at
+	`, input.Name, input.PersonID, input.IsDefault).Scan(&o.ID, &o.Name, &o.PersonID, &o.IsDefault, &o.CreatedAt)
+	if err != nil {
+		core.WriteError(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, o)
+}
+
+func (h *Handler) UpdateOwner(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input OwnerInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		core.WriteError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var wasDefault bool
+	err = tx.QueryRow(r.Context(), `SELECT is_default FROM owners WHERE id = $1`, id).Scan(&wasDefault)
+	if errors.Is(err, pgx.ErrNoRows) {
+		core.WriteError(w, http.StatusNotFound, "owner not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if wasDefault && !input.IsDefault {
+		core.WriteError(w, http.StatusBadRequest, "mark another owner as default instead")
+		return
+	}
+	if input.IsDefault && !wasDefault {
+		if _, err := tx.Exec(r.Context(), `UPDATE owners SET is_default = FALSE WHERE is_default`); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	var o Owner
+	err = tx.QueryRow(r.Context(), `
+		UPDATE owners SET name = $1, person_id = $2, is_default = $3
+		WHERE id = $4
+		RETURNING id, name, person_id, is_default, created_at
+	`, input.Name, input.PersonID, input.IsDefault, id).Scan(&o.ID, &o.Name, &o.PersonID, &o.IsDefault, &o.CreatedAt)
+	if err != nil {
+		core.WriteError(w, categoryErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, o)
+}
+
+func (h *Handler) DeleteOwner(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var isDefault bool
+	err = h.db.QueryRow(r.Context(), `SELECT is_default FROM owners WHERE id = $1`, id).Scan(&isDefault)
+	if errors.Is(err, pgx.ErrNoRows) {
+		core.WriteError(w, http.StatusNotFound, "owner not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if isDefault {
+		core.WriteError(w, http.StatusConflict, "the default owner cannot be deleted")
+		return
+	}
+
+	err = h.audited(r, func(tx pgx.Tx) error {
+		_, err := tx.Exec(r.Context(), `DELETE FROM owners WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		if status := categoryErrorStatus(err); status == http.StatusConflict {
+			core.WriteError(w, status, "owner still holds shares; reassign them first")
+			return
+		}
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetAssetOwners(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, assetOwnership)
+}
+
+func (h *Handler) SetAssetOwners(w http.ResponseWriter, r *http.Request) {
+	h.setShares(w, r, assetOwnership, "assets")
+}
+
+func (h *Handler) GetDebtOwners(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, debtOwnership)
+}
+
+func (h *Handler) SetDebtOwners(w http.ResponseWriter, r *http.Request) {
+	h.setShares(w, r, debtOwnership, "debts")
+}
+
+func (h *Handler) getShares(w http.ResponseWriter, r *http.Request, t ownershipTable) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	shares, err := t.load(r.Context(), h.db, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, shares)
+}
+
+// setShares replaces all shares of an asset or debt with the request body (a list of Ownership)
+func (h *Handler) setShares(w http.ResponseWriter, r *http.Request, t ownershipTable, itemTable string) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var shares []Ownership
+	if err := json.NewDecoder(r.Body).Decode(&shares); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateShares(shares); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var exists bool
+	if err := tx.QueryRow(r.Context(), `SELECT EXISTS (SELECT 1 FROM `+itemTable+` WHERE id = $1)`, id).Scan(&exists); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !exists {
+		core.WriteError(w, http.StatusNotFound, strings.TrimSuffix(itemTable, "s")+" not found")
+		return
+	}
+
+	if err := t.replace(r.Context(), tx, id, shares); err != nil {
+		core.WriteError(w, shareErrorStatus(err), err.Error())
+		return
+	}
+	result, err := t.load(r.Context(), tx, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
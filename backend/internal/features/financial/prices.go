@@ -85,10 +85,15 @@ func (s *PriceService) FetchPrice(ticker string) (float64, string, error) {
 
 // UpdateAssetPrice stores a price for an asset
 func (s *PriceService) UpdateAssetPrice(ctx context.Context, assetID int64, priceDate string, unitValue float64, source string) (*AssetPrice, error) {
+	return storeAssetPrice(ctx, s.db, assetID, priceDate, unitValue, source)
+}
+
+// storeAssetPrice upserts the price of an asset on a date
+func storeAssetPrice(ctx context.Context, q querier, assetID int64, priceDate string, unitValue float64, source string) (*AssetPrice, error) {
 	var price AssetPrice
 	var pDate time.Time
 
-	err := s.db.QueryRow(ctx, `
+	err := q.QueryRow(ctx, `
 		INSERT INTO asset_prices (asset_id, price_date, unit_value, source)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (asset_id, price_date) DO UPDATE SET unit_value = $3, source = $4, created_at = NOW()
@@ -13,6 +13,7 @@ func RegisterRoutes(r chi.Router, db *pgxpool.Pool) {
 		r.Get("/", h.ListAssets)
 		r.Post("/", h.CreateAsset)
 		r.Get("/{id}", h.GetAsset)
+		r.Put("/{id}", h.UpdateAsset)
 		r.Delete("/{id}", h.DeleteAsset)
 		// Asset entries (time-series)
 		r.Get("/{id}/entries", h.ListAssetEntries)
@@ -23,6 +24,17 @@ func RegisterRoutes(r chi.Router, db *pgxpool.Pool) {
 		r.Get("/{id}/prices", h.ListAssetPrices)
 		r.Post("/{id}/prices", h.CreateAssetPrice)
 		r.Post("/{id}/prices/fetch", h.FetchAssetPrice)
+		// Ownership shares
+		r.Get("/{id}/owners", h.GetAssetOwners)
+		r.Put("/{id}/owners", h.SetAssetOwners)
+	})
+
+	r.Route("/asset-categories", func(r chi.Router) {
+		r.Get("/", h.ListAssetCategories)
+		r.Post("/", h.CreateAssetCategory)
+		r.Put("/{id}", h.UpdateAssetCategory)
+		r.Delete("/{id}", h.DeleteAssetCategory)
+		r.Post("/{id}/merge", h.MergeAssetCategory)
 	})
 
 	r.Route("/debts", func(r chi.Router) {
@@ -35,9 +47,29 @@ func RegisterRoutes(r chi.Router, db *pgxpool.Pool) {
 		r.Post("/{id}/entries", h.CreateDebtEntry)
 		r.Put("/{id}/entries/{entryId}", h.UpdateDebtEntry)
 		r.Delete("/{id}/entries/{entryId}", h.DeleteDebtEntry)
+		// Ownership shares
+		r.Get("/{id}/owners", h.GetDebtOwners)
+		r.Put("/{id}/owners", h.SetDebtOwners)
+	})
+
+	// Household owners (dashboard and history endpoints accept ?owner=<id or name>)
+	r.Route("/owners", func(r chi.Router) {
+		r.Get("/", h.ListOwners)
+		r.Post("/", h.CreateOwner)
+		r.Put("/{id}", h.UpdateOwner)
+		r.Delete("/{id}", h.DeleteOwner)
+	})
+
+	r.Route("/savings-goals", func(r chi.Router) {
+		r.Get("/", h.ListSavingsGoals)
+		r.Post("/", h.CreateSavingsGoal)
+		r.Get("/{id}", h.GetSavingsGoal)
+		r.Put("/{id}", h.UpdateSavingsGoal)
+		r.Delete("/{id}", h.DeleteSavingsGoal)
 	})
 
 	r.Get("/dashboard/financial", h.GetDashboard)
+	r.Get("/dashboard/financial/categories", h.GetCategoryBreakdown)
 	r.Get("/dashboard/history", h.GetHistory)
 	r.Get("/dashboard/history/detailed", h.GetDetailedHistory)
 
@@ -49,4 +81,12 @@ func RegisterRoutes(r chi.Router, db *pgxpool.Pool) {
 		r.Post("/", h.UpsertCurrencyRate)
 		r.Delete("/{currency}", h.DeleteCurrencyRate)
 	})
+
+	// Export / restore (csv zip, xlsx or json archive)
+	r.Route("/financial", func(r chi.Router) {
+		r.Get("/export", h.ExportData)
+		r.Post("/restore", h.RestoreData)
+		// Audit trail of every change to the financial tables
+		r.Get("/audit", h.ListAuditLog)
+	})
 }
@@ -0,0 +1,486 @@
+package financial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// growthWindowMonths is how far back projections look to estimate monthly growth
+const growthWindowMonths = 6
+
+// minGrowthMonths is the history an asset needs before its growth is estimated
+const minGrowthMonths = 1
+
+// daysPerMonth is the average month length used for monthly rates
+const daysPerMonth = 30.4375
+
+// assetValuesAsOf returns the SEK value of each asset as of a date
+func (h *Handler) assetValuesAsOf(ctx context.Context, date string) (map[int64]float64, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT a.id, e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1)
+		FROM assets a
+		JOIN LATERAL (
+			SELECT units FROM asset_entries
+			WHERE asset_id = a.id AND entry_date <= $1
+			ORDER BY entry_date DESC, created_at DESC
+			LIMIT 1
+		) e ON true
+		LEFT JOIN LATERAL (
+			SELECT unit_value FROM asset_prices
+			WHERE asset_id = a.id AND price_date <= $1
+			ORDER BY price_date DESC, created_at DESC
+			LIMIT 1
+		) p ON true
+		LEFT JOIN currency_rates cr ON cr.currency = a.currency
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[int64]float64)
+	for rows.Next() {
+		var id int64
+		var value float64
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		values[id] = value
+	}
+	return values, rows.Err()
+}
+
+// assetBaseline is the first known value of an asset inside the growth window
+type assetBaseline struct {
+	date  time.Time
+	value float64
+}
+
+// assetBaselinesSince returns, for each asset with entries up to until, its SEK
+// value at since, or at its first entry when it was added after since. The
+// nearest price is used when none is known yet on that date.
+func (h *Handler) assetBaselinesSince(ctx context.Context, since, until string) (map[int64]assetBaseline, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT a.id, b.day, e.units * COALESCE(p.unit_value, 0) * COALESCE(cr.sek_rate, 1)
+		FROM assets a
+		JOIN LATERAL (
+			SELECT GREATEST($1::date, MIN(entry_date)) AS day, MIN(entry_date) AS first
+			FROM asset_entries WHERE asset_id = a.id
+		) b ON b.first IS NOT NULL AND b.first <= $2::date
+		JOIN LATERAL (
+			SELECT units FROM asset_entries
+			WHERE asset_id = a.id AND entry_date <= b.day
+			ORDER BY entry_date DESC, created_at DESC
+			LIMIT 1
+		) e ON true
+		LEFT JOIN LATERAL (
+			SELECT unit_value FROM asset_prices
+			WHERE asset_id = a.id
+			ORDER BY price_date > b.day, ABS(price_date - b.day), created_at DESC
+			LIMIT 1
+		) p ON true
+		LEFT JOIN currency_rates cr ON cr.currency = a.currency
+	`, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines := make(map[int64]assetBaseline)
+	for rows.Next() {
+		var id int64
+		var b assetBaseline
+		if err := rows.Scan(&id, &b.date, &b.value); err != nil {
+			return nil, err
+		}
+		baselines[id] = b
+	}
+	return baselines, rows.Err()
+}
+
+// goalGrowth estimates a goal's monthly growth from each linked asset's change
+// since its baseline, over the months actually elapsed. Assets with less than
+// minGrowthMonths of history are left out; nil means none has enough.
+func goalGrowth(g *SavingsGoal, current map[int64]float64, baselines map[int64]assetBaseline, today time.Time) *float64 {
+	var growth float64
+	known := false
+	for _, a := range g.Assets {
+		b, ok := baselines[a.AssetID]
+		if !ok {
+			continue
+		}
+		months := today.Sub(b.date).Hours() / 24 / daysPerMonth
+		if months < minGrowthMonths {
+			continue
+		}
+		growth += (current[a.AssetID] - b.value) * a.Share / 100 / months
+		known = true
+	}
+	if !known {
+		return nil
+	}
+	return &growth
+}
+
+// goalValue sums the goal's share of each linked asset
+func goalValue(g *SavingsGoal, values map[int64]float64) float64 {
+	var total float64
+	for _, a := range g.Assets {
+		total += values[a.AssetID] * a.Share / 100
+	}
+	return total
+}
+
+// computeGoalProgress fills in the computed fields of a goal from its value now and
+// its estimated monthly growth (nil when unknown)
+func computeGoalProgress(g *SavingsGoal, current float64, growth *float64, today time.Time) {
+	g.CurrentAmount = current
+	g.Remaining = math.Max(0, g.TargetAmount-current)
+	if g.TargetAmount > 0 {
+		g.Progress = current / g.TargetAmount * 100
+	}
+	g.MonthlyGrowth = growth
+
+	var targetDate time.Time
+	if g.TargetDate != nil {
+		targetDate, _ = time.Parse("2006-01-02", *g.TargetDate)
+		monthsLeft := targetDate.Sub(today).Hours() / 24 / daysPerMonth
+		required := g.Remaining
+		if monthsLeft > 1 {
+			required = g.Remaining / monthsLeft
+		}
+		g.RequiredMonthly = &required
+	}
+
+	if g.Remaining == 0 {
+		done := today.Format("2006-01-02")
+		g.ProjectedDate = &done
+	} else if g.MonthlyGrowth != nil && *g.MonthlyGrowth > 0 {
+		days := int(math.Ceil(g.Remaining / *g.MonthlyGrowth * daysPerMonth))
+		projected := today.AddDate(0, 0, days).Format("2006-01-02")
+		g.ProjectedDate = &projected
+	}
+
+	if g.TargetDate != nil {
+		onTrack := g.ProjectedDate != nil && *g.ProjectedDate <= *g.TargetDate
+		g.OnTrack = &onTrack
+	}
+}
+
+// loadSavingsGoals loads goals (all, or the one with the given ID) with their linked assets
+func (h *Handler) loadSavingsGoals(ctx context.Context, id *int64) ([]SavingsGoal, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT id, name, target_amount, target_date, notes, created_at
+		FROM savings_goals
+		WHERE $1::BIGINT IS NULL OR id = $1
+		ORDER BY target_date NULLS LAST, name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []SavingsGoal{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var g SavingsGoal
+		var targetDate *time.Time
+		if err := rows.Scan(&g.ID, &g.Name, &g.TargetAmount, &targetDate, &g.Notes, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		if targetDate != nil {
+			d := targetDate.Format("2006-01-02")
+			g.TargetDate = &d
+		}
+		g.Assets = []SavingsGoalAsset{}
+		index[g.ID] = len(goals)
+		goals = append(goals, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	linkRows, err := h.db.Query(ctx, `
+		SELECT l.goal_id, l.asset_id, a.name, l.share
+		FROM savings_goal_assets l
+		JOIN assets a ON a.id = l.asset_id
+		WHERE $1::BIGINT IS NULL OR l.goal_id = $1
+		ORDER BY a.name
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer linkRows.Close()
+
+	for linkRows.Next() {
+		var goalID int64
+		var a SavingsGoalAsset
+		if err := linkRows.Scan(&goalID, &a.AssetID, &a.AssetName, &a.Share); err != nil {
+			return nil, err
+		}
+		if i, ok := index[goalID]; ok {
+			goals[i].Assets = append(goals[i].Assets, a)
+		}
+	}
+	if err := linkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Progress is computed from the latest values and the first known values
+	// within the last six months
+	today := time.Now()
+	current, err := h.assetValuesAsOf(ctx, today.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	baselines, err := h.assetBaselinesSince(ctx, today.AddDate(0, -growthWindowMonths, 0).Format("2006-01-02"), today.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	for i := range goals {
+		g := &goals[i]
+		for j := range g.Assets {
+			g.Assets[j].Value = current[g.Assets[j].AssetID] * g.Assets[j].Share / 100
+		}
+		computeGoalProgress(g, goalValue(g, current), goalGrowth(g, current, baselines, today), today)
+	}
+
+	return goals, nil
+}
+
+// validateSavingsGoalInput normalizes and validates a goal request body
+func validateSavingsGoalInput(input *SavingsGoalInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	if input.TargetAmount <= 0 {
+		return errors.New("target_amount must be positive")
+	}
+	if input.TargetDate != nil {
+		if *input.TargetDate == "" {
+			input.TargetDate = nil
+		} else if _, err := time.Parse("2006-01-02", *input.TargetDate); err != nil {
+			return errors.New("target_date must be YYYY-MM-DD")
+		}
+	}
+	seen := make(map[int64]bool)
+	for i := range input.Assets {
+		a := &input.Assets[i]
+		if seen[a.AssetID] {
+			return fmt.Errorf("asset %d is linked more than once", a.AssetID)
+		}
+		seen[a.AssetID] = true
+		if a.Share == 0 {
+			a.Share = 100
+		}
+		if a.Share < 0 || a.Share > 100 {
+			return errors.New("share must be greater than 0 and at most 100")
+		}
+	}
+	return nil
+}
+
+// linkGoalAssets replaces the assets linked to a goal, making sure no asset is
+// allocated more than 100% across all goals
+func linkGoalAssets(ctx context.Context, tx pgx.Tx, goalID int64, assets []SavingsGoalAsset) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM savings_goal_assets WHERE goal_id = $1`, goalID); err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		var exists bool
+		var allocated float64
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM assets WHERE id = $1),
+				COALESCE((SELECT SUM(share) FROM savings_goal_assets WHERE asset_id = $1), 0)
+		`, a.AssetID).Scan(&exists, &allocated)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: asset %d does not exist", errInvalidGoal, a.AssetID)
+		}
+		if allocated+a.Share > 100.001 {
+			return fmt.Errorf("%w: asset %d is already %.2f%% allocated to other goals", errInvalidGoal, a.AssetID, allocated)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO savings_goal_assets (goal_id, asset_id, share)
+			VALUES ($1, $2, $3)
+		`, goalID, a.AssetID, a.Share)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errInvalidGoal marks goal errors caused by the request
+var errInvalidGoal = errors.New("invalid savings goal")
+
+func goalErrorStatus(err error) int {
+	if errors.Is(err, errInvalidGoal) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// --- Handlers ---
+
+func (h *Handler) ListSavingsGoals(w http.ResponseWriter, r *http.Request) {
+	goals, err := h.loadSavingsGoals(r.Context(), nil)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, goals)
+}
+
+func (h *Handler) GetSavingsGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	h.writeSavingsGoal(w, r, id, http.StatusOK)
+}
+
+// writeSavingsGoal responds with a single goal including its computed progress
+func (h *Handler) writeSavingsGoal(w http.ResponseWriter, r *http.Request, id int64, status int) {
+	goals, err := h.loadSavingsGoals(r.Context(), &id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(goals) == 0 {
+		core.WriteError(w, http.StatusNotFound, "savings goal not found")
+		return
+	}
+
+	core.WriteJSON(w, status, goals[0])
+}
+
+func (h *Handler) CreateSavingsGoal(w http.ResponseWriter, r *http.Request) {
+	var input SavingsGoalInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateSavingsGoalInput(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var id int64
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO savings_goals (name, target_amount, target_date, notes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, input.Name, input.TargetAmount, input.TargetDate, input.Notes).Scan(&id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := linkGoalAssets(r.Context(), tx, id, input.Assets); err != nil {
+		core.WriteError(w, goalErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeSavingsGoal(w, r, id, http.StatusCreated)
+}
+
+func (h *Handler) UpdateSavingsGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var input SavingsGoalInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateSavingsGoalInput(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.beginAudited(r)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	result, err := tx.Exec(r.Context(), `
+		UPDATE savings_goals SET name = $1, target_amount = $2, target_date = $3, notes = $4
+		WHERE id = $5
+	`, input.Name, input.TargetAmount, input.TargetDate, input.Notes, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "savings goal not found")
+		return
+	}
+
+	if err := linkGoalAssets(r.Context(), tx, id, input.Assets); err != nil {
+		core.WriteError(w, goalErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.writeSavingsGoal(w, r, id, http.StatusOK)
+}
+
+func (h *Handler) DeleteSavingsGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err = h.audited(r, func(tx pgx.Tx) error {
+		_, err := tx.Exec(r.Context(), `DELETE FROM savings_goals WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
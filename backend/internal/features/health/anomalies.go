@@ -0,0 +1,492 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Personal-baseline illness detection: each signal is scored against a
+// robust baseline (median and MAD of the preceding days), and days where
+// several signals deviate in the adverse direction at once are flagged.
+
+const (
+	anomalyBaselineDays = 60  // trailing window for the personal baseline
+	anomalyMinBaseline  = 14  // baseline points needed before scoring a day
+	anomalyZThreshold   = 2.0 // adverse robust z-score counted as deviating
+	anomalySevereZ      = 3.0
+	anomalyMinSignals   = 2 // deviating signals needed to flag a day
+	anomalyEpisodeGap   = 1 // unflagged days allowed inside one episode
+	madToSigma          = 1.4826
+
+	// anomalyDashboardDays is how far back the dashboard looks for an
+	// ongoing episode
+	anomalyDashboardDays = 14
+)
+
+// anomalySeverityRank orders severities for picking an episode's worst day
+var anomalySeverityRank = map[string]int{"mild": 1, "moderate": 2, "severe": 3}
+
+// anomalySeries is one candidate source for a signal
+type anomalySeries struct {
+	metric    string  // temperature, resting_heart_rate, hrv
+	source    string  // where the values come from
+	direction float64 // +1 when higher is adverse, -1 when lower is adverse
+	minScale  float64 // floor for the robust sigma, in the series' units
+	values    map[string]float64
+}
+
+// median returns the median of values (which it sorts in place)
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// robustBaseline returns the median and the MAD-based sigma of values
+func robustBaseline(values []float64, minScale float64) (float64, float64) {
+	med := median(append([]float64(nil), values...))
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+	return med, math.Max(madToSigma*median(deviations), minScale)
+}
+
+// anomalySeverity classifies a flagged day
+func anomalySeverity(deviating int, maxZ float64) string {
+	switch {
+	case deviating >= 3 && maxZ >= anomalySevereZ:
+		return "severe"
+	case deviating >= 3 || maxZ >= anomalySevereZ:
+		return "moderate"
+	default:
+		return "mild"
+	}
+}
+
+// loadAnomalySeries loads the candidate series for temperature, RHR and HRV
+// and picks, per signal, the first candidate with enough data. Raw values
+// from sleep sessions and imported metrics are preferred over Oura's
+// contributor scores.
+func loadAnomalySeries(ctx context.Context, q querier, start, end time.Time) ([]anomalySeries, error) {
+	newSeries := func(metric, source string, direction, minScale float64) *anomalySeries {
+		return &anomalySeries{metric: metric, source: source, direction: direction, minScale: minScale, values: map[string]float64{}}
+	}
+	temp := newSeries("temperature", "oura_temperature_deviation", 1, 0.1)
+	rhrSleep := newSeries("resting_heart_rate", "sleep_lowest_heart_rate", 1, 1)
+	rhrMetric := newSeries("resting_heart_rate", "daily_resting_heart_rate", 1, 1)
+	rhrScore := newSeries("resting_heart_rate", "oura_resting_heart_rate_score", -1, 2)
+	hrvSleep := newSeries("hrv", "sleep_average_hrv", -1, 2)
+	hrvMetric := newSeries("hrv", "daily_hrv_sdnn", -1, 2)
+	hrvScore := newSeries("hrv", "oura_hrv_balance_score", -1, 2)
+
+	rows, err := q.Query(ctx, `
+		SELECT day, temperature_deviation, readiness_resting_heart_rate::double precision,
+			readiness_hrv_balance::double precision
+		FROM oura_daily
+		WHERE day >= $1 AND day <= $2
+	`, formatDate(start), formatDate(end))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var day time.Time
+		var t, rhr, hrv *float64
+		if err := rows.Scan(&day, &t, &rhr, &hrv); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d := formatDate(day)
+		for _, v := range []struct {
+			s *anomalySeries
+			v *float64
+		}{{temp, t}, {rhrScore, rhr}, {hrvScore, hrv}} {
+			if v.v != nil {
+				v.s.values[d] = *v.v
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The main (longest) sleep of each day
+	rows, err = q.Query(ctx, `
+		SELECT DISTINCT ON (day) day, lowest_heart_rate::double precision, average_hrv::double precision
+		FROM sleep_sessions
+		WHERE NOT is_nap AND day >= $1 AND day <= $2
+		ORDER BY day, total_sleep_seconds DESC NULLS LAST
+	`, formatDate(start), formatDate(end))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var day time.Time
+		var rhr, hrv *float64
+		if err := rows.Scan(&day, &rhr, &hrv); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if rhr != nil {
+			rhrSleep.values[formatDate(day)] = *rhr
+		}
+		if hrv != nil {
+			hrvSleep.values[formatDate(day)] = *hrv
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT day, metric, AVG(value)
+		FROM daily_metrics
+		WHERE metric IN ('resting_heart_rate', 'hrv_sdnn') AND day >= $1 AND day <= $2
+		GROUP BY day, metric
+	`, formatDate(start), formatDate(end))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var day time.Time
+		var metric string
+		var value float64
+		if err := rows.Scan(&day, &metric, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if metric == "resting_heart_rate" {
+			rhrMetric.values[formatDate(day)] = value
+		} else {
+			hrvMetric.values[formatDate(day)] = value
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var chosen []anomalySeries
+	for _, candidates := range [][]*anomalySeries{
+		{temp},
+		{rhrSleep, rhrMetric, rhrScore},
+		{hrvSleep, hrvMetric, hrvScore},
+	} {
+		for _, c := range candidates {
+			if len(c.values) >= anomalyMinBaseline {
+				chosen = append(chosen, *c)
+				break
+			}
+		}
+	}
+	return chosen, nil
+}
+
+// scoreAnomalyDays scores every day in [start, end] that has data
+func scoreAnomalyDays(series []anomalySeries, start, end time.Time) []AnomalyDay {
+	var days []AnomalyDay
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		d := AnomalyDay{Day: formatDate(day), Signals: []AnomalySignal{}}
+		var maxZ float64
+		for _, s := range series {
+			value, ok := s.values[d.Day]
+			if !ok {
+				continue
+			}
+
+			var baseline []float64
+			for b := day.AddDate(0, 0, -anomalyBaselineDays); b.Before(day); b = b.AddDate(0, 0, 1) {
+				if v, ok := s.values[formatDate(b)]; ok {
+					baseline = append(baseline, v)
+				}
+			}
+
+			signal := AnomalySignal{Metric: s.metric, Source: s.source, Value: value}
+			if len(baseline) >= anomalyMinBaseline {
+				med, sigma := robustBaseline(baseline, s.minScale)
+				z := math.Round(s.direction*(value-med)/sigma*100) / 100
+				signal.Baseline = &med
+				signal.ZScore = &z
+				if z >= anomalyZThreshold {
+					signal.Deviating = true
+					d.Deviating++
+					d.Score += z
+					maxZ = math.Max(maxZ, z)
+				}
+			}
+			d.Signals = append(d.Signals, signal)
+		}
+		if len(d.Signals) == 0 {
+			continue
+		}
+		d.Score = math.Round(d.Score*100) / 100
+		if d.Deviating >= anomalyMinSignals {
+			d.Flagged = true
+			d.Severity = anomalySeverity(d.Deviating, maxZ)
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+// groupAnomalyEpisodes merges flagged days into episodes, allowing short
+// gaps of unflagged days inside an episode
+func groupAnomalyEpisodes(days []AnomalyDay) []AnomalyEpisode {
+	var episodes []AnomalyEpisode
+	var current *AnomalyEpisode
+	var lastFlagged time.Time
+
+	for _, d := range days {
+		if !d.Flagged {
+			continue
+		}
+		day, _ := time.Parse("2006-01-02", d.Day)
+		if current == nil || day.Sub(lastFlagged) > time.Duration(anomalyEpisodeGap+1)*24*time.Hour {
+			episodes = append(episodes, AnomalyEpisode{StartDay: d.Day, Signals: map[string]float64{}})
+			current = &episodes[len(episodes)-1]
+		}
+		lastFlagged = day
+
+		current.EndDay = d.Day
+		current.FlaggedDays++
+		if d.Score > current.PeakScore {
+			current.PeakScore = d.Score
+			current.PeakDay = d.Day
+		}
+		if anomalySeverityRank[d.Severity] > anomalySeverityRank[current.Severity] {
+			current.Severity = d.Severity
+		}
+		for _, s := range d.Signals {
+			if s.Deviating && *s.ZScore > current.Signals[s.Metric] {
+				current.Signals[s.Metric] = *s.ZScore
+			}
+		}
+	}
+	return episodes
+}
+
+// scoreAnomalyWindow scores the days from start to end without storing
+// anything
+func scoreAnomalyWindow(ctx context.Context, q querier, start, end time.Time) ([]AnomalyDay, error) {
+	series, err := loadAnomalySeries(ctx, q, start.AddDate(0, 0, -anomalyBaselineDays), end)
+	if err != nil {
+		return nil, err
+	}
+	days := scoreAnomalyDays(series, start, end)
+	if days == nil {
+		days = []AnomalyDay{}
+	}
+	return days, nil
+}
+
+// detectAnomalies re-detects the episodes from start to end and stores
+// them. It runs when data arrives and from the detection job, never on
+// reads. Stored episodes that reach into the window are re-detected from
+// their start so they are not split, and keep their ID while they overlap
+// the episode they became.
+func detectAnomalies(ctx context.Context, db *pgxpool.Pool, start, end time.Time) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// One detection at a time; a second waits and then sees the first's episodes
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('anomaly_episodes'))`); err != nil {
+		return err
+	}
+
+	type storedEpisode struct {
+		id         int64
+		start, end string
+	}
+	var stored []storedEpisode
+	detectFrom := start
+	rows, err := tx.Query(ctx, `
+		SELECT id, start_day, end_day FROM anomaly_episodes
+		WHERE end_day >= $1
+		ORDER BY start_day
+	`, formatDate(start))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var e storedEpisode
+		var startDay, endDay time.Time
+		if err := rows.Scan(&e.id, &startDay, &endDay); err != nil {
+			rows.Close()
+			return err
+		}
+		e.start, e.end = formatDate(startDay), formatDate(endDay)
+		stored = append(stored, e)
+		if startDay.Before(detectFrom) {
+			detectFrom = startDay
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	days, err := scoreAnomalyWindow(ctx, tx, detectFrom, end)
+	if err != nil {
+		return err
+	}
+	episodes := groupAnomalyEpisodes(days)
+
+	// Match each episode to the first stored one it overlaps
+	matched := make([]int64, len(episodes))
+	used := make(map[int64]bool)
+	for i, e := range episodes {
+		for _, s := range stored {
+			if !used[s.id] && s.start <= e.EndDay && s.end >= e.StartDay {
+				matched[i] = s.id
+				used[s.id] = true
+				break
+			}
+		}
+	}
+
+	// Episodes in the window that no longer hold (e.g. after a data fix)
+	// go first, so moved start days cannot collide with them
+	var stale []int64
+	for _, s := range stored {
+		if !used[s.id] {
+			stale = append(stale, s.id)
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM anomaly_episodes WHERE id = ANY($1)`, stale); err != nil {
+		return err
+	}
+
+	for i, e := range episodes {
+		signals, err := json.Marshal(e.Signals)
+		if err != nil {
+			return err
+		}
+		if matched[i] != 0 {
+			_, err = tx.Exec(ctx, `
+				UPDATE anomaly_episodes SET
+					start_day = $2, end_day = $3, peak_day = $4, flagged_days = $5,
+					severity = $6, peak_score = $7, signals = $8, updated_at = NOW()
+				WHERE id = $1
+			`, matched[i], e.StartDay, e.EndDay, e.PeakDay, e.FlaggedDays, e.Severity, e.PeakScore, signals)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO anomaly_episodes (start_day, end_day, peak_day, flagged_days, severity, peak_score, signals)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, e.StartDay, e.EndDay, e.PeakDay, e.FlaggedDays, e.Severity, e.PeakScore, signals)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// listAnomalyEpisodes returns stored episodes ending on or after since,
+// newest first; an episode is active if it reaches the latest data day
+func (h *Handler) listAnomalyEpisodes(ctx context.Context, since time.Time) ([]AnomalyEpisode, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT id, start_day, end_day, peak_day, flagged_days, severity, peak_score, signals, created_at,
+			end_day >= (SELECT MAX(day) FROM oura_daily) - $2
+		FROM anomaly_episodes
+		WHERE end_day >= $1
+		ORDER BY start_day DESC
+	`, formatDate(since), anomalyEpisodeGap)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	episodes := []AnomalyEpisode{}
+	for rows.Next() {
+		var e AnomalyEpisode
+		var startDay, endDay, peakDay time.Time
+		var signals []byte
+		var active *bool
+		if err := rows.Scan(&e.ID, &startDay, &endDay, &peakDay, &e.FlaggedDays, &e.Severity, &e.PeakScore,
+			&signals, &e.DetectedAt, &active); err != nil {
+			return nil, err
+		}
+		e.StartDay, e.EndDay, e.PeakDay = formatDate(startDay), formatDate(endDay), formatDate(peakDay)
+		e.Active = active != nil && *active
+		if err := json.Unmarshal(signals, &e.Signals); err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, e)
+	}
+	return episodes, rows.Err()
+}
+
+// anomalyWindow reads ?days= (default 90) and returns the window ending today
+func (h *Handler) anomalyWindow(r *http.Request) (time.Time, time.Time) {
+	days := 90 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+	end := h.today(r.Context())
+	return end.AddDate(0, 0, -(days - 1)), end
+}
+
+// GetAnomalies returns the stored episodes of the last ?days= days (default
+// 90) with the daily scores. Episodes are stored by DetectAnomalies and
+// when Oura data arrives.
+func (h *Handler) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	start, end := h.anomalyWindow(r)
+
+	report := AnomalyReport{}
+	var err error
+	report.Days, err = scoreAnomalyWindow(r.Context(), h.db, start, end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	report.Episodes, err = h.listAnomalyEpisodes(r.Context(), start)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range report.Episodes {
+		if report.Episodes[i].Active {
+			report.Active = &report.Episodes[i]
+			break
+		}
+	}
+
+	core.WriteJSON(w, http.StatusOK, report)
+}
+
+// DetectAnomalies re-runs the detector over the last ?days= days (default
+// 90), stores the episodes and returns them (for cron job)
+func (h *Handler) DetectAnomalies(w http.ResponseWriter, r *http.Request) {
+	start, end := h.anomalyWindow(r)
+
+	if err := detectAnomalies(r.Context(), h.db, start, end); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	episodes, err := h.listAnomalyEpisodes(r.Context(), start)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, episodes)
+}
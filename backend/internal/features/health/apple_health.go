@@ -0,0 +1,946 @@
+package health
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// appleDateLayout is the timestamp format used throughout export.xml
+	appleDateLayout = "2006-01-02 15:04:05 -0700"
+	// appleImportTimeout bounds a single import run
+	appleImportTimeout = 2 * time.Hour
+	// appleProgressInterval throttles progress writes to the database
+	appleProgressInterval = time.Second
+	// workoutDuplicateWindow treats workouts starting this close as the same one
+	workoutDuplicateWindow = 10 * time.Minute
+	// appleSleepGap joins sleep records less than this far apart into a session
+	appleSleepGap = time.Hour
+	// appleNapHours classifies shorter sessions as naps
+	appleNapHours = 3
+)
+
+var errAppleImportRunning = errors.New("an Apple Health import is already running")
+
+// appleStrengthWorkouts are HKWorkoutActivityType suffixes stored as strength
+var appleStrengthWorkouts = map[string]bool{
+	"TraditionalStrengthTraining":   true,
+	"FunctionalStrengthTraining":    true,
+	"CrossTraining":                 true,
+	"CoreTraining":                  true,
+	"HighIntensityIntervalTraining": true,
+}
+
+// appleAverage accumulates a daily mean
+type appleAverage struct {
+	sum   float64
+	count int
+}
+
+// appleWeight is the last body mass (or body composition) sample of a day
+type appleWeight struct {
+	at time.Time
+	kg float64
+}
+
+// appleBodyRecordKinds maps body composition record types to measurement kinds
+var appleBodyRecordKinds = map[string]string{
+	"HKQuantityTypeIdentifierBodyFatPercentage":  "body_fat_pct",
+	"HKQuantityTypeIdentifierLeanBodyMass":       "lean_mass_kg",
+	"HKQuantityTypeIdentifierWaistCircumference": "waist_cm",
+}
+
+// appleWorkout is a parsed Workout element
+type appleWorkout struct {
+	activity   string
+	start, end time.Time
+	minutes    float64
+	distanceKm float64
+	kcal       float64
+}
+
+// appleSleepRecord is one HKCategoryTypeIdentifierSleepAnalysis sample
+type appleSleepRecord struct {
+	start, end time.Time
+	value      string
+}
+
+// appleHealthData accumulates everything of interest while streaming export.xml
+type appleHealthData struct {
+	steps     map[string]map[string]float64 // day -> source -> total
+	weight    map[string]appleWeight
+	body      map[string]map[string]appleWeight // kind -> day -> last sample
+	restingHR map[string]*appleAverage
+	hrv       map[string]*appleAverage
+	workouts  []appleWorkout
+	sleep     map[string][]appleSleepRecord // source -> records
+
+	records int64
+	skipped int
+}
+
+func newAppleHealthData() *appleHealthData {
+	return &appleHealthData{
+		steps:     make(map[string]map[string]float64),
+		weight:    make(map[string]appleWeight),
+		body:      make(map[string]map[string]appleWeight),
+		restingHR: make(map[string]*appleAverage),
+		hrv:       make(map[string]*appleAverage),
+		sleep:     make(map[string][]appleSleepRecord),
+	}
+}
+
+// isOuraSource reports whether Apple Health got a sample from the Oura app.
+// Oura data is synced natively, so those samples would only duplicate it.
+func isOuraSource(source string) bool {
+	return strings.Contains(strings.ToLower(source), "oura")
+}
+
+// attr returns the value of an XML attribute, or ""
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// addRecord handles a Record element
+func (d *appleHealthData) addRecord(se xml.StartElement) {
+	recordType := attr(se, "type")
+	switch recordType {
+	case "HKQuantityTypeIdentifierStepCount",
+		"HKQuantityTypeIdentifierBodyMass",
+		"HKQuantityTypeIdentifierRestingHeartRate",
+		"HKQuantityTypeIdentifierHeartRateVariabilitySDNN",
+		"HKQuantityTypeIdentifierBodyFatPercentage",
+		"HKQuantityTypeIdentifierLeanBodyMass",
+		"HKQuantityTypeIdentifierWaistCircumference",
+		"HKCategoryTypeIdentifierSleepAnalysis":
+	default:
+		return
+	}
+
+	d.records++
+	source := attr(se, "sourceName")
+	if isOuraSource(source) {
+		d.skipped++
+		return
+	}
+
+	start, err := time.Parse(appleDateLayout, attr(se, "startDate"))
+	if err != nil {
+		return
+	}
+	end, err := time.Parse(appleDateLayout, attr(se, "endDate"))
+	if err != nil {
+		end = start
+	}
+	day := formatDate(start)
+
+	if recordType == "HKCategoryTypeIdentifierSleepAnalysis" {
+		d.sleep[source] = append(d.sleep[source], appleSleepRecord{start: start, end: end, value: attr(se, "value")})
+		return
+	}
+
+	value, err := strconv.ParseFloat(attr(se, "value"), 64)
+	if err != nil {
+		return
+	}
+
+	switch recordType {
+	case "HKQuantityTypeIdentifierStepCount":
+		if d.steps[day] == nil {
+			d.steps[day] = make(map[string]float64)
+		}
+		d.steps[day][source] += value
+
+	case "HKQuantityTypeIdentifierBodyMass":
+		switch attr(se, "unit") {
+		case "lb":
+			value *= 0.45359237
+		case "g":
+			value /= 1000
+		}
+		if prev, ok := d.weight[day]; !ok || start.After(prev.at) {
+			d.weight[day] = appleWeight{at: start, kg: value}
+		}
+
+	case "HKQuantityTypeIdentifierBodyFatPercentage",
+		"HKQuantityTypeIdentifierLeanBodyMass",
+		"HKQuantityTypeIdentifierWaistCircumference":
+		// Body fat is a fraction; convert everything to %, kg and cm
+		switch attr(se, "unit") {
+		case "%":
+			value *= 100
+		case "lb":
+			value *= 0.45359237
+		case "g":
+			value /= 1000
+		case "in":
+			value *= 2.54
+		case "m":
+			value *= 100
+		case "ft":
+			value *= 30.48
+		}
+		kind := appleBodyRecordKinds[recordType]
+		if d.body[kind] == nil {
+			d.body[kind] = make(map[string]appleWeight)
+		}
+		if prev, ok := d.body[kind][day]; !ok || start.After(prev.at) {
+			d.body[kind][day] = appleWeight{at: start, kg: value}
+		}
+
+	case "HKQuantityTypeIdentifierRestingHeartRate":
+		addAppleAverage(d.restingHR, day, value)
+
+	case "HKQuantityTypeIdentifierHeartRateVariabilitySDNN":
+		addAppleAverage(d.hrv, day, value)
+	}
+}
+
+func addAppleAverage(m map[string]*appleAverage, day string, value float64) {
+	avg, ok := m[day]
+	if !ok {
+		avg = &appleAverage{}
+		m[day] = avg
+	}
+	avg.sum += value
+	avg.count++
+}
+
+// startWorkout handles a Workout element; totals missing from its attributes
+// are filled in from WorkoutStatistics children (newer exports)
+func (d *appleHealthData) startWorkout(se xml.StartElement) *appleWorkout {
+	d.records++
+	if isOuraSource(attr(se, "sourceName")) {
+		d.skipped++
+		return nil
+	}
+
+	start, err := time.Parse(appleDateLayout, attr(se, "startDate"))
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(appleDateLayout, attr(se, "endDate"))
+	if err != nil {
+		end = start
+	}
+
+	wo := appleWorkout{
+		activity: strings.TrimPrefix(attr(se, "workoutActivityType"), "HKWorkoutActivityType"),
+		start:    start,
+		end:      end,
+		minutes:  end.Sub(start).Minutes(),
+	}
+	if v, err := strconv.ParseFloat(attr(se, "duration"), 64); err == nil {
+		switch attr(se, "durationUnit") {
+		case "s":
+			v /= 60
+		case "hr":
+			v *= 60
+		}
+		wo.minutes = v
+	}
+	if v, err := strconv.ParseFloat(attr(se, "totalDistance"), 64); err == nil {
+		wo.distanceKm = appleDistanceKm(v, attr(se, "totalDistanceUnit"))
+	}
+	if v, err := strconv.ParseFloat(attr(se, "totalEnergyBurned"), 64); err == nil {
+		wo.kcal = appleEnergyKcal(v, attr(se, "totalEnergyBurnedUnit"))
+	}
+
+	d.workouts = append(d.workouts, wo)
+	return &d.workouts[len(d.workouts)-1]
+}
+
+// addWorkoutStatistics fills distance/energy from a WorkoutStatistics element
+func (wo *appleWorkout) addWorkoutStatistics(se xml.StartElement) {
+	v, err := strconv.ParseFloat(attr(se, "sum"), 64)
+	if err != nil {
+		return
+	}
+	statType := attr(se, "type")
+	switch {
+	case strings.HasPrefix(statType, "HKQuantityTypeIdentifierDistance") && wo.distanceKm == 0:
+		wo.distanceKm = appleDistanceKm(v, attr(se, "unit"))
+	case statType == "HKQuantityTypeIdentifierActiveEnergyBurned" && wo.kcal == 0:
+		wo.kcal = appleEnergyKcal(v, attr(se, "unit"))
+	}
+}
+
+func appleDistanceKm(v float64, unit string) float64 {
+	switch unit {
+	case "mi":
+		return v * 1.609344
+	case "m":
+		return v / 1000
+	}
+	return v
+}
+
+func appleEnergyKcal(v float64, unit string) float64 {
+	if unit == "kJ" {
+		return v / 4.184
+	}
+	return v
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// parseAppleHealthXML streams export.xml, calling progress periodically
+func parseAppleHealthXML(r *countingReader, progress func(bytes, records int64)) (*appleHealthData, error) {
+	data := newAppleHealthData()
+	dec := xml.NewDecoder(bufio.NewReaderSize(r, 1<<20))
+	dec.Strict = false
+
+	var current *appleWorkout
+	lastProgress := time.Now()
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("export.xml: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "Record":
+				data.addRecord(t)
+			case "Workout":
+				current = data.startWorkout(t)
+			case "WorkoutStatistics":
+				if current != nil {
+					current.addWorkoutStatistics(t)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "Workout" {
+				current = nil
+			}
+		}
+
+		if time.Since(lastProgress) >= appleProgressInterval {
+			progress(r.n, data.records)
+			lastProgress = time.Now()
+		}
+	}
+
+	progress(r.n, data.records)
+	return data, nil
+}
+
+// appleSleepSession is a run of sleep records from one source
+type appleSleepSession struct {
+	start, end                     time.Time
+	asleep, deep, rem, light, wake int // seconds
+	hasStages                      bool
+}
+
+// buildAppleSleepSessions joins each source's sleep records into sessions,
+// then keeps the best non-overlapping session per night across sources
+// (stage detail first, then longest sleep)
+func buildAppleSleepSessions(bySource map[string][]appleSleepRecord) ([]appleSleepSession, int) {
+	var all []appleSleepSession
+	for _, records := range bySource {
+		sort.Slice(records, func(i, j int) bool { return records[i].start.Before(records[j].start) })
+
+		var cur *appleSleepSession
+		for _, rec := range records {
+			if cur == nil || rec.start.After(cur.end.Add(appleSleepGap)) {
+				if cur != nil {
+					all = append(all, *cur)
+				}
+				cur = &appleSleepSession{start: rec.start, end: rec.end}
+			}
+			if rec.end.After(cur.end) {
+				cur.end = rec.end
+			}
+
+			secs := int(rec.end.Sub(rec.start).Seconds())
+			switch strings.TrimPrefix(rec.value, "HKCategoryValueSleepAnalysis") {
+			case "Asleep", "AsleepUnspecified":
+				cur.asleep += secs
+			case "AsleepCore":
+				cur.asleep += secs
+				cur.light += secs
+				cur.hasStages = true
+			case "AsleepDeep":
+				cur.asleep += secs
+				cur.deep += secs
+				cur.hasStages = true
+			case "AsleepREM":
+				cur.asleep += secs
+				cur.rem += secs
+				cur.hasStages = true
+			case "Awake":
+				cur.wake += secs
+			}
+		}
+		if cur != nil {
+			all = append(all, *cur)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].hasStages != all[j].hasStages {
+			return all[i].hasStages
+		}
+		return all[i].asleep > all[j].asleep
+	})
+
+	var kept []appleSleepSession
+	skipped := 0
+	for _, s := range all {
+		if s.asleep == 0 {
+			continue
+		}
+		overlaps := false
+		for _, k := range kept {
+			if s.start.Before(k.end) && s.end.After(k.start) {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			skipped++
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept, skipped
+}
+
+// appleWorkoutInput maps an Apple workout onto the workouts table
+func appleWorkoutInput(wo appleWorkout) WorkoutInput {
+	workoutType := "cardio"
+	if appleStrengthWorkouts[wo.activity] {
+		workoutType = "strength"
+	}
+
+	// "TraditionalStrengthTraining" -> "traditional strength training"
+	var name strings.Builder
+	for i, c := range wo.activity {
+		if i > 0 && c >= 'A' && c <= 'Z' {
+			name.WriteByte(' ')
+		}
+		name.WriteRune(c)
+	}
+
+	notes := strings.ToLower(name.String())
+	if wo.minutes > 0 {
+		notes += fmt.Sprintf(", %d min", int(wo.minutes+0.5))
+	}
+	if wo.distanceKm > 0 {
+		notes += fmt.Sprintf(", %.1f km", wo.distanceKm)
+	}
+	if wo.kcal > 0 {
+		notes += fmt.Sprintf(", %d kcal", int(wo.kcal+0.5))
+	}
+
+	return WorkoutInput{
+		Date:  formatDate(wo.start),
+		Type:  workoutType,
+		Notes: notes,
+	}
+}
+
+// storeAppleHealthData writes the parsed export in one transaction. Existing
+// Oura and manual data always wins over imported samples.
+func storeAppleHealthData(ctx context.Context, q querier, data *appleHealthData) (AppleHealthImportCounts, error) {
+	counts := AppleHealthImportCounts{SkippedDuplicates: data.skipped}
+
+	// Steps: sources overlap (phone and watch), so take the largest source total
+	for day, sources := range data.steps {
+		var best float64
+		for _, total := range sources {
+			if total > best {
+				best = total
+			}
+		}
+		// Kept apart from oura_daily; the daily_steps view falls back to it
+		// where Oura has no step count
+		if err := upsertDailyMetric(ctx, q, day, "steps", best, "apple_health"); err != nil {
+			return counts, err
+		}
+		counts.StepDays++
+	}
+
+	for day, wt := range data.weight {
+		if wt.kg <= 0 || wt.kg > 500 {
+			continue
+		}
+		result, err := q.Exec(ctx, `
+			INSERT INTO weight_entries (date, weight_kg, notes, source)
+			VALUES ($1, $2, 'Apple Health', 'apple_health')
+			ON CONFLICT (date) DO UPDATE SET weight_kg = EXCLUDED.weight_kg
+			WHERE weight_entries.source = 'apple_health'
+		`, day, wt.kg)
+		if err != nil {
+			return counts, err
+		}
+		if result.RowsAffected() == 0 {
+			counts.SkippedDuplicates++
+		} else {
+			counts.WeightEntries++
+		}
+	}
+
+	for kind, days := range data.body {
+		for day, m := range days {
+			if validateBodyMeasurement(kind, m.kg) != nil {
+				continue
+			}
+			result, err := q.Exec(ctx, `
+				INSERT INTO body_measurements (date, kind, value, source, notes)
+				VALUES ($1, $2, $3, 'apple_health', 'Apple Health')
+				ON CONFLICT (date, kind) DO UPDATE SET value = EXCLUDED.value
+				WHERE body_measurements.source = 'apple_health'
+			`, day, kind, m.kg)
+			if err != nil {
+				return counts, err
+			}
+			if result.RowsAffected() == 0 {
+				counts.SkippedDuplicates++
+			} else {
+				counts.BodyMeasurements++
+			}
+		}
+	}
+
+	for day, avg := range data.restingHR {
+		if err := upsertDailyMetric(ctx, q, day, "resting_heart_rate", avg.sum/float64(avg.count), "apple_health"); err != nil {
+			return counts, err
+		}
+		counts.RestingHeartRates++
+	}
+	for day, avg := range data.hrv {
+		if err := upsertDailyMetric(ctx, q, day, "hrv_sdnn", avg.sum/float64(avg.count), "apple_health"); err != nil {
+			return counts, err
+		}
+		counts.HrvDays++
+	}
+
+	for _, wo := range data.workouts {
+		var duplicate bool
+		err := q.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM workouts
+				WHERE source <> 'apple_health'
+					AND started_at BETWEEN $1::timestamptz - $2 * INTERVAL '1 minute' AND $1::timestamptz + $2 * INTERVAL '1 minute'
+			)
+		`, wo.start, int(workoutDuplicateWindow.Minutes())).Scan(&duplicate)
+		if err != nil {
+			return counts, err
+		}
+		if duplicate {
+			counts.SkippedDuplicates++
+			continue
+		}
+
+		input := appleWorkoutInput(wo)
+		var distance *float64
+		var calories *int
+		if wo.distanceKm > 0 {
+			meters := wo.distanceKm * 1000
+			distance = &meters
+		}
+		if wo.kcal > 0 {
+			kcal := int(wo.kcal + 0.5)
+			calories = &kcal
+		}
+		_, err = q.Exec(ctx, `
+			INSERT INTO workouts (date, type, notes, source, external_id, started_at, duration_seconds, distance_meters, calories)
+			VALUES ($1, $2, $3, 'apple_health', $4, $5, $6, $7, $8)
+			ON CONFLICT (source, external_id) DO UPDATE SET
+				date = EXCLUDED.date,
+				type = EXCLUDED.type,
+				notes = EXCLUDED.notes,
+				duration_seconds = EXCLUDED.duration_seconds,
+				distance_meters = EXCLUDED.distance_meters,
+				calories = EXCLUDED.calories
+		`, input.Date, input.Type, input.Notes, wo.start.UTC().Format(time.RFC3339), wo.start,
+			int(wo.minutes*60+0.5), distance, calories)
+		if err != nil {
+			return counts, err
+		}
+		counts.Workouts++
+	}
+
+	sessions, skipped := buildAppleSleepSessions(data.sleep)
+	counts.SkippedDuplicates += skipped
+	for _, s := range sessions {
+		var duplicate bool
+		err := q.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM sleep_sessions
+				WHERE source <> 'apple_health' AND bedtime_start < $2 AND bedtime_end > $1
+			)
+		`, s.start, s.end).Scan(&duplicate)
+		if err != nil {
+			return counts, err
+		}
+		if duplicate {
+			counts.SkippedDuplicates++
+			continue
+		}
+
+		asleep, awake := s.asleep, s.wake
+		input := SleepSessionInput{
+			BedtimeStart:      s.start.Format(time.RFC3339),
+			BedtimeEnd:        s.end.Format(time.RFC3339),
+			TotalSleepSeconds: &asleep,
+			AwakeSeconds:      &awake,
+			IsNap:             s.asleep < appleNapHours*3600,
+			Source:            "apple_health",
+		}
+		if s.hasStages {
+			deep, rem, light := s.deep, s.rem, s.light
+			input.DeepSleepSeconds, input.RemSleepSeconds, input.LightSleepSeconds = &deep, &rem, &light
+		}
+		start, end, err := validateSleepSession(&input)
+		if err != nil {
+			continue
+		}
+		if _, err := upsertSleepSession(ctx, q, input, start, end); err != nil {
+			return counts, err
+		}
+		counts.SleepSessions++
+	}
+
+	return counts, nil
+}
+
+// upsertDailyMetric stores one daily value per metric and source
+func upsertDailyMetric(ctx context.Context, q querier, day, metric string, value float64, source string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO daily_metrics (day, metric, value, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (day, metric, source) DO UPDATE SET value = EXCLUDED.value
+	`, day, metric, value, source)
+	return err
+}
+
+// openAppleExport opens export.xml, either inside export.zip or as a plain file
+func openAppleExport(filePath string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != "PK\x03\x04" {
+		// Not a zip archive: treat the upload as export.xml itself
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	}
+	f.Close()
+
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// export.xml sits in apple_health_export/; export_cda.xml is a different format
+	var found *zip.File
+	for _, zf := range zr.File {
+		name := path.Base(zf.Name)
+		if name == "export.xml" {
+			found = zf
+			break
+		}
+		if strings.HasSuffix(name, ".xml") && !strings.Contains(name, "cda") &&
+			(found == nil || zf.UncompressedSize64 > found.UncompressedSize64) {
+			found = zf
+		}
+	}
+	if found == nil {
+		zr.Close()
+		return nil, 0, errors.New("export.xml not found in archive")
+	}
+
+	rc, err := found.Open()
+	if err != nil {
+		zr.Close()
+		return nil, 0, err
+	}
+	return &zipEntryReader{ReadCloser: rc, archive: zr}, int64(found.UncompressedSize64), nil
+}
+
+// zipEntryReader closes the archive together with the entry
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z *zipEntryReader) Close() error {
+	z.ReadCloser.Close()
+	return z.archive.Close()
+}
+
+// runAppleHealthImport parses and stores an uploaded export in the background
+func (h *Handler) runAppleHealthImport(id int64, filePath string) {
+	defer os.Remove(filePath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), appleImportTimeout)
+	defer cancel()
+
+	fail := func(err error) {
+		h.db.Exec(ctx, `
+			UPDATE apple_health_imports SET status = 'failed', error = $2, finished_at = NOW()
+			WHERE id = $1
+		`, id, err.Error())
+	}
+
+	export, size, err := openAppleExport(filePath)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer export.Close()
+
+	_, err = h.db.Exec(ctx, `
+		UPDATE apple_health_imports SET status = 'running', bytes_total = $2, started_at = NOW()
+		WHERE id = $1
+	`, id, size)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	data, err := parseAppleHealthXML(&countingReader{r: export}, func(bytes, records int64) {
+		h.db.Exec(ctx, `
+			UPDATE apple_health_imports SET bytes_processed = $2, records_processed = $3
+			WHERE id = $1
+		`, id, bytes, records)
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	counts, err := storeAppleHealthData(ctx, tx, data)
+	if err != nil {
+		fail(err)
+		return
+	}
+	countsJSON, _ := json.Marshal(counts)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE apple_health_imports SET status = 'completed', counts = $2, bytes_processed = bytes_total, finished_at = NOW()
+		WHERE id = $1
+	`, id, countsJSON)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		fail(err)
+	}
+}
+
+// appleImportColumns is the column list read by scanAppleHealthImport
+const appleImportColumns = `id, status, filename, bytes_total, bytes_processed, records_processed,
+	counts, error, created_at, started_at, finished_at`
+
+// scanAppleHealthImport scans a row selected with appleImportColumns
+func scanAppleHealthImport(row pgx.Row) (AppleHealthImport, error) {
+	var imp AppleHealthImport
+	var counts []byte
+	err := row.Scan(&imp.ID, &imp.Status, &imp.Filename, &imp.BytesTotal, &imp.BytesProcessed,
+		&imp.RecordsProcessed, &counts, &imp.Error, &imp.CreatedAt, &imp.StartedAt, &imp.FinishedAt)
+	if err != nil {
+		return imp, err
+	}
+	json.Unmarshal(counts, &imp.Counts)
+	if imp.BytesTotal > 0 {
+		imp.Progress = float64(imp.BytesProcessed) / float64(imp.BytesTotal) * 100
+	}
+	return imp, nil
+}
+
+// --- Apple Health import handlers ---
+
+// ImportAppleHealth accepts export.zip (or export.xml), either as the raw
+// request body or as the "file" field of a multipart form, and starts a
+// background import. Poll GET /health/import/apple-health/{id} for progress.
+func (h *Handler) ImportAppleHealth(w http.ResponseWriter, r *http.Request) {
+	var running bool
+	err := h.db.QueryRow(r.Context(), `
+		SELECT EXISTS (SELECT 1 FROM apple_health_imports WHERE status IN ('pending', 'running'))
+	`).Scan(&running)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if running {
+		core.WriteError(w, http.StatusConflict, errAppleImportRunning.Error())
+		return
+	}
+
+	// Exports are often larger than a gigabyte; lift the server read deadline
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	body := io.Reader(r.Body)
+	filename := r.URL.Query().Get("filename")
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				core.WriteError(w, http.StatusBadRequest, "file field is required")
+				return
+			}
+			if err != nil {
+				core.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if part.FormName() == "file" {
+				body = part
+				filename = part.FileName()
+				break
+			}
+		}
+	}
+	if filename == "" {
+		filename = "export.zip"
+	}
+
+	tmp, err := os.CreateTemp("", "apple-health-*")
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	size, err := io.Copy(tmp, body)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		core.WriteError(w, http.StatusBadRequest, "upload failed: "+err.Error())
+		return
+	}
+	if size == 0 {
+		os.Remove(tmp.Name())
+		core.WriteError(w, http.StatusBadRequest, "export file is empty")
+		return
+	}
+
+	imp, err := scanAppleHealthImport(h.db.QueryRow(r.Context(), `
+		INSERT INTO apple_health_imports (filename) VALUES ($1)
+		RETURNING `+appleImportColumns, filename))
+	if err != nil {
+		os.Remove(tmp.Name())
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			core.WriteError(w, http.StatusConflict, errAppleImportRunning.Error())
+			return
+		}
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	go h.runAppleHealthImport(imp.ID, tmp.Name())
+
+	core.WriteJSON(w, http.StatusAccepted, imp)
+}
+
+// ListAppleHealthImports returns past and running imports, newest first
+func (h *Handler) ListAppleHealthImports(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+appleImportColumns+`
+		FROM apple_health_imports
+		ORDER BY id DESC
+		LIMIT 50
+	`)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	results := []AppleHealthImport{}
+	for rows.Next() {
+		imp, err := scanAppleHealthImport(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results = append(results, imp)
+	}
+
+	core.WriteJSON(w, http.StatusOK, results)
+}
+
+// GetAppleHealthImport returns the status and progress of an import
+func (h *Handler) GetAppleHealthImport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	imp, err := scanAppleHealthImport(h.db.QueryRow(r.Context(),
+		`SELECT `+appleImportColumns+` FROM apple_health_imports WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Import not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, imp)
+}
@@ -0,0 +1,382 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	weightSmoothing     = 0.1  // Hacker's Diet: the trend moves 10% towards each day's weight
+	weightTrendWarmup   = 30   // extra entries loaded so the trend has settled
+	weightRateDays      = 28   // trend days fitted for the weekly rate
+	minWeightRatePoints = 4    // entries needed for a rate
+	weightRateStable    = 0.1  // kg/week below which the trend is stable
+	weightReachedKg     = 0.1  // trend this close to the target counts as reached
+	kcalPerKg           = 7700 // energy in one kg of body fat
+)
+
+// bodyMeasurementUnits lists the supported measurement kinds and their units
+var bodyMeasurementUnits = map[string]string{
+	"body_fat_pct":   "%",
+	"muscle_mass_kg": "kg",
+	"lean_mass_kg":   "kg",
+	"bone_mass_kg":   "kg",
+	"body_water_pct": "%",
+	"visceral_fat":   "level",
+	"waist_cm":       "cm",
+	"hip_cm":         "cm",
+	"chest_cm":       "cm",
+	"neck_cm":        "cm",
+	"arm_cm":         "cm",
+	"thigh_cm":       "cm",
+}
+
+// bodyMeasurementKinds returns the measurement kinds in a stable order
+func bodyMeasurementKinds() []string {
+	kinds := make([]string, 0, len(bodyMeasurementUnits))
+	for kind := range bodyMeasurementUnits {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// validateBodyMeasurement checks a value is plausible for its unit
+func validateBodyMeasurement(kind string, value float64) error {
+	unit, ok := bodyMeasurementUnits[kind]
+	if !ok {
+		return fmt.Errorf("unknown measurement %q", kind)
+	}
+	max := 300.0
+	if unit == "%" || unit == "level" {
+		max = 100
+	}
+	if value <= 0 || value >= max {
+		return fmt.Errorf("%s must be between 0 and %.0f", kind, max)
+	}
+	return nil
+}
+
+// smoothWeights sets the exponentially smoothed trend on chronological
+// entries. A gap of n days moves the trend as if the new weight had been
+// measured on each of them: 1-(1-α)^n of the difference.
+func smoothWeights(entries []WeightEntry) {
+	var trend float64
+	var prev time.Time
+	for i := range entries {
+		day, _ := time.Parse("2006-01-02", entries[i].Date)
+		if i == 0 {
+			trend = entries[i].WeightKg
+		} else {
+			gap := math.Max(1, day.Sub(prev).Hours()/24)
+			trend += (1 - math.Pow(1-weightSmoothing, gap)) * (entries[i].WeightKg - trend)
+		}
+		t := round2(trend)
+		entries[i].TrendKg = &t
+		prev = day
+	}
+}
+
+// weeklyWeightRate fits the trend over the last 28 days and returns the
+// slope in kg per week
+func weeklyWeightRate(entries []WeightEntry) (float64, bool) {
+	if len(entries) == 0 {
+		return 0, false
+	}
+	last, _ := time.Parse("2006-01-02", entries[len(entries)-1].Date)
+	var xs, ys []float64
+	for _, e := range entries {
+		day, _ := time.Parse("2006-01-02", e.Date)
+		offset := day.Sub(last).Hours() / 24
+		if offset <= -weightRateDays || e.TrendKg == nil {
+			continue
+		}
+		xs = append(xs, offset)
+		ys = append(ys, *e.TrendKg)
+	}
+	if len(xs) < minWeightRatePoints || xs[0] > -7 {
+		return 0, false
+	}
+	slope, _ := linearFit(xs, ys)
+	return slope * 7, true
+}
+
+// projectWeight estimates when the trend reaches the target at the current
+// weekly rate, counting from the date of the latest trend value
+func projectWeight(trendKg, rate float64, target float64, from string, deadline *string) *WeightProjection {
+	p := &WeightProjection{
+		TargetKg:    target,
+		RemainingKg: round2(target - trendKg),
+		Deadline:    deadline,
+	}
+	switch {
+	case math.Abs(p.RemainingKg) < weightReachedKg:
+		p.Status = "reached"
+	case math.Abs(rate) < 0.01:
+		p.Status = "flat"
+	case (p.RemainingKg > 0) == (rate > 0):
+		p.Status = "on_track"
+		weeks := round1(p.RemainingKg / rate)
+		p.Weeks = &weeks
+		start, _ := time.Parse("2006-01-02", from)
+		date := formatDate(start.AddDate(0, 0, int(math.Ceil(p.RemainingKg/rate*7))))
+		p.TargetDate = &date
+	default:
+		p.Status = "moving_away"
+	}
+
+	if deadline != nil && p.Status != "reached" {
+		start, _ := time.Parse("2006-01-02", from)
+		end, err := time.Parse("2006-01-02", *deadline)
+		if err == nil && end.After(start) {
+			required := round2(p.RemainingKg / (end.Sub(start).Hours() / 24 / 7))
+			p.RequiredWeeklyRate = &required
+		}
+		if p.TargetDate != nil && *p.TargetDate > *deadline {
+			p.Status = "behind"
+		}
+	}
+	return p
+}
+
+// weightTarget returns the target weight and deadline of the first active
+// goal on weight_kg
+func (h *Handler) weightTarget(ctx context.Context) (*float64, *string) {
+	var target float64
+	var deadline *time.Time
+	err := h.db.QueryRow(ctx, `
+		SELECT target, deadline FROM health_goals
+		WHERE active AND metric = 'weight_kg'
+		ORDER BY id
+		LIMIT 1
+	`).Scan(&target, &deadline)
+	if err != nil {
+		return nil, nil
+	}
+	if deadline == nil {
+		return &target, nil
+	}
+	d := formatDate(*deadline)
+	return &target, &d
+}
+
+// bodyMeasurementSummaries returns the latest value of each measured kind
+// with the change against the value about 30 days earlier
+func (h *Handler) bodyMeasurementSummaries(ctx context.Context) ([]BodyMeasurementSummary, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT DISTINCT ON (m.kind) m.kind, m.date, m.value::double precision, (
+			SELECT p.value::double precision FROM body_measurements p
+			WHERE p.kind = m.kind AND p.date <= m.date - 30
+			ORDER BY p.date DESC
+			LIMIT 1
+		)
+		FROM body_measurements m
+		ORDER BY m.kind, m.date DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []BodyMeasurementSummary{}
+	for rows.Next() {
+		var s BodyMeasurementSummary
+		var day time.Time
+		var previous *float64
+		if err := rows.Scan(&s.Kind, &day, &s.Latest, &previous); err != nil {
+			return nil, err
+		}
+		s.LatestDate = formatDate(day)
+		s.Unit = bodyMeasurementUnits[s.Kind]
+		if previous != nil {
+			change := round2(s.Latest - *previous)
+			s.Change30d = &change
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// loadBodyMeasurementSeries returns one correlation series per measurement
+// kind, including kinds without data so they can be used as goal metrics
+func (h *Handler) loadBodyMeasurementSeries(ctx context.Context, from, to string) ([]correlationSeries, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT kind, date, value::double precision FROM body_measurements
+		WHERE date >= $1 AND date <= $2
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]map[string]float64)
+	for rows.Next() {
+		var kind string
+		var day time.Time
+		var v float64
+		if err := rows.Scan(&kind, &day, &v); err != nil {
+			return nil, err
+		}
+		if values[kind] == nil {
+			values[kind] = make(map[string]float64)
+		}
+		values[kind][formatDate(day)] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var series []correlationSeries
+	for _, kind := range bodyMeasurementKinds() {
+		v := values[kind]
+		if v == nil {
+			v = map[string]float64{}
+		}
+		series = append(series, correlationSeries{key: kind, family: "body", values: v})
+	}
+	return series, nil
+}
+
+// --- Body measurement handlers ---
+
+// ListBodyMeasurements returns measurements, newest first. Query: ?kind=,
+// ?days= (default 365)
+func (h *Handler) ListBodyMeasurements(w http.ResponseWriter, r *http.Request) {
+	days := 365 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 3650 {
+			days = n
+		}
+	}
+	kind := r.URL.Query().Get("kind")
+	if _, ok := bodyMeasurementUnits[kind]; kind != "" && !ok {
+		core.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown measurement %q", kind))
+		return
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, date, kind, value::double precision, source, notes, created_at
+		FROM body_measurements
+		WHERE date >= $1 AND ($2 = '' OR kind = $2)
+		ORDER BY date DESC, kind
+	`, formatDate(h.today(r.Context()).AddDate(0, 0, -days)), kind)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	measurements := []BodyMeasurement{}
+	for rows.Next() {
+		var m BodyMeasurement
+		var day time.Time
+		if err := rows.Scan(&m.ID, &day, &m.Kind, &m.Value, &m.Source, &m.Notes, &m.CreatedAt); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		m.Date = formatDate(day)
+		m.Unit = bodyMeasurementUnits[m.Kind]
+		measurements = append(measurements, m)
+	}
+
+	core.WriteJSON(w, http.StatusOK, measurements)
+}
+
+// CreateBodyMeasurements records one or more measurements for a day,
+// replacing earlier values of the same kind on that day
+func (h *Handler) CreateBodyMeasurements(w http.ResponseWriter, r *http.Request) {
+	var input BodyMeasurementInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Default to today if no date provided
+	if input.Date == "" {
+		input.Date = h.todayKey(r.Context())
+	}
+	if _, err := time.Parse("2006-01-02", input.Date); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+		return
+	}
+	if len(input.Measurements) == 0 {
+		core.WriteError(w, http.StatusBadRequest, "measurements is required")
+		return
+	}
+	for kind, value := range input.Measurements {
+		if err := validateBodyMeasurement(kind, value); err != nil {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	created := []BodyMeasurement{}
+	for _, kind := range bodyMeasurementKinds() {
+		value, ok := input.Measurements[kind]
+		if !ok {
+			continue
+		}
+		m := BodyMeasurement{Date: input.Date, Kind: kind, Value: value, Unit: bodyMeasurementUnits[kind],
+			Source: "manual", Notes: strings.TrimSpace(input.Notes)}
+		err := tx.QueryRow(r.Context(), `
+			INSERT INTO body_measurements (date, kind, value, source, notes)
+			VALUES ($1, $2, $3, 'manual', $4)
+			ON CONFLICT (date, kind) DO UPDATE SET
+				value = EXCLUDED.value,
+				source = EXCLUDED.source,
+				notes = EXCLUDED.notes
+			RETURNING id, created_at
+		`, m.Date, m.Kind, m.Value, m.Notes).Scan(&m.ID, &m.CreatedAt)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		created = append(created, m)
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, created)
+}
+
+// DeleteBodyMeasurement removes a measurement
+func (h *Handler) DeleteBodyMeasurement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM body_measurements WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Measurement not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
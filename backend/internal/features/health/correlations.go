@@ -0,0 +1,536 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+const (
+	maxCorrelationLag      = 3
+	minCorrelationSamples  = 10
+	correlationSignificant = 0.05 // p-value, or q-value in discovery mode
+)
+
+// correlationSeries is one daily series that can be correlated. Family
+// groups series that are trivially related on the same day (a score and
+// its contributors), which discovery mode skips at lag 0.
+type correlationSeries struct {
+	key    string
+	label  string
+	family string
+	values map[string]float64
+}
+
+// correlationLabels names the series whose generated label reads poorly
+var correlationLabels = map[string]string{
+	"activity_steps":        "Steps",
+	"temperature_deviation": "Temperature deviation",
+	"weight_kg":             "Weight",
+	"sleep_hours":           "Sleep hours",
+	"workouts":              "Workouts",
+	"strength_sessions":     "Strength sessions",
+	"cardio_sessions":       "Cardio sessions",
+	"workout_minutes":       "Workout minutes",
+	"training_load":         "Training load",
+	"calendar_hours":        "Calendar hours",
+	"calendar_events":       "Calendar events",
+	"resting_heart_rate":    "Resting heart rate",
+	"hrv_sdnn":              "HRV (SDNN)",
+	"body_fat_pct":          "Body fat %",
+	"visceral_fat":          "Visceral fat",
+	"bp_systolic":           "Systolic BP",
+	"bp_diastolic":          "Diastolic BP",
+	"spo2":                  "SpO2",
+	"calories_in":           "Calories in",
+	"protein_g":             "Protein (g)",
+	"carbs_g":               "Carbs (g)",
+	"fat_g":                 "Fat (g)",
+	"water_ml":              "Water (ml)",
+}
+
+// correlationLabel returns a readable label for a series key
+func correlationLabel(key string) string {
+	if label, ok := correlationLabels[key]; ok {
+		return label
+	}
+	label := strings.ReplaceAll(key, "_", " ")
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// loadDaySeries runs a query returning (day, value) rows into a series
+func loadDaySeries(ctx context.Context, q querier, sql string, args ...interface{}) (map[string]float64, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]float64)
+	for rows.Next() {
+		var day time.Time
+		var v *float64
+		if err := rows.Scan(&day, &v); err != nil {
+			return nil, err
+		}
+		if v != nil {
+			values[formatDate(day)] = *v
+		}
+	}
+	return values, rows.Err()
+}
+
+// fillZeros turns a sparse event series into a daily one: days without an
+// event count as zero from the first recorded day onwards
+func fillZeros(values map[string]float64, start, end time.Time) {
+	first := ""
+	for day := range values {
+		if first == "" || day < first {
+			first = day
+		}
+	}
+	if first == "" {
+		return
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		d := formatDate(day)
+		if _, ok := values[d]; !ok && d > first {
+			values[d] = 0
+		}
+	}
+}
+
+// correlationQueries are the series loaded with one (day, value) query each.
+// Sparse series count days without an event as zero.
+var correlationQueries = []struct {
+	key, family, sql string
+	sparse           bool
+}{
+	{"sleep_hours", "sleep", `SELECT day, SUM(total_sleep_seconds) / 3600.0 FROM sleep_sessions
+		WHERE NOT is_nap AND total_sleep_seconds IS NOT NULL AND day >= $1 AND day <= $2 GROUP BY day`, false},
+	{"weight_kg", "body", `SELECT date, weight_kg::double precision FROM weight_entries WHERE date >= $1 AND date <= $2`, false},
+	{"resting_heart_rate", "body", `SELECT day, AVG(value) FROM daily_metrics
+		WHERE metric = 'resting_heart_rate' AND day >= $1 AND day <= $2 GROUP BY day`, false},
+	{"hrv_sdnn", "body", `SELECT day, AVG(value) FROM daily_metrics
+		WHERE metric = 'hrv_sdnn' AND day >= $1 AND day <= $2 GROUP BY day`, false},
+	{"workouts", "training", `SELECT date, COUNT(*)::double precision FROM workouts
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, true},
+	{"strength_sessions", "training", `SELECT date, COUNT(*)::double precision FROM workouts
+		WHERE type = 'strength' AND date >= $1 AND date <= $2 GROUP BY date`, true},
+	{"cardio_sessions", "training", `SELECT date, COUNT(*)::double precision FROM workouts
+		WHERE type = 'cardio' AND date >= $1 AND date <= $2 GROUP BY date`, true},
+	{"workout_minutes", "training", `SELECT date, SUM(duration_seconds) / 60.0 FROM workouts
+		WHERE date >= $1 AND date <= $2 AND duration_seconds IS NOT NULL GROUP BY date`, true},
+	{"bp_systolic", "body", `SELECT date, AVG(systolic)::double precision FROM vitals
+		WHERE kind = 'blood_pressure' AND date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"bp_diastolic", "body", `SELECT date, AVG(diastolic)::double precision FROM vitals
+		WHERE kind = 'blood_pressure' AND date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"spo2", "body", `SELECT date, AVG(value) FROM vitals
+		WHERE kind = 'spo2' AND date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"glucose", "body", `SELECT date, AVG(value) FROM vitals
+		WHERE kind = 'glucose' AND date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"calories_in", "nutrition", `SELECT date, SUM(calories)::double precision FROM meals
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"protein_g", "nutrition", `SELECT date, SUM(protein_g)::double precision FROM meals
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"carbs_g", "nutrition", `SELECT date, SUM(carbs_g)::double precision FROM meals
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"fat_g", "nutrition", `SELECT date, SUM(fat_g)::double precision FROM meals
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, false},
+	{"water_ml", "nutrition", `SELECT date, SUM(ml)::double precision FROM water_intake
+		WHERE date >= $1 AND date <= $2 GROUP BY date`, false},
+}
+
+// loadCorrelationSeries loads every series available for correlation
+// between start and end
+func (h *Handler) loadCorrelationSeries(ctx context.Context, start, end time.Time) ([]correlationSeries, error) {
+	from, to := formatDate(start), formatDate(end)
+	var all []correlationSeries
+
+	// oura_daily columns, one series each
+	for _, col := range ouraMetricColumns {
+		query := `SELECT day, ` + col + `::double precision FROM oura_daily WHERE day >= $1 AND day <= $2`
+		if col == "activity_steps" {
+			// Includes imported step counts on days Oura has none
+			query = `SELECT day, steps FROM daily_steps WHERE day >= $1 AND day <= $2`
+		}
+		values, err := loadDaySeries(ctx, h.db, query, from, to)
+		if err != nil {
+			return nil, err
+		}
+		family := strings.SplitN(col, "_", 2)[0]
+		if col == "temperature_deviation" {
+			family = "readiness"
+		}
+		all = append(all, correlationSeries{key: col, family: family, values: values})
+	}
+
+	for _, s := range correlationQueries {
+		values, err := loadDaySeries(ctx, h.db, s.sql, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if s.sparse {
+			fillZeros(values, start, end)
+		}
+		all = append(all, correlationSeries{key: s.key, family: s.family, values: values})
+	}
+
+	// Daily training load (session RPE)
+	days := int(end.Sub(start).Hours()/24) + 1
+	load, err := h.computeTrainingLoad(ctx, trainingLoadOptions{method: trainingLoadSRPE}, days, end)
+	if err != nil {
+		return nil, err
+	}
+	loadValues := make(map[string]float64)
+	for _, d := range load.Days {
+		if d.Workouts > 0 {
+			loadValues[d.Date] = d.Load
+		}
+	}
+	fillZeros(loadValues, start, end)
+	all = append(all, correlationSeries{key: "training_load", family: "training", values: loadValues})
+
+	calendar, err := h.loadCalendarSeries(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	all = append(all, calendar...)
+
+	body, err := h.loadBodyMeasurementSeries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	all = append(all, body...)
+
+	journal, err := h.loadJournalSeries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	all = append(all, journal...)
+
+	medications, err := h.loadMedicationSeries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	all = append(all, medications...)
+
+	for i := range all {
+		if all[i].label == "" {
+			all[i].label = correlationLabel(all[i].key)
+		}
+	}
+	return all, nil
+}
+
+// loadCalendarSeries returns the hours of timed events and the number of
+// events per day, with each event on the day it starts in the configured
+// zones
+func (h *Handler) loadCalendarSeries(ctx context.Context, start, end time.Time) ([]correlationSeries, error) {
+	// A day either side, since zones can move an event across midnight UTC
+	rows, err := h.db.Query(ctx, `
+		SELECT start_time, end_time, all_day
+		FROM calendar_events
+		WHERE status <> 'cancelled' AND start_time >= $1 AND start_time < $2
+	`, start.AddDate(0, 0, -1), end.AddDate(0, 0, 2))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := h.clock.get(ctx)
+	from, to := formatDate(start), formatDate(end)
+	hours := make(map[string]float64)
+	events := make(map[string]float64)
+	for rows.Next() {
+		var startTime, endTime time.Time
+		var allDay bool
+		if err := rows.Scan(&startTime, &endTime, &allDay); err != nil {
+			return nil, err
+		}
+		day := zones.dayOf(startTime)
+		if day < from || day > to {
+			continue
+		}
+		events[day]++
+		if !allDay {
+			hours[day] += endTime.Sub(startTime).Hours()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fillZeros(hours, start, end)
+	fillZeros(events, start, end)
+	return []correlationSeries{
+		{key: "calendar_hours", family: "calendar", values: hours},
+		{key: "calendar_events", family: "calendar", values: events},
+	}, nil
+}
+
+// dayKeys returns the YYYY-MM-DD keys from start to end plus the maximum
+// lag, so lagged lookups need no date arithmetic
+func dayKeys(start, end time.Time) []string {
+	var keys []string
+	for day := start; !day.After(end.AddDate(0, 0, maxCorrelationLag)); day = day.AddDate(0, 0, 1) {
+		keys = append(keys, formatDate(day))
+	}
+	return keys
+}
+
+// correlate pairs x on day t with y on day t+lag and computes the statistics
+func correlate(x, y correlationSeries, lag int, keys []string) *CorrelationResult {
+	var xs, ys []float64
+	for i := 0; i+maxCorrelationLag < len(keys); i++ {
+		xv, ok := x.values[keys[i]]
+		if !ok {
+			continue
+		}
+		yv, ok := y.values[keys[i+lag]]
+		if !ok {
+			continue
+		}
+		xs = append(xs, xv)
+		ys = append(ys, yv)
+	}
+
+	result := &CorrelationResult{X: x.key, Y: y.key, XLabel: x.label, YLabel: y.label, Lag: lag, N: len(xs)}
+	if len(xs) < minCorrelationSamples {
+		result.Insight = fmt.Sprintf("Not enough overlapping days to compare %s and %s (n=%d)", x.label, y.label, len(xs))
+		return result
+	}
+
+	result.Pearson = math.Round(pearsonCorrelation(xs, ys)*1000) / 1000
+	result.Spearman = math.Round(spearmanCorrelation(xs, ys)*1000) / 1000
+	result.PValue = correlationPValue(result.Pearson, len(xs))
+	lo, hi := correlationCI(result.Pearson, len(xs))
+	result.CILow, result.CIHigh = math.Round(lo*1000)/1000, math.Round(hi*1000)/1000
+	result.Strength = correlationStrength(result.Pearson)
+	result.Direction = correlationDirection(result.Pearson)
+	result.Significant = result.PValue < correlationSignificant
+	result.Insight = correlationInsight(result)
+	return result
+}
+
+// lagPhrase describes when y is measured relative to x
+func lagPhrase(lag int) string {
+	switch lag {
+	case 0:
+		return "the same day"
+	case 1:
+		return "the next day"
+	default:
+		return fmt.Sprintf("%d days later", lag)
+	}
+}
+
+// correlationInsight writes a plain-language summary of a result
+func correlationInsight(c *CorrelationResult) string {
+	stats := fmt.Sprintf("r=%.2f, n=%d", c.Pearson, c.N)
+	if !c.Significant || c.Strength == "none" {
+		return fmt.Sprintf("No reliable link between %s and %s %s (%s)",
+			strings.ToLower(c.XLabel), strings.ToLower(c.YLabel), lagPhrase(c.Lag), stats)
+	}
+	direction := "higher"
+	if c.Pearson < 0 {
+		direction = "lower"
+	}
+	return fmt.Sprintf("Higher %s goes with %s %s %s — a %s relationship (%s)",
+		strings.ToLower(c.XLabel), direction, strings.ToLower(c.YLabel), lagPhrase(c.Lag), c.Strength, stats)
+}
+
+// correlationWindow parses ?days= (default 180) into a date range ending today
+func (h *Handler) correlationWindow(r *http.Request) (time.Time, time.Time) {
+	days := 180 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 730 {
+			days = n
+		}
+	}
+	end := h.today(r.Context())
+	return end.AddDate(0, 0, -(days - 1)), end
+}
+
+// findCorrelationSeries returns the series with the given key
+func findCorrelationSeries(all []correlationSeries, key string) (correlationSeries, bool) {
+	for _, s := range all {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return correlationSeries{}, false
+}
+
+// correlationSeriesExists reports whether key names a series that
+// loadCorrelationSeries returns, without loading any values
+func (h *Handler) correlationSeriesExists(ctx context.Context, key string) (bool, error) {
+	static := []string{"training_load", "calendar_hours", "calendar_events", "mood", "energy", "stress"}
+	static = append(static, ouraMetricColumns...)
+	static = append(static, bodyMeasurementKinds()...)
+	for _, q := range correlationQueries {
+		static = append(static, q.key)
+	}
+	for _, k := range static {
+		if k == key {
+			return true, nil
+		}
+	}
+
+	var exists bool
+	switch {
+	case strings.HasPrefix(key, "tag_"):
+		err := h.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM journal_tags WHERE name = $1)`,
+			strings.TrimPrefix(key, "tag_")).Scan(&exists)
+		return exists, err
+	case strings.HasPrefix(key, "med_"):
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, "med_"), 10, 64)
+		if err != nil {
+			return false, nil
+		}
+		err = h.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM medications WHERE id = $1)`, id).Scan(&exists)
+		return exists, err
+	}
+	return false, nil
+}
+
+// --- Correlation handlers ---
+
+// ListCorrelationSeries returns the series that can be correlated
+func (h *Handler) ListCorrelationSeries(w http.ResponseWriter, r *http.Request) {
+	start, end := h.correlationWindow(r)
+	all, err := h.loadCorrelationSeries(r.Context(), start, end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	infos := make([]CorrelationSeriesInfo, 0, len(all))
+	for _, s := range all {
+		infos = append(infos, CorrelationSeriesInfo{Key: s.key, Label: s.label, Family: s.family, Days: len(s.values)})
+	}
+	core.WriteJSON(w, http.StatusOK, infos)
+}
+
+// GetCorrelation correlates ?x= with ?y= at ?lag= (0-3), or at every lag
+// when lag is omitted
+func (h *Handler) GetCorrelation(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	xKey, yKey := query.Get("x"), query.Get("y")
+	if xKey == "" || yKey == "" {
+		core.WriteError(w, http.StatusBadRequest, "x and y are required")
+		return
+	}
+
+	lags := []int{0, 1, 2, 3}
+	if l := query.Get("lag"); l != "" {
+		lag, err := strconv.Atoi(l)
+		if err != nil || lag < 0 || lag > maxCorrelationLag {
+			core.WriteError(w, http.StatusBadRequest, "lag must be between 0 and 3")
+			return
+		}
+		lags = []int{lag}
+	}
+
+	start, end := h.correlationWindow(r)
+	all, err := h.loadCorrelationSeries(r.Context(), start, end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	x, okX := findCorrelationSeries(all, xKey)
+	y, okY := findCorrelationSeries(all, yKey)
+	if !okX || !okY {
+		core.WriteError(w, http.StatusBadRequest, "unknown series; see /dashboard/health/correlations/series")
+		return
+	}
+
+	keys := dayKeys(start, end)
+	results := make([]CorrelationResult, 0, len(lags))
+	for _, lag := range lags {
+		results = append(results, *correlate(x, y, lag, keys))
+	}
+	core.WriteJSON(w, http.StatusOK, results)
+}
+
+// DiscoverCorrelations tests every pair of series at every lag and ranks
+// the strongest relationships that survive false discovery rate control.
+// Query: ?days=, ?min_n= (default 30), ?limit= (default 20).
+func (h *Handler) DiscoverCorrelations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	minN := 30
+	if v := query.Get("min_n"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= minCorrelationSamples {
+			minN = n
+		}
+	}
+	limit := 20
+	if v := query.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+
+	start, end := h.correlationWindow(r)
+	all, err := h.loadCorrelationSeries(r.Context(), start, end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	keys := dayKeys(start, end)
+	var tested []*CorrelationResult
+	for _, x := range all {
+		if len(x.values) < minN {
+			continue
+		}
+		for _, y := range all {
+			if x.key == y.key || len(y.values) < minN {
+				continue
+			}
+			for lag := 0; lag <= maxCorrelationLag; lag++ {
+				// Same-day pairs are symmetric and same-family pairs are
+				// trivially related
+				if lag == 0 && (x.key > y.key || x.family == y.family) {
+					continue
+				}
+				if c := correlate(x, y, lag, keys); c.N >= minN {
+					tested = append(tested, c)
+				}
+			}
+		}
+	}
+
+	pValues := make([]float64, len(tested))
+	for i, c := range tested {
+		pValues[i] = c.PValue
+	}
+	qValues := benjaminiHochberg(pValues)
+
+	discovery := CorrelationDiscovery{Tested: len(tested), Results: []CorrelationResult{}}
+	for i, c := range tested {
+		q := qValues[i]
+		c.QValue = &q
+		c.Significant = q < correlationSignificant
+		c.Insight = correlationInsight(c)
+		if c.Significant && c.Strength != "none" {
+			discovery.Results = append(discovery.Results, *c)
+		}
+	}
+	sort.Slice(discovery.Results, func(i, j int) bool {
+		return math.Abs(discovery.Results[i].Pearson) > math.Abs(discovery.Results[j].Pearson)
+	})
+	discovery.Significant = len(discovery.Results)
+	if len(discovery.Results) > limit {
+		discovery.Results = discovery.Results[:limit]
+	}
+
+	core.WriteJSON(w, http.StatusOK, discovery)
+}
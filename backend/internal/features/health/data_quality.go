@@ -0,0 +1,291 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+const (
+	ingestionSourceOuraBulk  = "oura_bulk"
+	ingestionSourceOuraSync  = "oura_sync"
+	ingestionSourceSleepBulk = "sleep_sessions_bulk"
+)
+
+// ouraValueRange is the plausible range of an oura_daily column
+type ouraValueRange struct {
+	min, max float64
+}
+
+// ouraPlausibleRanges are the columns whose values fall outside the
+// 0-100 score range. Every other column is a score or score contributor.
+var ouraPlausibleRanges = map[string]ouraValueRange{
+	"activity_steps":           {0, 80000},
+	"activity_total_calories":  {800, 8000},
+	"activity_active_calories": {0, 5000},
+	"temperature_deviation":    {-3, 3},
+}
+
+// ouraScoreFamilies are the score columns that show a family was recorded
+var ouraScoreFamilies = []struct{ family, column string }{
+	{"sleep", "sleep_score"},
+	{"readiness", "readiness_score"},
+	{"activity", "activity_score"},
+}
+
+// ouraPlausibility returns why a value is implausible, or ""
+func ouraPlausibility(column string, value float64) string {
+	rng, ok := ouraPlausibleRanges[column]
+	if !ok {
+		rng = ouraValueRange{0, 100}
+	}
+	switch {
+	case value < rng.min:
+		return fmt.Sprintf("below %g", rng.min)
+	case value > rng.max:
+		return fmt.Sprintf("above %g", rng.max)
+	}
+	return ""
+}
+
+// ouraInputValues returns the metrics set in an input, keyed by column
+func ouraInputValues(input OuraDailyInput) map[string]float64 {
+	data, _ := json.Marshal(input)
+	var values map[string]interface{}
+	json.Unmarshal(data, &values)
+
+	out := make(map[string]float64)
+	for key, v := range values {
+		if f, ok := v.(float64); ok {
+			out[key] = f
+		}
+	}
+	return out
+}
+
+// validateOuraDaily rejects a row without a valid day or with a value that
+// cannot be right: a negative count or a score above 100
+func validateOuraDaily(input OuraDailyInput) error {
+	if input.Day == "" {
+		return fmt.Errorf("day is required")
+	}
+	if _, err := time.Parse("2006-01-02", input.Day); err != nil {
+		return fmt.Errorf("day must be YYYY-MM-DD")
+	}
+	values := ouraInputValues(input)
+	columns := make([]string, 0, len(values))
+	for col := range values {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	for _, col := range columns {
+		v := values[col]
+		if _, ranged := ouraPlausibleRanges[col]; ranged {
+			if v < 0 && col != "temperature_deviation" {
+				return fmt.Errorf("%s must not be negative", col)
+			}
+			continue
+		}
+		if v < 0 || v > 100 {
+			return fmt.Errorf("%s must be between 0 and 100", col)
+		}
+	}
+	return nil
+}
+
+// recordIngestionErrors stores rows that failed to import so the data
+// quality report can show them later. A negative index is stored as no row.
+func recordIngestionErrors(ctx context.Context, q querier, source string, rows []IngestionRowError, payloads []interface{}) error {
+	for i, row := range rows {
+		var day *string
+		if _, err := time.Parse("2006-01-02", row.Day); err == nil {
+			day = &row.Day
+		}
+		var index *int
+		if row.Index >= 0 {
+			index = &rows[i].Index
+		}
+		var payload []byte
+		if i < len(payloads) && payloads[i] != nil {
+			payload, _ = json.Marshal(payloads[i])
+		}
+		_, err := q.Exec(ctx, `
+			INSERT INTO ingestion_errors (source, day, row_index, reason, payload)
+			VALUES ($1, $2, $3, $4, $5)
+		`, source, day, index, row.Error, payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearIngestionErrors removes recorded ingestion failures. Query:
+// ?source= to clear only one source
+func (h *Handler) ClearIngestionErrors(w http.ResponseWriter, r *http.Request) {
+	_, err := h.db.Exec(r.Context(), `
+		DELETE FROM ingestion_errors WHERE $1 = '' OR source = $1
+	`, r.URL.Query().Get("source"))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dataGaps groups consecutive missing days into ranges
+func dataGaps(missing []string) []DataGap {
+	gaps := []DataGap{}
+	for _, day := range missing {
+		t, _ := time.Parse("2006-01-02", day)
+		if n := len(gaps); n > 0 {
+			end, _ := time.Parse("2006-01-02", gaps[n-1].End)
+			if t.Equal(end.AddDate(0, 0, 1)) {
+				gaps[n-1].End = day
+				gaps[n-1].Days++
+				continue
+			}
+		}
+		gaps = append(gaps, DataGap{Start: day, End: day, Days: 1})
+	}
+	return gaps
+}
+
+// GetDataQuality reports missing Oura days, days with partial metrics,
+// implausible stored values and recent ingestion failures. Today is left
+// out because its data is still arriving. Query: ?days= (default 90)
+func (h *Handler) GetDataQuality(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	days := 90 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 3650 {
+			days = n
+		}
+	}
+	end := h.today(ctx).AddDate(0, 0, -1)
+	start := end.AddDate(0, 0, -(days - 1))
+	from, to := formatDate(start), formatDate(end)
+
+	cols := make([]string, len(ouraMetricColumns))
+	for i, col := range ouraMetricColumns {
+		cols[i] = col + "::double precision"
+	}
+	rows, err := h.db.Query(ctx, `
+		SELECT day, `+strings.Join(cols, ", ")+`
+		FROM oura_daily
+		WHERE day >= $1 AND day <= $2
+		ORDER BY day
+	`, from, to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	report := DataQualityReport{
+		Days:           days,
+		From:           from,
+		To:             to,
+		Gaps:           []DataGap{},
+		PartialDays:    []PartialDay{},
+		Implausible:    []ImplausibleValue{},
+		IngestionFails: []IngestionError{},
+	}
+	present := make(map[string]bool)
+	for rows.Next() {
+		var day time.Time
+		values := make([]*float64, len(ouraMetricColumns))
+		dest := []interface{}{&day}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		d := formatDate(day)
+		present[d] = true
+
+		byColumn := make(map[string]*float64, len(values))
+		for i, col := range ouraMetricColumns {
+			byColumn[col] = values[i]
+			if values[i] == nil {
+				continue
+			}
+			if reason := ouraPlausibility(col, *values[i]); reason != "" {
+				report.Implausible = append(report.Implausible, ImplausibleValue{
+					Date: d, Metric: col, Value: *values[i], Reason: reason,
+				})
+			}
+		}
+		var missing []string
+		for _, f := range ouraScoreFamilies {
+			if byColumn[f.column] == nil {
+				missing = append(missing, f.family)
+			}
+		}
+		if len(missing) > 0 {
+			report.PartialDays = append(report.PartialDays, PartialDay{Date: d, Missing: missing})
+		} else {
+			report.CompleteDays++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var missing []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if d := formatDate(day); !present[d] {
+			missing = append(missing, d)
+		}
+	}
+	report.MissingDays = len(missing)
+	report.Gaps = dataGaps(missing)
+	report.CompletenessPct = round1(float64(report.CompleteDays) / float64(days) * 100)
+
+	failures, err := h.db.Query(ctx, `
+		SELECT id, source, day, row_index, reason, created_at
+		FROM ingestion_errors
+		WHERE created_at >= $1
+		ORDER BY created_at DESC
+		LIMIT 500
+	`, start)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer failures.Close()
+	for failures.Next() {
+		var e IngestionError
+		var day *time.Time
+		if err := failures.Scan(&e.ID, &e.Source, &day, &e.RowIndex, &e.Reason, &e.CreatedAt); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if day != nil {
+			d := formatDate(*day)
+			e.Day = &d
+		}
+		report.IngestionFails = append(report.IngestionFails, e)
+	}
+	if err := failures.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// The last Oura API sync failure, if the connection has one
+	h.db.QueryRow(ctx, `SELECT last_error FROM oura_connection WHERE id = 1`).Scan(&report.OuraSyncError)
+
+	core.WriteJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,410 @@
+package health
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Minimal decoder for Garmin FIT activity files. Only the session, lap,
+// record and sport messages are interpreted; everything else is skipped.
+
+// FIT global message numbers
+const (
+	fitMsgSport   = 12
+	fitMsgSession = 18
+	fitMsgLap     = 19
+	fitMsgRecord  = 20
+)
+
+// fitTimestampField is field 253 of every timestamped message
+const fitTimestampField = 253
+
+// fitEpoch is the FIT time origin, 1989-12-31T00:00:00Z
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// semicirclesToDegrees converts FIT positions to degrees
+const semicirclesToDegrees = 180.0 / (1 << 31)
+
+// fitFieldDef is one field of a definition message
+type fitFieldDef struct {
+	num      byte
+	size     byte
+	baseType byte
+}
+
+// fitDefinition describes the layout of a local message type
+type fitDefinition struct {
+	global    uint16
+	bigEndian bool
+	fields    []fitFieldDef
+	devSize   int // bytes of developer fields, skipped
+}
+
+// fitValue is a decoded numeric field; valid is false for FIT "invalid" values
+type fitValue struct {
+	v     int64
+	valid bool
+}
+
+// fitMessage holds the numeric fields of one data message
+type fitMessage map[byte]fitValue
+
+func (m fitMessage) uint(field byte) (int64, bool) {
+	f, ok := m[field]
+	return f.v, ok && f.valid
+}
+
+// fitActivity is what the decoder extracts from an activity file
+type fitActivity struct {
+	sport    string
+	subSport string
+	sessions []fitMessage
+	laps     []fitMessage
+	records  []fitMessage
+}
+
+// fitSports names the FIT sport enum values we care about
+var fitSports = map[int64]string{
+	0: "generic", 1: "running", 2: "cycling", 4: "fitness_equipment", 5: "swimming",
+	10: "training", 11: "walking", 12: "cross_country_skiing", 13: "alpine_skiing",
+	15: "rowing", 16: "mountaineering", 17: "hiking", 19: "paddling", 37: "stand_up_paddleboarding",
+}
+
+// fitSubSports names the FIT sub_sport values that change the workout type
+var fitSubSports = map[int64]string{
+	20: "strength_training", 26: "cardio_training", 62: "hiit",
+}
+
+// isFIT reports whether data starts with a FIT file header
+func isFIT(data []byte) bool {
+	return len(data) >= 12 && string(data[8:12]) == ".FIT"
+}
+
+// decodeFIT parses a FIT activity file
+func decodeFIT(data []byte) (*fitActivity, error) {
+	if !isFIT(data) {
+		return nil, errors.New("not a FIT file")
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || headerSize > len(data) {
+		return nil, errors.New("invalid FIT header")
+	}
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	end := headerSize + dataSize
+	if end > len(data) {
+		return nil, errors.New("FIT file is truncated")
+	}
+
+	act := &fitActivity{}
+	defs := make(map[byte]*fitDefinition)
+	var lastTimestamp uint32
+
+	r := bytes.NewReader(data[headerSize:end])
+	for r.Len() > 0 {
+		header, _ := r.ReadByte()
+
+		// Compressed timestamp header: a data message with a 5-bit time offset
+		if header&0x80 != 0 {
+			local := (header >> 5) & 0x03
+			offset := uint32(header & 0x1F)
+			timestamp := lastTimestamp&^0x1F + offset
+			if offset < lastTimestamp&0x1F {
+				timestamp += 0x20
+			}
+			lastTimestamp = timestamp
+
+			msg, global, err := readFITData(r, defs, local)
+			if err != nil {
+				return nil, err
+			}
+			msg[fitTimestampField] = fitValue{v: int64(timestamp), valid: true}
+			act.add(global, msg)
+			continue
+		}
+
+		local := header & 0x0F
+		if header&0x40 != 0 {
+			def, err := readFITDefinition(r, header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			defs[local] = def
+			continue
+		}
+
+		msg, global, err := readFITData(r, defs, local)
+		if err != nil {
+			return nil, err
+		}
+		if ts, ok := msg.uint(fitTimestampField); ok {
+			lastTimestamp = uint32(ts)
+		}
+		act.add(global, msg)
+	}
+
+	return act, nil
+}
+
+// add files a decoded message by its global number
+func (a *fitActivity) add(global uint16, msg fitMessage) {
+	switch global {
+	case fitMsgSport:
+		if v, ok := msg.uint(0); ok {
+			a.sport = fitSports[v]
+		}
+		if v, ok := msg.uint(1); ok {
+			a.subSport = fitSubSports[v]
+		}
+	case fitMsgSession:
+		a.sessions = append(a.sessions, msg)
+		if v, ok := msg.uint(5); ok && a.sport == "" {
+			a.sport = fitSports[v]
+		}
+		if v, ok := msg.uint(6); ok && a.subSport == "" {
+			a.subSport = fitSubSports[v]
+		}
+	case fitMsgLap:
+		a.laps = append(a.laps, msg)
+	case fitMsgRecord:
+		a.records = append(a.records, msg)
+	}
+}
+
+// readFITDefinition reads a definition message body
+func readFITDefinition(r *bytes.Reader, hasDevFields bool) (*fitDefinition, error) {
+	fixed := make([]byte, 5)
+	if _, err := r.Read(fixed); err != nil {
+		return nil, errors.New("FIT definition is truncated")
+	}
+	def := &fitDefinition{bigEndian: fixed[1] == 1}
+	if def.bigEndian {
+		def.global = binary.BigEndian.Uint16(fixed[2:4])
+	} else {
+		def.global = binary.LittleEndian.Uint16(fixed[2:4])
+	}
+
+	def.fields = make([]fitFieldDef, fixed[4])
+	for i := range def.fields {
+		var f [3]byte
+		if n, _ := r.Read(f[:]); n != 3 {
+			return nil, errors.New("FIT definition is truncated")
+		}
+		def.fields[i] = fitFieldDef{num: f[0], size: f[1], baseType: f[2]}
+	}
+
+	if hasDevFields {
+		count, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.New("FIT definition is truncated")
+		}
+		for i := 0; i < int(count); i++ {
+			var f [3]byte
+			if n, _ := r.Read(f[:]); n != 3 {
+				return nil, errors.New("FIT definition is truncated")
+			}
+			def.devSize += int(f[1])
+		}
+	}
+	return def, nil
+}
+
+// readFITData reads a data message using its local definition
+func readFITData(r *bytes.Reader, defs map[byte]*fitDefinition, local byte) (fitMessage, uint16, error) {
+	def, ok := defs[local]
+	if !ok {
+		return nil, 0, fmt.Errorf("FIT data message for undefined local type %d", local)
+	}
+
+	msg := make(fitMessage, len(def.fields))
+	for _, f := range def.fields {
+		buf := make([]byte, f.size)
+		if n, _ := r.Read(buf); n != int(f.size) {
+			return nil, 0, errors.New("FIT data message is truncated")
+		}
+		if v, ok := decodeFITNumber(buf, f.baseType, def.bigEndian); ok {
+			msg[f.num] = v
+		}
+	}
+	if def.devSize > 0 {
+		if _, err := r.Seek(int64(def.devSize), 1); err != nil {
+			return nil, 0, err
+		}
+	}
+	return msg, def.global, nil
+}
+
+// decodeFITNumber decodes 1, 2 and 4 byte integer fields; other sizes
+// (strings, arrays, 64-bit values) are not needed and are ignored
+func decodeFITNumber(buf []byte, baseType byte, bigEndian bool) (fitValue, bool) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	signed := false
+	switch baseType & 0x1F {
+	case 1, 3, 5: // sint8, sint16, sint32
+		signed = true
+	case 7, 8, 9, 13, 14, 15, 16: // string, float32, float64, byte, 64-bit types
+		return fitValue{}, false
+	}
+
+	var u uint32
+	var invalid uint32
+	switch len(buf) {
+	case 1:
+		u, invalid = uint32(buf[0]), 0xFF
+		if signed {
+			invalid = 0x7F
+		}
+	case 2:
+		u, invalid = uint32(order.Uint16(buf)), 0xFFFF
+		if signed {
+			invalid = 0x7FFF
+		}
+	case 4:
+		u, invalid = order.Uint32(buf), 0xFFFFFFFF
+		if signed {
+			invalid = 0x7FFFFFFF
+		}
+	default:
+		return fitValue{}, false
+	}
+
+	// uint8z/uint16z/uint32z use zero as the invalid value
+	if baseType&0x1F == 10 || baseType&0x1F == 11 || baseType&0x1F == 12 {
+		invalid = 0
+	}
+
+	value := fitValue{v: int64(u), valid: u != invalid}
+	if signed {
+		switch len(buf) {
+		case 1:
+			value.v = int64(int8(u))
+		case 2:
+			value.v = int64(int16(u))
+		case 4:
+			value.v = int64(int32(u))
+		}
+	}
+	return value, true
+}
+
+// fitTime converts a FIT timestamp to time.Time
+func fitTime(ts int64) time.Time {
+	return fitEpoch.Add(time.Duration(ts) * time.Second)
+}
+
+// trackPoints converts record messages into track points
+func (a *fitActivity) trackPoints() []TrackPoint {
+	points := make([]TrackPoint, 0, len(a.records))
+	for _, rec := range a.records {
+		ts, ok := rec.uint(fitTimestampField)
+		if !ok {
+			continue
+		}
+		p := TrackPoint{Time: fitTime(ts)}
+
+		lat, latOK := rec.uint(0)
+		lon, lonOK := rec.uint(1)
+		if latOK && lonOK {
+			la, lo := float64(lat)*semicirclesToDegrees, float64(lon)*semicirclesToDegrees
+			p.Lat, p.Lon = &la, &lo
+		}
+		if alt, ok := rec.uint(78); ok { // enhanced_altitude
+			ele := float64(alt)/5 - 500
+			p.Elevation = &ele
+		} else if alt, ok := rec.uint(2); ok {
+			ele := float64(alt)/5 - 500
+			p.Elevation = &ele
+		}
+		if hr, ok := rec.uint(3); ok && hr > 0 {
+			v := int(hr)
+			p.HeartRate = &v
+		}
+		if d, ok := rec.uint(5); ok {
+			dist := float64(d) / 100
+			p.Distance = &dist
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// workoutLaps converts lap messages into laps
+func (a *fitActivity) workoutLaps() []WorkoutLap {
+	laps := make([]WorkoutLap, 0, len(a.laps))
+	for i, msg := range a.laps {
+		start, ok := msg.uint(2)
+		if !ok {
+			continue
+		}
+		lap := WorkoutLap{Index: i + 1, StartTime: fitTime(start)}
+		if v, ok := msg.uint(7); ok {
+			lap.DurationSeconds = float64(v) / 1000
+		}
+		if v, ok := msg.uint(9); ok {
+			lap.DistanceMeters = float64(v) / 100
+		}
+		if v, ok := msg.uint(15); ok {
+			hr := int(v)
+			lap.AvgHeartRate = &hr
+		}
+		if v, ok := msg.uint(16); ok {
+			hr := int(v)
+			lap.MaxHeartRate = &hr
+		}
+		if v, ok := msg.uint(21); ok {
+			gain := float64(v)
+			lap.ElevationGainMeters = &gain
+		}
+		if v, ok := msg.uint(11); ok {
+			kcal := int(v)
+			lap.Calories = &kcal
+		}
+		setLapPace(&lap)
+		laps = append(laps, lap)
+	}
+	return laps
+}
+
+// summary builds the workout totals from the session message, falling back
+// to the track for anything the device did not record
+func (a *fitActivity) summary(points []TrackPoint) workoutSummary {
+	s := summarizeTrack(points)
+	if len(a.sessions) == 0 {
+		return s
+	}
+
+	session := a.sessions[0]
+	if v, ok := session.uint(2); ok {
+		s.start = fitTime(v)
+	}
+	if v, ok := session.uint(7); ok {
+		d := int(v / 1000)
+		s.durationSeconds = &d
+	}
+	if v, ok := session.uint(9); ok {
+		d := float64(v) / 100
+		s.distanceMeters = &d
+	}
+	if v, ok := session.uint(22); ok {
+		g := float64(v)
+		s.elevationGain = &g
+	}
+	if v, ok := session.uint(16); ok {
+		hr := int(v)
+		s.avgHeartRate = &hr
+	}
+	if v, ok := session.uint(17); ok {
+		hr := int(v)
+		s.maxHeartRate = &hr
+	}
+	if v, ok := session.uint(11); ok {
+		kcal := int(v)
+		s.calories = &kcal
+	}
+	return s
+}
@@ -0,0 +1,637 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+const (
+	forecastHistoryDays      = 730 // days of history the models are fitted on
+	forecastMinSamples       = 60
+	forecastHoldoutShare     = 0.2 // newest share of samples held out for accuracy
+	forecastMinHoldout       = 14
+	forecastMinCoverage      = 0.3  // share of samples a feature needs a value on
+	forecastRidge            = 1.0  // L2 penalty on the standardised coefficients
+	forecastInterval         = 1.28 // hold-out RMSEs either side for an 80% range
+	forecastMaxTags          = 6
+	forecastMaxModelAge      = 7 * 24 * time.Hour
+	forecastTopContributions = 5
+	forecastTypicalBedtimes  = 14 // nights the typical bedtime is taken over
+)
+
+// forecastTargets are the next-day scores predicted
+var forecastTargets = []string{"readiness_score", "sleep_score"}
+
+// forecastBaseFeatures are the features of day D used to predict day D+1.
+// Journal tags common enough in the history are added when training.
+var forecastBaseFeatures = []string{
+	"readiness_score", "sleep_score", "readiness_7d", "sleep_score_7d",
+	"activity_score", "steps_k", "active_calories", "training_load", "workouts",
+	"bedtime_hours", "hrv_delta", "temperature_deviation",
+}
+
+var forecastFeatureLabels = map[string]string{
+	"readiness_score":       "Readiness today",
+	"sleep_score":           "Sleep score today",
+	"readiness_7d":          "Readiness 7-day average",
+	"sleep_score_7d":        "Sleep score 7-day average",
+	"activity_score":        "Activity score",
+	"steps_k":               "Steps (thousands)",
+	"active_calories":       "Active calories",
+	"training_load":         "Training load",
+	"workouts":              "Workouts",
+	"bedtime_hours":         "Bedtime (hours after midnight before)",
+	"hrv_delta":             "HRV vs 4-week baseline",
+	"temperature_deviation": "Temperature deviation",
+}
+
+var errForecastHistory = fmt.Errorf("a forecast needs at least %d days of Oura history", forecastMinSamples)
+
+// errForecastUntrained is reported until the retraining job has run
+var errForecastUntrained = errors.New("forecast models have not been trained yet; run POST /health/forecast/train")
+
+// forecastData holds the daily series the forecast features are built from
+type forecastData struct {
+	series   map[string]map[string]float64 // correlation series by key
+	labels   map[string]string             // correlation series labels by key
+	bedtimes map[string]float64            // main sleep ending on a day, minutes after the previous midnight
+	hrv      map[string]float64            // main sleep average HRV by day
+}
+
+// loadForecastData loads the history up to end
+func (h *Handler) loadForecastData(ctx context.Context, end time.Time) (*forecastData, error) {
+	start := end.AddDate(0, 0, -forecastHistoryDays)
+	all, err := h.loadCorrelationSeries(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	d := &forecastData{
+		series:   make(map[string]map[string]float64, len(all)),
+		labels:   make(map[string]string, len(all)),
+		bedtimes: make(map[string]float64),
+		hrv:      make(map[string]float64),
+	}
+	for _, s := range all {
+		d.series[s.key] = s.values
+		d.labels[s.key] = s.label
+	}
+
+	sessions, err := h.loadSleepSessions(ctx, forecastHistoryDays+2)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range mainSleeps(sessions) {
+		d.bedtimes[s.Day] = bedtimeMinutes(s.BedtimeStart)
+		if s.AverageHrv != nil {
+			d.hrv[s.Day] = float64(*s.AverageHrv)
+		}
+	}
+	return d, nil
+}
+
+// windowMean averages a series over the days from..to, requiring min values
+func windowMean(values map[string]float64, from, to time.Time, min int) (float64, bool) {
+	var sum float64
+	var n int
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if v, ok := values[formatDate(day)]; ok {
+			sum += v
+			n++
+		}
+	}
+	if n < min || n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// feature returns a feature of day D, or false when it is unknown
+func (d *forecastData) feature(key string, day time.Time) (float64, bool) {
+	date := formatDate(day)
+	switch key {
+	case "steps_k":
+		v, ok := d.series["activity_steps"][date]
+		return v / 1000, ok
+	case "active_calories":
+		v, ok := d.series["activity_active_calories"][date]
+		return v, ok
+	case "readiness_7d":
+		return windowMean(d.series["readiness_score"], day.AddDate(0, 0, -6), day, 3)
+	case "sleep_score_7d":
+		return windowMean(d.series["sleep_score"], day.AddDate(0, 0, -6), day, 3)
+	case "bedtime_hours":
+		// The night after day D ends on D+1
+		v, ok := d.bedtimes[formatDate(day.AddDate(0, 0, 1))]
+		return v / 60, ok
+	case "hrv_delta":
+		v, ok := d.hrv[date]
+		if !ok {
+			return 0, false
+		}
+		baseline, ok := windowMean(d.hrv, day.AddDate(0, 0, -28), day.AddDate(0, 0, -1), 7)
+		return v - baseline, ok
+	}
+	v, ok := d.series[key][date]
+	return v, ok
+}
+
+// label returns a readable label for a feature
+func (d *forecastData) label(key string) string {
+	if label, ok := forecastFeatureLabels[key]; ok {
+		return label
+	}
+	if label, ok := d.labels[key]; ok && label != "" {
+		return label
+	}
+	return correlationLabel(key)
+}
+
+// forecastTags returns the journal tags used on between 5% and 95% of the
+// days, the most common first
+func (d *forecastData) forecastTags(days []time.Time) []string {
+	type tagCount struct {
+		key   string
+		count int
+	}
+	var counts []tagCount
+	for key, values := range d.series {
+		if !strings.HasPrefix(key, "tag_") {
+			continue
+		}
+		c := tagCount{key: key}
+		for _, day := range days {
+			if values[formatDate(day)] > 0 {
+				c.count++
+			}
+		}
+		share := float64(c.count) / float64(len(days))
+		if share >= 0.05 && share <= 0.95 {
+			counts = append(counts, c)
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return counts[i].key < counts[j].key
+	})
+	var tags []string
+	for i := 0; i < len(counts) && i < forecastMaxTags; i++ {
+		tags = append(tags, counts[i].key)
+	}
+	return tags
+}
+
+// forecastSample is one day's features and the next day's target
+type forecastSample struct {
+	day      time.Time
+	x        []float64 // NaN when unknown
+	y        float64
+	baseline float64 // the target on day D, the naive forecast
+}
+
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting
+func solveLinear(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, errors.New("singular system")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+// fitRidge fits a ridge regression on standardised features. Unknown
+// values are imputed with the feature mean, which standardises to zero.
+func fitRidge(samples []forecastSample, keys []string, labels func(string) string) ([]ForecastWeight, float64, error) {
+	p := len(keys)
+	weights := make([]ForecastWeight, p)
+	for j, key := range keys {
+		var values []float64
+		for _, s := range samples {
+			if !math.IsNaN(s.x[j]) {
+				values = append(values, s.x[j])
+			}
+		}
+		mean, sd := meanStdDev(values)
+		weights[j] = ForecastWeight{Key: key, Label: labels(key), Mean: mean, SD: sd}
+	}
+
+	var ys []float64
+	for _, s := range samples {
+		ys = append(ys, s.y)
+	}
+	intercept, _ := meanStdDev(ys)
+
+	a := make([][]float64, p)
+	for j := range a {
+		a[j] = make([]float64, p)
+		a[j][j] = forecastRidge
+	}
+	b := make([]float64, p)
+	z := make([]float64, p)
+	for _, s := range samples {
+		for j, w := range weights {
+			z[j] = w.standardise(s.x[j])
+		}
+		for j := 0; j < p; j++ {
+			b[j] += z[j] * (s.y - intercept)
+			for k := 0; k < p; k++ {
+				a[j][k] += z[j] * z[k]
+			}
+		}
+	}
+	coefs, err := solveLinear(a, b)
+	if err != nil {
+		return nil, 0, err
+	}
+	for j := range weights {
+		weights[j].Coef = coefs[j]
+	}
+	return weights, intercept, nil
+}
+
+// standardise converts a feature value to standard deviations from the
+// mean, zero when unknown or constant
+func (w ForecastWeight) standardise(v float64) float64 {
+	if math.IsNaN(v) || w.SD == 0 {
+		return 0
+	}
+	return (v - w.Mean) / w.SD
+}
+
+// predict applies a model to a feature vector ordered as its features
+func (m *ForecastModel) predict(x []float64) float64 {
+	v := m.Intercept
+	for j, w := range m.Features {
+		v += w.Coef * w.standardise(x[j])
+	}
+	return v
+}
+
+// forecastTrust grades hold-out accuracy against the naive forecast that
+// tomorrow equals today
+func forecastTrust(a ForecastAccuracy) string {
+	switch {
+	case a.MAE < a.BaselineMAE*0.9 && a.R2 >= 0.2:
+		return "good"
+	case a.MAE < a.BaselineMAE:
+		return "fair"
+	}
+	return "poor"
+}
+
+// fitForecastModel fits a target on the days of the history. The newest
+// samples are held out to measure accuracy; the stored model is then
+// refitted on all samples.
+func fitForecastModel(target string, d *forecastData, keys []string, days []time.Time) (*ForecastModel, error) {
+	var samples []forecastSample
+	for _, day := range days {
+		y, ok := d.series[target][formatDate(day.AddDate(0, 0, 1))]
+		if !ok {
+			continue
+		}
+		baseline, ok := d.series[target][formatDate(day)]
+		if !ok {
+			continue
+		}
+		s := forecastSample{day: day, x: make([]float64, len(keys)), y: y, baseline: baseline}
+		for j, key := range keys {
+			s.x[j] = math.NaN()
+			if v, ok := d.feature(key, day); ok {
+				s.x[j] = v
+			}
+		}
+		samples = append(samples, s)
+	}
+	if len(samples) < forecastMinSamples {
+		return nil, errForecastHistory
+	}
+
+	// Drop features too rarely known to learn from
+	var used []int
+	for j := range keys {
+		known := 0
+		for _, s := range samples {
+			if !math.IsNaN(s.x[j]) {
+				known++
+			}
+		}
+		if float64(known) >= forecastMinCoverage*float64(len(samples)) {
+			used = append(used, j)
+		}
+	}
+	usedKeys := make([]string, len(used))
+	for i, j := range used {
+		usedKeys[i] = keys[j]
+	}
+	for i := range samples {
+		x := make([]float64, len(used))
+		for k, j := range used {
+			x[k] = samples[i].x[j]
+		}
+		samples[i].x = x
+	}
+
+	holdout := int(float64(len(samples)) * forecastHoldoutShare)
+	if holdout < forecastMinHoldout {
+		holdout = forecastMinHoldout
+	}
+	train, test := samples[:len(samples)-holdout], samples[len(samples)-holdout:]
+
+	weights, intercept, err := fitRidge(train, usedKeys, d.label)
+	if err != nil {
+		return nil, err
+	}
+	check := &ForecastModel{Intercept: intercept, Features: weights}
+	var absErr, sqErr, baseErr, sumY float64
+	for _, s := range test {
+		e := check.predict(s.x) - s.y
+		absErr += math.Abs(e)
+		sqErr += e * e
+		baseErr += math.Abs(s.baseline - s.y)
+		sumY += s.y
+	}
+	n := float64(len(test))
+	meanY := sumY / n
+	var totErr float64
+	for _, s := range test {
+		totErr += (s.y - meanY) * (s.y - meanY)
+	}
+	accuracy := ForecastAccuracy{
+		HoldoutSamples: len(test),
+		HoldoutFrom:    formatDate(test[0].day),
+		HoldoutTo:      formatDate(test[len(test)-1].day),
+		MAE:            round2(absErr / n),
+		RMSE:           round2(math.Sqrt(sqErr / n)),
+		BaselineMAE:    round2(baseErr / n),
+	}
+	if totErr > 0 {
+		accuracy.R2 = round2(1 - sqErr/totErr)
+	}
+	accuracy.Trust = forecastTrust(accuracy)
+
+	weights, intercept, err = fitRidge(samples, usedKeys, d.label)
+	if err != nil {
+		return nil, err
+	}
+	return &ForecastModel{
+		Target:    target,
+		From:      formatDate(samples[0].day),
+		To:        formatDate(samples[len(samples)-1].day),
+		Samples:   len(samples),
+		Intercept: intercept,
+		Features:  weights,
+		Accuracy:  accuracy,
+	}, nil
+}
+
+// trainForecastModels fits and stores a model for every target
+func (h *Handler) trainForecastModels(ctx context.Context) ([]ForecastModel, error) {
+	today := h.today(ctx)
+	d, err := h.loadForecastData(ctx, today)
+	if err != nil {
+		return nil, err
+	}
+
+	// Today is left out: its activity is still accumulating
+	var days []time.Time
+	for day := today.AddDate(0, 0, -forecastHistoryDays); day.Before(today); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	keys := append(append([]string{}, forecastBaseFeatures...), d.forecastTags(days)...)
+
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	models := []ForecastModel{}
+	for _, target := range forecastTargets {
+		m, err := fitForecastModel(target, d, keys, days)
+		if err != nil {
+			return nil, err
+		}
+		m.TrainedAt = time.Now()
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO forecast_models (target, model, trained_at) VALUES ($1, $2, $3)
+			ON CONFLICT (target) DO UPDATE SET model = EXCLUDED.model, trained_at = EXCLUDED.trained_at
+		`, target, data, m.TrainedAt)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, *m)
+	}
+	return models, tx.Commit(ctx)
+}
+
+// loadForecastModels returns the stored models by target
+func (h *Handler) loadForecastModels(ctx context.Context) (map[string]*ForecastModel, error) {
+	rows, err := h.db.Query(ctx, `SELECT target, model FROM forecast_models`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	models := make(map[string]*ForecastModel)
+	for rows.Next() {
+		var target string
+		var data []byte
+		if err := rows.Scan(&target, &data); err != nil {
+			return nil, err
+		}
+		var m ForecastModel
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		models[target] = &m
+	}
+	return models, rows.Err()
+}
+
+// forecastBedtime returns tonight's bedtime in minutes after the previous
+// midnight: from ?bedtime=HH:MM, the night already recorded, or the median
+// of recent nights
+func forecastBedtime(d *forecastData, query string, day time.Time) (float64, string, bool, error) {
+	if query != "" {
+		t, err := time.Parse("15:04", query)
+		if err != nil {
+			return 0, "", false, fmt.Errorf("bedtime must be HH:MM")
+		}
+		return bedtimeMinutes(t), "query", true, nil
+	}
+	if v, ok := d.bedtimes[formatDate(day.AddDate(0, 0, 1))]; ok {
+		return v, "recorded", true, nil
+	}
+	var recent []float64
+	for i := 0; i < forecastTypicalBedtimes; i++ {
+		if v, ok := d.bedtimes[formatDate(day.AddDate(0, 0, -i))]; ok {
+			recent = append(recent, v)
+		}
+	}
+	if len(recent) == 0 {
+		return 0, "", false, nil
+	}
+	sort.Float64s(recent)
+	return percentileSorted(recent, 0.5), "typical", true, nil
+}
+
+// --- Handlers ---
+
+// TrainForecast refits the forecast models on the current history. Run it
+// daily from cron (scripts/retrain-forecast.sh).
+func (h *Handler) TrainForecast(w http.ResponseWriter, r *http.Request) {
+	models, err := h.trainForecastModels(r.Context())
+	if err == errForecastHistory {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, models)
+}
+
+// GetForecast predicts tomorrow's readiness and sleep score from today's
+// activity, workouts, tonight's bedtime, journal tags and recent trends.
+// Query: ?bedtime=HH:MM (default the recorded or typical bedtime)
+func (h *Handler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	today := h.today(ctx)
+
+	models, err := h.loadForecastModels(ctx)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(models) == 0 {
+		core.WriteJSON(w, http.StatusOK, ReadinessForecast{Predictions: []ForecastPrediction{}, Message: errForecastUntrained.Error()})
+		return
+	}
+
+	d, err := h.loadForecastData(ctx, today)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Forecast from the newest day with Oura data, if it is recent
+	day := today
+	if _, ok := d.series["readiness_score"][formatDate(today)]; !ok {
+		for i := 1; i <= 2; i++ {
+			if _, ok := d.series["readiness_score"][formatDate(today.AddDate(0, 0, -i))]; ok {
+				day = today.AddDate(0, 0, -i)
+				break
+			}
+		}
+	}
+
+	bedtime, source, haveBedtime, err := forecastBedtime(d, r.URL.Query().Get("bedtime"), day)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	forecast := ReadinessForecast{
+		ForDate:     formatDate(day.AddDate(0, 0, 1)),
+		BasedOn:     formatDate(day),
+		Predictions: []ForecastPrediction{},
+	}
+	if haveBedtime {
+		forecast.Bedtime, forecast.BedtimeSource = formatClock(bedtime), source
+	}
+
+	var untrained []string
+	for _, target := range forecastTargets {
+		m := models[target]
+		if m == nil {
+			untrained = append(untrained, correlationLabel(target))
+			continue
+		}
+		p := ForecastPrediction{
+			Target:          target,
+			Label:           correlationLabel(target),
+			Accuracy:        m.Accuracy,
+			TrainedAt:       m.TrainedAt,
+			Stale:           time.Since(m.TrainedAt) > forecastMaxModelAge,
+			Contributions:   []ForecastContribution{},
+			MissingFeatures: []string{},
+		}
+		x := make([]float64, len(m.Features))
+		for j, f := range m.Features {
+			x[j] = math.NaN()
+			if f.Key == "bedtime_hours" && haveBedtime {
+				x[j] = bedtime / 60
+			} else if v, ok := d.feature(f.Key, day); ok {
+				x[j] = v
+			}
+			if math.IsNaN(x[j]) {
+				p.MissingFeatures = append(p.MissingFeatures, f.Key)
+				continue
+			}
+			value := round2(x[j])
+			p.Contributions = append(p.Contributions, ForecastContribution{
+				Feature: f.Key,
+				Label:   f.Label,
+				Value:   value,
+				Effect:  round1(f.Coef * f.standardise(x[j])),
+			})
+		}
+		sort.SliceStable(p.Contributions, func(i, j int) bool {
+			return math.Abs(p.Contributions[i].Effect) > math.Abs(p.Contributions[j].Effect)
+		})
+		if len(p.Contributions) > forecastTopContributions {
+			p.Contributions = p.Contributions[:forecastTopContributions]
+		}
+
+		predicted := m.predict(x)
+		spread := forecastInterval * m.Accuracy.RMSE
+		p.Predicted = round1(math.Max(0, math.Min(100, predicted)))
+		p.Low = round1(math.Max(0, predicted-spread))
+		p.High = round1(math.Min(100, predicted+spread))
+		forecast.Predictions = append(forecast.Predictions, p)
+	}
+	if len(untrained) > 0 {
+		forecast.Message = fmt.Sprintf("no model trained yet for %s", strings.Join(untrained, ", "))
+	}
+
+	core.WriteJSON(w, http.StatusOK, forecast)
+}
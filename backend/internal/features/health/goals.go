@@ -0,0 +1,601 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	goalTrendDays      = 28 // daily values fitted for deadline projections
+	minGoalTrendPoints = 5
+	maxGoalHistory     = 366
+)
+
+// goalTypeName is the format of goal names, e.g. weekly_strength
+var goalTypeName = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// goalComparators, goalPeriods and goalAggregations are the allowed goal settings
+var (
+	goalComparators  = map[string]bool{">=": true, "<=": true, ">": true, "<": true, "==": true}
+	goalPeriods      = map[string]int{"day": 90, "week": 26, "month": 12} // default history length
+	goalAggregations = map[string]bool{"sum": true, "avg": true, "count": true}
+)
+
+// legacyGoals are the definitions of the original fixed goal types, used
+// when one is saved with only a target
+var legacyGoals = map[string]HealthGoal{
+	"step_goal":         {Label: "Daily steps", Metric: "activity_steps", Comparator: ">=", Period: "day", Aggregation: "sum"},
+	"sleep_score":       {Label: "Sleep score", Metric: "sleep_score", Comparator: ">=", Period: "day", Aggregation: "avg"},
+	"readiness_score":   {Label: "Readiness score", Metric: "readiness_score", Comparator: ">=", Period: "day", Aggregation: "avg"},
+	"workout_frequency": {Label: "Workouts per week", Metric: "workouts", Comparator: ">=", Period: "week", Aggregation: "sum"},
+}
+
+// healthGoalColumns is the column list matching scanHealthGoal
+const healthGoalColumns = `id, goal_type, label, metric, comparator, target, period, aggregation,
+	deadline, active, created_at, updated_at`
+
+// scanHealthGoal scans a row selected with healthGoalColumns
+func scanHealthGoal(row pgx.Row) (HealthGoal, error) {
+	var g HealthGoal
+	var deadline *time.Time
+	err := row.Scan(&g.ID, &g.GoalType, &g.Label, &g.Metric, &g.Comparator, &g.Target, &g.Period,
+		&g.Aggregation, &deadline, &g.Active, &g.CreatedAt, &g.UpdatedAt)
+	if deadline != nil {
+		d := formatDate(*deadline)
+		g.Deadline = &d
+	}
+	return g, err
+}
+
+// loadHealthGoals returns the goals, optionally including inactive ones
+func (h *Handler) loadHealthGoals(ctx context.Context, includeInactive bool) ([]HealthGoal, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT `+healthGoalColumns+`
+		FROM health_goals
+		WHERE active OR $1
+		ORDER BY goal_type
+	`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []HealthGoal{}
+	for rows.Next() {
+		g, err := scanHealthGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+// validateHealthGoal fills defaults and checks a goal definition. Metrics
+// are the keys of the correlation series.
+func (h *Handler) validateHealthGoal(ctx context.Context, input *HealthGoalInput) error {
+	if !goalTypeName.MatchString(input.GoalType) {
+		return fmt.Errorf("goal_type must be 1-50 lowercase letters, digits or underscores")
+	}
+	if legacy, ok := legacyGoals[input.GoalType]; ok && input.Metric == "" {
+		input.Metric, input.Comparator = legacy.Metric, legacy.Comparator
+		input.Period, input.Aggregation = legacy.Period, legacy.Aggregation
+		if input.Label == "" {
+			input.Label = legacy.Label
+		}
+	}
+	if input.Comparator == "" {
+		input.Comparator = ">="
+	}
+	if input.Period == "" {
+		input.Period = "day"
+	}
+	if input.Aggregation == "" {
+		input.Aggregation = "avg"
+	}
+
+	if input.Metric == "" {
+		return fmt.Errorf("metric is required")
+	}
+	if !goalComparators[input.Comparator] {
+		return fmt.Errorf("comparator must be one of >=, <=, >, <, ==")
+	}
+	if _, ok := goalPeriods[input.Period]; !ok {
+		return fmt.Errorf("period must be day, week or month")
+	}
+	if !goalAggregations[input.Aggregation] {
+		return fmt.Errorf("aggregation must be sum, avg or count")
+	}
+	if math.IsNaN(input.Target) || math.IsInf(input.Target, 0) {
+		return fmt.Errorf("target must be a number")
+	}
+	if input.Deadline != nil {
+		if _, err := time.Parse("2006-01-02", *input.Deadline); err != nil {
+			return fmt.Errorf("deadline must be YYYY-MM-DD")
+		}
+	}
+
+	exists, err := h.correlationSeriesExists(ctx, input.Metric)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("unknown metric %q", input.Metric)
+	}
+
+	input.Label = strings.TrimSpace(input.Label)
+	if input.Label == "" {
+		input.Label = fmt.Sprintf("%s %s %s per %s", correlationLabel(input.Metric), input.Comparator,
+			strconv.FormatFloat(input.Target, 'f', -1, 64), input.Period)
+	}
+	return nil
+}
+
+// --- Period evaluation ---
+
+// goalPeriodStart returns the first day of the period containing day;
+// weeks start on Monday
+func goalPeriodStart(day time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7 // Sunday = 7
+		}
+		return day.AddDate(0, 0, -(weekday - 1))
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+// goalPeriodShift moves a period start by n periods
+func goalPeriodShift(start time.Time, period string, n int) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7*n)
+	case "month":
+		return start.AddDate(0, n, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// aggregateGoal aggregates the values between from and to inclusive. Count
+// is the number of days with a non-zero value. ok is false without data.
+func aggregateGoal(values map[string]float64, from, to time.Time, aggregation string) (value float64, days int, ok bool) {
+	var sum float64
+	var nonZero int
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		v, present := values[formatDate(day)]
+		if !present {
+			continue
+		}
+		days++
+		sum += v
+		if v != 0 {
+			nonZero++
+		}
+	}
+	if days == 0 {
+		return 0, 0, false
+	}
+	switch aggregation {
+	case "sum":
+		return sum, days, true
+	case "count":
+		return float64(nonZero), days, true
+	default:
+		return sum / float64(days), days, true
+	}
+}
+
+// compareGoal applies the goal comparator to a value
+func compareGoal(value float64, comparator string, target float64) bool {
+	switch comparator {
+	case "<=":
+		return value <= target
+	case ">":
+		return value > target
+	case "<":
+		return value < target
+	case "==":
+		return math.Abs(value-target) < 1e-9
+	default:
+		return value >= target
+	}
+}
+
+// goalProgressPercent is how close a value is to the target, 0-100
+func goalProgressPercent(value float64, comparator string, target float64) float64 {
+	if compareGoal(value, comparator, target) {
+		return 100
+	}
+	var p float64
+	switch comparator {
+	case "<=", "<":
+		if value != 0 {
+			p = target / value * 100
+		}
+	default:
+		if target != 0 {
+			p = value / target * 100
+		}
+	}
+	return math.Max(0, math.Min(100, round1(p)))
+}
+
+// evaluateGoalPeriods evaluates the last n periods up to today, oldest first.
+// The last period is the current one and is not complete yet.
+func evaluateGoalPeriods(goal HealthGoal, values map[string]float64, today time.Time, n int) []GoalPeriodResult {
+	start := goalPeriodShift(goalPeriodStart(today, goal.Period), goal.Period, -(n - 1))
+	periods := make([]GoalPeriodResult, 0, n)
+	for i := 0; i < n; i++ {
+		end := goalPeriodShift(start, goal.Period, 1).AddDate(0, 0, -1)
+		to := end
+		if to.After(today) {
+			to = today
+		}
+		p := GoalPeriodResult{Start: formatDate(start), End: formatDate(end), Complete: end.Before(today)}
+		if value, days, ok := aggregateGoal(values, start, to, goal.Aggregation); ok {
+			v := round2(value)
+			p.Value, p.Days = &v, days
+			p.Met = compareGoal(value, goal.Comparator, goal.Target)
+		}
+		periods = append(periods, p)
+		start = goalPeriodShift(start, goal.Period, 1)
+	}
+	return periods
+}
+
+// goalStreaks returns the current and best runs of met periods and the
+// start of the last met period. The current period only extends a streak;
+// not having met it yet does not break one.
+func goalStreaks(periods []GoalPeriodResult) (current, best int, lastAchieved string) {
+	run := 0
+	for _, p := range periods {
+		switch {
+		case p.Met:
+			run++
+			if run > best {
+				best = run
+			}
+			lastAchieved = p.Start
+		case p.Complete:
+			run = 0
+		}
+	}
+	for i := len(periods) - 1; i >= 0; i-- {
+		if periods[i].Met {
+			current++
+		} else if periods[i].Complete {
+			break
+		}
+	}
+	return current, best, lastAchieved
+}
+
+// projectGoal projects an average goal with a deadline along the trend of
+// the last 28 days, and a sum or count goal to the end of the current period
+// at the pace so far. It returns nil when neither applies.
+func projectGoal(goal HealthGoal, values map[string]float64, today time.Time, current GoalPeriodResult) *GoalProjection {
+	if goal.Deadline != nil && (goal.Aggregation == "avg" || goal.Period == "day") {
+		deadline, _ := time.Parse("2006-01-02", *goal.Deadline)
+		daysLeft := deadline.Sub(today).Hours() / 24
+		if daysLeft < 0 {
+			return nil
+		}
+
+		var xs, ys []float64
+		for i := -(goalTrendDays - 1); i <= 0; i++ {
+			if v, ok := values[formatDate(today.AddDate(0, 0, i))]; ok {
+				xs = append(xs, float64(i))
+				ys = append(ys, v)
+			}
+		}
+		if len(xs) < minGoalTrendPoints {
+			return nil
+		}
+		slope, intercept := linearFit(xs, ys)
+		projected := intercept + slope*daysLeft
+		p := &GoalProjection{
+			Basis:          "trend",
+			ProjectedFor:   *goal.Deadline,
+			ProjectedValue: round2(projected),
+			OnTrack:        compareGoal(projected, goal.Comparator, goal.Target),
+			Current:        round2(intercept),
+		}
+		rate := round2(slope * 7)
+		p.RatePerWeek = &rate
+		if daysLeft > 0 {
+			required := round2((goal.Target - intercept) / daysLeft * 7)
+			p.RequiredRatePerWeek = &required
+		}
+		if slope != 0 && !compareGoal(intercept, goal.Comparator, goal.Target) {
+			if days := (goal.Target - intercept) / slope; days > 0 && days < 3650 {
+				d := formatDate(today.AddDate(0, 0, int(math.Ceil(days))))
+				p.EstimatedDate = &d
+			}
+		}
+		return p
+	}
+
+	if goal.Period == "day" || goal.Aggregation == "avg" || current.Complete || current.Value == nil {
+		return nil
+	}
+	start, _ := time.Parse("2006-01-02", current.Start)
+	end, _ := time.Parse("2006-01-02", current.End)
+	elapsed := today.Sub(start).Hours()/24 + 1
+	total := end.Sub(start).Hours()/24 + 1
+	projected := *current.Value * total / elapsed
+	return &GoalProjection{
+		Basis:          "pace",
+		ProjectedFor:   current.End,
+		ProjectedValue: round2(projected),
+		OnTrack:        compareGoal(projected, goal.Comparator, goal.Target),
+		Current:        *current.Value,
+	}
+}
+
+// goalProgress evaluates a goal over its default history
+func goalProgress(goal HealthGoal, values map[string]float64, today time.Time) GoalProgress {
+	periods := evaluateGoalPeriods(goal, values, today, goalPeriods[goal.Period])
+	current := periods[len(periods)-1]
+
+	progress := GoalProgress{Goal: goal, Met: current.Met}
+	if current.Value != nil {
+		progress.CurrentValue = *current.Value
+		progress.Progress = goalProgressPercent(*current.Value, goal.Comparator, goal.Target)
+	}
+	if goal.Period == "week" {
+		progress.WeeklyCount = int(progress.CurrentValue)
+	}
+	progress.CurrentStreak, progress.BestStreak, progress.LastAchieved = goalStreaks(periods)
+	progress.Projection = projectGoal(goal, values, today, current)
+	return progress
+}
+
+// goalSeries loads the series of every goal metric far enough back for
+// the longest history requested
+func (h *Handler) goalSeries(ctx context.Context, goals []HealthGoal, today time.Time, periods int) (map[string]map[string]float64, error) {
+	start := today.AddDate(0, 0, -goalTrendDays)
+	for _, g := range goals {
+		n := periods
+		if n == 0 {
+			n = goalPeriods[g.Period]
+		}
+		if s := goalPeriodShift(goalPeriodStart(today, g.Period), g.Period, -(n - 1)); s.Before(start) {
+			start = s
+		}
+	}
+
+	all, err := h.loadCorrelationSeries(ctx, start, today)
+	if err != nil {
+		return nil, err
+	}
+	series := make(map[string]map[string]float64, len(goals))
+	for _, g := range goals {
+		values := map[string]float64{}
+		if s, ok := findCorrelationSeries(all, g.Metric); ok {
+			values = s.values
+		}
+		series[g.Metric] = values
+	}
+	return series, nil
+}
+
+// goalMetForWeek decides whether a goal was met in the week from..to:
+// daily goals compare their average day, weekly goals the week's aggregate
+// and monthly goals the month to date
+func goalMetForWeek(goal HealthGoal, values map[string]float64, from, to time.Time) bool {
+	aggregation := goal.Aggregation
+	switch goal.Period {
+	case "day":
+		if aggregation == "count" {
+			// Share of days with a non-zero value against a 0/1 daily target
+			count, days, ok := aggregateGoal(values, from, to, "count")
+			return ok && compareGoal(count/float64(days), goal.Comparator, goal.Target)
+		}
+		aggregation = "avg"
+	case "month":
+		from = goalPeriodStart(to, "month")
+	}
+	value, _, ok := aggregateGoal(values, from, to, aggregation)
+	return ok && compareGoal(value, goal.Comparator, goal.Target)
+}
+
+// --- Goal handlers ---
+
+// ListGoals returns all health goals
+func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	goals, err := h.loadHealthGoals(r.Context(), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, goals)
+}
+
+// UpsertGoal creates or updates a health goal, keyed by goal_type
+func (h *Handler) UpsertGoal(w http.ResponseWriter, r *http.Request) {
+	var input HealthGoalInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := h.validateHealthGoal(r.Context(), &input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	active := true
+	if input.Active != nil {
+		active = *input.Active
+	}
+
+	goal, err := scanHealthGoal(h.db.QueryRow(r.Context(), `
+		INSERT INTO health_goals (goal_type, label, metric, comparator, target, period, aggregation,
+			deadline, active, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (goal_type) DO UPDATE SET
+			label = EXCLUDED.label,
+			metric = EXCLUDED.metric,
+			comparator = EXCLUDED.comparator,
+			target = EXCLUDED.target,
+			period = EXCLUDED.period,
+			aggregation = EXCLUDED.aggregation,
+			deadline = EXCLUDED.deadline,
+			active = EXCLUDED.active,
+			updated_at = NOW()
+		RETURNING `+healthGoalColumns,
+		input.GoalType, input.Label, input.Metric, input.Comparator, input.Target, input.Period,
+		input.Aggregation, input.Deadline, active))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, goal)
+}
+
+// DeleteGoal removes a health goal (or just deactivates it)
+func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	goalType := chi.URLParam(r, "type")
+
+	// Soft delete by deactivating
+	result, err := h.db.Exec(r.Context(), `
+		UPDATE health_goals SET active = false, updated_at = NOW()
+		WHERE goal_type = $1
+	`, goalType)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Goal not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetGoalsOverview returns progress, streaks and projections of the active
+// goals for the dashboard
+func (h *Handler) GetGoalsOverview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	today := h.today(ctx)
+
+	overview := GoalsOverview{
+		Goals: []GoalProgress{},
+	}
+
+	goals, err := h.loadHealthGoals(ctx, false)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(goals) == 0 {
+		core.WriteJSON(w, http.StatusOK, overview)
+		return
+	}
+
+	series, err := h.goalSeries(ctx, goals, today, 0)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	weekStart := goalPeriodStart(today, "week")
+	for _, goal := range goals {
+		values := series[goal.Metric]
+		progress := goalProgress(goal, values, today)
+		overview.Goals = append(overview.Goals, progress)
+
+		if progress.Met {
+			overview.TodaysMet++
+		}
+		overview.TodaysTotal++
+
+		// Daily goals count each day of this week, longer goals their current period
+		if goal.Period != "day" {
+			if progress.Met {
+				overview.WeeklyMet++
+			}
+			overview.WeeklyTotal++
+			continue
+		}
+		for _, p := range evaluateGoalPeriods(goal, values, today, int(today.Sub(weekStart).Hours()/24)+1) {
+			if p.Value == nil {
+				continue
+			}
+			if p.Met {
+				overview.WeeklyMet++
+			}
+			overview.WeeklyTotal++
+		}
+	}
+
+	core.WriteJSON(w, http.StatusOK, overview)
+}
+
+// GetGoalHistory returns a goal's achievement per period with streaks and
+// its projection. Query: ?periods= (default 90 days, 26 weeks or 12 months)
+func (h *Handler) GetGoalHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	today := h.today(ctx)
+
+	goal, err := scanHealthGoal(h.db.QueryRow(ctx, `
+		SELECT `+healthGoalColumns+` FROM health_goals WHERE goal_type = $1
+	`, chi.URLParam(r, "type")))
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Goal not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	n := goalPeriods[goal.Period]
+	if p := r.URL.Query().Get("periods"); p != "" {
+		if v, err := strconv.Atoi(p); err == nil && v > 0 && v <= maxGoalHistory {
+			n = v
+		}
+	}
+
+	series, err := h.goalSeries(ctx, []HealthGoal{goal}, today, n)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	values := series[goal.Metric]
+
+	periods := evaluateGoalPeriods(goal, values, today, n)
+	history := GoalHistory{Goal: goal, Periods: periods}
+	for _, p := range periods {
+		if p.Met {
+			history.MetPeriods++
+		}
+		if p.Complete && p.Value != nil {
+			history.CompletePeriods++
+		}
+	}
+	history.CurrentStreak, history.BestStreak, history.LastAchieved = goalStreaks(periods)
+	history.Projection = projectGoal(goal, values, today, periods[len(periods)-1])
+
+	core.WriteJSON(w, http.StatusOK, history)
+}
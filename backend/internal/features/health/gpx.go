@@ -0,0 +1,76 @@
+package health
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// gpxFile covers the parts of GPX 1.1 used for workouts, including the
+// Garmin TrackPointExtension heart rate
+type gpxFile struct {
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Type     string `xml:"type"`
+		Segments []struct {
+			Points []struct {
+				Lat       float64  `xml:"lat,attr"`
+				Lon       float64  `xml:"lon,attr"`
+				Elevation *float64 `xml:"ele"`
+				Time      string   `xml:"time"`
+				HeartRate *int     `xml:"extensions>TrackPointExtension>hr"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// isGPX reports whether data looks like a GPX document
+func isGPX(data []byte) bool {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("<gpx"))
+}
+
+// decodeGPX parses a GPX file into track points plus the track name and type
+func decodeGPX(data []byte) ([]TrackPoint, string, string, error) {
+	var doc gpxFile
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, "", "", err
+	}
+
+	var points []TrackPoint
+	var name, activity string
+	for _, trk := range doc.Tracks {
+		if name == "" {
+			name = strings.TrimSpace(trk.Name)
+		}
+		if activity == "" {
+			activity = strings.ToLower(strings.TrimSpace(trk.Type))
+		}
+		for _, seg := range trk.Segments {
+			for _, pt := range seg.Points {
+				t, err := time.Parse(time.RFC3339, strings.TrimSpace(pt.Time))
+				if err != nil {
+					continue
+				}
+				lat, lon := pt.Lat, pt.Lon
+				points = append(points, TrackPoint{
+					Time:      t,
+					Lat:       &lat,
+					Lon:       &lon,
+					Elevation: pt.Elevation,
+					HeartRate: pt.HeartRate,
+				})
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, "", "", errors.New("GPX file has no timestamped track points")
+	}
+	return points, name, activity, nil
+}
@@ -1,22 +1,41 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Handler struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	oura  *OuraSyncer
+	clock *dayClock
 }
 
 func NewHandler(db *pgxpool.Pool) *Handler {
-	return &Handler{db: db}
+	clock := newDayClock(db)
+	return &Handler{
+		db:    db,
+		oura:  NewOuraSyncer(db),
+		clock: clock,
+	}
+}
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
 // formatDate converts time.Time to YYYY-MM-DD string
@@ -129,13 +148,34 @@ func (h *Handler) UpsertOuraDaily(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if input.Day == "" {
-		core.WriteError(w, http.StatusBadRequest, "day is required")
+	if err := validateOuraDaily(input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := upsertOuraDaily(r.Context(), h.db, input)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// A changed day also moves the baselines of the days after it
+	day, _ := time.Parse("2006-01-02", input.Day)
+	if err := detectAnomalies(r.Context(), h.db, day, h.today(r.Context())); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	core.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"id":  id,
+		"day": input.Day,
+	})
+}
+
+// upsertOuraDaily inserts or replaces the metrics stored for input.Day
+func upsertOuraDaily(ctx context.Context, q querier, input OuraDailyInput) (int64, error) {
 	var id int64
-	err := h.db.QueryRow(r.Context(), `
+	err := q.QueryRow(ctx, `
 		INSERT INTO oura_daily (
 			day,
 			sleep_score, sleep_deep_sleep, sleep_efficiency, sleep_latency,
@@ -196,19 +236,11 @@ func (h *Handler) UpsertOuraDaily(w http.ResponseWriter, r *http.Request) {
 		input.ActivityMeetDailyTargets, input.ActivityMoveEveryHour, input.ActivityRecoveryTime,
 		input.ActivityStayActive, input.ActivityTrainingFrequency, input.ActivityTrainingVolume,
 	).Scan(&id)
-
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	core.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"id":  id,
-		"day": input.Day,
-	})
+	return id, err
 }
 
-// BulkUpsertOuraDaily handles bulk import of historical data
+// BulkUpsertOuraDaily handles bulk import of historical data. Rows that
+// fail are reported by index and recorded for the data quality report.
 func (h *Handler) BulkUpsertOuraDaily(w http.ResponseWriter, r *http.Request) {
 	var inputs []OuraDailyInput
 	if err := json.NewDecoder(r.Body).Decode(&inputs); err != nil {
@@ -216,81 +248,38 @@ func (h *Handler) BulkUpsertOuraDaily(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	inserted := 0
-	for _, input := range inputs {
-		if input.Day == "" {
+	result := BulkUpsertResult{Total: len(inputs), Errors: []IngestionRowError{}}
+	var failed []interface{}
+	var first time.Time
+	for i, input := range inputs {
+		err := validateOuraDaily(input)
+		if err == nil {
+			_, err = upsertOuraDaily(r.Context(), h.db, input)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, IngestionRowError{Index: i, Day: input.Day, Error: err.Error()})
+			failed = append(failed, input)
 			continue
 		}
+		result.Inserted++
+		if day, _ := time.Parse("2006-01-02", input.Day); first.IsZero() || day.Before(first) {
+			first = day
+		}
+	}
+	result.Failed = len(result.Errors)
 
-		_, err := h.db.Exec(r.Context(), `
-			INSERT INTO oura_daily (
-				day,
-				sleep_score, sleep_deep_sleep, sleep_efficiency, sleep_latency,
-				sleep_rem_sleep, sleep_restfulness, sleep_timing, sleep_total_sleep,
-				readiness_score, readiness_activity_balance, readiness_body_temperature,
-				readiness_hrv_balance, readiness_previous_day_activity, readiness_previous_night,
-				readiness_recovery_index, readiness_resting_heart_rate, readiness_sleep_balance,
-				readiness_sleep_regularity, temperature_deviation,
-				activity_score, activity_active_calories, activity_steps, activity_total_calories,
-				activity_meet_daily_targets, activity_move_every_hour, activity_recovery_time,
-				activity_stay_active, activity_training_frequency, activity_training_volume
-			) VALUES (
-				$1,
-				$2, $3, $4, $5, $6, $7, $8, $9,
-				$10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
-				$21, $22, $23, $24, $25, $26, $27, $28, $29, $30
-			)
-			ON CONFLICT (day) DO UPDATE SET
-				sleep_score = EXCLUDED.sleep_score,
-				sleep_deep_sleep = EXCLUDED.sleep_deep_sleep,
-				sleep_efficiency = EXCLUDED.sleep_efficiency,
-				sleep_latency = EXCLUDED.sleep_latency,
-				sleep_rem_sleep = EXCLUDED.sleep_rem_sleep,
-				sleep_restfulness = EXCLUDED.sleep_restfulness,
-				sleep_timing = EXCLUDED.sleep_timing,
-				sleep_total_sleep = EXCLUDED.sleep_total_sleep,
-				readiness_score = EXCLUDED.readiness_score,
-				readiness_activity_balance = EXCLUDED.readiness_activity_balance,
-				readiness_body_temperature = EXCLUDED.readiness_body_temperature,
-				readiness_hrv_balance = EXCLUDED.readiness_hrv_balance,
-				readiness_previous_day_activity = EXCLUDED.readiness_previous_day_activity,
-				readiness_previous_night = EXCLUDED.readiness_previous_night,
-				readiness_recovery_index = EXCLUDED.readiness_recovery_index,
-				readiness_resting_heart_rate = EXCLUDED.readiness_resting_heart_rate,
-				readiness_sleep_balance = EXCLUDED.readiness_sleep_balance,
-				readiness_sleep_regularity = EXCLUDED.readiness_sleep_regularity,
-				temperature_deviation = EXCLUDED.temperature_deviation,
-				activity_score = EXCLUDED.activity_score,
-				activity_active_calories = EXCLUDED.activity_active_calories,
-				activity_steps = EXCLUDED.activity_steps,
-				activity_total_calories = EXCLUDED.activity_total_calories,
-				activity_meet_daily_targets = EXCLUDED.activity_meet_daily_targets,
-				activity_move_every_hour = EXCLUDED.activity_move_every_hour,
-				activity_recovery_time = EXCLUDED.activity_recovery_time,
-				activity_stay_active = EXCLUDED.activity_stay_active,
-				activity_training_frequency = EXCLUDED.activity_training_frequency,
-				activity_training_volume = EXCLUDED.activity_training_volume
-		`,
-			input.Day,
-			input.SleepScore, input.SleepDeepSleep, input.SleepEfficiency, input.SleepLatency,
-			input.SleepRemSleep, input.SleepRestfulness, input.SleepTiming, input.SleepTotalSleep,
-			input.ReadinessScore, input.ReadinessActivityBalance, input.ReadinessBodyTemperature,
-			input.ReadinessHrvBalance, input.ReadinessPreviousDayActivity, input.ReadinessPreviousNight,
-			input.ReadinessRecoveryIndex, input.ReadinessRestingHeartRate, input.ReadinessSleepBalance,
-			input.ReadinessSleepRegularity, input.TemperatureDeviation,
-			input.ActivityScore, input.ActivityActiveCalories, input.ActivitySteps, input.ActivityTotalCalories,
-			input.ActivityMeetDailyTargets, input.ActivityMoveEveryHour, input.ActivityRecoveryTime,
-			input.ActivityStayActive, input.ActivityTrainingFrequency, input.ActivityTrainingVolume,
-		)
-		if err == nil {
-			inserted++
+	if err := recordIngestionErrors(r.Context(), h.db, ingestionSourceOuraBulk, result.Errors, failed); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.Inserted > 0 {
+		if err := detectAnomalies(r.Context(), h.db, first, h.today(r.Context())); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
 		}
 	}
 
-	core.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"inserted": inserted,
-		"total":    len(inputs),
-	})
+	core.WriteJSON(w, http.StatusOK, result)
 }
 
 // DeleteOuraDaily removes a day's Oura data
@@ -395,10 +384,57 @@ func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
 	dash.Activity = buildScoreInsight(latest.ActivityScore, avg7dActivity, avg30dActivity,
 		buildActivityContributors(&latest))
 
-	// Compute verdict
-	dash.Verdict, dash.VerdictType = computeVerdict(latest.SleepScore, latest.ReadinessScore)
+	// Evaluate the verdict rules (Phase 13) on the latest day, which also
+	// carries that day's training load (Phase 12)
+	dash.Verdict, dash.VerdictType = "No data available", "unknown"
+	rules, _, err := h.verdictRules(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	contexts, err := h.loadVerdictContexts(r.Context(), dayTime, dayTime)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(contexts) > 0 {
+		vc := contexts[len(contexts)-1]
+		if vc.load != nil && vc.load.FormBand != "unknown" {
+			dash.TrainingLoad = vc.load
+		}
+		if rule := evaluateVerdictRules(rules, vc); rule != nil {
+			dash.Verdict, dash.VerdictType, dash.VerdictRule = rule.Message, rule.VerdictType, rule.Name
+		}
+	}
+
+	// Illness/anomaly early warning (Phase 14): surface an episode that is
+	// still ongoing
+	since := dayTime.AddDate(0, 0, -anomalyDashboardDays)
+	episodes, err := h.listAnomalyEpisodes(r.Context(), since)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range episodes {
+		if episodes[i].Active {
+			dash.Anomaly = &episodes[i]
+			break
+		}
+	}
 
-	// Activity metrics (Phase 2)
+	// Activity metrics (Phase 2); imported steps fill in when Oura has none
+	if latest.ActivitySteps == nil {
+		var steps float64
+		err := h.db.QueryRow(r.Context(), `SELECT steps FROM daily_steps WHERE day = $1`, dayTime).Scan(&steps)
+		switch {
+		case err == nil:
+			n := int(steps)
+			latest.ActivitySteps = &n
+		case !errors.Is(err, pgx.ErrNoRows):
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 	dash.ActivityMetrics = &ActivityMetrics{
 		Steps:          latest.ActivitySteps,
 		ActiveCalories: latest.ActivityActiveCalories,
@@ -435,43 +471,6 @@ func buildScoreInsight(score *int, avg7d, avg30d float64, contributors []Contrib
 	return insight
 }
 
-// computeVerdict determines the daily recommendation
-func computeVerdict(sleepScore, readinessScore *int) (string, string) {
-	sleep := 0
-	readiness := 0
-	if sleepScore != nil {
-		sleep = *sleepScore
-	}
-	if readinessScore != nil {
-		readiness = *readinessScore
-	}
-
-	// No data
-	if sleep == 0 && readiness == 0 {
-		return "No data available", "unknown"
-	}
-
-	// Push day: both scores 80+
-	if readiness >= 80 && sleep >= 80 {
-		return "Well rested — good day for intensity", "push"
-	}
-
-	// Recovery day: either score below 60
-	if readiness < 60 || sleep < 60 {
-		if readiness < 50 || sleep < 50 {
-			return "Recovery needed — take it easy today", "recovery"
-		}
-		return "Below baseline — keep activity light", "recovery"
-	}
-
-	// Normal day: scores between 60-79
-	if readiness >= 70 && sleep >= 70 {
-		return "Solid day — normal activity is fine", "normal"
-	}
-
-	return "Average day — listen to your body", "normal"
-}
-
 // buildSleepContributors extracts top sleep contributors
 func buildSleepContributors(d *OuraDaily) []Contributor {
 	contributors := []Contributor{}
@@ -663,6 +662,20 @@ func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 // --- Workout handlers ---
 
+// workoutColumns is the column list read by scanWorkout
+const workoutColumns = `id, date, type, notes, source, started_at, duration_seconds, distance_meters,
+	elevation_gain_meters, avg_heart_rate, max_heart_rate, calories, rpe, created_at`
+
+// scanWorkout scans a row selected with workoutColumns
+func scanWorkout(row pgx.Row) (Workout, error) {
+	var wo Workout
+	var dateTime time.Time
+	err := row.Scan(&wo.ID, &dateTime, &wo.Type, &wo.Notes, &wo.Source, &wo.StartedAt, &wo.DurationSeconds,
+		&wo.DistanceMeters, &wo.ElevationGainMeters, &wo.AvgHeartRate, &wo.MaxHeartRate, &wo.Calories, &wo.RPE, &wo.CreatedAt)
+	wo.Date = formatDate(dateTime)
+	return wo, err
+}
+
 // ListWorkouts returns all workouts, ordered by date desc
 func (h *Handler) ListWorkouts(w http.ResponseWriter, r *http.Request) {
 	limit := 100
@@ -673,7 +686,7 @@ func (h *Handler) ListWorkouts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	rows, err := h.db.Query(r.Context(), `
-		SELECT id, date, type, notes, created_at
+		SELECT `+workoutColumns+`
 		FROM workouts
 		ORDER BY date DESC, id DESC
 		LIMIT $1
@@ -686,20 +699,18 @@ func (h *Handler) ListWorkouts(w http.ResponseWriter, r *http.Request) {
 
 	var results []Workout
 	for rows.Next() {
-		var wo Workout
-		var dateTime time.Time
-		if err := rows.Scan(&wo.ID, &dateTime, &wo.Type, &wo.Notes, &wo.CreatedAt); err != nil {
+		wo, err := scanWorkout(rows)
+		if err != nil {
 			core.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		wo.Date = formatDate(dateTime)
 		results = append(results, wo)
 	}
 
 	core.WriteJSON(w, http.StatusOK, results)
 }
 
-// CreateWorkout adds a new workout
+// CreateWorkout adds a new workout, with its strength sets if given
 func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 	var input WorkoutInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -709,7 +720,7 @@ func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 
 	// Default to today if no date provided
 	if input.Date == "" {
-		input.Date = formatDate(time.Now())
+		input.Date = h.todayKey(r.Context())
 	}
 
 	// Validate type
@@ -717,19 +728,43 @@ func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 		core.WriteError(w, http.StatusBadRequest, "type must be 'strength' or 'cardio'")
 		return
 	}
+	if err := validateWorkoutInput(input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
 
 	var id int64
-	err := h.db.QueryRow(r.Context(), `
-		INSERT INTO workouts (date, type, notes)
-		VALUES ($1, $2, $3)
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO workouts (date, type, notes, duration_seconds, rpe, avg_heart_rate, distance_meters)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id
-	`, input.Date, input.Type, input.Notes).Scan(&id)
+	`, input.Date, input.Type, input.Notes, input.DurationSeconds, input.RPE, input.AvgHeartRate,
+		input.DistanceMeters).Scan(&id)
 
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if input.Sets != nil {
+		if err := replaceWorkoutSets(r.Context(), tx, id, input.Sets); err != nil {
+			writeWorkoutSetError(w, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	core.WriteJSON(w, http.StatusCreated, map[string]interface{}{
 		"id":   id,
 		"date": input.Date,
@@ -737,7 +772,7 @@ func (h *Handler) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetWorkout returns a single workout by ID
+// GetWorkout returns a single workout by ID, including its strength sets
 func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
@@ -745,18 +780,93 @@ func (h *Handler) GetWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var wo Workout
-	var dateTime time.Time
-	err = h.db.QueryRow(r.Context(), `
-		SELECT id, date, type, notes, created_at
+	wo, err := scanWorkout(h.db.QueryRow(r.Context(), `
+		SELECT `+workoutColumns+`
 		FROM workouts
 		WHERE id = $1
-	`, id).Scan(&wo.ID, &dateTime, &wo.Type, &wo.Notes, &wo.CreatedAt)
+	`, id))
 	if err != nil {
 		core.WriteError(w, http.StatusNotFound, "Workout not found")
 		return
 	}
-	wo.Date = formatDate(dateTime)
+
+	wo.Sets, err = loadWorkoutSets(r.Context(), h.db, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, wo)
+}
+
+// UpdateWorkout edits a workout. Empty strings and omitted metrics keep
+// their current values, so imported workouts can be annotated with an RPE
+// or sets without losing recorded data.
+func (h *Handler) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var input WorkoutInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if input.Type != "" && input.Type != "strength" && input.Type != "cardio" {
+		core.WriteError(w, http.StatusBadRequest, "type must be 'strength' or 'cardio'")
+		return
+	}
+	if err := validateWorkoutInput(input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	wo, err := scanWorkout(tx.QueryRow(r.Context(), `
+		UPDATE workouts SET
+			date = COALESCE(NULLIF($2, '')::date, date),
+			type = COALESCE(NULLIF($3, ''), type),
+			notes = COALESCE(NULLIF($4, ''), notes),
+			duration_seconds = COALESCE($5, duration_seconds),
+			rpe = COALESCE($6, rpe),
+			avg_heart_rate = COALESCE($7, avg_heart_rate),
+			distance_meters = COALESCE($8, distance_meters)
+		WHERE id = $1
+		RETURNING `+workoutColumns, id, input.Date, input.Type, input.Notes, input.DurationSeconds, input.RPE,
+		input.AvgHeartRate, input.DistanceMeters))
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Workout not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if input.Sets != nil {
+		if err := replaceWorkoutSets(r.Context(), tx, id, input.Sets); err != nil {
+			writeWorkoutSetError(w, err)
+			return
+		}
+	}
+	wo.Sets, err = loadWorkoutSets(r.Context(), tx, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	core.WriteJSON(w, http.StatusOK, wo)
 }
@@ -980,7 +1090,7 @@ func (h *Handler) CreateWeightEntry(w http.ResponseWriter, r *http.Request) {
 
 	// Default to today if no date provided
 	if input.Date == "" {
-		input.Date = formatDate(time.Now())
+		input.Date = h.todayKey(r.Context())
 	}
 
 	// Validate weight
@@ -995,7 +1105,8 @@ func (h *Handler) CreateWeightEntry(w http.ResponseWriter, r *http.Request) {
 		VALUES ($1, $2, $3)
 		ON CONFLICT (date) DO UPDATE SET
 			weight_kg = EXCLUDED.weight_kg,
-			notes = EXCLUDED.notes
+			notes = EXCLUDED.notes,
+			source = 'manual'
 		RETURNING id
 	`, input.Date, input.WeightKg, input.Notes).Scan(&id)
 
@@ -1049,8 +1160,13 @@ func (h *Handler) DeleteWeightEntry(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetWeightTrend returns weight history with statistics
+// GetWeightTrend returns weight history with the smoothed trend weight,
+// weekly rate of change, projection to a target weight and the latest body
+// measurements. Query: ?days= (entries, default 90), ?target= (kg, default
+// the active weight_kg goal)
 func (h *Handler) GetWeightTrend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	days := 90 // default
 	if d := r.URL.Query().Get("days"); d != "" {
 		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
@@ -1059,17 +1175,25 @@ func (h *Handler) GetWeightTrend(w http.ResponseWriter, r *http.Request) {
 	}
 
 	trend := WeightTrend{
-		History: []WeightEntry{},
-		Trend:   "stable",
+		History:      []WeightEntry{},
+		Trend:        "stable",
+		Measurements: []BodyMeasurementSummary{},
 	}
 
-	// Fetch weight entries
-	rows, err := h.db.Query(r.Context(), `
+	measurements, err := h.bodyMeasurementSummaries(ctx)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	trend.Measurements = measurements
+
+	// Fetch weight entries, with extra entries to warm up the trend
+	rows, err := h.db.Query(ctx, `
 		SELECT id, date, weight_kg, notes, created_at
 		FROM weight_entries
 		ORDER BY date DESC
 		LIMIT $1
-	`, days)
+	`, days+weightTrendWarmup)
 	if err != nil {
 		core.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1093,15 +1217,22 @@ func (h *Handler) GetWeightTrend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Latest entry
-	trend.Latest = &entries[0]
-
 	// Reverse for chronological order
 	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
 		entries[i], entries[j] = entries[j], entries[i]
 	}
+	smoothWeights(entries)
+	rate, hasRate := weeklyWeightRate(entries)
+	if len(entries) > days {
+		entries = entries[len(entries)-days:]
+	}
 	trend.History = entries
 
+	// Latest entry
+	latest := entries[len(entries)-1]
+	trend.Latest = &latest
+	trend.TrendWeight = latest.TrendKg
+
 	// Calculate statistics
 	var sum7d, sum30d float64
 	var count7d, count30d int
@@ -1133,20 +1264,39 @@ func (h *Handler) GetWeightTrend(w http.ResponseWriter, r *http.Request) {
 	if count7d > 0 {
 		avg7d := sum7d / float64(count7d)
 		trend.Avg7d = &avg7d
+	}
 
-		// Calculate trend vs 7d average
-		delta := trend.Latest.WeightKg - avg7d
-		trend.TrendDelta = delta
-		if delta > 0.5 {
+	if count30d > 0 {
+		avg30d := sum30d / float64(count30d)
+		trend.Avg30d = &avg30d
+	}
+
+	// Direction follows the trend's weekly rate rather than single weigh-ins
+	trend.TrendDelta = round2(latest.WeightKg - *latest.TrendKg)
+	if hasRate {
+		weekly := round2(rate)
+		trend.WeeklyRate = &weekly
+		balance := math.Round(rate * kcalPerKg / 7)
+		trend.DailyEnergyBalance = &balance
+		if rate > weightRateStable {
 			trend.Trend = "up"
-		} else if delta < -0.5 {
+		} else if rate < -weightRateStable {
 			trend.Trend = "down"
 		}
 	}
 
-	if count30d > 0 {
-		avg30d := sum30d / float64(count30d)
-		trend.Avg30d = &avg30d
+	// Projection to the requested or goal target
+	target, deadline := h.weightTarget(ctx)
+	if t := r.URL.Query().Get("target"); t != "" {
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil || v <= 0 || v >= 500 {
+			core.WriteError(w, http.StatusBadRequest, "target must be a weight in kg")
+			return
+		}
+		target, deadline = &v, nil
+	}
+	if target != nil {
+		trend.Projection = projectWeight(*latest.TrendKg, rate, *target, latest.Date, deadline)
 	}
 
 	core.WriteJSON(w, http.StatusOK, trend)
@@ -1510,20 +1660,15 @@ func pearsonCorrelation(x, y []float64) float64 {
 		return 0
 	}
 
-	// Import math for Sqrt
 	return numerator / sqrt(denominator)
 }
 
-// sqrt is a simple square root implementation
+// sqrt returns the square root of x, or 0 for non-positive x
 func sqrt(x float64) float64 {
 	if x <= 0 {
 		return 0
 	}
-	z := x / 2
-	for i := 0; i < 20; i++ {
-		z = (z + x/z) / 2
-	}
-	return z
+	return math.Sqrt(x)
 }
 
 func correlationStrength(r float64) string {
@@ -1951,362 +2096,6 @@ func calculateWorkoutCorrelation(data []dailyData, workoutDates map[string]bool)
 	}
 }
 
-// --- Phase 6: Goals handlers ---
-
-// ListGoals returns all health goals
-func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
-	includeInactive := r.URL.Query().Get("all") == "true"
-
-	query := `SELECT id, goal_type, target, active, created_at, updated_at FROM health_goals`
-	if !includeInactive {
-		query += ` WHERE active = true`
-	}
-	query += ` ORDER BY goal_type`
-
-	rows, err := h.db.Query(r.Context(), query)
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	defer rows.Close()
-
-	var goals []HealthGoal
-	for rows.Next() {
-		var g HealthGoal
-		if err := rows.Scan(&g.ID, &g.GoalType, &g.Target, &g.Active, &g.CreatedAt, &g.UpdatedAt); err != nil {
-			core.WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		goals = append(goals, g)
-	}
-
-	core.WriteJSON(w, http.StatusOK, goals)
-}
-
-// UpsertGoal creates or updates a health goal
-func (h *Handler) UpsertGoal(w http.ResponseWriter, r *http.Request) {
-	var input HealthGoalInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
-		return
-	}
-
-	// Validate goal type
-	validTypes := map[string]bool{
-		"step_goal":         true,
-		"sleep_score":       true,
-		"readiness_score":   true,
-		"workout_frequency": true,
-	}
-	if !validTypes[input.GoalType] {
-		core.WriteError(w, http.StatusBadRequest, "Invalid goal_type. Must be: step_goal, sleep_score, readiness_score, or workout_frequency")
-		return
-	}
-
-	if input.Target <= 0 {
-		core.WriteError(w, http.StatusBadRequest, "target must be positive")
-		return
-	}
-
-	active := true
-	if input.Active != nil {
-		active = *input.Active
-	}
-
-	var id int64
-	err := h.db.QueryRow(r.Context(), `
-		INSERT INTO health_goals (goal_type, target, active, updated_at)
-		VALUES ($1, $2, $3, NOW())
-		ON CONFLICT (goal_type) DO UPDATE SET
-			target = EXCLUDED.target,
-			active = EXCLUDED.active,
-			updated_at = NOW()
-		RETURNING id
-	`, input.GoalType, input.Target, active).Scan(&id)
-
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	core.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"id":        id,
-		"goal_type": input.GoalType,
-		"target":    input.Target,
-		"active":    active,
-	})
-}
-
-// DeleteGoal removes a health goal (or just deactivates it)
-func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
-	goalType := chi.URLParam(r, "type")
-
-	// Soft delete by deactivating
-	result, err := h.db.Exec(r.Context(), `
-		UPDATE health_goals SET active = false, updated_at = NOW()
-		WHERE goal_type = $1
-	`, goalType)
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	if result.RowsAffected() == 0 {
-		core.WriteError(w, http.StatusNotFound, "Goal not found")
-		return
-	}
-
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// GetGoalsOverview returns goal progress for the dashboard
-func (h *Handler) GetGoalsOverview(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	today := formatDate(time.Now())
-
-	// Get week boundaries (Monday to Sunday)
-	now := time.Now()
-	weekday := int(now.Weekday())
-	if weekday == 0 {
-		weekday = 7 // Sunday = 7
-	}
-	weekStart := now.AddDate(0, 0, -(weekday - 1))
-	weekStartStr := formatDate(weekStart)
-
-	overview := GoalsOverview{
-		Goals: []GoalProgress{},
-	}
-
-	// Fetch active goals
-	goalRows, err := h.db.Query(ctx, `
-		SELECT id, goal_type, target, active, created_at, updated_at
-		FROM health_goals
-		WHERE active = true
-		ORDER BY goal_type
-	`)
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	defer goalRows.Close()
-
-	var goals []HealthGoal
-	for goalRows.Next() {
-		var g HealthGoal
-		if err := goalRows.Scan(&g.ID, &g.GoalType, &g.Target, &g.Active, &g.CreatedAt, &g.UpdatedAt); err != nil {
-			core.WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		goals = append(goals, g)
-	}
-
-	if len(goals) == 0 {
-		core.WriteJSON(w, http.StatusOK, overview)
-		return
-	}
-
-	// Fetch today's Oura data
-	var todayData OuraDaily
-	h.db.QueryRow(ctx, `
-		SELECT COALESCE(sleep_score, 0), COALESCE(readiness_score, 0), COALESCE(activity_steps, 0)
-		FROM oura_daily
-		WHERE day = $1
-	`, today).Scan(&todayData.SleepScore, &todayData.ReadinessScore, &todayData.ActivitySteps)
-
-	// Fetch this week's workout count
-	var weeklyWorkouts int
-	h.db.QueryRow(ctx, `
-		SELECT COUNT(*) FROM workouts WHERE date >= $1
-	`, weekStartStr).Scan(&weeklyWorkouts)
-
-	// Fetch historical data for streak calculation (last 90 days)
-	histRows, err := h.db.Query(ctx, `
-		SELECT day, COALESCE(sleep_score, 0), COALESCE(readiness_score, 0), COALESCE(activity_steps, 0)
-		FROM oura_daily
-		ORDER BY day ASC
-		LIMIT 90
-	`)
-	if err != nil {
-		core.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	defer histRows.Close()
-
-	var histData []goalDailyData
-	for histRows.Next() {
-		var d goalDailyData
-		var dayTime time.Time
-		if err := histRows.Scan(&dayTime, &d.sleep, &d.readiness, &d.steps); err != nil {
-			continue
-		}
-		d.day = formatDate(dayTime)
-		histData = append(histData, d)
-	}
-
-	// Fetch weekly workout counts per day
-	workoutRows, err := h.db.Query(ctx, `SELECT date FROM workouts ORDER BY date ASC`)
-	workoutDates := make(map[string]bool)
-	if err == nil {
-		defer workoutRows.Close()
-		for workoutRows.Next() {
-			var dateTime time.Time
-			if err := workoutRows.Scan(&dateTime); err == nil {
-				workoutDates[formatDate(dateTime)] = true
-			}
-		}
-	}
-
-	// Calculate progress for each goal
-	for _, goal := range goals {
-		progress := GoalProgress{
-			Goal: goal,
-		}
-
-		switch goal.GoalType {
-		case "step_goal":
-			if todayData.ActivitySteps != nil {
-				progress.CurrentValue = *todayData.ActivitySteps
-			}
-			progress.Progress = float64(progress.CurrentValue) / float64(goal.Target) * 100
-			if progress.Progress > 100 {
-				progress.Progress = 100
-			}
-			progress.Met = progress.CurrentValue >= goal.Target
-			progress.CurrentStreak, progress.BestStreak, progress.LastAchieved = calculateGoalStreak(histData, func(d goalDailyData) bool {
-				return d.steps >= goal.Target
-			})
-
-		case "sleep_score":
-			if todayData.SleepScore != nil {
-				progress.CurrentValue = *todayData.SleepScore
-			}
-			progress.Progress = float64(progress.CurrentValue) / float64(goal.Target) * 100
-			if progress.Progress > 100 {
-				progress.Progress = 100
-			}
-			progress.Met = progress.CurrentValue >= goal.Target
-			progress.CurrentStreak, progress.BestStreak, progress.LastAchieved = calculateGoalStreak(histData, func(d goalDailyData) bool {
-				return d.sleep >= goal.Target
-			})
-
-		case "readiness_score":
-			if todayData.ReadinessScore != nil {
-				progress.CurrentValue = *todayData.ReadinessScore
-			}
-			progress.Progress = float64(progress.CurrentValue) / float64(goal.Target) * 100
-			if progress.Progress > 100 {
-				progress.Progress = 100
-			}
-			progress.Met = progress.CurrentValue >= goal.Target
-			progress.CurrentStreak, progress.BestStreak, progress.LastAchieved = calculateGoalStreak(histData, func(d goalDailyData) bool {
-				return d.readiness >= goal.Target
-			})
-
-		case "workout_frequency":
-			progress.WeeklyCount = weeklyWorkouts
-			progress.CurrentValue = weeklyWorkouts
-			progress.Progress = float64(weeklyWorkouts) / float64(goal.Target) * 100
-			if progress.Progress > 100 {
-				progress.Progress = 100
-			}
-			progress.Met = weeklyWorkouts >= goal.Target
-			// For workout frequency, streak is weeks meeting the goal
-			// Simplified: just show if current week is met
-			if progress.Met {
-				progress.CurrentStreak = 1
-			}
-		}
-
-		overview.Goals = append(overview.Goals, progress)
-
-		if progress.Met {
-			overview.TodaysMet++
-		}
-		overview.TodaysTotal++
-	}
-
-	// Calculate weekly totals
-	// This is simplified - count days where at least one goal was met this week
-	for i := 0; i < 7 && i < len(histData); i++ {
-		idx := len(histData) - 1 - i
-		if idx < 0 {
-			break
-		}
-		d := histData[idx]
-		// Check if this day is in the current week
-		if dayTime, err := time.Parse("2006-01-02", d.day); err == nil {
-			if !dayTime.Before(weekStart) {
-				met := false
-				for _, goal := range goals {
-					switch goal.GoalType {
-					case "step_goal":
-						if d.steps >= goal.Target {
-							met = true
-						}
-					case "sleep_score":
-						if d.sleep >= goal.Target {
-							met = true
-						}
-					case "readiness_score":
-						if d.readiness >= goal.Target {
-							met = true
-						}
-					}
-				}
-				if met {
-					overview.WeeklyMet++
-				}
-				overview.WeeklyTotal++
-			}
-		}
-	}
-
-	core.WriteJSON(w, http.StatusOK, overview)
-}
-
-// goalDailyData is used for goal streak calculations
-type goalDailyData struct {
-	day       string
-	sleep     int
-	readiness int
-	steps     int
-}
-
-// calculateGoalStreak calculates current and best streak for a goal condition
-func calculateGoalStreak(data []goalDailyData, condition func(goalDailyData) bool) (int, int, string) {
-	if len(data) == 0 {
-		return 0, 0, ""
-	}
-
-	var currentStreak, bestStreak int
-	var lastAchieved string
-
-	// Calculate best streak
-	tempStreak := 0
-	for _, d := range data {
-		if condition(d) {
-			tempStreak++
-			if tempStreak > bestStreak {
-				bestStreak = tempStreak
-			}
-			lastAchieved = d.day
-		} else {
-			tempStreak = 0
-		}
-	}
-
-	// Calculate current streak (from end)
-	for i := len(data) - 1; i >= 0; i-- {
-		if condition(data[i]) {
-			currentStreak++
-		} else {
-			break
-		}
-	}
-
-	return currentStreak, bestStreak, lastAchieved
-}
-
 // GetWeeklySummary returns the weekly health review
 func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -2316,7 +2105,7 @@ func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 	// If week=current, use current week (may be incomplete)
 	weekParam := r.URL.Query().Get("week")
 
-	now := time.Now()
+	now := h.today(ctx)
 	var weekStart, weekEnd time.Time
 
 	if weekParam == "current" {
@@ -2498,36 +2287,14 @@ func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 		SELECT COUNT(*) FROM workouts WHERE date >= $1 AND date <= $2
 	`, weekStartStr, weekEndStr).Scan(&summary.WorkoutCount)
 
-	// Count goals met
-	goalRows, err := h.db.Query(ctx, `
-		SELECT goal_type, target FROM health_goals WHERE active = true
-	`)
-	if err == nil {
-		defer goalRows.Close()
-		for goalRows.Next() {
-			var goalType string
-			var target int
-			if err := goalRows.Scan(&goalType, &target); err != nil {
-				continue
-			}
-			summary.GoalsTotal++
-
-			// Check if goal was met (on average or total)
-			switch goalType {
-			case "step_goal":
-				if summary.AvgSteps >= float64(target) {
-					summary.GoalsMet++
-				}
-			case "sleep_score":
-				if summary.AvgSleep >= float64(target) {
-					summary.GoalsMet++
-				}
-			case "readiness_score":
-				if summary.AvgReadiness >= float64(target) {
-					summary.GoalsMet++
-				}
-			case "workout_frequency":
-				if summary.WorkoutCount >= target {
+	// Count goals met over the week
+	if goals, err := h.loadHealthGoals(ctx, false); err == nil && len(goals) > 0 {
+		from, _ := time.Parse("2006-01-02", weekStartStr)
+		to, _ := time.Parse("2006-01-02", weekEndStr)
+		if series, err := h.goalSeries(ctx, goals, to, 1); err == nil {
+			for _, goal := range goals {
+				summary.GoalsTotal++
+				if goalMetForWeek(goal, series[goal.Metric], from, to) {
 					summary.GoalsMet++
 				}
 			}
@@ -2569,11 +2336,16 @@ func (h *Handler) GetWeeklySummary(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch active streaks
 	histRows, err := h.db.Query(ctx, `
-		SELECT day, COALESCE(sleep_score, 0), COALESCE(readiness_score, 0), COALESCE(activity_steps, 0)
-		FROM oura_daily
+		SELECT day, sleep, readiness, steps FROM (
+			SELECT day, COALESCE(sleep_score, 0) AS sleep, COALESCE(readiness_score, 0) AS readiness,
+				COALESCE(activity_steps, 0) AS steps
+			FROM oura_daily
+			WHERE day <= $1
+			ORDER BY day DESC
+			LIMIT 90
+		) recent
 		ORDER BY day ASC
-		LIMIT 90
-	`)
+	`, formatDate(now))
 	if err == nil {
 		defer histRows.Close()
 		var histData []dailyData
@@ -0,0 +1,534 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// journalTagName is the format of tag catalogue names, e.g. late_meal
+var journalTagName = regexp.MustCompile(`^[a-z0-9_]{1,40}$`)
+
+// errUnknownTag is returned when a journal entry uses a tag missing from the catalogue
+var errUnknownTag = errors.New("unknown tag")
+
+// journalEntrySelect selects entries with their tag names for scanJournalEntry
+const journalEntrySelect = `
+	SELECT j.id, j.day, j.note, j.mood, j.energy, j.stress, j.created_at, j.updated_at,
+		COALESCE(ARRAY_AGG(t.name ORDER BY t.name) FILTER (WHERE t.name IS NOT NULL), '{}')
+	FROM daily_journal j
+	LEFT JOIN daily_journal_tags jt ON jt.journal_id = j.id
+	LEFT JOIN journal_tags t ON t.id = jt.tag_id`
+
+// scanJournalEntry scans a row selected with journalEntrySelect
+func scanJournalEntry(row pgx.Row) (JournalEntry, error) {
+	var e JournalEntry
+	var day time.Time
+	err := row.Scan(&e.ID, &day, &e.Note, &e.Mood, &e.Energy, &e.Stress, &e.CreatedAt, &e.UpdatedAt, &e.Tags)
+	e.Day = formatDate(day)
+	return e, err
+}
+
+// validateRating checks an optional 1-5 rating
+func validateRating(name string, v *int) error {
+	if v != nil && (*v < 1 || *v > 5) {
+		return fmt.Errorf("%s must be between 1 and 5", name)
+	}
+	return nil
+}
+
+// setJournalTags replaces the tags of a journal entry
+func setJournalTags(ctx context.Context, q querier, journalID int64, tags []string) error {
+	if _, err := q.Exec(ctx, `DELETE FROM daily_journal_tags WHERE journal_id = $1`, journalID); err != nil {
+		return err
+	}
+	for _, name := range tags {
+		result, err := q.Exec(ctx, `
+			INSERT INTO daily_journal_tags (journal_id, tag_id)
+			SELECT $1, id FROM journal_tags WHERE name = $2
+			ON CONFLICT DO NOTHING
+		`, journalID, name)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			// Either a duplicate in the request or a missing tag
+			var exists bool
+			if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM journal_tags WHERE name = $1)`, name).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%w: %q", errUnknownTag, name)
+			}
+		}
+	}
+	return nil
+}
+
+// --- Journal handlers ---
+
+// ListJournalEntries returns journal entries, newest first. Query: ?from=,
+// ?to= (YYYY-MM-DD), ?tag= to only return days with that tag, ?limit=
+func (h *Handler) ListJournalEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 90
+	if l := query.Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+	from, to := query.Get("from"), query.Get("to")
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			core.WriteError(w, http.StatusBadRequest, "from and to must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	rows, err := h.db.Query(r.Context(), journalEntrySelect+`
+		WHERE ($1 = '' OR j.day >= $1::date) AND ($2 = '' OR j.day <= $2::date)
+			AND ($3 = '' OR EXISTS (
+				SELECT 1 FROM daily_journal_tags x JOIN journal_tags xt ON xt.id = x.tag_id
+				WHERE x.journal_id = j.id AND xt.name = $3))
+		GROUP BY j.id
+		ORDER BY j.day DESC
+		LIMIT $4
+	`, from, to, query.Get("tag"), limit)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	entries := []JournalEntry{}
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	core.WriteJSON(w, http.StatusOK, entries)
+}
+
+// UpsertJournalEntry creates or replaces the journal entry for a day
+func (h *Handler) UpsertJournalEntry(w http.ResponseWriter, r *http.Request) {
+	var input JournalEntryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Default to today if no day provided
+	if input.Day == "" {
+		input.Day = h.todayKey(r.Context())
+	}
+	if _, err := time.Parse("2006-01-02", input.Day); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "day must be YYYY-MM-DD")
+		return
+	}
+	for _, rating := range []struct {
+		name  string
+		value *int
+	}{{"mood", input.Mood}, {"energy", input.Energy}, {"stress", input.Stress}} {
+		if err := validateRating(rating.name, rating.value); err != nil {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var id int64
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO daily_journal (day, note, mood, energy, stress)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (day) DO UPDATE SET
+			note = EXCLUDED.note,
+			mood = EXCLUDED.mood,
+			energy = EXCLUDED.energy,
+			stress = EXCLUDED.stress,
+			updated_at = NOW()
+		RETURNING id
+	`, input.Day, strings.TrimSpace(input.Note), input.Mood, input.Energy, input.Stress).Scan(&id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := setJournalTags(r.Context(), tx, id, input.Tags); err != nil {
+		if errors.Is(err, errUnknownTag) {
+			core.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entry, err := scanJournalEntry(tx.QueryRow(r.Context(), journalEntrySelect+`
+		WHERE j.id = $1
+		GROUP BY j.id
+	`, id))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, entry)
+}
+
+// GetJournalEntry returns the journal entry of a day
+func (h *Handler) GetJournalEntry(w http.ResponseWriter, r *http.Request) {
+	day := chi.URLParam(r, "day")
+
+	entry, err := scanJournalEntry(h.db.QueryRow(r.Context(), journalEntrySelect+`
+		WHERE j.day = $1
+		GROUP BY j.id
+	`, day))
+	if err != nil {
+		core.WriteError(w, http.StatusNotFound, "Journal entry not found")
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, entry)
+}
+
+// DeleteJournalEntry removes the journal entry of a day
+func (h *Handler) DeleteJournalEntry(w http.ResponseWriter, r *http.Request) {
+	day := chi.URLParam(r, "day")
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM daily_journal WHERE day = $1`, day)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Journal entry not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Tag catalogue handlers ---
+
+// ListJournalTags returns the tag catalogue with how often each tag was used
+func (h *Handler) ListJournalTags(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Query(r.Context(), `
+		SELECT t.id, t.name, t.label, t.created_at, COUNT(jt.journal_id)
+		FROM journal_tags t
+		LEFT JOIN daily_journal_tags jt ON jt.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name
+	`)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	tags := []JournalTag{}
+	for rows.Next() {
+		var t JournalTag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Label, &t.CreatedAt, &t.Uses); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		tags = append(tags, t)
+	}
+
+	core.WriteJSON(w, http.StatusOK, tags)
+}
+
+// CreateJournalTag adds a tag to the catalogue
+func (h *Handler) CreateJournalTag(w http.ResponseWriter, r *http.Request) {
+	var input JournalTagInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if !journalTagName.MatchString(input.Name) {
+		core.WriteError(w, http.StatusBadRequest, "name must be 1-40 lowercase letters, digits or underscores")
+		return
+	}
+	input.Label = strings.TrimSpace(input.Label)
+	if input.Label == "" {
+		input.Label = correlationLabel(input.Name)
+	}
+
+	t := JournalTag{Name: input.Name, Label: input.Label}
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO journal_tags (name, label)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, t.Name, t.Label).Scan(&t.ID, &t.CreatedAt)
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Tag already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, t)
+}
+
+// DeleteJournalTag removes a tag and its uses from the journal
+func (h *Handler) DeleteJournalTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM journal_tags WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Tag impact ---
+
+// tagImpactMetrics are the next-night outcomes compared for each tag
+var tagImpactMetrics = []string{"sleep_score", "hrv", "readiness_score"}
+
+// welchPValue is the two-sided p-value of Welch's t-test for two samples
+func welchPValue(a, b []float64) *float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return nil
+	}
+	meanA, sdA := sampleMeanStdDev(a)
+	meanB, sdB := sampleMeanStdDev(b)
+	va, vb := sdA*sdA/float64(len(a)), sdB*sdB/float64(len(b))
+	if va+vb == 0 {
+		return nil
+	}
+	t := (meanA - meanB) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	p := studentTTwoSided(t, df)
+	return &p
+}
+
+// meanOf returns the mean of values, or nil when empty
+func meanOf(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := math.Round(sum/float64(len(values))*10) / 10
+	return &mean
+}
+
+// GetTagImpact compares the following night's sleep score, HRV and
+// readiness on journaled days with vs without each tag. A tag logged on
+// day D is matched with Oura day D+1, which covers the night after D.
+func (h *Handler) GetTagImpact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	days := 180 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 730 {
+			days = n
+		}
+	}
+	startDate := h.today(ctx).AddDate(0, 0, -days)
+
+	// Next-night outcomes per journal day
+	rows, err := h.db.Query(ctx, `
+		SELECT j.id, o.sleep_score::double precision, s.average_hrv::double precision,
+			o.readiness_score::double precision
+		FROM daily_journal j
+		LEFT JOIN oura_daily o ON o.day = j.day + 1
+		LEFT JOIN LATERAL (
+			SELECT average_hrv FROM sleep_sessions
+			WHERE day = j.day + 1 AND NOT is_nap
+			ORDER BY total_sleep_seconds DESC NULLS LAST
+			LIMIT 1
+		) s ON TRUE
+		WHERE j.day >= $1
+	`, formatDate(startDate))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	outcomes := make(map[int64][]*float64)
+	for rows.Next() {
+		var id int64
+		values := make([]*float64, len(tagImpactMetrics))
+		if err := rows.Scan(&id, &values[0], &values[1], &values[2]); err != nil {
+			rows.Close()
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		outcomes[id] = values
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Which journal days carry which tag
+	rows, err = h.db.Query(ctx, `
+		SELECT t.name, t.label, COALESCE(ARRAY_AGG(jt.journal_id) FILTER (WHERE jt.journal_id IS NOT NULL), '{}')
+		FROM journal_tags t
+		LEFT JOIN (
+			daily_journal_tags jt JOIN daily_journal j ON j.id = jt.journal_id AND j.day >= $1
+		) ON jt.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name
+	`, formatDate(startDate))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	report := TagImpactReport{Days: days, JournalDays: len(outcomes), Tags: []TagImpact{}}
+	for rows.Next() {
+		var impact TagImpact
+		var ids []int64
+		if err := rows.Scan(&impact.Tag, &impact.Label, &ids); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		tagged := make(map[int64]bool, len(ids))
+		for _, id := range ids {
+			tagged[id] = true
+		}
+		impact.TaggedDays = len(ids)
+
+		for m, metric := range tagImpactMetrics {
+			var with, without []float64
+			for id, values := range outcomes {
+				if values[m] == nil {
+					continue
+				}
+				if tagged[id] {
+					with = append(with, *values[m])
+				} else {
+					without = append(without, *values[m])
+				}
+			}
+			tm := TagImpactMetric{
+				Metric:     metric,
+				WithAvg:    meanOf(with),
+				WithoutAvg: meanOf(without),
+				NWith:      len(with),
+				NWithout:   len(without),
+				PValue:     welchPValue(with, without),
+			}
+			if tm.WithAvg != nil && tm.WithoutAvg != nil {
+				diff := math.Round((*tm.WithAvg-*tm.WithoutAvg)*10) / 10
+				tm.Difference = &diff
+			}
+			impact.Metrics = append(impact.Metrics, tm)
+		}
+		report.Tags = append(report.Tags, impact)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, report)
+}
+
+// loadJournalSeries returns the mood, energy and stress ratings and one
+// 0/1 series per tag for correlation. Tag series only cover journaled
+// days, so an unlogged day is missing rather than counted as "no tag".
+func (h *Handler) loadJournalSeries(ctx context.Context, from, to string) ([]correlationSeries, error) {
+	rows, err := h.db.Query(ctx, journalEntrySelect+`
+		WHERE j.day >= $1 AND j.day <= $2
+		GROUP BY j.id
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ratings := map[string]map[string]float64{"mood": {}, "energy": {}, "stress": {}}
+	for _, e := range entries {
+		for key, v := range map[string]*int{"mood": e.Mood, "energy": e.Energy, "stress": e.Stress} {
+			if v != nil {
+				ratings[key][e.Day] = float64(*v)
+			}
+		}
+	}
+	series := []correlationSeries{
+		{key: "mood", family: "journal", values: ratings["mood"]},
+		{key: "energy", family: "journal", values: ratings["energy"]},
+		{key: "stress", family: "journal", values: ratings["stress"]},
+	}
+
+	tagRows, err := h.db.Query(ctx, `SELECT name, label FROM journal_tags ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var name, label string
+		if err := tagRows.Scan(&name, &label); err != nil {
+			return nil, err
+		}
+		values := make(map[string]float64, len(entries))
+		for _, e := range entries {
+			values[e.Day] = 0
+			for _, t := range e.Tags {
+				if t == name {
+					values[e.Day] = 1
+				}
+			}
+		}
+		series = append(series, correlationSeries{key: "tag_" + name, label: "Tag: " + label, family: "journal", values: values})
+	}
+	return series, tagRows.Err()
+}
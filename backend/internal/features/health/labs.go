@@ -0,0 +1,640 @@
+package health
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxCSVImportBytes caps lab and vitals CSV uploads
+	maxCSVImportBytes = 10 << 20
+
+	minLabTrendPoints = 3
+	minLabTrendDays   = 30
+	labStableFraction = 0.02 // a yearly change below 2% of the mean is stable
+)
+
+// labConversion converts a value to the marker's unit: value*factor + offset
+type labConversion struct {
+	factor, offset float64
+}
+
+// labMarker is a known lab test with its unit, accepted units and a
+// general adult reference range (zero for no bound)
+type labMarker struct {
+	label   string
+	unit    string
+	units   map[string]labConversion // keyed by unitKey
+	refLow  float64
+	refHigh float64
+}
+
+// labMarkers are the tests whose units are normalised. Other tests are
+// stored as entered.
+var labMarkers = map[string]labMarker{
+	"glucose":           {"Glucose", "mmol/L", map[string]labConversion{"mg/dl": {1.0 / 18, 0}}, 3.9, 5.6},
+	"hba1c":             {"HbA1c", "mmol/mol", map[string]labConversion{"%": {10.929, -23.5}}, 20, 42},
+	"total_cholesterol": {"Total cholesterol", "mmol/L", map[string]labConversion{"mg/dl": {0.02586, 0}}, 0, 5.0},
+	"ldl":               {"LDL cholesterol", "mmol/L", map[string]labConversion{"mg/dl": {0.02586, 0}}, 0, 3.0},
+	"hdl":               {"HDL cholesterol", "mmol/L", map[string]labConversion{"mg/dl": {0.02586, 0}}, 1.0, 0},
+	"triglycerides":     {"Triglycerides", "mmol/L", map[string]labConversion{"mg/dl": {0.01129, 0}}, 0, 1.7},
+	"creatinine":        {"Creatinine", "µmol/L", map[string]labConversion{"mg/dl": {88.42, 0}}, 60, 110},
+	"vitamin_d":         {"Vitamin D (25-OH)", "nmol/L", map[string]labConversion{"ng/ml": {2.496, 0}}, 50, 125},
+	"vitamin_b12":       {"Vitamin B12", "pmol/L", map[string]labConversion{"pg/ml": {0.738, 0}, "ng/l": {0.738, 0}}, 150, 650},
+	"ferritin":          {"Ferritin", "µg/L", map[string]labConversion{"ng/ml": {1, 0}}, 30, 400},
+	"hemoglobin":        {"Hemoglobin", "g/L", map[string]labConversion{"g/dl": {10, 0}, "mmol/l": {16.11, 0}}, 130, 175},
+	"tsh":               {"TSH", "mIU/L", map[string]labConversion{"uiu/ml": {1, 0}, "mu/l": {1, 0}}, 0.4, 4.0},
+	"crp":               {"CRP", "mg/L", map[string]labConversion{"mg/dl": {10, 0}}, 0, 5},
+	"testosterone":      {"Testosterone", "nmol/L", map[string]labConversion{"ng/dl": {0.0347, 0}, "ng/ml": {3.47, 0}}, 8.6, 29},
+	"alt":               {"ALT", "U/L", map[string]labConversion{"ukat/l": {60, 0}, "iu/l": {1, 0}}, 0, 45},
+	"ast":               {"AST", "U/L", map[string]labConversion{"ukat/l": {60, 0}, "iu/l": {1, 0}}, 0, 35},
+}
+
+// labMarkerAliases maps common report names to marker keys
+var labMarkerAliases = map[string]string{
+	"fasting_glucose":             "glucose",
+	"blood_glucose":               "glucose",
+	"a1c":                         "hba1c",
+	"hemoglobin_a1c":              "hba1c",
+	"haemoglobin_a1c":             "hba1c",
+	"cholesterol":                 "total_cholesterol",
+	"ldl_cholesterol":             "ldl",
+	"ldl_c":                       "ldl",
+	"hdl_cholesterol":             "hdl",
+	"hdl_c":                       "hdl",
+	"triglyceride":                "triglycerides",
+	"trig":                        "triglycerides",
+	"25_oh_vitamin_d":             "vitamin_d",
+	"25_hydroxyvitamin_d":         "vitamin_d",
+	"vitamin_d3":                  "vitamin_d",
+	"b12":                         "vitamin_b12",
+	"cobalamin":                   "vitamin_b12",
+	"haemoglobin":                 "hemoglobin",
+	"hb":                          "hemoglobin",
+	"hgb":                         "hemoglobin",
+	"c_reactive_protein":          "crp",
+	"hs_crp":                      "crp",
+	"thyroid_stimulating_hormone": "tsh",
+	"total_testosterone":          "testosterone",
+	"alanine_aminotransferase":    "alt",
+	"aspartate_aminotransferase":  "ast",
+}
+
+var labSlugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// labMarkerKey returns the marker key for a test name
+func labMarkerKey(test string) string {
+	key := strings.Trim(labSlugInvalid.ReplaceAllString(strings.ToLower(test), "_"), "_")
+	if alias, ok := labMarkerAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// unitKey normalises a unit for comparison: lower case, no spaces, µ as u
+func unitKey(unit string) string {
+	u := strings.ToLower(strings.ReplaceAll(unit, " ", ""))
+	u = strings.NewReplacer("µ", "u", "μ", "u", "mcg", "ug").Replace(u)
+	return u
+}
+
+// normaliseLabValue converts a value of a known marker to the marker's
+// unit. Unknown markers keep the value and unit as entered.
+func normaliseLabValue(marker string, value float64, unit string) (float64, string, error) {
+	m, ok := labMarkers[marker]
+	if !ok {
+		return value, strings.TrimSpace(unit), nil
+	}
+	key := unitKey(unit)
+	if key == "" || key == unitKey(m.unit) {
+		return value, m.unit, nil
+	}
+	conv, ok := m.units[key]
+	if !ok {
+		accepted := []string{m.unit}
+		for u := range m.units {
+			accepted = append(accepted, u)
+		}
+		sort.Strings(accepted[1:])
+		return 0, "", fmt.Errorf("unknown unit %q for %s; expected one of %s", unit, m.label, strings.Join(accepted, ", "))
+	}
+	return round2(value*conv.factor + conv.offset), m.unit, nil
+}
+
+// labFlag is low or high outside the reference range, otherwise normal.
+// It is empty without a range.
+func labFlag(value float64, low, high *float64) string {
+	switch {
+	case low != nil && value < *low:
+		return "low"
+	case high != nil && value > *high:
+		return "high"
+	case low == nil && high == nil:
+		return ""
+	default:
+		return "normal"
+	}
+}
+
+// parseReferenceRange parses "3.5-5.0", "<5.2" or ">1.0"
+func parseReferenceRange(s string) (*float64, *float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return nil, nil, nil
+	}
+	parse := func(v string) (*float64, error) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimLeft(v, "<>=≤≥")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference range %q", s)
+		}
+		return &f, nil
+	}
+	switch {
+	case strings.HasPrefix(s, "<") || strings.HasPrefix(s, "≤"):
+		high, err := parse(s)
+		return nil, high, err
+	case strings.HasPrefix(s, ">") || strings.HasPrefix(s, "≥"):
+		low, err := parse(s)
+		return low, nil, err
+	}
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("invalid reference range %q", s)
+	}
+	low, err := parse(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	high, err := parse(parts[1])
+	return low, high, err
+}
+
+// prepareLabResult validates an input and normalises its value and
+// reference range to the marker's unit
+func prepareLabResult(input LabResultInput) (LabResult, error) {
+	res := LabResult{
+		Test:          strings.TrimSpace(input.Test),
+		OriginalValue: input.Value,
+		OriginalUnit:  strings.TrimSpace(input.Unit),
+		Lab:           strings.TrimSpace(input.Lab),
+		SampleDate:    input.SampleDate,
+		Notes:         strings.TrimSpace(input.Notes),
+	}
+	if res.Test == "" {
+		return res, fmt.Errorf("test is required")
+	}
+	res.Marker = labMarkerKey(res.Test)
+	if res.Marker == "" {
+		return res, fmt.Errorf("test must contain letters or digits")
+	}
+	if math.IsNaN(input.Value) || math.IsInf(input.Value, 0) {
+		return res, fmt.Errorf("value must be a number")
+	}
+	if _, err := time.Parse("2006-01-02", res.SampleDate); err != nil {
+		return res, fmt.Errorf("sample_date must be YYYY-MM-DD")
+	}
+
+	var err error
+	res.Value, res.Unit, err = normaliseLabValue(res.Marker, input.Value, input.Unit)
+	if err != nil {
+		return res, err
+	}
+	for _, bound := range []struct {
+		in  *float64
+		out **float64
+	}{{input.RefLow, &res.RefLow}, {input.RefHigh, &res.RefHigh}} {
+		if bound.in == nil {
+			continue
+		}
+		v, _, err := normaliseLabValue(res.Marker, *bound.in, input.Unit)
+		if err != nil {
+			return res, err
+		}
+		*bound.out = &v
+	}
+	if res.RefLow == nil && res.RefHigh == nil {
+		if m, ok := labMarkers[res.Marker]; ok {
+			if m.refLow > 0 {
+				res.RefLow = &m.refLow
+			}
+			if m.refHigh > 0 {
+				res.RefHigh = &m.refHigh
+			}
+		}
+	}
+	res.Flag = labFlag(res.Value, res.RefLow, res.RefHigh)
+	return res, nil
+}
+
+// labResultColumns is the column list matching scanLabResult
+const labResultColumns = `id, marker, test, value, unit, original_value, original_unit,
+	ref_low, ref_high, lab, sample_date, notes, source, created_at`
+
+// scanLabResult scans a row selected with labResultColumns
+func scanLabResult(row pgx.Row) (LabResult, error) {
+	var res LabResult
+	var day time.Time
+	err := row.Scan(&res.ID, &res.Marker, &res.Test, &res.Value, &res.Unit, &res.OriginalValue, &res.OriginalUnit,
+		&res.RefLow, &res.RefHigh, &res.Lab, &day, &res.Notes, &res.Source, &res.CreatedAt)
+	if err != nil {
+		return res, err
+	}
+	res.SampleDate = formatDate(day)
+	res.Flag = labFlag(res.Value, res.RefLow, res.RefHigh)
+	return res, nil
+}
+
+// upsertLabResult stores a result, replacing the same marker on the same day
+func upsertLabResult(ctx context.Context, q querier, res LabResult, source string) (LabResult, error) {
+	return scanLabResult(q.QueryRow(ctx, `
+		INSERT INTO lab_results (marker, test, value, unit, original_value, original_unit,
+			ref_low, ref_high, lab, sample_date, notes, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (marker, sample_date) DO UPDATE SET
+			test = EXCLUDED.test,
+			value = EXCLUDED.value,
+			unit = EXCLUDED.unit,
+			original_value = EXCLUDED.original_value,
+			original_unit = EXCLUDED.original_unit,
+			ref_low = EXCLUDED.ref_low,
+			ref_high = EXCLUDED.ref_high,
+			lab = EXCLUDED.lab,
+			notes = EXCLUDED.notes,
+			source = EXCLUDED.source
+		RETURNING `+labResultColumns,
+		res.Marker, res.Test, res.Value, res.Unit, res.OriginalValue, res.OriginalUnit,
+		res.RefLow, res.RefHigh, res.Lab, res.SampleDate, res.Notes, source))
+}
+
+// ListLabResults returns lab results, newest first. Query: ?marker=, ?from=, ?to=
+func (h *Handler) ListLabResults(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" {
+		from = "0001-01-01"
+	}
+	if to == "" {
+		to = "9999-12-31"
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+labResultColumns+`
+		FROM lab_results
+		WHERE ($1 = '' OR marker = $1) AND sample_date >= $2 AND sample_date <= $3
+		ORDER BY sample_date DESC, marker
+	`, query.Get("marker"), from, to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	results := []LabResult{}
+	for rows.Next() {
+		res, err := scanLabResult(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results = append(results, res)
+	}
+
+	core.WriteJSON(w, http.StatusOK, results)
+}
+
+// CreateLabResult records a lab result; the same marker on the same day is replaced
+func (h *Handler) CreateLabResult(w http.ResponseWriter, r *http.Request) {
+	var input LabResultInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	res, err := prepareLabResult(input)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err = upsertLabResult(r.Context(), h.db, res, "manual")
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, res)
+}
+
+// DeleteLabResult removes a lab result
+func (h *Handler) DeleteLabResult(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM lab_results WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Lab result not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListLabMarkers returns each marker with results and its latest value,
+// plus the known markers with their units
+func (h *Handler) ListLabMarkers(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Query(r.Context(), `
+		SELECT DISTINCT ON (marker) `+labResultColumns+`,
+			COUNT(*) OVER (PARTITION BY marker)
+		FROM lab_results
+		ORDER BY marker, sample_date DESC
+	`)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	markers := []LabMarkerSummary{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var s LabMarkerSummary
+		var latest LabResult
+		var day time.Time
+		if err := rows.Scan(&latest.ID, &latest.Marker, &latest.Test, &latest.Value, &latest.Unit,
+			&latest.OriginalValue, &latest.OriginalUnit, &latest.RefLow, &latest.RefHigh, &latest.Lab,
+			&day, &latest.Notes, &latest.Source, &latest.CreatedAt, &s.Results); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		latest.SampleDate = formatDate(day)
+		latest.Flag = labFlag(latest.Value, latest.RefLow, latest.RefHigh)
+		s.Marker, s.Label, s.Unit, s.Latest = latest.Marker, latest.Test, latest.Unit, &latest
+		if m, ok := labMarkers[s.Marker]; ok {
+			s.Label = m.label
+		}
+		seen[s.Marker] = true
+		markers = append(markers, s)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for key, m := range labMarkers {
+		if !seen[key] {
+			markers = append(markers, LabMarkerSummary{Marker: key, Label: m.label, Unit: m.unit})
+		}
+	}
+	sort.Slice(markers, func(i, j int) bool { return markers[i].Label < markers[j].Label })
+
+	core.WriteJSON(w, http.StatusOK, markers)
+}
+
+// GetLabMarkerTrend returns all results of one marker with the change since
+// the previous result and the yearly rate of change
+func (h *Handler) GetLabMarkerTrend(w http.ResponseWriter, r *http.Request) {
+	marker := chi.URLParam(r, "marker")
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+labResultColumns+`
+		FROM lab_results
+		WHERE marker = $1
+		ORDER BY sample_date
+	`, marker)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var results []LabResult
+	for rows.Next() {
+		res, err := scanLabResult(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(results) == 0 {
+		core.WriteError(w, http.StatusNotFound, "No results for marker")
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, labMarkerTrend(marker, results))
+}
+
+// labMarkerTrend summarises a marker's results, oldest first
+func labMarkerTrend(marker string, results []LabResult) LabMarkerTrend {
+	latest := results[len(results)-1]
+	trend := LabMarkerTrend{
+		Marker:  marker,
+		Label:   latest.Test,
+		Unit:    latest.Unit,
+		RefLow:  latest.RefLow,
+		RefHigh: latest.RefHigh,
+		Latest:  &latest,
+		Results: results,
+	}
+	if m, ok := labMarkers[marker]; ok {
+		trend.Label = m.label
+	}
+
+	for _, res := range results {
+		if res.Flag == "low" || res.Flag == "high" {
+			trend.OutOfRange++
+		}
+	}
+	if len(results) > 1 {
+		change := round2(latest.Value - results[len(results)-2].Value)
+		trend.Change = &change
+	}
+
+	first, _ := time.Parse("2006-01-02", results[0].SampleDate)
+	last, _ := time.Parse("2006-01-02", latest.SampleDate)
+	if len(results) < minLabTrendPoints || last.Sub(first).Hours()/24 < minLabTrendDays {
+		return trend
+	}
+	xs := make([]float64, len(results))
+	ys := make([]float64, len(results))
+	var sum float64
+	for i, res := range results {
+		day, _ := time.Parse("2006-01-02", res.SampleDate)
+		xs[i] = day.Sub(first).Hours() / 24 / 365.25
+		ys[i] = res.Value
+		sum += res.Value
+	}
+	slope, _ := linearFit(xs, ys)
+	perYear := round2(slope)
+	trend.ChangePerYear = &perYear
+	mean := sum / float64(len(results))
+	switch {
+	case math.Abs(slope) < labStableFraction*math.Abs(mean):
+		trend.Direction = "stable"
+	case slope > 0:
+		trend.Direction = "rising"
+	default:
+		trend.Direction = "falling"
+	}
+	return trend
+}
+
+// --- CSV import ---
+
+// readCSVUpload returns a CSV reader for the request body or its "file"
+// multipart field, and the header columns keyed by lower-case name
+func readCSVUpload(w http.ResponseWriter, r *http.Request) (*csv.Reader, map[string]int, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCSVImportBytes)
+	body := io.Reader(r.Body)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, fmt.Errorf("file field is required")
+		}
+		body = file
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header")
+	}
+	cols := make(map[string]int)
+	for i, col := range header {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))] = i
+	}
+	return reader, cols, nil
+}
+
+// csvValue returns the first non-empty field among the column names
+func csvValue(record []string, cols map[string]int, names ...string) string {
+	for _, name := range names {
+		if i, ok := cols[name]; ok && i < len(record) {
+			if v := strings.TrimSpace(record[i]); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// csvFloat parses an optional number, accepting a decimal comma
+func csvFloat(record []string, cols map[string]int, names ...string) (*float64, error) {
+	v := csvValue(record, cols, names...)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %q is not a number", names[0], v)
+	}
+	return &f, nil
+}
+
+// ImportLabResults loads lab results from CSV with the columns test, value,
+// unit, date and optionally ref_low, ref_high or reference_range, lab and
+// notes. Rows replace the same marker on the same day; bad rows are
+// reported and skipped.
+func (h *Handler) ImportLabResults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reader, cols, err := readCSVUpload(w, r)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, required := range []string{"test", "value"} {
+		if _, ok := cols[required]; !ok {
+			core.WriteError(w, http.StatusBadRequest, "CSV needs a "+required+" column")
+			return
+		}
+	}
+
+	result := CSVImportResult{Errors: []CSVRowError{}}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		skip := func(err error) {
+			result.Skipped++
+			result.Errors = append(result.Errors, CSVRowError{Row: row, Error: err.Error()})
+		}
+		if err != nil {
+			skip(err)
+			continue
+		}
+
+		input := LabResultInput{
+			Test:       csvValue(record, cols, "test", "marker"),
+			Unit:       csvValue(record, cols, "unit"),
+			Lab:        csvValue(record, cols, "lab"),
+			SampleDate: csvValue(record, cols, "date", "sample_date"),
+			Notes:      csvValue(record, cols, "notes"),
+		}
+		value, err := csvFloat(record, cols, "value")
+		if err == nil && value == nil {
+			err = fmt.Errorf("value is required")
+		}
+		if err != nil {
+			skip(err)
+			continue
+		}
+		input.Value = *value
+		if input.RefLow, err = csvFloat(record, cols, "ref_low"); err != nil {
+			skip(err)
+			continue
+		}
+		if input.RefHigh, err = csvFloat(record, cols, "ref_high"); err != nil {
+			skip(err)
+			continue
+		}
+		if input.RefLow == nil && input.RefHigh == nil {
+			if input.RefLow, input.RefHigh, err = parseReferenceRange(csvValue(record, cols, "reference_range", "range")); err != nil {
+				skip(err)
+				continue
+			}
+		}
+
+		res, err := prepareLabResult(input)
+		if err != nil {
+			skip(err)
+			continue
+		}
+		if _, err := upsertLabResult(ctx, h.db, res, "csv"); err != nil {
+			skip(err)
+			continue
+		}
+		result.Imported++
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,755 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	medicationScheduleDaily    = "daily"
+	medicationScheduleWeekdays = "weekdays" // specific ISO weekdays, 1 = Monday
+	medicationScheduleAsNeeded = "as_needed"
+
+	maxMedicationTimesPerDay   = 6
+	defaultRefillThresholdDays = 7
+	asNeededUsageDays          = 30 // window for the average use of as-needed doses, as in medicationColumns
+)
+
+// medicationColumns is the column list matching scanMedication. The last
+// two columns are the stock left after intakes since the last count and
+// the doses taken in the as-needed usage window before today ($2).
+const medicationColumns = `m.id, m.name, m.kind, m.dose_amount, m.dose_unit, m.schedule, m.weekdays,
+	m.times_per_day, m.start_date, m.stop_date, m.units_per_dose, m.stock_units, m.stock_counted_at,
+	m.refill_threshold_days, m.notes, m.created_at,
+	m.stock_units - m.units_per_dose * COALESCE((
+		SELECT SUM(i.doses) FROM medication_intakes i
+		WHERE i.medication_id = m.id AND i.taken_at >= m.stock_counted_at
+	), 0),
+	COALESCE((
+		SELECT SUM(i.doses) FROM medication_intakes i
+		WHERE i.medication_id = m.id AND i.date > $2::date - 30
+	), 0)`
+
+// scanMedication scans a row selected with medicationColumns and works out
+// the stock status as of today
+func scanMedication(row pgx.Row, today time.Time) (Medication, error) {
+	var m Medication
+	var start time.Time
+	var stop *time.Time
+	var remaining *float64
+	var recentDoses float64
+	err := row.Scan(&m.ID, &m.Name, &m.Kind, &m.DoseAmount, &m.DoseUnit, &m.Schedule, &m.Weekdays,
+		&m.TimesPerDay, &start, &stop, &m.UnitsPerDose, &m.StockUnits, &m.StockCountedAt,
+		&m.RefillThresholdDays, &m.Notes, &m.CreatedAt, &remaining, &recentDoses)
+	if err != nil {
+		return m, err
+	}
+	if m.Weekdays == nil {
+		m.Weekdays = []int{}
+	}
+	m.StartDate = formatDate(start)
+	if stop != nil {
+		s := formatDate(*stop)
+		m.StopDate = &s
+	}
+	m.Active = m.activeOn(formatDate(today))
+	if remaining != nil {
+		m.Stock = medicationStock(m, *remaining, recentDoses, today)
+	}
+	return m, nil
+}
+
+// activeOn reports whether the medication is taken on a day, YYYY-MM-DD
+func (m Medication) activeOn(day string) bool {
+	return day >= m.StartDate && (m.StopDate == nil || day <= *m.StopDate)
+}
+
+// scheduledOn returns the doses due on a day; as-needed doses are never due
+func (m Medication) scheduledOn(day time.Time) int {
+	if !m.activeOn(formatDate(day)) {
+		return 0
+	}
+	switch m.Schedule {
+	case medicationScheduleDaily:
+		return m.TimesPerDay
+	case medicationScheduleWeekdays:
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		for _, d := range m.Weekdays {
+			if d == weekday {
+				return m.TimesPerDay
+			}
+		}
+	}
+	return 0
+}
+
+// dailyUnits is the average number of stock units used per day: from the
+// schedule, or from recent use for as-needed medications
+func (m Medication) dailyUnits(recentDoses float64) float64 {
+	switch m.Schedule {
+	case medicationScheduleDaily:
+		return m.UnitsPerDose * float64(m.TimesPerDay)
+	case medicationScheduleWeekdays:
+		return m.UnitsPerDose * float64(m.TimesPerDay*len(m.Weekdays)) / 7
+	default:
+		return m.UnitsPerDose * recentDoses / asNeededUsageDays
+	}
+}
+
+// medicationStock estimates when the stock of an active medication runs
+// out at the current rate
+func medicationStock(m Medication, remaining, recentDoses float64, today time.Time) *MedicationStock {
+	stock := &MedicationStock{Remaining: round1(math.Max(remaining, 0))}
+	perDay := m.dailyUnits(recentDoses)
+	if !m.Active || perDay <= 0 {
+		return stock
+	}
+	daysLeft := int(math.Floor(stock.Remaining / perDay))
+	runsOut := formatDate(today.AddDate(0, 0, daysLeft))
+	stock.DaysLeft = &daysLeft
+	stock.RunsOutOn = &runsOut
+	if m.StopDate != nil && *m.StopDate < runsOut {
+		return stock // enough to last until the stop date
+	}
+	stock.RefillDue = daysLeft <= m.RefillThresholdDays
+	return stock
+}
+
+// validateMedication normalises a medication input and applies defaults
+func validateMedication(input *MedicationInput, today string) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch input.Kind {
+	case "":
+		input.Kind = "supplement"
+	case "medication", "supplement":
+	default:
+		return fmt.Errorf("kind must be medication or supplement")
+	}
+	if input.DoseAmount < 0 {
+		return fmt.Errorf("dose_amount must not be negative")
+	}
+	input.DoseUnit = strings.TrimSpace(input.DoseUnit)
+
+	switch input.Schedule {
+	case "":
+		input.Schedule = medicationScheduleDaily
+	case medicationScheduleDaily, medicationScheduleAsNeeded:
+	case medicationScheduleWeekdays:
+		if len(input.Weekdays) == 0 {
+			return fmt.Errorf("weekdays is required for the weekdays schedule")
+		}
+	default:
+		return fmt.Errorf("schedule must be daily, weekdays or as_needed")
+	}
+	if input.Schedule != medicationScheduleWeekdays {
+		input.Weekdays = nil
+	}
+	seen := make(map[int]bool)
+	for _, d := range input.Weekdays {
+		if d < 1 || d > 7 || seen[d] {
+			return fmt.Errorf("weekdays must be distinct ISO weekdays 1 (Monday) to 7 (Sunday)")
+		}
+		seen[d] = true
+	}
+	sort.Ints(input.Weekdays)
+	if input.Weekdays == nil {
+		input.Weekdays = []int{}
+	}
+
+	if input.TimesPerDay == 0 {
+		input.TimesPerDay = 1
+	}
+	if input.TimesPerDay < 1 || input.TimesPerDay > maxMedicationTimesPerDay {
+		return fmt.Errorf("times_per_day must be between 1 and %d", maxMedicationTimesPerDay)
+	}
+	if input.UnitsPerDose == 0 {
+		input.UnitsPerDose = 1
+	}
+	if input.UnitsPerDose < 0 {
+		return fmt.Errorf("units_per_dose must be positive")
+	}
+	if input.StockUnits != nil && *input.StockUnits < 0 {
+		return fmt.Errorf("stock_units must not be negative")
+	}
+	if input.RefillThresholdDays == nil {
+		days := defaultRefillThresholdDays
+		input.RefillThresholdDays = &days
+	}
+	if *input.RefillThresholdDays < 0 || *input.RefillThresholdDays > 90 {
+		return fmt.Errorf("refill_threshold_days must be between 0 and 90")
+	}
+
+	if input.StartDate == "" {
+		input.StartDate = today
+	}
+	if _, err := time.Parse("2006-01-02", input.StartDate); err != nil {
+		return fmt.Errorf("start_date must be YYYY-MM-DD")
+	}
+	if input.StopDate != nil {
+		if _, err := time.Parse("2006-01-02", *input.StopDate); err != nil {
+			return fmt.Errorf("stop_date must be YYYY-MM-DD")
+		}
+		if *input.StopDate < input.StartDate {
+			return fmt.Errorf("stop_date must not be before start_date")
+		}
+	}
+	return nil
+}
+
+// loadMedications returns medications ordered by name, all or one, with
+// their stock as of today
+func loadMedications(ctx context.Context, q querier, id int64, today time.Time) ([]Medication, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+medicationColumns+`
+		FROM medications m
+		WHERE $1 = 0 OR m.id = $1
+		ORDER BY m.name, m.id
+	`, id, formatDate(today))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medications := []Medication{}
+	for rows.Next() {
+		m, err := scanMedication(rows, today)
+		if err != nil {
+			return nil, err
+		}
+		medications = append(medications, m)
+	}
+	return medications, rows.Err()
+}
+
+// medicationByID loads one medication, or pgx.ErrNoRows
+func (h *Handler) medicationByID(ctx context.Context, id int64) (Medication, error) {
+	medications, err := loadMedications(ctx, h.db, id, h.today(ctx))
+	if err != nil {
+		return Medication{}, err
+	}
+	if len(medications) == 0 {
+		return Medication{}, pgx.ErrNoRows
+	}
+	return medications[0], nil
+}
+
+// medicationFromRequest parses {id} and loads the medication, writing the
+// error response when it fails
+func (h *Handler) medicationFromRequest(w http.ResponseWriter, r *http.Request) (Medication, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return Medication{}, false
+	}
+	m, err := h.medicationByID(r.Context(), id)
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Medication not found")
+		return m, false
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return m, false
+	}
+	return m, true
+}
+
+// ListMedications returns medications and supplements. Query: ?active=true
+// to leave out stopped and not yet started ones
+func (h *Handler) ListMedications(w http.ResponseWriter, r *http.Request) {
+	medications, err := loadMedications(r.Context(), h.db, 0, h.today(r.Context()))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("active") == "true" {
+		active := []Medication{}
+		for _, m := range medications {
+			if m.Active {
+				active = append(active, m)
+			}
+		}
+		medications = active
+	}
+
+	core.WriteJSON(w, http.StatusOK, medications)
+}
+
+// CreateMedication adds a medication or supplement. A stock count given
+// here is counted now.
+func (h *Handler) CreateMedication(w http.ResponseWriter, r *http.Request) {
+	var input MedicationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateMedication(&input, h.todayKey(r.Context())); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var id int64
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO medications (name, kind, dose_amount, dose_unit, schedule, weekdays, times_per_day,
+			start_date, stop_date, units_per_dose, stock_units, stock_counted_at, refill_threshold_days, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+			CASE WHEN $11::double precision IS NULL THEN NULL ELSE NOW() END, $12, $13)
+		RETURNING id
+	`, input.Name, input.Kind, input.DoseAmount, input.DoseUnit, input.Schedule, input.Weekdays,
+		input.TimesPerDay, input.StartDate, input.StopDate, input.UnitsPerDose, input.StockUnits,
+		*input.RefillThresholdDays, strings.TrimSpace(input.Notes)).Scan(&id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	m, err := h.medicationByID(r.Context(), id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, m)
+}
+
+// GetMedication returns one medication with its stock status
+func (h *Handler) GetMedication(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.medicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, m)
+}
+
+// UpdateMedication replaces a medication's details. The stock is changed
+// through the refill endpoint, so stock_units is ignored here.
+func (h *Handler) UpdateMedication(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.medicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var input MedicationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateMedication(&input, h.todayKey(r.Context())); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err := h.db.Exec(r.Context(), `
+		UPDATE medications SET name = $2, kind = $3, dose_amount = $4, dose_unit = $5, schedule = $6,
+			weekdays = $7, times_per_day = $8, start_date = $9, stop_date = $10, units_per_dose = $11,
+			refill_threshold_days = $12, notes = $13
+		WHERE id = $1
+	`, m.ID, input.Name, input.Kind, input.DoseAmount, input.DoseUnit, input.Schedule, input.Weekdays,
+		input.TimesPerDay, input.StartDate, input.StopDate, input.UnitsPerDose,
+		*input.RefillThresholdDays, strings.TrimSpace(input.Notes))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	m, err = h.medicationByID(r.Context(), m.ID)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, m)
+}
+
+// DeleteMedication removes a medication and its intake log. Set a
+// stop_date instead to keep the history.
+func (h *Handler) DeleteMedication(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM medications WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Medication not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RefillMedication records a new stock count: add units to what is left,
+// or set the counted total
+func (h *Handler) RefillMedication(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.medicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var input MedicationRefillInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if (input.Add == nil) == (input.Count == nil) {
+		core.WriteError(w, http.StatusBadRequest, "exactly one of add or count is required")
+		return
+	}
+
+	var units float64
+	if input.Count != nil {
+		units = *input.Count
+	} else {
+		units = *input.Add
+		if m.Stock != nil {
+			units += m.Stock.Remaining
+		}
+	}
+	if units < 0 {
+		core.WriteError(w, http.StatusBadRequest, "stock must not be negative")
+		return
+	}
+
+	_, err := h.db.Exec(r.Context(), `
+		UPDATE medications SET stock_units = $2, stock_counted_at = NOW() WHERE id = $1
+	`, m.ID, units)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	m, err = h.medicationByID(r.Context(), m.ID)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, m)
+}
+
+// --- Intake log ---
+
+// ListMedicationIntakes returns a medication's intakes, newest first. Query: ?days= (default 30)
+func (h *Handler) ListMedicationIntakes(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.medicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	days := 30 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, medication_id, taken_at, date, doses, notes
+		FROM medication_intakes
+		WHERE medication_id = $1 AND date > $2
+		ORDER BY taken_at DESC
+	`, m.ID, formatDate(h.today(r.Context()).AddDate(0, 0, -days)))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	intakes := []MedicationIntake{}
+	for rows.Next() {
+		var in MedicationIntake
+		var day time.Time
+		if err := rows.Scan(&in.ID, &in.MedicationID, &in.TakenAt, &day, &in.Doses, &in.Notes); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		in.Date = formatDate(day)
+		intakes = append(intakes, in)
+	}
+
+	core.WriteJSON(w, http.StatusOK, intakes)
+}
+
+// LogMedicationIntake records taking a medication
+func (h *Handler) LogMedicationIntake(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.medicationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var input MedicationIntakeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	in := MedicationIntake{MedicationID: m.ID, TakenAt: time.Now(), Doses: 1, Notes: strings.TrimSpace(input.Notes)}
+	if input.TakenAt != "" {
+		t, err := time.Parse(time.RFC3339, input.TakenAt)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "taken_at must be RFC 3339")
+			return
+		}
+		in.TakenAt = t
+	}
+	if input.Doses != nil {
+		in.Doses = *input.Doses
+	}
+	if in.Doses <= 0 || in.Doses > 20 {
+		core.WriteError(w, http.StatusBadRequest, "doses must be between 0 and 20")
+		return
+	}
+	in.Date = h.dayOf(r.Context(), in.TakenAt)
+	if !m.activeOn(in.Date) {
+		core.WriteError(w, http.StatusBadRequest, "taken_at is outside the medication's start and stop dates")
+		return
+	}
+
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO medication_intakes (medication_id, taken_at, date, doses, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, in.MedicationID, in.TakenAt, in.Date, in.Doses, in.Notes).Scan(&in.ID)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, in)
+}
+
+// DeleteMedicationIntake removes a logged intake
+func (h *Handler) DeleteMedicationIntake(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	intakeID, err := strconv.ParseInt(chi.URLParam(r, "intakeId"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `
+		DELETE FROM medication_intakes WHERE id = $1 AND medication_id = $2
+	`, intakeID, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Intake not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Adherence ---
+
+// medicationDoses returns the doses taken per medication and day
+func medicationDoses(ctx context.Context, q querier, from, to string) (map[int64]map[string]float64, error) {
+	rows, err := q.Query(ctx, `
+		SELECT medication_id, date, SUM(doses)::double precision
+		FROM medication_intakes
+		WHERE date >= $1 AND date <= $2
+		GROUP BY medication_id, date
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	doses := make(map[int64]map[string]float64)
+	for rows.Next() {
+		var id int64
+		var day time.Time
+		var n float64
+		if err := rows.Scan(&id, &day, &n); err != nil {
+			return nil, err
+		}
+		if doses[id] == nil {
+			doses[id] = make(map[string]float64)
+		}
+		doses[id][formatDate(day)] = n
+	}
+	return doses, rows.Err()
+}
+
+// medicationAdherence compares the doses taken with the schedule from
+// start to today. Today is reported separately and only counts once all
+// of its doses are taken, so the day in progress is never missed.
+func medicationAdherence(m Medication, taken map[string]float64, start, today time.Time) MedicationAdherence {
+	a := MedicationAdherence{
+		MedicationID: m.ID,
+		Name:         m.Name,
+		Kind:         m.Kind,
+		Schedule:     m.Schedule,
+		MissedDoses:  []MissedDose{},
+		Stock:        m.Stock,
+	}
+
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		d := formatDate(day)
+		if !m.activeOn(d) {
+			continue
+		}
+		got := taken[d]
+		a.TakenDoses += got
+		due := m.scheduledOn(day)
+		if day.Equal(today) {
+			a.Today = &MedicationToday{Due: due, Taken: got}
+			if due == 0 || got < float64(due) {
+				continue
+			}
+		}
+		if due == 0 {
+			continue
+		}
+		a.ScheduledDoses += due
+		a.OnScheduleDoses += math.Min(got, float64(due))
+		if got < float64(due) {
+			a.MissedDoses = append(a.MissedDoses, MissedDose{Date: d, Due: due, Taken: got})
+			a.CurrentStreak = 0
+		} else {
+			a.CurrentStreak++
+		}
+	}
+	if a.ScheduledDoses > 0 {
+		pct := round1(a.OnScheduleDoses / float64(a.ScheduledDoses) * 100)
+		a.AdherencePct = &pct
+	}
+	// Newest missed doses first
+	sort.Slice(a.MissedDoses, func(i, j int) bool { return a.MissedDoses[i].Date > a.MissedDoses[j].Date })
+	return a
+}
+
+// GetMedicationAdherence returns adherence, missed doses and refill
+// reminders for medications active in the window. Query: ?days= (default 30)
+func (h *Handler) GetMedicationAdherence(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	days := 30 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+	today := h.today(ctx)
+	start := today.AddDate(0, 0, -(days - 1))
+	from, to := formatDate(start), formatDate(today)
+
+	medications, err := loadMedications(ctx, h.db, 0, today)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	doses, err := medicationDoses(ctx, h.db, from, to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	report := MedicationAdherenceReport{
+		Days:            days,
+		From:            from,
+		To:              to,
+		Medications:     []MedicationAdherence{},
+		RefillReminders: []RefillReminder{},
+	}
+	var scheduled int
+	var onSchedule float64
+	for _, m := range medications {
+		if m.StartDate > to || (m.StopDate != nil && *m.StopDate < from) {
+			continue
+		}
+		a := medicationAdherence(m, doses[m.ID], start, today)
+		report.Medications = append(report.Medications, a)
+		scheduled += a.ScheduledDoses
+		onSchedule += a.OnScheduleDoses
+
+		if m.Stock != nil && m.Stock.RefillDue {
+			report.RefillReminders = append(report.RefillReminders, RefillReminder{
+				MedicationID: m.ID,
+				Name:         m.Name,
+				Remaining:    m.Stock.Remaining,
+				DaysLeft:     *m.Stock.DaysLeft,
+				RunsOutOn:    *m.Stock.RunsOutOn,
+			})
+		}
+	}
+	if scheduled > 0 {
+		pct := round1(onSchedule / float64(scheduled) * 100)
+		report.AdherencePct = &pct
+	}
+	sort.Slice(report.RefillReminders, func(i, j int) bool {
+		return report.RefillReminders[i].DaysLeft < report.RefillReminders[j].DaysLeft
+	})
+
+	core.WriteJSON(w, http.StatusOK, report)
+}
+
+// loadMedicationSeries returns the doses taken per day for each medication
+// and supplement, zero on days it was active but not taken
+func (h *Handler) loadMedicationSeries(ctx context.Context, from, to string) ([]correlationSeries, error) {
+	medications, err := loadMedications(ctx, h.db, 0, h.today(ctx))
+	if err != nil {
+		return nil, err
+	}
+	doses, err := medicationDoses(ctx, h.db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []correlationSeries
+	for _, m := range medications {
+		values := make(map[string]float64)
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			d := formatDate(day)
+			if m.activeOn(d) {
+				values[d] = doses[m.ID][d]
+			}
+		}
+		series = append(series, correlationSeries{
+			key:    fmt.Sprintf("med_%d", m.ID),
+			label:  m.Name,
+			family: "medication",
+			values: values,
+		})
+	}
+	return series, nil
+}
@@ -95,6 +95,493 @@ func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
 		`INSERT INTO health_goals (goal_type, target, active)
 		SELECT 'workout_frequency', 3, true
 		WHERE NOT EXISTS (SELECT 1 FROM health_goals WHERE goal_type = 'workout_frequency')`,
+
+		// Generalised goals (Phase 18) - any series, comparator, period and
+		// aggregation; the built-in goal types are backfilled
+		`ALTER TABLE health_goals ALTER COLUMN target TYPE DOUBLE PRECISION`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS label VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS metric VARCHAR(60) NOT NULL DEFAULT ''`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS comparator VARCHAR(2) NOT NULL DEFAULT '>='`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS period VARCHAR(10) NOT NULL DEFAULT 'day'`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS aggregation VARCHAR(10) NOT NULL DEFAULT 'avg'`,
+		`ALTER TABLE health_goals ADD COLUMN IF NOT EXISTS deadline DATE`,
+		`UPDATE health_goals SET
+			metric = CASE goal_type
+				WHEN 'step_goal' THEN 'activity_steps'
+				WHEN 'workout_frequency' THEN 'workouts'
+				ELSE goal_type END,
+			label = CASE goal_type
+				WHEN 'step_goal' THEN 'Daily steps'
+				WHEN 'sleep_score' THEN 'Sleep score'
+				WHEN 'readiness_score' THEN 'Readiness score'
+				WHEN 'workout_frequency' THEN 'Workouts per week'
+				ELSE goal_type END,
+			period = CASE WHEN goal_type = 'workout_frequency' THEN 'week' ELSE 'day' END,
+			aggregation = CASE WHEN goal_type IN ('step_goal', 'workout_frequency') THEN 'sum' ELSE 'avg' END
+		WHERE metric = ''`,
+
+		// Oura API connection (Phase 7) - a single row holding the encrypted
+		// credentials and sync bookkeeping
+		`CREATE TABLE IF NOT EXISTS oura_connection (
+			id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			token_type VARCHAR(20) NOT NULL CHECK (token_type IN ('personal', 'oauth')),
+			access_token BYTEA,
+			refresh_token BYTEA,
+			expires_at TIMESTAMPTZ,
+			last_synced_day DATE,
+			last_sync_at TIMESTAMPTZ,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Workout source attribution so synced workouts can be deduplicated
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual'`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS external_id TEXT`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_workouts_source_external ON workouts(source, external_id)`,
+
+		// Sleep sessions (Phase 8) - real bedtimes and stage durations.
+		// utc_offset_minutes keeps the local clock the session was recorded in.
+		`CREATE TABLE IF NOT EXISTS sleep_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			day DATE NOT NULL,
+			bedtime_start TIMESTAMPTZ NOT NULL,
+			bedtime_end TIMESTAMPTZ NOT NULL,
+			utc_offset_minutes INT NOT NULL DEFAULT 0,
+			total_sleep_seconds INT,
+			deep_sleep_seconds INT,
+			rem_sleep_seconds INT,
+			light_sleep_seconds INT,
+			awake_seconds INT,
+			average_heart_rate DECIMAL(5, 2),
+			lowest_heart_rate INT,
+			average_hrv INT,
+			is_nap BOOLEAN NOT NULL DEFAULT FALSE,
+			source VARCHAR(20) NOT NULL DEFAULT 'manual',
+			external_id TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (source, bedtime_start),
+			CHECK (bedtime_end > bedtime_start)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sleep_sessions_day ON sleep_sessions(day DESC)`,
+
+		// Apple Health import (Phase 9) - source attribution for imported rows
+		`ALTER TABLE weight_entries ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'manual'`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ`,
+
+		// Raw daily measurements that have no column in oura_daily
+		// (resting heart rate, HRV SDNN, ...), one value per source
+		`CREATE TABLE IF NOT EXISTS daily_metrics (
+			id BIGSERIAL PRIMARY KEY,
+			day DATE NOT NULL,
+			metric VARCHAR(40) NOT NULL,
+			value DOUBLE PRECISION NOT NULL,
+			source VARCHAR(20) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (day, metric, source)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_daily_metrics_metric_day ON daily_metrics(metric, day DESC)`,
+
+		// Apple Health import jobs and their progress
+		`CREATE TABLE IF NOT EXISTS apple_health_imports (
+			id BIGSERIAL PRIMARY KEY,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+			filename TEXT NOT NULL DEFAULT '',
+			bytes_total BIGINT NOT NULL DEFAULT 0,
+			bytes_processed BIGINT NOT NULL DEFAULT 0,
+			records_processed BIGINT NOT NULL DEFAULT 0,
+			counts JSONB NOT NULL DEFAULT '{}',
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			started_at TIMESTAMPTZ,
+			finished_at TIMESTAMPTZ
+		)`,
+		// Imports run in-process, so any still running at startup were interrupted
+		`UPDATE apple_health_imports SET status = 'failed', error = 'interrupted by server restart', finished_at = NOW()
+		WHERE status IN ('pending', 'running')`,
+		// Only one import may be active at a time
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_apple_health_imports_active ON apple_health_imports ((TRUE))
+		WHERE status IN ('pending', 'running')`,
+
+		// Workout files (Phase 10) - recorded metrics and GPS tracks
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS duration_seconds INT`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS distance_meters DOUBLE PRECISION`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS elevation_gain_meters DOUBLE PRECISION`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS avg_heart_rate INT`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS max_heart_rate INT`,
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS calories INT`,
+		`CREATE TABLE IF NOT EXISTS workout_tracks (
+			workout_id BIGINT PRIMARY KEY REFERENCES workouts(id) ON DELETE CASCADE,
+			format VARCHAR(10) NOT NULL,
+			points JSONB NOT NULL DEFAULT '[]',
+			laps JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Strength training (Phase 11) - session RPE, exercise catalogue and sets
+		`ALTER TABLE workouts ADD COLUMN IF NOT EXISTS rpe SMALLINT CHECK (rpe BETWEEN 1 AND 10)`,
+		`CREATE TABLE IF NOT EXISTS exercises (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			muscle_group VARCHAR(20) NOT NULL,
+			equipment VARCHAR(20) NOT NULL DEFAULT 'other',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_name ON exercises (LOWER(name))`,
+		// Seed the catalogue once; deleted entries are not recreated
+		`INSERT INTO exercises (name, muscle_group, equipment)
+		SELECT * FROM (VALUES
+			('Back Squat', 'quads', 'barbell'),
+			('Front Squat', 'quads', 'barbell'),
+			('Leg Press', 'quads', 'machine'),
+			('Deadlift', 'back', 'barbell'),
+			('Romanian Deadlift', 'hamstrings', 'barbell'),
+			('Leg Curl', 'hamstrings', 'machine'),
+			('Hip Thrust', 'glutes', 'barbell'),
+			('Calf Raise', 'calves', 'machine'),
+			('Bench Press', 'chest', 'barbell'),
+			('Incline Dumbbell Press', 'chest', 'dumbbell'),
+			('Push-up', 'chest', 'bodyweight'),
+			('Overhead Press', 'shoulders', 'barbell'),
+			('Lateral Raise', 'shoulders', 'dumbbell'),
+			('Pull-up', 'back', 'bodyweight'),
+			('Barbell Row', 'back', 'barbell'),
+			('Lat Pulldown', 'back', 'cable'),
+			('Biceps Curl', 'biceps', 'dumbbell'),
+			('Triceps Pushdown', 'triceps', 'cable'),
+			('Dip', 'triceps', 'bodyweight'),
+			('Plank', 'core', 'bodyweight'),
+			('Kettlebell Swing', 'full_body', 'kettlebell')
+		) AS seed(name, muscle_group, equipment)
+		WHERE NOT EXISTS (SELECT 1 FROM exercises)`,
+		`CREATE TABLE IF NOT EXISTS workout_sets (
+			id BIGSERIAL PRIMARY KEY,
+			workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+			exercise_id BIGINT NOT NULL REFERENCES exercises(id),
+			position INT NOT NULL DEFAULT 0,
+			sets INT NOT NULL CHECK (sets > 0),
+			reps INT NOT NULL CHECK (reps > 0),
+			weight_kg DECIMAL(6, 2) NOT NULL DEFAULT 0 CHECK (weight_kg >= 0),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_workout_sets_workout ON workout_sets(workout_id, position)`,
+		`CREATE INDEX IF NOT EXISTS idx_workout_sets_exercise ON workout_sets(exercise_id)`,
+
+		// Verdict rules (Phase 13) - ordered, first match wins; empty means built-in defaults
+		`CREATE TABLE IF NOT EXISTS verdict_rules (
+			id BIGSERIAL PRIMARY KEY,
+			position INT NOT NULL,
+			name VARCHAR(100) NOT NULL UNIQUE,
+			conditions JSONB NOT NULL DEFAULT '[]',
+			verdict_type VARCHAR(20) NOT NULL CHECK (verdict_type IN ('push', 'normal', 'recovery', 'unknown')),
+			message TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Illness/anomaly episodes (Phase 14) - runs of days where temperature,
+		// RHR and HRV deviate jointly from the personal baseline
+		`CREATE TABLE IF NOT EXISTS anomaly_episodes (
+			id BIGSERIAL PRIMARY KEY,
+			start_day DATE NOT NULL UNIQUE,
+			end_day DATE NOT NULL,
+			peak_day DATE NOT NULL,
+			flagged_days INT NOT NULL,
+			severity VARCHAR(10) NOT NULL CHECK (severity IN ('mild', 'moderate', 'severe')),
+			peak_score DOUBLE PRECISION NOT NULL,
+			signals JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CHECK (end_day >= start_day)
+		)`,
+
+		// Daily journal (Phase 16) - subjective ratings, a note and tags per day
+		`CREATE TABLE IF NOT EXISTS journal_tags (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(40) NOT NULL UNIQUE,
+			label VARCHAR(100) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		// Seed the catalogue once; deleted tags are not recreated
+		`INSERT INTO journal_tags (name, label)
+		SELECT * FROM (VALUES
+			('alcohol', 'Alcohol'),
+			('late_meal', 'Late meal'),
+			('caffeine_after_14', 'Caffeine after 14:00'),
+			('travel', 'Travel'),
+			('sauna', 'Sauna'),
+			('meditation', 'Meditation'),
+			('screen_late', 'Screens late'),
+			('stressful_day', 'Stressful day')
+		) AS seed(name, label)
+		WHERE NOT EXISTS (SELECT 1 FROM journal_tags)`,
+		`CREATE TABLE IF NOT EXISTS daily_journal (
+			id BIGSERIAL PRIMARY KEY,
+			day DATE NOT NULL UNIQUE,
+			note TEXT NOT NULL DEFAULT '',
+			mood SMALLINT CHECK (mood BETWEEN 1 AND 5),
+			energy SMALLINT CHECK (energy BETWEEN 1 AND 5),
+			stress SMALLINT CHECK (stress BETWEEN 1 AND 5),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS daily_journal_tags (
+			journal_id BIGINT NOT NULL REFERENCES daily_journal(id) ON DELETE CASCADE,
+			tag_id BIGINT NOT NULL REFERENCES journal_tags(id) ON DELETE CASCADE,
+			PRIMARY KEY (journal_id, tag_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_daily_journal_tags_tag ON daily_journal_tags(tag_id)`,
+
+		// Self-experiments (Phase 17) - N-of-1 trials of baseline (A) and
+		// intervention (B) periods, repeated for cycles in an ABAB design
+		`CREATE TABLE IF NOT EXISTS experiments (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			hypothesis TEXT NOT NULL DEFAULT '',
+			metrics JSONB NOT NULL DEFAULT '[]',
+			design VARCHAR(10) NOT NULL CHECK (design IN ('ab', 'abab')),
+			start_date DATE NOT NULL,
+			baseline_days INT NOT NULL CHECK (baseline_days > 0),
+			intervention_days INT NOT NULL CHECK (intervention_days > 0),
+			cycles INT NOT NULL DEFAULT 1 CHECK (cycles > 0),
+			lag_days INT NOT NULL DEFAULT 1 CHECK (lag_days BETWEEN 0 AND 3),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS experiment_compliance (
+			experiment_id BIGINT NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+			day DATE NOT NULL,
+			complied BOOLEAN NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (experiment_id, day)
+		)`,
+
+		// Body measurements (Phase 19) - body composition and circumferences,
+		// one row per day and kind
+		`CREATE TABLE IF NOT EXISTS body_measurements (
+			id BIGSERIAL PRIMARY KEY,
+			date DATE NOT NULL,
+			kind VARCHAR(30) NOT NULL,
+			value DECIMAL(7, 2) NOT NULL CHECK (value > 0),
+			source VARCHAR(20) NOT NULL DEFAULT 'manual',
+			notes TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (date, kind)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_body_measurements_kind ON body_measurements(kind, date DESC)`,
+
+		// Nutrition (Phase 20) - a food catalogue with macros per 100 g,
+		// recipes built from it, logged meals and water intake. Meal items
+		// keep the macros they were logged with so catalogue edits don't
+		// rewrite history.
+		`CREATE TABLE IF NOT EXISTS foods (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			calories DOUBLE PRECISION NOT NULL CHECK (calories >= 0),
+			protein_g DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (protein_g >= 0),
+			carbs_g DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (carbs_g >= 0),
+			fat_g DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (fat_g >= 0),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_foods_name ON foods(LOWER(name))`,
+		// Seed the catalogue once; deleted foods are not recreated
+		`INSERT INTO foods (name, calories, protein_g, carbs_g, fat_g)
+		SELECT * FROM (VALUES
+			('Oats', 379, 13.2, 67.7, 6.5),
+			('Whole milk', 64, 3.4, 4.7, 3.6),
+			('Greek yoghurt', 97, 9.0, 3.9, 5.0),
+			('Egg', 143, 12.6, 0.7, 9.5),
+			('Chicken breast', 165, 31.0, 0.0, 3.6),
+			('Salmon', 208, 20.4, 0.0, 13.4),
+			('White rice, cooked', 130, 2.7, 28.2, 0.3),
+			('Pasta, cooked', 158, 5.8, 30.9, 0.9),
+			('Potato, boiled', 87, 1.9, 20.1, 0.1),
+			('Rye bread', 259, 8.5, 48.3, 3.3),
+			('Banana', 89, 1.1, 22.8, 0.3),
+			('Apple', 52, 0.3, 13.8, 0.2),
+			('Broccoli', 34, 2.8, 6.6, 0.4),
+			('Olive oil', 884, 0.0, 0.0, 100.0),
+			('Butter', 717, 0.9, 0.1, 81.1),
+			('Cheese', 356, 25.0, 1.3, 28.0),
+			('Peanut butter', 588, 25.1, 20.0, 50.4),
+			('Whey protein', 400, 80.0, 8.0, 6.0)
+		) AS seed(name, calories, protein_g, carbs_g, fat_g)
+		WHERE NOT EXISTS (SELECT 1 FROM foods)`,
+		`CREATE TABLE IF NOT EXISTS recipes (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(200) NOT NULL UNIQUE,
+			servings INT NOT NULL DEFAULT 1 CHECK (servings > 0),
+			notes TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS recipe_items (
+			recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+			food_id BIGINT NOT NULL REFERENCES foods(id) ON DELETE RESTRICT,
+			position INT NOT NULL,
+			grams DOUBLE PRECISION NOT NULL CHECK (grams > 0),
+			PRIMARY KEY (recipe_id, position)
+		)`,
+		`CREATE TABLE IF NOT EXISTS meals (
+			id BIGSERIAL PRIMARY KEY,
+			eaten_at TIMESTAMPTZ NOT NULL,
+			date DATE NOT NULL,
+			name VARCHAR(200) NOT NULL DEFAULT '',
+			tags TEXT[] NOT NULL DEFAULT '{}',
+			notes TEXT NOT NULL DEFAULT '',
+			calories DOUBLE PRECISION NOT NULL DEFAULT 0,
+			protein_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+			carbs_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+			fat_g DOUBLE PRECISION NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_meals_date ON meals(date)`,
+		`CREATE TABLE IF NOT EXISTS meal_items (
+			meal_id BIGINT NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
+			position INT NOT NULL,
+			food_id BIGINT REFERENCES foods(id) ON DELETE SET NULL,
+			recipe_id BIGINT REFERENCES recipes(id) ON DELETE SET NULL,
+			name VARCHAR(200) NOT NULL,
+			grams DOUBLE PRECISION,
+			servings DOUBLE PRECISION,
+			calories DOUBLE PRECISION NOT NULL,
+			protein_g DOUBLE PRECISION NOT NULL,
+			carbs_g DOUBLE PRECISION NOT NULL,
+			fat_g DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (meal_id, position)
+		)`,
+		`CREATE TABLE IF NOT EXISTS water_intake (
+			id BIGSERIAL PRIMARY KEY,
+			date DATE NOT NULL,
+			consumed_at TIMESTAMPTZ NOT NULL,
+			ml INT NOT NULL CHECK (ml > 0)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_water_intake_date ON water_intake(date)`,
+
+		// Medications and supplements (Phase 21) - dosing schedules and an
+		// intake log. Stock is counted at stock_counted_at and reduced by the
+		// intakes logged since.
+		`CREATE TABLE IF NOT EXISTS medications (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(200) NOT NULL,
+			kind VARCHAR(20) NOT NULL DEFAULT 'supplement' CHECK (kind IN ('medication', 'supplement')),
+			dose_amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+			dose_unit VARCHAR(20) NOT NULL DEFAULT '',
+			schedule VARCHAR(20) NOT NULL DEFAULT 'daily' CHECK (schedule IN ('daily', 'weekdays', 'as_needed')),
+			weekdays INT[] NOT NULL DEFAULT '{}',
+			times_per_day INT NOT NULL DEFAULT 1 CHECK (times_per_day > 0),
+			start_date DATE NOT NULL,
+			stop_date DATE,
+			units_per_dose DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (units_per_dose > 0),
+			stock_units DOUBLE PRECISION,
+			stock_counted_at TIMESTAMPTZ,
+			refill_threshold_days INT NOT NULL DEFAULT 7,
+			notes TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CHECK (stop_date IS NULL OR stop_date >= start_date)
+		)`,
+		`CREATE TABLE IF NOT EXISTS medication_intakes (
+			id BIGSERIAL PRIMARY KEY,
+			medication_id BIGINT NOT NULL REFERENCES medications(id) ON DELETE CASCADE,
+			taken_at TIMESTAMPTZ NOT NULL,
+			date DATE NOT NULL,
+			doses DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (doses > 0),
+			notes TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_medication_intakes_med ON medication_intakes(medication_id, date)`,
+
+		// Lab results and vitals (Phase 22) - values are stored in the
+		// marker's unit, with the value and unit as reported kept alongside
+		`CREATE TABLE IF NOT EXISTS lab_results (
+			id BIGSERIAL PRIMARY KEY,
+			marker VARCHAR(100) NOT NULL,
+			test VARCHAR(200) NOT NULL,
+			value DOUBLE PRECISION NOT NULL,
+			unit VARCHAR(30) NOT NULL DEFAULT '',
+			original_value DOUBLE PRECISION NOT NULL,
+			original_unit VARCHAR(30) NOT NULL DEFAULT '',
+			ref_low DOUBLE PRECISION,
+			ref_high DOUBLE PRECISION,
+			lab VARCHAR(200) NOT NULL DEFAULT '',
+			sample_date DATE NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			source VARCHAR(20) NOT NULL DEFAULT 'manual',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (marker, sample_date)
+		)`,
+		`CREATE TABLE IF NOT EXISTS vitals (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(20) NOT NULL CHECK (kind IN ('blood_pressure', 'spo2', 'glucose')),
+			measured_at TIMESTAMPTZ NOT NULL,
+			date DATE NOT NULL,
+			systolic INT,
+			diastolic INT,
+			pulse INT,
+			value DOUBLE PRECISION,
+			unit VARCHAR(20) NOT NULL DEFAULT '',
+			context VARCHAR(20) NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			source VARCHAR(20) NOT NULL DEFAULT 'manual',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (kind, measured_at)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_vitals_date ON vitals(kind, date)`,
+
+		// Data quality (Phase 23) - rows that failed bulk import or sync,
+		// kept with the reason and the rejected payload
+		`CREATE TABLE IF NOT EXISTS ingestion_errors (
+			id BIGSERIAL PRIMARY KEY,
+			source VARCHAR(30) NOT NULL,
+			day DATE,
+			row_index INT,
+			reason TEXT NOT NULL,
+			payload JSONB,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ingestion_errors_created ON ingestion_errors(created_at DESC)`,
+
+		// Timezones (Phase 24) - the home zone that decides where days
+		// start, and per-day zones while travelling
+		`CREATE TABLE IF NOT EXISTS health_settings (
+			id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			home_timezone TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS timezone_overrides (
+			day DATE PRIMARY KEY,
+			timezone TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Readiness forecast (Phase 26) - the fitted regression per target,
+		// replaced each time the models are retrained
+		`CREATE TABLE IF NOT EXISTS forecast_models (
+			target VARCHAR(30) PRIMARY KEY,
+			model JSONB NOT NULL,
+			trained_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+
+		// Apple Health steps (Phase 9) - imported counts stay in
+		// daily_metrics with their source. Step-only oura_daily rows written
+		// by earlier imports are removed; daily_steps prefers Oura's count.
+		`UPDATE oura_daily o SET activity_steps = NULL
+		FROM daily_metrics m
+		WHERE m.day = o.day AND m.metric = 'steps' AND m.source = 'apple_health'
+			AND o.activity_steps = trunc(m.value) AND o.activity_score IS NULL`,
+		`DELETE FROM oura_daily o
+		WHERE EXISTS (SELECT 1 FROM daily_metrics m
+				WHERE m.day = o.day AND m.metric = 'steps' AND m.source = 'apple_health')
+			AND NOT EXISTS (SELECT 1 FROM jsonb_each(to_jsonb(o) - 'id' - 'day' - 'created_at')
+				WHERE value <> 'null'::jsonb)`,
+		`CREATE OR REPLACE VIEW daily_steps AS
+		SELECT day, activity_steps::double precision AS steps, 'oura'::varchar(20) AS source
+		FROM oura_daily WHERE activity_steps IS NOT NULL
+		UNION ALL
+		SELECT * FROM (
+			SELECT DISTINCT ON (m.day) m.day, m.value, m.source
+			FROM daily_metrics m
+			WHERE m.metric = 'steps'
+				AND NOT EXISTS (SELECT 1 FROM oura_daily o WHERE o.day = m.day AND o.activity_steps IS NOT NULL)
+			ORDER BY m.day, m.value DESC
+		) imported`,
 	}
 
 	for _, migration := range migrations {
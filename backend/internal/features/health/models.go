@@ -113,8 +113,9 @@ type HealthDashboard struct {
 	LatestDay string `json:"latest_day"`
 
 	// Verdict
-	Verdict     string `json:"verdict"`      // Human-readable recommendation
-	VerdictType string `json:"verdict_type"` // "push", "normal", "recovery"
+	Verdict     string `json:"verdict"`                // Human-readable recommendation
+	VerdictType string `json:"verdict_type"`           // "push", "normal", "recovery"
+	VerdictRule string `json:"verdict_rule,omitempty"` // Name of the rule that fired
 
 	// Legacy fields (for backward compatibility)
 	SleepScore      *int    `json:"sleep_score,omitempty"`
@@ -131,6 +132,12 @@ type HealthDashboard struct {
 
 	// Activity metrics (Phase 2)
 	ActivityMetrics *ActivityMetrics `json:"activity_metrics,omitempty"`
+
+	// Training load (Phase 12)
+	TrainingLoad *TrainingLoadStatus `json:"training_load,omitempty"`
+
+	// Active illness/anomaly episode (Phase 14)
+	Anomaly *AnomalyEpisode `json:"anomaly,omitempty"`
 }
 
 // ScoreHistoryPoint represents a single day's scores for charting
@@ -148,14 +155,36 @@ type Workout struct {
 	Date      string    `json:"date"`      // YYYY-MM-DD
 	Type      string    `json:"type"`      // strength, cardio
 	Notes     string    `json:"notes"`
+	Source    string    `json:"source"`    // manual, oura, apple_health, fit, gpx
 	CreatedAt time.Time `json:"created_at"`
+
+	// Recorded metrics, when known
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+	DurationSeconds     *int       `json:"duration_seconds,omitempty"`
+	DistanceMeters      *float64   `json:"distance_meters,omitempty"`
+	ElevationGainMeters *float64   `json:"elevation_gain_meters,omitempty"`
+	AvgHeartRate        *int       `json:"avg_heart_rate,omitempty"`
+	MaxHeartRate        *int       `json:"max_heart_rate,omitempty"`
+	Calories            *int       `json:"calories,omitempty"`
+	RPE                 *int       `json:"rpe,omitempty"` // session RPE, 1-10
+
+	// Strength sets, only loaded for a single workout
+	Sets []WorkoutSet `json:"sets,omitempty"`
 }
 
-// WorkoutInput is the request body for creating a workout
+// WorkoutInput is the request body for creating or updating a workout
 type WorkoutInput struct {
 	Date  string `json:"date"`  // YYYY-MM-DD, defaults to today
 	Type  string `json:"type"`  // strength, cardio
 	Notes string `json:"notes"`
+
+	DurationSeconds *int     `json:"duration_seconds"`
+	RPE             *int     `json:"rpe"` // 1-10
+	AvgHeartRate    *int     `json:"avg_heart_rate"`
+	DistanceMeters  *float64 `json:"distance_meters"`
+
+	// Sets replaces the workout's strength sets when present
+	Sets []WorkoutSetInput `json:"sets"`
 }
 
 // SleepBreakdownPoint represents sleep component scores for a single day
@@ -211,6 +240,7 @@ type WeightEntry struct {
 	ID        int64     `json:"id"`
 	Date      string    `json:"date"`      // YYYY-MM-DD
 	WeightKg  float64   `json:"weight_kg"`
+	TrendKg   *float64  `json:"trend_kg,omitempty"` // smoothed trend, weight trend only
 	Notes     string    `json:"notes"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -224,14 +254,58 @@ type WeightInput struct {
 
 // WeightTrend contains weight history and statistics
 type WeightTrend struct {
-	Latest       *WeightEntry   `json:"latest,omitempty"`
-	History      []WeightEntry  `json:"history"`
-	Avg7d        *float64       `json:"avg_7d,omitempty"`
-	Avg30d       *float64       `json:"avg_30d,omitempty"`
-	Trend        string         `json:"trend"`       // "up", "down", "stable"
-	TrendDelta   float64        `json:"trend_delta"` // kg difference from 7d avg
-	MinWeight    *float64       `json:"min_weight,omitempty"`
-	MaxWeight    *float64       `json:"max_weight,omitempty"`
+	Latest             *WeightEntry             `json:"latest,omitempty"`
+	History            []WeightEntry            `json:"history"`
+	Avg7d              *float64                 `json:"avg_7d,omitempty"`
+	Avg30d             *float64                 `json:"avg_30d,omitempty"`
+	TrendWeight        *float64                 `json:"trend_weight,omitempty"`         // exponentially smoothed (Hacker's Diet)
+	WeeklyRate         *float64                 `json:"weekly_rate,omitempty"`          // kg/week of the trend over 28 days
+	DailyEnergyBalance *float64                 `json:"daily_energy_balance,omitempty"` // kcal/day implied by the rate
+	Trend              string                   `json:"trend"`                          // "up", "down", "stable"
+	TrendDelta         float64                  `json:"trend_delta"`                    // kg difference of the latest weight from the trend
+	MinWeight          *float64                 `json:"min_weight,omitempty"`
+	MaxWeight          *float64                 `json:"max_weight,omitempty"`
+	Projection         *WeightProjection        `json:"projection,omitempty"`
+	Measurements       []BodyMeasurementSummary `json:"measurements"`
+}
+
+// WeightProjection estimates when the trend weight reaches a target
+type WeightProjection struct {
+	TargetKg           float64  `json:"target_kg"`
+	RemainingKg        float64  `json:"remaining_kg"`
+	Status             string   `json:"status"` // reached, on_track, behind, moving_away, flat
+	Weeks              *float64 `json:"weeks,omitempty"`
+	TargetDate         *string  `json:"target_date,omitempty"`
+	Deadline           *string  `json:"deadline,omitempty"`
+	RequiredWeeklyRate *float64 `json:"required_weekly_rate,omitempty"`
+}
+
+// BodyMeasurement is one body composition or circumference measurement
+type BodyMeasurement struct {
+	ID        int64     `json:"id"`
+	Date      string    `json:"date"`
+	Kind      string    `json:"kind"` // body_fat_pct, muscle_mass_kg, waist_cm, ...
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	Source    string    `json:"source"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BodyMeasurementInput records measurements for one day, keyed by kind
+type BodyMeasurementInput struct {
+	Date         string             `json:"date"` // YYYY-MM-DD, defaults to today
+	Notes        string             `json:"notes"`
+	Measurements map[string]float64 `json:"measurements"`
+}
+
+// BodyMeasurementSummary is the latest value of a measurement kind
+type BodyMeasurementSummary struct {
+	Kind       string   `json:"kind"`
+	Unit       string   `json:"unit"`
+	Latest     float64  `json:"latest"`
+	LatestDate string   `json:"latest_date"`
+	Change30d  *float64 `json:"change_30d,omitempty"` // against the last value at least 30 days earlier
 }
 
 // BodyMetricPoint represents a single day's body metrics
@@ -352,41 +426,89 @@ type HealthInsights struct {
 
 // --- Phase 6: Goals & Streaks ---
 
-// HealthGoal represents a configurable health target
+// HealthGoal is a target on any stored daily series, evaluated per period
 type HealthGoal struct {
-	ID        int64     `json:"id"`
-	GoalType  string    `json:"goal_type"`  // step_goal, sleep_score, readiness_score, workout_frequency
-	Target    int       `json:"target"`     // Target value (e.g., 10000 steps, 80 score, 3 workouts/week)
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64     `json:"id"`
+	GoalType    string    `json:"goal_type"` // Unique name; step_goal, sleep_score, readiness_score and workout_frequency are built in
+	Label       string    `json:"label"`
+	Metric      string    `json:"metric"`      // Series key, see /dashboard/health/correlations/series
+	Comparator  string    `json:"comparator"`  // >=, <=, >, <, ==
+	Target      float64   `json:"target"`      // Target value (e.g., 10000 steps, 7 hours, 80 kg)
+	Period      string    `json:"period"`      // day, week, month
+	Aggregation string    `json:"aggregation"` // sum, avg, count (days with a non-zero value)
+	Deadline    *string   `json:"deadline,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// HealthGoalInput is the request body for creating/updating goals
+// HealthGoalInput is the request body for creating/updating goals. The
+// built-in goal types only need a target.
 type HealthGoalInput struct {
-	GoalType string `json:"goal_type"`
-	Target   int    `json:"target"`
-	Active   *bool  `json:"active,omitempty"`
+	GoalType    string  `json:"goal_type"`
+	Label       string  `json:"label"`
+	Metric      string  `json:"metric"`
+	Comparator  string  `json:"comparator"` // default >=
+	Target      float64 `json:"target"`
+	Period      string  `json:"period"`      // default day
+	Aggregation string  `json:"aggregation"` // default avg
+	Deadline    *string `json:"deadline,omitempty"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+// GoalPeriodResult is a goal's aggregate over one period
+type GoalPeriodResult struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Value    *float64 `json:"value"` // nil without data
+	Days     int      `json:"days"`  // days with data
+	Met      bool     `json:"met"`
+	Complete bool     `json:"complete"` // false for the current period
+}
+
+// GoalProjection estimates where a goal ends up
+type GoalProjection struct {
+	Basis               string   `json:"basis"`         // trend (deadline, 28-day fit) or pace (current period)
+	ProjectedFor        string   `json:"projected_for"` // deadline or end of the period
+	ProjectedValue      float64  `json:"projected_value"`
+	OnTrack             bool     `json:"on_track"`
+	Current             float64  `json:"current"`                          // trend value today, or period value so far
+	RatePerWeek         *float64 `json:"rate_per_week,omitempty"`          // trend only
+	RequiredRatePerWeek *float64 `json:"required_rate_per_week,omitempty"` // trend only
+	EstimatedDate       *string  `json:"estimated_date,omitempty"`         // when the trend reaches the target
 }
 
-// GoalProgress represents progress toward a single goal
+// GoalProgress represents progress toward a single goal in its current period
 type GoalProgress struct {
-	Goal          HealthGoal `json:"goal"`
-	CurrentValue  int        `json:"current_value"`   // Today's value
-	Progress      float64    `json:"progress"`        // 0-100 percentage
-	Met           bool       `json:"met"`             // Whether goal was met today
-	CurrentStreak int        `json:"current_streak"`  // Consecutive days meeting goal
-	BestStreak    int        `json:"best_streak"`     // All-time best streak
-	WeeklyCount   int        `json:"weekly_count"`    // Days met this week (for workout_frequency)
-	LastAchieved  string     `json:"last_achieved,omitempty"`
+	Goal          HealthGoal      `json:"goal"`
+	CurrentValue  float64         `json:"current_value"`  // Current period's aggregate
+	Progress      float64         `json:"progress"`       // 0-100 percentage
+	Met           bool            `json:"met"`            // Whether the current period is met
+	CurrentStreak int             `json:"current_streak"` // Consecutive periods meeting goal
+	BestStreak    int             `json:"best_streak"`    // Best streak in the history window
+	WeeklyCount   int             `json:"weekly_count"`   // Current value for weekly goals
+	LastAchieved  string          `json:"last_achieved,omitempty"`
+	Projection    *GoalProjection `json:"projection,omitempty"`
+}
+
+// GoalHistory is the response of GET /health/goals/{type}/history
+type GoalHistory struct {
+	Goal            HealthGoal         `json:"goal"`
+	Periods         []GoalPeriodResult `json:"periods"` // oldest first, current last
+	MetPeriods      int                `json:"met_periods"`
+	CompletePeriods int                `json:"complete_periods"` // complete periods with data
+	CurrentStreak   int                `json:"current_streak"`
+	BestStreak      int                `json:"best_streak"`
+	LastAchieved    string             `json:"last_achieved,omitempty"`
+	Projection      *GoalProjection    `json:"projection,omitempty"`
 }
 
 // GoalsOverview is the dashboard response for goals
 type GoalsOverview struct {
 	Goals       []GoalProgress `json:"goals"`
-	TodaysMet   int            `json:"todays_met"`
+	TodaysMet   int            `json:"todays_met"` // Goals met in their current period
 	TodaysTotal int            `json:"todays_total"`
-	WeeklyMet   int            `json:"weekly_met"`   // Goals met this week (sum across all days)
+	WeeklyMet   int            `json:"weekly_met"`   // Goal periods met this week (each day for daily goals)
 	WeeklyTotal int            `json:"weekly_total"` // Total goal opportunities this week
 }
 
@@ -430,3 +552,1282 @@ type WeeklySummary struct {
 	// Streaks
 	ActiveStreaks []Streak `json:"active_streaks"`
 }
+
+// --- Phase 7: Oura API sync ---
+
+// OuraConnectionInput is the request body for storing Oura credentials.
+// Either a personal access token or an OAuth refresh token is required.
+type OuraConnectionInput struct {
+	PersonalAccessToken string `json:"personal_access_token,omitempty"`
+	RefreshToken        string `json:"refresh_token,omitempty"`
+	AccessToken         string `json:"access_token,omitempty"`
+	ExpiresIn           int    `json:"expires_in,omitempty"` // seconds
+}
+
+// OuraConnection describes the stored Oura connection without exposing tokens
+type OuraConnection struct {
+	Connected     bool       `json:"connected"`
+	TokenType     string     `json:"token_type,omitempty"` // personal, oauth
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	LastSyncedDay *string    `json:"last_synced_day,omitempty"`
+	LastSyncAt    *time.Time `json:"last_sync_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Syncing       bool       `json:"syncing"`
+
+	LastResult *OuraSyncResult `json:"last_result,omitempty"` // since server start
+}
+
+// OuraSyncRange is an inclusive range of days fetched from the Oura API
+type OuraSyncRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// OuraSyncResult summarises a completed sync run
+type OuraSyncResult struct {
+	Ranges           []OuraSyncRange `json:"ranges"`
+	DaysUpserted     int             `json:"days_upserted"`
+	SessionsUpserted int             `json:"sessions_upserted"`
+	WorkoutsUpserted int             `json:"workouts_upserted"`
+	LastSyncedDay    string          `json:"last_synced_day"`
+	StartedAt        time.Time       `json:"started_at"`
+	FinishedAt       time.Time       `json:"finished_at"`
+}
+
+// --- Phase 8: Sleep sessions ---
+
+// SleepSession is a single sleep period with real times and stage durations.
+// Bedtimes are returned in the UTC offset they were recorded in.
+type SleepSession struct {
+	ID                int64     `json:"id"`
+	Day               string    `json:"day"` // YYYY-MM-DD, the day the sleep ends
+	BedtimeStart      time.Time `json:"bedtime_start"`
+	BedtimeEnd        time.Time `json:"bedtime_end"`
+	UTCOffsetMinutes  int       `json:"utc_offset_minutes"`
+	TimeInBedSeconds  int       `json:"time_in_bed_seconds"`
+	TotalSleepSeconds *int      `json:"total_sleep_seconds,omitempty"`
+	DeepSleepSeconds  *int      `json:"deep_sleep_seconds,omitempty"`
+	RemSleepSeconds   *int      `json:"rem_sleep_seconds,omitempty"`
+	LightSleepSeconds *int      `json:"light_sleep_seconds,omitempty"`
+	AwakeSeconds      *int      `json:"awake_seconds,omitempty"`
+	Efficiency        *float64  `json:"efficiency,omitempty"` // % of time in bed asleep
+	AverageHeartRate  *float64  `json:"average_heart_rate,omitempty"`
+	LowestHeartRate   *int      `json:"lowest_heart_rate,omitempty"`
+	AverageHrv        *int      `json:"average_hrv,omitempty"`
+	IsNap             bool      `json:"is_nap"`
+	Source            string    `json:"source"`
+	ExternalID        *string   `json:"external_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// SleepSessionInput is the request body for ingesting a sleep session.
+// Bedtimes are RFC 3339 timestamps including their UTC offset.
+type SleepSessionInput struct {
+	Day               string   `json:"day,omitempty"` // defaults to the local date of bedtime_end
+	BedtimeStart      string   `json:"bedtime_start"`
+	BedtimeEnd        string   `json:"bedtime_end"`
+	TotalSleepSeconds *int     `json:"total_sleep_seconds,omitempty"`
+	DeepSleepSeconds  *int     `json:"deep_sleep_seconds,omitempty"`
+	RemSleepSeconds   *int     `json:"rem_sleep_seconds,omitempty"`
+	LightSleepSeconds *int     `json:"light_sleep_seconds,omitempty"`
+	AwakeSeconds      *int     `json:"awake_seconds,omitempty"`
+	AverageHeartRate  *float64 `json:"average_heart_rate,omitempty"`
+	LowestHeartRate   *int     `json:"lowest_heart_rate,omitempty"`
+	AverageHrv        *int     `json:"average_hrv,omitempty"`
+	IsNap             bool     `json:"is_nap"`
+	Source            string   `json:"source,omitempty"` // defaults to manual
+	ExternalID        *string  `json:"external_id,omitempty"`
+}
+
+// SleepTimingNight is one main sleep in local clock time
+type SleepTimingNight struct {
+	Day             string  `json:"day"`
+	Bedtime         string  `json:"bedtime"`   // HH:MM local
+	WakeTime        string  `json:"wake_time"` // HH:MM local
+	Midsleep        string  `json:"midsleep"`  // HH:MM local
+	TotalSleepHours float64 `json:"total_sleep_hours"`
+	TimeInBedHours  float64 `json:"time_in_bed_hours"`
+	FreeDay         bool    `json:"free_day"` // woke up on a weekend
+}
+
+// SleepTimingAnalysis describes bedtime consistency over a window
+type SleepTimingAnalysis struct {
+	Days   int `json:"days"`
+	Nights int `json:"nights"`
+	Naps   int `json:"naps"`
+
+	AvgBedtime  string `json:"avg_bedtime,omitempty"`
+	AvgWakeTime string `json:"avg_wake_time,omitempty"`
+	AvgMidsleep string `json:"avg_midsleep,omitempty"`
+
+	// Standard deviations in minutes; lower is more consistent
+	BedtimeSDMinutes  float64 `json:"bedtime_sd_minutes"`
+	WakeTimeSDMinutes float64 `json:"wake_time_sd_minutes"`
+	MidsleepSDMinutes float64 `json:"midsleep_sd_minutes"`
+	// Average shift of midsleep between consecutive nights
+	NightToNightShiftMinutes float64 `json:"night_to_night_shift_minutes"`
+	Consistency              string  `json:"consistency"` // very consistent, consistent, variable, irregular
+
+	AvgTotalSleepHours float64            `json:"avg_total_sleep_hours"`
+	AvgEfficiency      float64            `json:"avg_efficiency"`
+	StageShare         map[string]float64 `json:"stage_share"` // % of time in bed
+	AvgHeartRate       *float64           `json:"avg_heart_rate,omitempty"`
+	AvgLowestHeartRate *float64           `json:"avg_lowest_heart_rate,omitempty"`
+	AvgHrv             *float64           `json:"avg_hrv,omitempty"`
+
+	Detail []SleepTimingNight `json:"detail"`
+}
+
+// SleepChronotype estimates chronotype from work vs free day sleep
+// (MCTQ-style corrected mid-sleep on free days)
+type SleepChronotype struct {
+	Days       int `json:"days"`
+	WorkNights int `json:"work_nights"`
+	FreeNights int `json:"free_nights"`
+
+	MidsleepWork       string  `json:"midsleep_work,omitempty"`
+	MidsleepFree       string  `json:"midsleep_free,omitempty"`
+	SleepHoursWork     float64 `json:"sleep_hours_work"`
+	SleepHoursFree     float64 `json:"sleep_hours_free"`
+	SocialJetLagHours  float64 `json:"social_jet_lag_hours"`
+	SocialJetLagRating string  `json:"social_jet_lag_rating"` // minimal, moderate, high
+
+	// Mid-sleep on free days corrected for sleep debt accumulated on work days
+	CorrectedMidsleepFree string `json:"corrected_midsleep_free,omitempty"`
+	Chronotype            string `json:"chronotype"` // extreme early, early, intermediate, late, extreme late, unknown
+	Message               string `json:"message"`
+}
+
+// --- Phase 9: Apple Health import ---
+
+// AppleHealthImportCounts tallies what an import wrote and skipped
+type AppleHealthImportCounts struct {
+	StepDays          int `json:"step_days"`
+	WeightEntries     int `json:"weight_entries"`
+	BodyMeasurements  int `json:"body_measurements"`
+	RestingHeartRates int `json:"resting_heart_rates"`
+	HrvDays           int `json:"hrv_days"`
+	Workouts          int `json:"workouts"`
+	SleepSessions     int `json:"sleep_sessions"`
+	SkippedDuplicates int `json:"skipped_duplicates"` // already present from Oura or a manual entry
+}
+
+// AppleHealthImport is the status resource of an import job
+type AppleHealthImport struct {
+	ID               int64                   `json:"id"`
+	Status           string                  `json:"status"` // pending, running, completed, failed
+	Filename         string                  `json:"filename"`
+	BytesTotal       int64                   `json:"bytes_total"`
+	BytesProcessed   int64                   `json:"bytes_processed"`
+	Progress         float64                 `json:"progress"` // % of export.xml parsed
+	RecordsProcessed int64                   `json:"records_processed"`
+	Counts           AppleHealthImportCounts `json:"counts"`
+	Error            string                  `json:"error,omitempty"`
+	CreatedAt        time.Time               `json:"created_at"`
+	StartedAt        *time.Time              `json:"started_at,omitempty"`
+	FinishedAt       *time.Time              `json:"finished_at,omitempty"`
+}
+
+// --- Phase 10: Workout files & tracks ---
+
+// TrackPoint is one recorded sample of a workout track
+type TrackPoint struct {
+	Time      time.Time `json:"t"`
+	Lat       *float64  `json:"lat,omitempty"`
+	Lon       *float64  `json:"lon,omitempty"`
+	Elevation *float64  `json:"ele,omitempty"` // meters
+	HeartRate *int      `json:"hr,omitempty"`
+	Distance  *float64  `json:"d,omitempty"` // cumulative meters, when recorded
+}
+
+// WorkoutLap is a lap (or automatic 1 km split) of a workout
+type WorkoutLap struct {
+	Index               int       `json:"index"`
+	StartTime           time.Time `json:"start_time"`
+	DurationSeconds     float64   `json:"duration_seconds"`
+	DistanceMeters      float64   `json:"distance_meters"`
+	PaceSecondsPerKm    *float64  `json:"pace_seconds_per_km,omitempty"`
+	ElevationGainMeters *float64  `json:"elevation_gain_meters,omitempty"`
+	AvgHeartRate        *int      `json:"avg_heart_rate,omitempty"`
+	MaxHeartRate        *int      `json:"max_heart_rate,omitempty"`
+	Calories            *int      `json:"calories,omitempty"`
+}
+
+// GeoJSONFeature is a GeoJSON Feature holding the track polyline
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"` // Feature
+	Geometry   GeoJSONLineString      `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONLineString holds [lon, lat, ele] positions
+type GeoJSONLineString struct {
+	Type        string      `json:"type"` // LineString
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// WorkoutTrack is the response of GET /health/workouts/{id}/track
+type WorkoutTrack struct {
+	WorkoutID int64          `json:"workout_id"`
+	Format    string         `json:"format"` // fit, gpx
+	GeoJSON   GeoJSONFeature `json:"geojson"`
+	Laps      []WorkoutLap   `json:"laps"`
+}
+
+// WorkoutImportResult is returned after importing a FIT or GPX file
+type WorkoutImportResult struct {
+	Workout Workout `json:"workout"`
+	Points  int     `json:"points"`
+	Laps    int     `json:"laps"`
+	Merged  bool    `json:"merged"` // attached to an existing workout from another source
+}
+
+// --- Phase 11: Strength training ---
+
+// Exercise is an entry of the exercise catalogue
+type Exercise struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	MuscleGroup string    `json:"muscle_group"` // chest, back, shoulders, biceps, triceps, quads, hamstrings, glutes, calves, core, full_body
+	Equipment   string    `json:"equipment"`    // barbell, dumbbell, machine, cable, bodyweight, kettlebell, other
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExerciseInput is the request body for creating or updating an exercise
+type ExerciseInput struct {
+	Name        string `json:"name"`
+	MuscleGroup string `json:"muscle_group"`
+	Equipment   string `json:"equipment"` // defaults to other
+}
+
+// WorkoutSet is one exercise of a strength workout: sets x reps at a weight
+type WorkoutSet struct {
+	ID           int64   `json:"id"`
+	ExerciseID   int64   `json:"exercise_id"`
+	Exercise     string  `json:"exercise"`
+	MuscleGroup  string  `json:"muscle_group"`
+	Sets         int     `json:"sets"`
+	Reps         int     `json:"reps"`
+	WeightKg     float64 `json:"weight_kg"`
+	Volume       float64 `json:"volume"`        // sets x reps x weight
+	Estimated1RM float64 `json:"estimated_1rm"` // Epley
+}
+
+// WorkoutSetInput identifies the exercise by ID or by catalogue name
+type WorkoutSetInput struct {
+	ExerciseID int64   `json:"exercise_id"`
+	Exercise   string  `json:"exercise"`
+	Sets       int     `json:"sets"`
+	Reps       int     `json:"reps"`
+	WeightKg   float64 `json:"weight_kg"`
+}
+
+// OneRepMaxPoint is the best estimated 1RM of an exercise on one day
+type OneRepMaxPoint struct {
+	Date         string  `json:"date"`
+	Estimated1RM float64 `json:"estimated_1rm"`
+	WeightKg     float64 `json:"weight_kg"`
+	Reps         int     `json:"reps"`
+}
+
+// OneRepMaxProgress is the response of GET /dashboard/health/strength/1rm
+type OneRepMaxProgress struct {
+	Exercise Exercise         `json:"exercise"`
+	Points   []OneRepMaxPoint `json:"points"`
+	Best     *OneRepMaxPoint  `json:"best,omitempty"`
+	Change   *float64         `json:"change,omitempty"` // kg, last point vs first point
+}
+
+// MuscleGroupVolume is the training volume of one muscle group in a week
+type MuscleGroupVolume struct {
+	MuscleGroup string  `json:"muscle_group"`
+	Sets        int     `json:"sets"`
+	Reps        int     `json:"reps"`
+	Volume      float64 `json:"volume"` // kg lifted, sets x reps x weight
+}
+
+// WeeklyVolume groups muscle group volume by ISO week (Monday start)
+type WeeklyVolume struct {
+	WeekStart    string              `json:"week_start"`
+	TotalVolume  float64             `json:"total_volume"`
+	MuscleGroups []MuscleGroupVolume `json:"muscle_groups"`
+}
+
+// --- Phase 12: Training load ---
+
+// TrainingLoadDay is one day of the training load series. Load is in
+// arbitrary units: minutes x RPE for srpe, TRIMP for trimp.
+type TrainingLoadDay struct {
+	Date        string   `json:"date"`
+	Load        float64  `json:"load"`
+	Workouts    int      `json:"workouts"`
+	Estimated   int      `json:"estimated"`    // workouts whose intensity was estimated
+	AcuteLoad   float64  `json:"acute_load"`   // sum of the last 7 days
+	ChronicLoad float64  `json:"chronic_load"` // weekly average of the last 28 days
+	ACWR        *float64 `json:"acwr,omitempty"`
+	Fitness     float64  `json:"fitness"` // 42-day exponentially weighted load
+	Fatigue     float64  `json:"fatigue"` // 7-day exponentially weighted load
+	Form        float64  `json:"form"`    // fitness - fatigue going into the day
+}
+
+// TrainingLoadStatus is the training load state for the latest day
+type TrainingLoadStatus struct {
+	Date        string   `json:"date"`
+	AcuteLoad   float64  `json:"acute_load"`
+	ChronicLoad float64  `json:"chronic_load"`
+	ACWR        *float64 `json:"acwr,omitempty"`
+	RiskBand    string   `json:"risk_band"` // low, optimal, elevated, high, unknown
+	Fitness     float64  `json:"fitness"`
+	Fatigue     float64  `json:"fatigue"`
+	Form        float64  `json:"form"`
+	FormBand    string   `json:"form_band"` // fresh, neutral, productive, overreaching, unknown
+}
+
+// TrainingLoad is the response of GET /dashboard/health/training-load
+type TrainingLoad struct {
+	Method           string             `json:"method"` // srpe, trimp
+	MaxHeartRate     int                `json:"max_heart_rate"`
+	RestingHeartRate int                `json:"resting_heart_rate"`
+	Current          TrainingLoadStatus `json:"current"`
+	Days             []TrainingLoadDay  `json:"days"`
+	SkippedWorkouts  int                `json:"skipped_workouts"` // no duration recorded
+}
+
+// --- Phase 13: Verdict rules ---
+
+// VerdictCondition compares one metric of the day against a value. Value is
+// a number, or a string for the *_band metrics; exists/missing take none.
+type VerdictCondition struct {
+	Metric string      `json:"metric"`
+	Op     string      `json:"op"` // >, >=, <, <=, ==, !=, exists, missing
+	Value  interface{} `json:"value,omitempty"`
+}
+
+// VerdictRule produces a verdict when all of its conditions hold; a rule
+// without conditions matches every day
+type VerdictRule struct {
+	ID          int64              `json:"id,omitempty"`
+	Name        string             `json:"name"`
+	Conditions  []VerdictCondition `json:"conditions"`
+	VerdictType string             `json:"verdict_type"` // push, normal, recovery, unknown
+	Message     string             `json:"message"`
+}
+
+// VerdictRuleSet is the ordered rule list; the first matching rule wins
+type VerdictRuleSet struct {
+	Source  string        `json:"source"` // custom, default
+	Rules   []VerdictRule `json:"rules"`
+	Metrics []string      `json:"metrics"` // metrics conditions may reference
+}
+
+// VerdictRuleTestInput is the request body for testing a rule set
+type VerdictRuleTestInput struct {
+	Rules []VerdictRule `json:"rules"` // defaults to the active rule set
+	Days  int           `json:"days"`  // defaults to 90
+}
+
+// VerdictTestDay is the verdict a rule set gives for one historical day
+type VerdictTestDay struct {
+	Day         string `json:"day"`
+	Verdict     string `json:"verdict"`
+	VerdictType string `json:"verdict_type"`
+	Rule        string `json:"rule,omitempty"`
+}
+
+// VerdictRuleStat counts how often a rule fired
+type VerdictRuleStat struct {
+	Rule        string `json:"rule"`
+	VerdictType string `json:"verdict_type"`
+	Count       int    `json:"count"`
+}
+
+// VerdictRuleTest is the result of replaying a rule set over history
+type VerdictRuleTest struct {
+	Days      int               `json:"days"`   // days with data
+	Counts    map[string]int    `json:"counts"` // by verdict type
+	Unmatched int               `json:"unmatched"`
+	Rules     []VerdictRuleStat `json:"rules"`
+	Results   []VerdictTestDay  `json:"results"`
+}
+
+// --- Phase 14: Illness & anomaly detection ---
+
+// AnomalySignal is one signal of a day scored against the personal baseline
+type AnomalySignal struct {
+	Metric    string   `json:"metric"` // temperature, resting_heart_rate, hrv
+	Source    string   `json:"source"`
+	Value     float64  `json:"value"`
+	Baseline  *float64 `json:"baseline,omitempty"` // median of the previous 60 days
+	ZScore    *float64 `json:"z_score,omitempty"`  // robust z, positive = adverse direction
+	Deviating bool     `json:"deviating"`
+}
+
+// AnomalyDay is the detector output for one day
+type AnomalyDay struct {
+	Day       string          `json:"day"`
+	Signals   []AnomalySignal `json:"signals"`
+	Deviating int             `json:"deviating"`
+	Score     float64         `json:"score"` // sum of deviating z-scores
+	Flagged   bool            `json:"flagged"`
+	Severity  string          `json:"severity,omitempty"` // mild, moderate, severe
+}
+
+// AnomalyEpisode is a stored run of flagged days
+type AnomalyEpisode struct {
+	ID          int64              `json:"id"`
+	StartDay    string             `json:"start_day"`
+	EndDay      string             `json:"end_day"`
+	PeakDay     string             `json:"peak_day"`
+	FlaggedDays int                `json:"flagged_days"`
+	Severity    string             `json:"severity"` // mild, moderate, severe
+	PeakScore   float64            `json:"peak_score"`
+	Signals     map[string]float64 `json:"signals"` // peak z-score per deviating metric
+	Active      bool               `json:"active"`
+	DetectedAt  time.Time          `json:"detected_at"`
+}
+
+// AnomalyReport is the response of GET /dashboard/health/anomalies
+type AnomalyReport struct {
+	Active   *AnomalyEpisode  `json:"active,omitempty"`
+	Episodes []AnomalyEpisode `json:"episodes"`
+	Days     []AnomalyDay     `json:"days"`
+}
+
+// --- Phase 15: Correlation engine ---
+
+// CorrelationSeriesInfo describes a series available for correlation
+type CorrelationSeriesInfo struct {
+	Key    string `json:"key"`
+	Label  string `json:"label"`
+	Family string `json:"family"` // sleep, readiness, activity, body, training, calendar, journal
+	Days   int    `json:"days"`   // days with a value in the window
+}
+
+// CorrelationResult relates series X on day t to series Y on day t+lag
+type CorrelationResult struct {
+	X           string   `json:"x"`
+	Y           string   `json:"y"`
+	XLabel      string   `json:"x_label"`
+	YLabel      string   `json:"y_label"`
+	Lag         int      `json:"lag"` // days, 0-3
+	N           int      `json:"n"`
+	Pearson     float64  `json:"pearson"`
+	Spearman    float64  `json:"spearman"`
+	PValue      float64  `json:"p_value"`
+	QValue      *float64 `json:"q_value,omitempty"` // FDR-adjusted, discovery mode only
+	CILow       float64  `json:"ci_low"`            // 95% CI of the Pearson r
+	CIHigh      float64  `json:"ci_high"`
+	Strength    string   `json:"strength"`  // "strong", "moderate", "weak", "none"
+	Direction   string   `json:"direction"` // "positive", "negative", "neutral"
+	Significant bool     `json:"significant"`
+	Insight     string   `json:"insight"`
+}
+
+// CorrelationDiscovery ranks the strongest significant relationships
+type CorrelationDiscovery struct {
+	Tested      int                 `json:"tested"`      // pair/lag combinations tested
+	Significant int                 `json:"significant"` // passing the FDR threshold
+	Results     []CorrelationResult `json:"results"`
+}
+
+// --- Phase 16: Journal ---
+
+// JournalTag is a catalogue entry that journal days can be tagged with
+type JournalTag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"` // slug, e.g. late_meal
+	Label     string    `json:"label"`
+	Uses      int       `json:"uses"` // journal days carrying the tag
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JournalTagInput is the request body for creating a tag
+type JournalTagInput struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// JournalEntry is the subjective log of one day
+type JournalEntry struct {
+	ID        int64     `json:"id"`
+	Day       string    `json:"day"`
+	Note      string    `json:"note"`
+	Mood      *int      `json:"mood,omitempty"`   // 1-5
+	Energy    *int      `json:"energy,omitempty"` // 1-5
+	Stress    *int      `json:"stress,omitempty"` // 1-5
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JournalEntryInput is the request body for logging a day
+type JournalEntryInput struct {
+	Day    string   `json:"day"` // defaults to today
+	Note   string   `json:"note"`
+	Mood   *int     `json:"mood"`
+	Energy *int     `json:"energy"`
+	Stress *int     `json:"stress"`
+	Tags   []string `json:"tags"` // tag names from the catalogue
+}
+
+// TagImpactMetric compares a next-night metric with vs without a tag
+type TagImpactMetric struct {
+	Metric     string   `json:"metric"` // sleep_score, hrv, readiness_score
+	WithAvg    *float64 `json:"with_avg"`
+	WithoutAvg *float64 `json:"without_avg"`
+	Difference *float64 `json:"difference"`
+	NWith      int      `json:"n_with"`
+	NWithout   int      `json:"n_without"`
+	PValue     *float64 `json:"p_value"` // Welch's t-test, nil below 2 samples per group
+}
+
+// TagImpact summarises the effect of one tag
+type TagImpact struct {
+	Tag        string            `json:"tag"`
+	Label      string            `json:"label"`
+	TaggedDays int               `json:"tagged_days"`
+	Metrics    []TagImpactMetric `json:"metrics"`
+}
+
+// TagImpactReport is the response of GET /dashboard/health/tags/impact
+type TagImpactReport struct {
+	Days        int         `json:"days"`
+	JournalDays int         `json:"journal_days"`
+	Tags        []TagImpact `json:"tags"`
+}
+
+// --- Phase 17: Self-experiments ---
+
+// ExperimentMetric is a target metric and the hypothesised change
+type ExperimentMetric struct {
+	Metric    string `json:"metric"`    // oura_daily column or weight_kg
+	Direction string `json:"direction"` // increase, decrease
+}
+
+// ExperimentPhase is one baseline (A) or intervention (B) period
+type ExperimentPhase struct {
+	Label string `json:"label"` // A1, B1, A2, ...
+	Kind  string `json:"kind"`  // baseline, intervention
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Experiment is an N-of-1 trial comparing baseline and intervention periods
+type Experiment struct {
+	ID               int64              `json:"id"`
+	Name             string             `json:"name"`
+	Hypothesis       string             `json:"hypothesis"`
+	Metrics          []ExperimentMetric `json:"metrics"`
+	Design           string             `json:"design"` // ab, abab
+	StartDate        string             `json:"start_date"`
+	EndDate          string             `json:"end_date"`
+	BaselineDays     int                `json:"baseline_days"`
+	InterventionDays int                `json:"intervention_days"`
+	Cycles           int                `json:"cycles"`   // A/B pairs, 1 for ab
+	LagDays          int                `json:"lag_days"` // outcome measured this many days later
+	Status           string             `json:"status"`   // planned, running, completed
+	Phases           []ExperimentPhase  `json:"phases,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+}
+
+// ExperimentInput is the request body for creating an experiment
+type ExperimentInput struct {
+	Name             string             `json:"name"`
+	Hypothesis       string             `json:"hypothesis"`
+	Metrics          []ExperimentMetric `json:"metrics"`
+	Design           string             `json:"design"`     // default ab
+	StartDate        string             `json:"start_date"` // default today
+	BaselineDays     int                `json:"baseline_days"`
+	InterventionDays int                `json:"intervention_days"`
+	Cycles           int                `json:"cycles"`   // abab only, default 2
+	LagDays          *int               `json:"lag_days"` // default 1
+}
+
+// ExperimentCompliance records whether the protocol was followed on a day
+type ExperimentCompliance struct {
+	Day      string `json:"day"`
+	Complied bool   `json:"complied"`
+	Note     string `json:"note"`
+}
+
+// ExperimentComplianceSummary counts intervention days so far
+type ExperimentComplianceSummary struct {
+	InterventionDays int      `json:"intervention_days"`
+	Logged           int      `json:"logged"`
+	Complied         int      `json:"complied"`
+	Excluded         int      `json:"excluded"` // logged as not complied, left out of the analysis
+	Rate             *float64 `json:"rate"`     // complied / logged
+}
+
+// ExperimentMetricResult compares one metric between the periods
+type ExperimentMetricResult struct {
+	Metric           string   `json:"metric"`
+	Label            string   `json:"label"`
+	Direction        string   `json:"direction"`
+	NBaseline        int      `json:"n_baseline"`
+	NIntervention    int      `json:"n_intervention"`
+	BaselineMean     *float64 `json:"baseline_mean"`
+	InterventionMean *float64 `json:"intervention_mean"`
+	Difference       *float64 `json:"difference"` // intervention - baseline
+	PercentChange    *float64 `json:"percent_change"`
+	EffectSize       *float64 `json:"effect_size"` // Cohen's d
+	Magnitude        string   `json:"magnitude,omitempty"`
+	CILow            *float64 `json:"ci_low"` // 95% bootstrap CI of the difference
+	CIHigh           *float64 `json:"ci_high"`
+	Verdict          string   `json:"verdict"` // supported, contradicted, inconclusive, insufficient_data
+}
+
+// ExperimentAnalysis is the response of GET /health/experiments/{id}/analysis
+type ExperimentAnalysis struct {
+	Experiment Experiment                  `json:"experiment"`
+	Phases     []ExperimentPhase           `json:"phases"`
+	Compliance ExperimentComplianceSummary `json:"compliance"`
+	Metrics    []ExperimentMetricResult    `json:"metrics"`
+	Verdict    string                      `json:"verdict"` // also mixed when metrics disagree
+	Notes      []string                    `json:"notes"`
+}
+
+// --- Phase 20: Nutrition ---
+
+// Macros holds energy and macronutrients, per 100 g for foods
+type Macros struct {
+	Calories float64 `json:"calories"`
+	ProteinG float64 `json:"protein_g"`
+	CarbsG   float64 `json:"carbs_g"`
+	FatG     float64 `json:"fat_g"`
+}
+
+// Food is an entry in the local food catalogue, with macros per 100 g
+type Food struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Macros
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FoodInput is the request body for creating or updating a food
+type FoodInput struct {
+	Name string `json:"name"`
+	Macros
+}
+
+// RecipeItem is an amount of a food in a recipe
+type RecipeItem struct {
+	FoodID int64   `json:"food_id"`
+	Food   string  `json:"food"`
+	Grams  float64 `json:"grams"`
+	Macros
+}
+
+// Recipe is a user-defined dish made of catalogue foods
+type Recipe struct {
+	ID         int64        `json:"id"`
+	Name       string       `json:"name"`
+	Servings   int          `json:"servings"`
+	Notes      string       `json:"notes"`
+	Items      []RecipeItem `json:"items"`
+	Total      Macros       `json:"total"`
+	PerServing Macros       `json:"per_serving"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// RecipeItemInput is one ingredient in a recipe request
+type RecipeItemInput struct {
+	FoodID int64   `json:"food_id"`
+	Grams  float64 `json:"grams"`
+}
+
+// RecipeInput is the request body for creating a recipe
+type RecipeInput struct {
+	Name     string            `json:"name"`
+	Servings int               `json:"servings"` // default 1
+	Notes    string            `json:"notes"`
+	Items    []RecipeItemInput `json:"items"`
+}
+
+// MealItem is a food or recipe portion in a meal, with the macros it was
+// logged with
+type MealItem struct {
+	FoodID   *int64   `json:"food_id,omitempty"`
+	RecipeID *int64   `json:"recipe_id,omitempty"`
+	Name     string   `json:"name"`
+	Grams    *float64 `json:"grams,omitempty"`
+	Servings *float64 `json:"servings,omitempty"`
+	Macros
+}
+
+// MealItemInput references a food by grams or a recipe by servings
+type MealItemInput struct {
+	FoodID   *int64   `json:"food_id"`
+	Grams    *float64 `json:"grams"`
+	RecipeID *int64   `json:"recipe_id"`
+	Servings *float64 `json:"servings"` // default 1
+}
+
+// Meal is a logged meal; its macros are the sum of its items
+type Meal struct {
+	ID      int64     `json:"id"`
+	EatenAt time.Time `json:"eaten_at"`
+	Date    string    `json:"date"`
+	Name    string    `json:"name"`
+	Tags    []string  `json:"tags"`
+	Notes   string    `json:"notes"`
+	Macros
+	Items     []MealItem `json:"items"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MealInput is the request body for logging a meal. Calories and macros
+// are only used when there are no items.
+type MealInput struct {
+	EatenAt string   `json:"eaten_at"` // RFC 3339, default now
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Notes   string   `json:"notes"`
+	Macros
+	Items []MealItemInput `json:"items"`
+}
+
+// WaterEntry is a logged drink
+type WaterEntry struct {
+	ID         int64     `json:"id"`
+	Date       string    `json:"date"`
+	ConsumedAt time.Time `json:"consumed_at"`
+	Ml         int       `json:"ml"`
+}
+
+// WaterInput is the request body for logging a drink
+type WaterInput struct {
+	Ml         int    `json:"ml"`
+	ConsumedAt string `json:"consumed_at"` // RFC 3339, default now
+}
+
+// WaterDay is the water intake of one day
+type WaterDay struct {
+	Date    string       `json:"date"`
+	TotalMl int          `json:"total_ml"`
+	Entries []WaterEntry `json:"entries"`
+}
+
+// EnergyBalanceDay combines intake with Oura total calories for a day
+type EnergyBalanceDay struct {
+	Date     string   `json:"date"`
+	Meals    int      `json:"meals"`
+	Intake   *float64 `json:"intake"` // nil when no meals are logged
+	ProteinG *float64 `json:"protein_g"`
+	CarbsG   *float64 `json:"carbs_g"`
+	FatG     *float64 `json:"fat_g"`
+	WaterMl  int      `json:"water_ml"`
+	Burned   *float64 `json:"burned"`  // Oura total calories
+	Balance  *float64 `json:"balance"` // intake - burned
+	TrendKg  *float64 `json:"trend_kg"`
+}
+
+// EnergyBalance is the response of GET /dashboard/health/energy-balance
+type EnergyBalance struct {
+	Days             int                `json:"days"`
+	From             string             `json:"from"`
+	To               string             `json:"to"`
+	LoggedDays       int                `json:"logged_days"`
+	AvgIntake        *float64           `json:"avg_intake"` // over days with intake and burn
+	AvgBurned        *float64           `json:"avg_burned"`
+	AvgBalance       *float64           `json:"avg_balance"`
+	ExpectedChangeKg *float64           `json:"expected_change_kg"` // from the average balance
+	TrendChangeKg    *float64           `json:"trend_change_kg"`    // smoothed weight trend
+	ImpliedIntake    *float64           `json:"implied_intake"`     // intake matching the trend
+	Insight          string             `json:"insight"`
+	History          []EnergyBalanceDay `json:"history"`
+}
+
+// --- Phase 21: Medications and supplements ---
+
+// MedicationStock is the estimated stock left after intakes since the last count
+type MedicationStock struct {
+	Remaining float64 `json:"remaining"`   // units, e.g. tablets
+	DaysLeft  *int    `json:"days_left"`   // at the scheduled or recent rate
+	RunsOutOn *string `json:"runs_out_on"` // YYYY-MM-DD
+	RefillDue bool    `json:"refill_due"`
+}
+
+// Medication is a medication or supplement with its dosing schedule
+type Medication struct {
+	ID                  int64            `json:"id"`
+	Name                string           `json:"name"`
+	Kind                string           `json:"kind"` // medication, supplement
+	DoseAmount          float64          `json:"dose_amount"`
+	DoseUnit            string           `json:"dose_unit"` // e.g. mg, IU
+	Schedule            string           `json:"schedule"`  // daily, weekdays, as_needed
+	Weekdays            []int            `json:"weekdays"`  // ISO weekdays for the weekdays schedule
+	TimesPerDay         int              `json:"times_per_day"`
+	StartDate           string           `json:"start_date"`
+	StopDate            *string          `json:"stop_date"`
+	UnitsPerDose        float64          `json:"units_per_dose"`
+	StockUnits          *float64         `json:"stock_units"` // at the last count
+	StockCountedAt      *time.Time       `json:"stock_counted_at"`
+	RefillThresholdDays int              `json:"refill_threshold_days"`
+	Notes               string           `json:"notes"`
+	Active              bool             `json:"active"`
+	Stock               *MedicationStock `json:"stock"`
+	CreatedAt           time.Time        `json:"created_at"`
+}
+
+// MedicationInput is the request body for creating or updating a medication
+type MedicationInput struct {
+	Name                string   `json:"name"`
+	Kind                string   `json:"kind"` // default supplement
+	DoseAmount          float64  `json:"dose_amount"`
+	DoseUnit            string   `json:"dose_unit"`
+	Schedule            string   `json:"schedule"` // default daily
+	Weekdays            []int    `json:"weekdays"`
+	TimesPerDay         int      `json:"times_per_day"` // default 1
+	StartDate           string   `json:"start_date"`    // default today
+	StopDate            *string  `json:"stop_date"`
+	UnitsPerDose        float64  `json:"units_per_dose"` // default 1
+	StockUnits          *float64 `json:"stock_units"`    // only on create
+	RefillThresholdDays *int     `json:"refill_threshold_days"`
+	Notes               string   `json:"notes"`
+}
+
+// MedicationRefillInput is the request body for recording a refill
+type MedicationRefillInput struct {
+	Add   *float64 `json:"add"`   // units added to what is left
+	Count *float64 `json:"count"` // units counted in total
+}
+
+// MedicationIntake is one logged intake
+type MedicationIntake struct {
+	ID           int64     `json:"id"`
+	MedicationID int64     `json:"medication_id"`
+	TakenAt      time.Time `json:"taken_at"`
+	Date         string    `json:"date"`
+	Doses        float64   `json:"doses"`
+	Notes        string    `json:"notes"`
+}
+
+// MedicationIntakeInput is the request body for logging an intake
+type MedicationIntakeInput struct {
+	TakenAt string   `json:"taken_at"` // RFC 3339, default now
+	Doses   *float64 `json:"doses"`    // default 1
+	Notes   string   `json:"notes"`
+}
+
+// MissedDose is a scheduled day with fewer doses taken than due
+type MissedDose struct {
+	Date  string  `json:"date"`
+	Due   int     `json:"due"`
+	Taken float64 `json:"taken"`
+}
+
+// MedicationToday is today's progress
+type MedicationToday struct {
+	Due   int     `json:"due"`
+	Taken float64 `json:"taken"`
+}
+
+// MedicationAdherence is one medication's adherence over the window
+type MedicationAdherence struct {
+	MedicationID    int64            `json:"medication_id"`
+	Name            string           `json:"name"`
+	Kind            string           `json:"kind"`
+	Schedule        string           `json:"schedule"`
+	ScheduledDoses  int              `json:"scheduled_doses"`
+	OnScheduleDoses float64          `json:"on_schedule_doses"` // taken, capped at the doses due each day
+	TakenDoses      float64          `json:"taken_doses"`       // all doses, including as-needed
+	AdherencePct    *float64         `json:"adherence_pct"`     // nil for as-needed
+	CurrentStreak   int              `json:"current_streak"`    // scheduled days in a row fully taken
+	MissedDoses     []MissedDose     `json:"missed_doses"`      // newest first
+	Today           *MedicationToday `json:"today"`
+	Stock           *MedicationStock `json:"stock"`
+}
+
+// RefillReminder flags a medication that runs out within its refill threshold
+type RefillReminder struct {
+	MedicationID int64   `json:"medication_id"`
+	Name         string  `json:"name"`
+	Remaining    float64 `json:"remaining"`
+	DaysLeft     int     `json:"days_left"`
+	RunsOutOn    string  `json:"runs_out_on"`
+}
+
+// MedicationAdherenceReport is the response of GET /dashboard/health/medications
+type MedicationAdherenceReport struct {
+	Days            int                   `json:"days"`
+	From            string                `json:"from"`
+	To              string                `json:"to"`
+	AdherencePct    *float64              `json:"adherence_pct"` // across scheduled medications
+	Medications     []MedicationAdherence `json:"medications"`
+	RefillReminders []RefillReminder      `json:"refill_reminders"`
+}
+
+// --- Phase 22: Lab results and vitals ---
+
+// LabResult is one lab test result, normalised to the marker's unit
+type LabResult struct {
+	ID            int64     `json:"id"`
+	Marker        string    `json:"marker"` // normalised test key, e.g. ldl
+	Test          string    `json:"test"`   // name as reported
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	OriginalValue float64   `json:"original_value"`
+	OriginalUnit  string    `json:"original_unit"`
+	RefLow        *float64  `json:"ref_low"`
+	RefHigh       *float64  `json:"ref_high"`
+	Flag          string    `json:"flag"` // low, normal, high, or empty without a range
+	Lab           string    `json:"lab"`
+	SampleDate    string    `json:"sample_date"`
+	Notes         string    `json:"notes"`
+	Source        string    `json:"source"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LabResultInput is the request body for recording a lab result. The
+// reference range is in the same unit as the value.
+type LabResultInput struct {
+	Test       string   `json:"test"`
+	Value      float64  `json:"value"`
+	Unit       string   `json:"unit"`
+	RefLow     *float64 `json:"ref_low"`
+	RefHigh    *float64 `json:"ref_high"`
+	Lab        string   `json:"lab"`
+	SampleDate string   `json:"sample_date"`
+	Notes      string   `json:"notes"`
+}
+
+// LabMarkerSummary is a marker with its latest result
+type LabMarkerSummary struct {
+	Marker  string     `json:"marker"`
+	Label   string     `json:"label"`
+	Unit    string     `json:"unit"`
+	Results int        `json:"results"`
+	Latest  *LabResult `json:"latest"`
+}
+
+// LabMarkerTrend is the response of GET /dashboard/health/labs/{marker}
+type LabMarkerTrend struct {
+	Marker        string      `json:"marker"`
+	Label         string      `json:"label"`
+	Unit          string      `json:"unit"`
+	RefLow        *float64    `json:"ref_low"` // of the latest result
+	RefHigh       *float64    `json:"ref_high"`
+	Latest        *LabResult  `json:"latest"`
+	Change        *float64    `json:"change"`          // since the previous result
+	ChangePerYear *float64    `json:"change_per_year"` // least-squares fit
+	Direction     string      `json:"direction,omitempty"`
+	OutOfRange    int         `json:"out_of_range"`
+	Results       []LabResult `json:"results"` // oldest first
+}
+
+// CSVRowError is a CSV row that could not be imported
+type CSVRowError struct {
+	Row   int    `json:"row"` // 1-based, the header is row 1
+	Error string `json:"error"`
+}
+
+// CSVImportResult is returned after a CSV import
+type CSVImportResult struct {
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"`
+	Skipped    int           `json:"skipped"`
+	Errors     []CSVRowError `json:"errors"`
+}
+
+// Vital is a blood pressure, SpO2 or glucose reading
+type Vital struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"` // blood_pressure, spo2, glucose
+	MeasuredAt time.Time `json:"measured_at"`
+	Date       string    `json:"date"`
+	Systolic   *int      `json:"systolic,omitempty"`
+	Diastolic  *int      `json:"diastolic,omitempty"`
+	Pulse      *int      `json:"pulse,omitempty"`
+	Value      *float64  `json:"value,omitempty"` // SpO2 in %, glucose in mmol/L
+	Unit       string    `json:"unit"`
+	Context    string    `json:"context,omitempty"` // glucose: fasting, post_meal, random
+	Flag       string    `json:"flag"`
+	Notes      string    `json:"notes"`
+	Source     string    `json:"source"`
+}
+
+// VitalInput is the request body for recording a reading
+type VitalInput struct {
+	Kind       string   `json:"kind"`
+	MeasuredAt string   `json:"measured_at"` // RFC 3339 or YYYY-MM-DD, default now
+	Systolic   *int     `json:"systolic"`
+	Diastolic  *int     `json:"diastolic"`
+	Pulse      *int     `json:"pulse"`
+	Value      *float64 `json:"value"`
+	Unit       string   `json:"unit"` // glucose: mmol/L or mg/dL
+	Context    string   `json:"context"`
+	Notes      string   `json:"notes"`
+}
+
+// VitalDay is the average of a day's readings
+type VitalDay struct {
+	Date      string   `json:"date"`
+	Readings  int      `json:"readings"`
+	Systolic  *float64 `json:"systolic,omitempty"`
+	Diastolic *float64 `json:"diastolic,omitempty"`
+	Value     *float64 `json:"value,omitempty"`
+	Pulse     *float64 `json:"pulse,omitempty"`
+}
+
+// VitalTrend is the response of GET /dashboard/health/vitals/{kind}
+type VitalTrend struct {
+	Kind          string         `json:"kind"`
+	Days          int            `json:"days"`
+	Readings      int            `json:"readings"`
+	AvgSystolic   *float64       `json:"avg_systolic,omitempty"`
+	AvgDiastolic  *float64       `json:"avg_diastolic,omitempty"`
+	AvgValue      *float64       `json:"avg_value,omitempty"`
+	Category      string         `json:"category,omitempty"` // blood pressure category of the averages
+	ChangePerWeek *float64       `json:"change_per_week"`    // systolic or value
+	Flags         map[string]int `json:"flags"`
+	Latest        *Vital         `json:"latest"`
+	Daily         []VitalDay     `json:"daily"`
+}
+
+// --- Phase 23: Data quality ---
+
+// IngestionRowError is a row of a bulk import that was not stored
+type IngestionRowError struct {
+	Index int    `json:"index"` // position in the request array
+	Day   string `json:"day,omitempty"`
+	Error string `json:"error"`
+}
+
+// BulkUpsertResult is returned by the bulk import endpoints
+type BulkUpsertResult struct {
+	Inserted int                 `json:"inserted"`
+	Total    int                 `json:"total"`
+	Failed   int                 `json:"failed"`
+	Errors   []IngestionRowError `json:"errors"`
+}
+
+// IngestionError is a recorded import failure
+type IngestionError struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"` // oura_bulk, oura_sync, sleep_sessions_bulk
+	Day       *string   `json:"day"`
+	RowIndex  *int      `json:"row_index"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DataGap is a run of consecutive days without Oura data
+type DataGap struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+// PartialDay is a day with Oura data but without some score families
+type PartialDay struct {
+	Date    string   `json:"date"`
+	Missing []string `json:"missing"` // sleep, readiness, activity
+}
+
+// ImplausibleValue is a stored value outside its plausible range
+type ImplausibleValue struct {
+	Date   string  `json:"date"`
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	Reason string  `json:"reason"`
+}
+
+// DataQualityReport is the response of GET /dashboard/health/data-quality
+type DataQualityReport struct {
+	Days            int                `json:"days"`
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	CompleteDays    int                `json:"complete_days"`
+	MissingDays     int                `json:"missing_days"`
+	CompletenessPct float64            `json:"completeness_pct"`
+	Gaps            []DataGap          `json:"gaps"`
+	PartialDays     []PartialDay       `json:"partial_days"`
+	Implausible     []ImplausibleValue `json:"implausible"`
+	IngestionFails  []IngestionError   `json:"ingestion_failures"`
+	OuraSyncError   string             `json:"oura_sync_error,omitempty"`
+}
+
+// --- Phase 24: Timezones ---
+
+// TimezoneSettings is the response of GET /health/timezone
+type TimezoneSettings struct {
+	HomeTimezone    string             `json:"home_timezone"` // empty when the server's zone is used
+	CurrentTimezone string             `json:"current_timezone"`
+	Today           string             `json:"today"`
+	LocalTime       string             `json:"local_time"`
+	Overrides       []TimezoneOverride `json:"overrides"` // 30 days either side of today
+}
+
+// TimezoneSettingsInput is the request body for PUT /health/timezone
+type TimezoneSettingsInput struct {
+	HomeTimezone string `json:"home_timezone"` // IANA name, e.g. Europe/Oslo
+}
+
+// TimezoneOverride is the zone in force on one day away from home
+type TimezoneOverride struct {
+	Day      string `json:"day"`
+	Timezone string `json:"timezone"`
+	Source   string `json:"source"` // manual, sleep
+	Note     string `json:"note,omitempty"`
+}
+
+// TimezoneOverrideInput sets the zone for a day or a trip
+type TimezoneOverrideInput struct {
+	Start    string `json:"start"`
+	End      string `json:"end,omitempty"` // defaults to start
+	Timezone string `json:"timezone"`      // IANA name or UTC offset, e.g. +09:00
+	Note     string `json:"note,omitempty"`
+}
+
+// --- Phase 25: Monthly and yearly reports ---
+
+// ReportMetric is a metric's average over a report period with its
+// comparisons. Deltas are the period average minus the other period's.
+type ReportMetric struct {
+	Key           string   `json:"key"`
+	Label         string   `json:"label"`
+	Avg           *float64 `json:"avg"` // nil without data
+	Days          int      `json:"days"`
+	PreviousAvg   *float64 `json:"previous_avg,omitempty"`
+	PreviousDelta *float64 `json:"previous_delta,omitempty"`
+	LastYearAvg   *float64 `json:"last_year_avg,omitempty"` // monthly reports only
+	LastYearDelta *float64 `json:"last_year_delta,omitempty"`
+	Percentile    *float64 `json:"percentile,omitempty"` // rank among all months or years, 0-100
+	PeriodsRanked int      `json:"periods_ranked"`
+}
+
+// ReportDay is a day ranked by the mean of its Oura scores
+type ReportDay struct {
+	Date      string   `json:"date"`
+	Score     float64  `json:"score"`
+	Sleep     *float64 `json:"sleep,omitempty"`
+	Readiness *float64 `json:"readiness,omitempty"`
+	Activity  *float64 `json:"activity,omitempty"`
+}
+
+// ReportGoal is how often a goal was met in the goal periods starting in
+// the report period
+type ReportGoal struct {
+	GoalType      string   `json:"goal_type"`
+	Label         string   `json:"label"`
+	Period        string   `json:"period"`
+	Evaluated     int      `json:"evaluated"` // goal periods with data
+	Met           int      `json:"met"`
+	AttainmentPct *float64 `json:"attainment_pct"`
+}
+
+// ReportWorkouts totals the workouts of a report period
+type ReportWorkouts struct {
+	Count         int     `json:"count"`
+	Strength      int     `json:"strength"`
+	Cardio        int     `json:"cardio"`
+	Minutes       float64 `json:"minutes"`
+	DistanceKm    float64 `json:"distance_km"`
+	Calories      int     `json:"calories"`
+	PreviousCount *int    `json:"previous_count,omitempty"`
+	LastYearCount *int    `json:"last_year_count,omitempty"`
+}
+
+// ReportWeight is the change of the smoothed weight trend over a period
+type ReportWeight struct {
+	Entries  int     `json:"entries"`
+	StartKg  float64 `json:"start_kg"`
+	EndKg    float64 `json:"end_kg"`
+	ChangeKg float64 `json:"change_kg"`
+	MinKg    float64 `json:"min_kg"`
+	MaxKg    float64 `json:"max_kg"`
+}
+
+// HealthReport is the response of GET /dashboard/health/monthly and
+// /dashboard/health/yearly
+type HealthReport struct {
+	Period            string         `json:"period"` // month, year
+	Label             string         `json:"label"`  // e.g. May 2024
+	Start             string         `json:"start"`
+	End               string         `json:"end"`
+	Complete          bool           `json:"complete"` // false while the period is running
+	DaysWithData      int            `json:"days_with_data"`
+	Previous          string         `json:"previous"`            // label of the previous period
+	LastYear          string         `json:"last_year,omitempty"` // label of the same month last year
+	Metrics           []ReportMetric `json:"metrics"`
+	TopDays           []ReportDay    `json:"top_days"`
+	BottomDays        []ReportDay    `json:"bottom_days"`
+	Goals             []ReportGoal   `json:"goals"`
+	GoalAttainmentPct *float64       `json:"goal_attainment_pct"`
+	Workouts          ReportWorkouts `json:"workouts"`
+	Weight            *ReportWeight  `json:"weight"`
+	Highlights        []string       `json:"highlights"`
+	Lowlights         []string       `json:"lowlights"`
+}
+
+// --- Phase 26: Readiness forecast ---
+
+// ForecastWeight is a feature of a forecast model. Coef is the effect in
+// score points of one standard deviation above the mean.
+type ForecastWeight struct {
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Mean  float64 `json:"mean"`
+	SD    float64 `json:"sd"`
+	Coef  float64 `json:"coef"`
+}
+
+// ForecastAccuracy is a model's error on the newest days, held out of
+// its fit. BaselineMAE is the error of assuming tomorrow equals today.
+type ForecastAccuracy struct {
+	HoldoutSamples int     `json:"holdout_samples"`
+	HoldoutFrom    string  `json:"holdout_from"`
+	HoldoutTo      string  `json:"holdout_to"`
+	MAE            float64 `json:"mae"`
+	RMSE           float64 `json:"rmse"`
+	R2             float64 `json:"r2"`
+	BaselineMAE    float64 `json:"baseline_mae"`
+	Trust          string  `json:"trust"` // good, fair, poor
+}
+
+// ForecastModel is a ridge regression predicting a score from the day before
+type ForecastModel struct {
+	Target    string           `json:"target"` // readiness_score, sleep_score
+	TrainedAt time.Time        `json:"trained_at"`
+	From      string           `json:"from"` // first and last day of the samples
+	To        string           `json:"to"`
+	Samples   int              `json:"samples"`
+	Intercept float64          `json:"intercept"`
+	Features  []ForecastWeight `json:"features"`
+	Accuracy  ForecastAccuracy `json:"accuracy"`
+}
+
+// ForecastContribution is how far a feature moves a prediction from the
+// average day
+type ForecastContribution struct {
+	Feature string  `json:"feature"`
+	Label   string  `json:"label"`
+	Value   float64 `json:"value"`
+	Effect  float64 `json:"effect"` // score points
+}
+
+// ForecastPrediction is the forecast of one score with an 80% range
+type ForecastPrediction struct {
+	Target          string                 `json:"target"`
+	Label           string                 `json:"label"`
+	Predicted       float64                `json:"predicted"`
+	Low             float64                `json:"low"`
+	High            float64                `json:"high"`
+	Contributions   []ForecastContribution `json:"contributions"` // largest effects first
+	MissingFeatures []string               `json:"missing_features"`
+	Accuracy        ForecastAccuracy       `json:"accuracy"`
+	TrainedAt       time.Time              `json:"trained_at"`
+	Stale           bool                   `json:"stale"` // not retrained for over a week
+}
+
+// ReadinessForecast is the response of GET /dashboard/health/forecast
+type ReadinessForecast struct {
+	ForDate       string               `json:"for_date"`
+	BasedOn       string               `json:"based_on"`
+	Bedtime       string               `json:"bedtime,omitempty"`        // HH:MM
+	BedtimeSource string               `json:"bedtime_source,omitempty"` // query, recorded, typical
+	Predictions   []ForecastPrediction `json:"predictions"`
+	Message       string               `json:"message,omitempty"`
+}
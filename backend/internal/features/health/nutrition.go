@@ -0,0 +1,932 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxMealCalories     = 10000
+	maxWaterMl          = 5000 // per entry
+	energyExplainedKg   = 0.5  // implied and actual trend change this close agree
+	maxEnergyBalanceDay = 365
+)
+
+// errUnknownFood is returned when a meal or recipe references a missing food or recipe
+var errUnknownFood = errors.New("unknown food")
+
+// add accumulates other into m
+func (m *Macros) add(other Macros) {
+	m.Calories += other.Calories
+	m.ProteinG += other.ProteinG
+	m.CarbsG += other.CarbsG
+	m.FatG += other.FatG
+}
+
+// scaled returns m multiplied by factor, rounded to one decimal
+func (m Macros) scaled(factor float64) Macros {
+	return Macros{
+		Calories: round1(m.Calories * factor),
+		ProteinG: round1(m.ProteinG * factor),
+		CarbsG:   round1(m.CarbsG * factor),
+		FatG:     round1(m.FatG * factor),
+	}
+}
+
+// validateMacros checks macro values are non-negative and plausible
+func validateMacros(m Macros, maxCalories float64) error {
+	for _, v := range []float64{m.Calories, m.ProteinG, m.CarbsG, m.FatG} {
+		if v < 0 || math.IsNaN(v) {
+			return fmt.Errorf("calories and macros must not be negative")
+		}
+	}
+	if m.Calories > maxCalories {
+		return fmt.Errorf("calories must be at most %.0f", maxCalories)
+	}
+	return nil
+}
+
+// normaliseTags lowercases, trims and de-duplicates meal tags
+func normaliseTags(tags []string) []string {
+	out := []string{}
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		t = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(t)), " ", "_")
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// --- Food catalogue ---
+
+// ListFoods returns the food catalogue. Query: ?q= to filter by name
+func (h *Handler) ListFoods(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, name, calories, protein_g, carbs_g, fat_g, created_at
+		FROM foods
+		WHERE $1 = '' OR name ILIKE '%' || $1 || '%'
+		ORDER BY name
+	`, strings.TrimSpace(r.URL.Query().Get("q")))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	foods := []Food{}
+	for rows.Next() {
+		var f Food
+		if err := rows.Scan(&f.ID, &f.Name, &f.Calories, &f.ProteinG, &f.CarbsG, &f.FatG, &f.CreatedAt); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		foods = append(foods, f)
+	}
+
+	core.WriteJSON(w, http.StatusOK, foods)
+}
+
+// validateFood checks a food input
+func validateFood(input *FoodInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if err := validateMacros(input.Macros, 1000); err != nil {
+		return err
+	}
+	if input.ProteinG+input.CarbsG+input.FatG > 100 {
+		return fmt.Errorf("macros cannot exceed 100 g per 100 g")
+	}
+	return nil
+}
+
+// CreateFood adds a food with macros per 100 g
+func (h *Handler) CreateFood(w http.ResponseWriter, r *http.Request) {
+	var input FoodInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateFood(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f := Food{Name: input.Name, Macros: input.Macros}
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO foods (name, calories, protein_g, carbs_g, fat_g)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, f.Name, f.Calories, f.ProteinG, f.CarbsG, f.FatG).Scan(&f.ID, &f.CreatedAt)
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Food already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, f)
+}
+
+// UpdateFood changes a food's name or macros. Logged meals keep the
+// values they were logged with.
+func (h *Handler) UpdateFood(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var input FoodInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateFood(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f := Food{ID: id, Name: input.Name, Macros: input.Macros}
+	err = h.db.QueryRow(r.Context(), `
+		UPDATE foods SET name = $2, calories = $3, protein_g = $4, carbs_g = $5, fat_g = $6
+		WHERE id = $1
+		RETURNING created_at
+	`, id, f.Name, f.Calories, f.ProteinG, f.CarbsG, f.FatG).Scan(&f.CreatedAt)
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Food not found")
+		return
+	}
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Food already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, f)
+}
+
+// DeleteFood removes a food that no recipe uses
+func (h *Handler) DeleteFood(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM foods WHERE id = $1`, id)
+	if isConstraintViolation(err, "23503") {
+		core.WriteError(w, http.StatusConflict, "Food is used by a recipe")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Food not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// foodMacros returns the name and per-100 g macros of a food
+func foodMacros(ctx context.Context, q querier, id int64) (string, Macros, error) {
+	var name string
+	var m Macros
+	err := q.QueryRow(ctx, `
+		SELECT name, calories, protein_g, carbs_g, fat_g FROM foods WHERE id = $1
+	`, id).Scan(&name, &m.Calories, &m.ProteinG, &m.CarbsG, &m.FatG)
+	if err == pgx.ErrNoRows {
+		return "", m, fmt.Errorf("%w: food %d", errUnknownFood, id)
+	}
+	return name, m, err
+}
+
+// --- Recipes ---
+
+// loadRecipes returns recipes with their items and totals, all or one
+func loadRecipes(ctx context.Context, q querier, id int64) ([]Recipe, error) {
+	rows, err := q.Query(ctx, `
+		SELECT r.id, r.name, r.servings, r.notes, r.created_at,
+			ri.food_id, f.name, ri.grams, f.calories, f.protein_g, f.carbs_g, f.fat_g
+		FROM recipes r
+		LEFT JOIN recipe_items ri ON ri.recipe_id = r.id
+		LEFT JOIN foods f ON f.id = ri.food_id
+		WHERE $1 = 0 OR r.id = $1
+		ORDER BY r.name, r.id, ri.position
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes := []Recipe{}
+	for rows.Next() {
+		var rec Recipe
+		var foodID *int64
+		var foodName *string
+		var grams *float64
+		var per100 struct{ calories, protein, carbs, fat *float64 }
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.Servings, &rec.Notes, &rec.CreatedAt,
+			&foodID, &foodName, &grams, &per100.calories, &per100.protein, &per100.carbs, &per100.fat); err != nil {
+			return nil, err
+		}
+		if len(recipes) == 0 || recipes[len(recipes)-1].ID != rec.ID {
+			rec.Items = []RecipeItem{}
+			recipes = append(recipes, rec)
+		}
+		current := &recipes[len(recipes)-1]
+		if foodID == nil {
+			continue
+		}
+		m := Macros{Calories: *per100.calories, ProteinG: *per100.protein, CarbsG: *per100.carbs, FatG: *per100.fat}
+		item := RecipeItem{FoodID: *foodID, Food: *foodName, Grams: *grams, Macros: m.scaled(*grams / 100)}
+		current.Items = append(current.Items, item)
+		current.Total.add(item.Macros)
+	}
+	for i := range recipes {
+		recipes[i].Total = recipes[i].Total.scaled(1)
+		recipes[i].PerServing = recipes[i].Total.scaled(1 / float64(recipes[i].Servings))
+	}
+	return recipes, rows.Err()
+}
+
+// ListRecipes returns all recipes with totals and per-serving macros
+func (h *Handler) ListRecipes(w http.ResponseWriter, r *http.Request) {
+	recipes, err := loadRecipes(r.Context(), h.db, 0)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, recipes)
+}
+
+// GetRecipe returns one recipe
+func (h *Handler) GetRecipe(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	recipes, err := loadRecipes(r.Context(), h.db, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(recipes) == 0 {
+		core.WriteError(w, http.StatusNotFound, "Recipe not found")
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, recipes[0])
+}
+
+// CreateRecipe adds a recipe made of catalogue foods
+func (h *Handler) CreateRecipe(w http.ResponseWriter, r *http.Request) {
+	var input RecipeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		core.WriteError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if input.Servings == 0 {
+		input.Servings = 1
+	}
+	if input.Servings < 1 || input.Servings > 100 {
+		core.WriteError(w, http.StatusBadRequest, "servings must be between 1 and 100")
+		return
+	}
+	if len(input.Items) == 0 {
+		core.WriteError(w, http.StatusBadRequest, "items is required")
+		return
+	}
+	for _, item := range input.Items {
+		if item.Grams <= 0 || item.Grams > 10000 {
+			core.WriteError(w, http.StatusBadRequest, "grams must be between 0 and 10000")
+			return
+		}
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var id int64
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO recipes (name, servings, notes) VALUES ($1, $2, $3) RETURNING id
+	`, input.Name, input.Servings, strings.TrimSpace(input.Notes)).Scan(&id)
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Recipe already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i, item := range input.Items {
+		_, err := tx.Exec(r.Context(), `
+			INSERT INTO recipe_items (recipe_id, food_id, position, grams) VALUES ($1, $2, $3, $4)
+		`, id, item.FoodID, i, item.Grams)
+		if isConstraintViolation(err, "23503") {
+			core.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown food %d", item.FoodID))
+			return
+		}
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	recipes, err := loadRecipes(r.Context(), tx, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, recipes[0])
+}
+
+// DeleteRecipe removes a recipe. Logged meals keep their values.
+func (h *Handler) DeleteRecipe(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM recipes WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Recipe not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Meals ---
+
+// validateMealItems checks each item names a food with grams or a recipe
+// with servings, defaulting servings to one
+func validateMealItems(inputs []MealItemInput) error {
+	for i := range inputs {
+		in := &inputs[i]
+		switch {
+		case in.FoodID != nil && in.RecipeID == nil:
+			if in.Grams == nil || *in.Grams <= 0 || *in.Grams > 10000 {
+				return fmt.Errorf("grams must be between 0 and 10000 for a food")
+			}
+		case in.RecipeID != nil && in.FoodID == nil:
+			if in.Servings == nil {
+				one := 1.0
+				in.Servings = &one
+			}
+			if *in.Servings <= 0 || *in.Servings > 50 {
+				return fmt.Errorf("servings must be between 0 and 50")
+			}
+		default:
+			return fmt.Errorf("each item needs either food_id with grams or recipe_id with servings")
+		}
+	}
+	return nil
+}
+
+// resolveMealItems computes the macros of validated meal items from the catalogue
+func resolveMealItems(ctx context.Context, q querier, inputs []MealItemInput) ([]MealItem, error) {
+	items := make([]MealItem, 0, len(inputs))
+	for _, in := range inputs {
+		if in.FoodID != nil {
+			name, per100, err := foodMacros(ctx, q, *in.FoodID)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, MealItem{FoodID: in.FoodID, Name: name, Grams: in.Grams, Macros: per100.scaled(*in.Grams / 100)})
+			continue
+		}
+
+		recipes, err := loadRecipes(ctx, q, *in.RecipeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(recipes) == 0 {
+			return nil, fmt.Errorf("%w: recipe %d", errUnknownFood, *in.RecipeID)
+		}
+		items = append(items, MealItem{RecipeID: in.RecipeID, Name: recipes[0].Name, Servings: in.Servings,
+			Macros: recipes[0].PerServing.scaled(*in.Servings)})
+	}
+	return items, nil
+}
+
+// loadMeals returns meals between two dates with their items, newest first
+func loadMeals(ctx context.Context, q querier, from, to string) ([]Meal, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, eaten_at, date, name, tags, notes, calories, protein_g, carbs_g, fat_g, created_at
+		FROM meals
+		WHERE date >= $1 AND date <= $2
+		ORDER BY eaten_at DESC
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	meals := []Meal{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var m Meal
+		var day time.Time
+		if err := rows.Scan(&m.ID, &m.EatenAt, &day, &m.Name, &m.Tags, &m.Notes,
+			&m.Calories, &m.ProteinG, &m.CarbsG, &m.FatG, &m.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Date = formatDate(day)
+		m.Items = []MealItem{}
+		index[m.ID] = len(meals)
+		meals = append(meals, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(meals) == 0 {
+		return meals, err
+	}
+
+	ids := make([]int64, 0, len(meals))
+	for _, m := range meals {
+		ids = append(ids, m.ID)
+	}
+	itemRows, err := q.Query(ctx, `
+		SELECT meal_id, food_id, recipe_id, name, grams, servings, calories, protein_g, carbs_g, fat_g
+		FROM meal_items
+		WHERE meal_id = ANY($1)
+		ORDER BY meal_id, position
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var mealID int64
+		var it MealItem
+		if err := itemRows.Scan(&mealID, &it.FoodID, &it.RecipeID, &it.Name, &it.Grams, &it.Servings,
+			&it.Calories, &it.ProteinG, &it.CarbsG, &it.FatG); err != nil {
+			return nil, err
+		}
+		meals[index[mealID]].Items = append(meals[index[mealID]].Items, it)
+	}
+	return meals, itemRows.Err()
+}
+
+// ListMeals returns meals for ?date= (default today) or ?from= and ?to=
+func (h *Handler) ListMeals(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	today := h.todayKey(r.Context())
+	from, to := query.Get("from"), query.Get("to")
+	if d := query.Get("date"); d != "" || from == "" {
+		if d == "" {
+			d = today
+		}
+		from, to = d, d
+	}
+	if to == "" {
+		to = today
+	}
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			core.WriteError(w, http.StatusBadRequest, "dates must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	meals, err := loadMeals(r.Context(), h.db, from, to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, meals)
+}
+
+// CreateMeal logs a meal. Items from the food catalogue or recipes set the
+// totals; without items the calories and macros are taken as given.
+func (h *Handler) CreateMeal(w http.ResponseWriter, r *http.Request) {
+	var input MealInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	eatenAt := time.Now()
+	if input.EatenAt != "" {
+		t, err := time.Parse(time.RFC3339, input.EatenAt)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "eaten_at must be RFC 3339, e.g. 2024-05-01T12:30:00+02:00")
+			return
+		}
+		eatenAt = t
+	}
+
+	if err := validateMealItems(input.Items); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	items, err := resolveMealItems(r.Context(), tx, input.Items)
+	if errors.Is(err, errUnknownFood) {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	meal := Meal{
+		EatenAt: eatenAt,
+		Date:    h.dayOf(r.Context(), eatenAt),
+		Name:    strings.TrimSpace(input.Name),
+		Tags:    normaliseTags(input.Tags),
+		Notes:   strings.TrimSpace(input.Notes),
+		Items:   items,
+		Macros:  input.Macros,
+	}
+	if len(items) > 0 {
+		meal.Macros = Macros{}
+		for _, it := range items {
+			meal.Macros.add(it.Macros)
+		}
+		meal.Macros = meal.Macros.scaled(1)
+	}
+	if err := validateMacros(meal.Macros, maxMealCalories); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if meal.Calories == 0 && len(items) == 0 {
+		core.WriteError(w, http.StatusBadRequest, "calories or items are required")
+		return
+	}
+
+	err = tx.QueryRow(r.Context(), `
+		INSERT INTO meals (eaten_at, date, name, tags, notes, calories, protein_g, carbs_g, fat_g)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, meal.EatenAt, meal.Date, meal.Name, meal.Tags, meal.Notes,
+		meal.Calories, meal.ProteinG, meal.CarbsG, meal.FatG).Scan(&meal.ID, &meal.CreatedAt)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i, it := range items {
+		if _, err := tx.Exec(r.Context(), `
+			INSERT INTO meal_items (meal_id, position, food_id, recipe_id, name, grams, servings,
+				calories, protein_g, carbs_g, fat_g)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, meal.ID, i, it.FoodID, it.RecipeID, it.Name, it.Grams, it.Servings,
+			it.Calories, it.ProteinG, it.CarbsG, it.FatG); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, meal)
+}
+
+// DeleteMeal removes a meal
+func (h *Handler) DeleteMeal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM meals WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Meal not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Water ---
+
+// ListWaterIntake returns daily water totals with their entries. Query: ?days= (default 7)
+func (h *Handler) ListWaterIntake(w http.ResponseWriter, r *http.Request) {
+	days := 7 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, date, consumed_at, ml FROM water_intake
+		WHERE date > $1
+		ORDER BY consumed_at DESC
+	`, formatDate(h.today(r.Context()).AddDate(0, 0, -days)))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	daily := []WaterDay{}
+	for rows.Next() {
+		var e WaterEntry
+		var day time.Time
+		if err := rows.Scan(&e.ID, &day, &e.ConsumedAt, &e.Ml); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		e.Date = formatDate(day)
+		if len(daily) == 0 || daily[len(daily)-1].Date != e.Date {
+			daily = append(daily, WaterDay{Date: e.Date, Entries: []WaterEntry{}})
+		}
+		current := &daily[len(daily)-1]
+		current.TotalMl += e.Ml
+		current.Entries = append(current.Entries, e)
+	}
+
+	core.WriteJSON(w, http.StatusOK, daily)
+}
+
+// CreateWaterEntry logs a drink
+func (h *Handler) CreateWaterEntry(w http.ResponseWriter, r *http.Request) {
+	var input WaterInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if input.Ml <= 0 || input.Ml > maxWaterMl {
+		core.WriteError(w, http.StatusBadRequest, fmt.Sprintf("ml must be between 1 and %d", maxWaterMl))
+		return
+	}
+
+	e := WaterEntry{ConsumedAt: time.Now(), Ml: input.Ml}
+	if input.ConsumedAt != "" {
+		t, err := time.Parse(time.RFC3339, input.ConsumedAt)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "consumed_at must be RFC 3339")
+			return
+		}
+		e.ConsumedAt = t
+	}
+	e.Date = h.dayOf(r.Context(), e.ConsumedAt)
+
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO water_intake (date, consumed_at, ml) VALUES ($1, $2, $3) RETURNING id
+	`, e.Date, e.ConsumedAt, e.Ml).Scan(&e.ID)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, e)
+}
+
+// DeleteWaterEntry removes a drink
+func (h *Handler) DeleteWaterEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM water_intake WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Water entry not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Energy balance ---
+
+// GetEnergyBalance combines logged intake with Oura total calories per day
+// and compares the implied weight change with the smoothed weight trend.
+// Days without logged meals have no intake or balance. Query: ?days= (default 30)
+func (h *Handler) GetEnergyBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	days := 30 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= maxEnergyBalanceDay {
+			days = n
+		}
+	}
+	end := h.today(ctx)
+	start := end.AddDate(0, 0, -(days - 1))
+	from, to := formatDate(start), formatDate(end)
+
+	rows, err := h.db.Query(ctx, `
+		SELECT d.day::date,
+			m.meals, m.calories, m.protein_g, m.carbs_g, m.fat_g,
+			COALESCE(wi.ml, 0), o.activity_total_calories::double precision
+		FROM generate_series($1::date, $2::date, '1 day') AS d(day)
+		LEFT JOIN (
+			SELECT date, COUNT(*) AS meals, SUM(calories)::double precision AS calories,
+				SUM(protein_g)::double precision AS protein_g, SUM(carbs_g)::double precision AS carbs_g,
+				SUM(fat_g)::double precision AS fat_g
+			FROM meals GROUP BY date
+		) m ON m.date = d.day
+		LEFT JOIN (SELECT date, SUM(ml)::int AS ml FROM water_intake GROUP BY date) wi ON wi.date = d.day
+		LEFT JOIN oura_daily o ON o.day = d.day
+		ORDER BY d.day
+	`, from, to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	report := EnergyBalance{Days: days, From: from, To: to, History: []EnergyBalanceDay{}}
+	var sumIntake, sumBurned, sumBalance float64
+	var balanced int
+	for rows.Next() {
+		var d EnergyBalanceDay
+		var day time.Time
+		var meals *int
+		if err := rows.Scan(&day, &meals, &d.Intake, &d.ProteinG, &d.CarbsG, &d.FatG, &d.WaterMl, &d.Burned); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		d.Date = formatDate(day)
+		if meals != nil {
+			d.Meals = *meals
+			report.LoggedDays++
+		}
+		if d.Intake != nil && d.Burned != nil {
+			balance := math.Round(*d.Intake - *d.Burned)
+			d.Balance = &balance
+			sumIntake += *d.Intake
+			sumBurned += *d.Burned
+			sumBalance += balance
+			balanced++
+		}
+		report.History = append(report.History, d)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if balanced > 0 {
+		n := float64(balanced)
+		avgIntake, avgBurned, avgBalance := math.Round(sumIntake/n), math.Round(sumBurned/n), math.Round(sumBalance/n)
+		report.AvgIntake, report.AvgBurned, report.AvgBalance = &avgIntake, &avgBurned, &avgBalance
+	}
+
+	// Smoothed weight trend over the window, warmed up beforehand
+	weights, err := h.db.Query(ctx, `
+		SELECT date, weight_kg FROM weight_entries
+		WHERE date >= $1 AND date <= $2
+		ORDER BY date
+	`, formatDate(start.AddDate(0, 0, -weightTrendWarmup)), to)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var entries []WeightEntry
+	for weights.Next() {
+		var e WeightEntry
+		var day time.Time
+		if err := weights.Scan(&day, &e.WeightKg); err != nil {
+			weights.Close()
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		e.Date = formatDate(day)
+		entries = append(entries, e)
+	}
+	weights.Close()
+	if err := weights.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	smoothWeights(entries)
+
+	trendByDay := make(map[string]float64)
+	var first, last *WeightEntry
+	for i := range entries {
+		if entries[i].Date < from {
+			continue
+		}
+		trendByDay[entries[i].Date] = *entries[i].TrendKg
+		if first == nil {
+			first = &entries[i]
+		}
+		last = &entries[i]
+	}
+	for i := range report.History {
+		if t, ok := trendByDay[report.History[i].Date]; ok {
+			report.History[i].TrendKg = &t
+		}
+	}
+
+	if first != nil && last != first {
+		firstDay, _ := time.Parse("2006-01-02", first.Date)
+		lastDay, _ := time.Parse("2006-01-02", last.Date)
+		span := lastDay.Sub(firstDay).Hours() / 24
+		change := round2(*last.TrendKg - *first.TrendKg)
+		report.TrendChangeKg = &change
+		if report.AvgBurned != nil && span >= 7 {
+			implied := math.Round(*report.AvgBurned + change*kcalPerKg/span)
+			report.ImpliedIntake = &implied
+		}
+		if report.AvgBalance != nil {
+			expected := round2(*report.AvgBalance * span / kcalPerKg)
+			report.ExpectedChangeKg = &expected
+		}
+	}
+	report.Insight = energyBalanceInsight(report)
+
+	core.WriteJSON(w, http.StatusOK, report)
+}
+
+// energyBalanceInsight explains the weight trend with the logged balance
+func energyBalanceInsight(report EnergyBalance) string {
+	switch {
+	case report.LoggedDays == 0:
+		return "No meals logged in this period"
+	case report.AvgBalance == nil:
+		return "No days with both logged meals and Oura total calories"
+	case report.ExpectedChangeKg == nil || report.TrendChangeKg == nil:
+		return fmt.Sprintf("Average balance of %+.0f kcal/day on %d logged days", *report.AvgBalance, report.LoggedDays)
+	}
+
+	diff := *report.TrendChangeKg - *report.ExpectedChangeKg
+	switch {
+	case math.Abs(diff) < energyExplainedKg:
+		return fmt.Sprintf("Logged intake explains the weight trend: %+.0f kcal/day ≈ %+.1f kg, trend %+.1f kg",
+			*report.AvgBalance, *report.ExpectedChangeKg, *report.TrendChangeKg)
+	case diff > 0:
+		return fmt.Sprintf("The weight trend is %.1f kg above what logged intake implies; meals may be under-logged or burn overestimated",
+			diff)
+	default:
+		return fmt.Sprintf("The weight trend is %.1f kg below what logged intake implies; burn may be underestimated or portions over-logged",
+			-diff)
+	}
+}
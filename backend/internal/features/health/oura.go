@@ -0,0 +1,471 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultOuraAPIURL   = "https://api.ouraring.com/v2/usercollection"
+	defaultOuraTokenURL = "https://api.ouraring.com/oauth/token"
+
+	// ouraMaxRetries bounds how often a rate-limited request is retried
+	ouraMaxRetries = 5
+	// ouraMaxRetryWait caps the wait between retries, whatever Retry-After says
+	ouraMaxRetryWait = 2 * time.Minute
+)
+
+// errOuraUnauthorized is returned when the API rejects the access token
+var errOuraUnauthorized = errors.New("oura rejected the access token")
+
+// OuraClient talks to the Oura API v2 usercollection endpoints.
+// BaseURL can be pointed at a stand-in server (OURA_API_URL).
+type OuraClient struct {
+	BaseURL string
+	token   string
+	client  *http.Client
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewOuraClient creates a client authenticating with the given bearer token
+func NewOuraClient(token string) *OuraClient {
+	baseURL := os.Getenv("OURA_API_URL")
+	if baseURL == "" {
+		baseURL = defaultOuraAPIURL
+	}
+	return &OuraClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		sleep: sleepContext,
+	}
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ouraPage is the envelope shared by all list endpoints
+type ouraPage[T any] struct {
+	Data      []T     `json:"data"`
+	NextToken *string `json:"next_token"`
+}
+
+// ouraSleepContributors are the daily_sleep score contributors
+type ouraSleepContributors struct {
+	DeepSleep   *int `json:"deep_sleep"`
+	Efficiency  *int `json:"efficiency"`
+	Latency     *int `json:"latency"`
+	RemSleep    *int `json:"rem_sleep"`
+	Restfulness *int `json:"restfulness"`
+	Timing      *int `json:"timing"`
+	TotalSleep  *int `json:"total_sleep"`
+}
+
+// OuraDailySleep is a daily_sleep document
+type OuraDailySleep struct {
+	ID           string                `json:"id"`
+	Day          string                `json:"day"`
+	Score        *int                  `json:"score"`
+	Contributors ouraSleepContributors `json:"contributors"`
+}
+
+// ouraReadinessContributors are the readiness score contributors
+type ouraReadinessContributors struct {
+	ActivityBalance     *int `json:"activity_balance"`
+	BodyTemperature     *int `json:"body_temperature"`
+	HrvBalance          *int `json:"hrv_balance"`
+	PreviousDayActivity *int `json:"previous_day_activity"`
+	PreviousNight       *int `json:"previous_night"`
+	RecoveryIndex       *int `json:"recovery_index"`
+	RestingHeartRate    *int `json:"resting_heart_rate"`
+	SleepBalance        *int `json:"sleep_balance"`
+	SleepRegularity     *int `json:"sleep_regularity"`
+}
+
+// OuraDailyReadiness is a daily_readiness document
+type OuraDailyReadiness struct {
+	ID                   string                    `json:"id"`
+	Day                  string                    `json:"day"`
+	Score                *int                      `json:"score"`
+	TemperatureDeviation *float64                  `json:"temperature_deviation"`
+	Contributors         ouraReadinessContributors `json:"contributors"`
+}
+
+// OuraDailyActivity is a daily_activity document
+type OuraDailyActivity struct {
+	ID             string `json:"id"`
+	Day            string `json:"day"`
+	Score          *int   `json:"score"`
+	ActiveCalories *int   `json:"active_calories"`
+	Steps          *int   `json:"steps"`
+	TotalCalories  *int   `json:"total_calories"`
+	Contributors   struct {
+		MeetDailyTargets  *int `json:"meet_daily_targets"`
+		MoveEveryHour     *int `json:"move_every_hour"`
+		RecoveryTime      *int `json:"recovery_time"`
+		StayActive        *int `json:"stay_active"`
+		TrainingFrequency *int `json:"training_frequency"`
+		TrainingVolume    *int `json:"training_volume"`
+	} `json:"contributors"`
+}
+
+// OuraSleepSession is a sleep document (one per sleep period)
+type OuraSleepSession struct {
+	ID                 string    `json:"id"`
+	Day                string    `json:"day"`
+	Type               string    `json:"type"` // long_sleep, sleep, late_nap, rest
+	BedtimeStart       time.Time `json:"bedtime_start"`
+	BedtimeEnd         time.Time `json:"bedtime_end"`
+	TotalSleepDuration *int      `json:"total_sleep_duration"`
+	DeepSleepDuration  *int      `json:"deep_sleep_duration"`
+	RemSleepDuration   *int      `json:"rem_sleep_duration"`
+	LightSleepDuration *int      `json:"light_sleep_duration"`
+	AwakeTime          *int      `json:"awake_time"`
+	AverageHeartRate   *float64  `json:"average_heart_rate"`
+	LowestHeartRate    *int      `json:"lowest_heart_rate"`
+	AverageHrv         *int      `json:"average_hrv"`
+	Readiness          *struct {
+		Score                *int                      `json:"score"`
+		TemperatureDeviation *float64                  `json:"temperature_deviation"`
+		Contributors         ouraReadinessContributors `json:"contributors"`
+	} `json:"readiness"`
+}
+
+// OuraWorkout is a workout document
+type OuraWorkout struct {
+	ID            string    `json:"id"`
+	Day           string    `json:"day"`
+	Activity      string    `json:"activity"`
+	Label         *string   `json:"label"`
+	Intensity     string    `json:"intensity"`
+	Calories      *float64  `json:"calories"`
+	Distance      *float64  `json:"distance"`
+	Source        string    `json:"source"`
+	StartDatetime time.Time `json:"start_datetime"`
+	EndDatetime   time.Time `json:"end_datetime"`
+}
+
+// DailySleep fetches daily_sleep documents for the inclusive day range
+func (c *OuraClient) DailySleep(ctx context.Context, start, end time.Time) ([]OuraDailySleep, error) {
+	return fetchOuraCollection[OuraDailySleep](ctx, c, "daily_sleep", start, end)
+}
+
+// DailyReadiness fetches daily_readiness documents for the inclusive day range
+func (c *OuraClient) DailyReadiness(ctx context.Context, start, end time.Time) ([]OuraDailyReadiness, error) {
+	return fetchOuraCollection[OuraDailyReadiness](ctx, c, "daily_readiness", start, end)
+}
+
+// DailyActivity fetches daily_activity documents for the inclusive day range
+func (c *OuraClient) DailyActivity(ctx context.Context, start, end time.Time) ([]OuraDailyActivity, error) {
+	return fetchOuraCollection[OuraDailyActivity](ctx, c, "daily_activity", start, end)
+}
+
+// SleepSessions fetches sleep documents for the inclusive day range
+func (c *OuraClient) SleepSessions(ctx context.Context, start, end time.Time) ([]OuraSleepSession, error) {
+	return fetchOuraCollection[OuraSleepSession](ctx, c, "sleep", start, end)
+}
+
+// Workouts fetches workout documents for the inclusive day range
+func (c *OuraClient) Workouts(ctx context.Context, start, end time.Time) ([]OuraWorkout, error) {
+	return fetchOuraCollection[OuraWorkout](ctx, c, "workout", start, end)
+}
+
+// fetchOuraCollection pages through an endpoint by following next_token.
+// Oura treats end_date as exclusive for some collections, so the range is
+// widened by a day; callers key everything by the document's own day.
+func fetchOuraCollection[T any](ctx context.Context, c *OuraClient, endpoint string, start, end time.Time) ([]T, error) {
+	params := url.Values{}
+	params.Set("start_date", formatDate(start))
+	params.Set("end_date", formatDate(end.AddDate(0, 0, 1)))
+
+	var all []T
+	for {
+		var page ouraPage[T]
+		if err := c.get(ctx, endpoint, params, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Data...)
+
+		if page.NextToken == nil || *page.NextToken == "" {
+			return all, nil
+		}
+		params.Set("next_token", *page.NextToken)
+	}
+}
+
+// get performs a GET request, retrying when rate limited (429) or when Oura
+// is temporarily unavailable
+func (c *OuraClient) get(ctx context.Context, endpoint string, params url.Values, out interface{}) error {
+	reqURL := c.BaseURL + "/" + endpoint + "?" + params.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			err := json.NewDecoder(resp.Body).Decode(out)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("oura %s: %w", endpoint, err)
+			}
+			return nil
+
+		case resp.StatusCode == http.StatusUnauthorized:
+			resp.Body.Close()
+			return errOuraUnauthorized
+
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			wait := retryAfter(resp.Header.Get("Retry-After"), attempt)
+			resp.Body.Close()
+			if attempt+1 >= ouraMaxRetries {
+				return fmt.Errorf("oura %s: status %d after %d attempts", endpoint, resp.StatusCode, attempt+1)
+			}
+			if err := c.sleep(ctx, wait); err != nil {
+				return err
+			}
+
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("oura %s returned status %d", endpoint, resp.StatusCode)
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header (seconds or HTTP date), falling back
+// to exponential backoff starting at one second
+func retryAfter(header string, attempt int) time.Duration {
+	wait := time.Second << attempt
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		wait = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		wait = time.Until(t)
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > ouraMaxRetryWait {
+		wait = ouraMaxRetryWait
+	}
+	return wait
+}
+
+// ouraTokenResponse is returned by the OAuth token endpoint
+type ouraTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// refreshOuraToken exchanges a refresh token for a new access token using
+// OURA_CLIENT_ID and OURA_CLIENT_SECRET. Oura rotates refresh tokens, so the
+// returned refresh token must replace the stored one.
+func refreshOuraToken(ctx context.Context, client *http.Client, refreshToken string) (*ouraTokenResponse, error) {
+	clientID := os.Getenv("OURA_CLIENT_ID")
+	clientSecret := os.Getenv("OURA_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, errors.New("OURA_CLIENT_ID and OURA_CLIENT_SECRET are required to refresh OAuth tokens")
+	}
+
+	tokenURL := os.Getenv("OURA_TOKEN_URL")
+	if tokenURL == "" {
+		tokenURL = defaultOuraTokenURL
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oura token refresh returned status %d", resp.StatusCode)
+	}
+
+	var token ouraTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("oura token refresh returned no access token")
+	}
+	return &token, nil
+}
+
+// mergeOuraDays maps the daily collections onto one OuraDailyInput per day.
+// The readiness embedded in the main sleep session fills in days for which
+// daily_readiness has no document yet.
+func mergeOuraDays(sleep []OuraDailySleep, readiness []OuraDailyReadiness, activity []OuraDailyActivity, sessions []OuraSleepSession) []OuraDailyInput {
+	days := make(map[string]*OuraDailyInput)
+	day := func(d string) *OuraDailyInput {
+		if in, ok := days[d]; ok {
+			return in
+		}
+		in := &OuraDailyInput{Day: d}
+		days[d] = in
+		return in
+	}
+
+	for _, s := range sleep {
+		in := day(s.Day)
+		in.SleepScore = s.Score
+		in.SleepDeepSleep = s.Contributors.DeepSleep
+		in.SleepEfficiency = s.Contributors.Efficiency
+		in.SleepLatency = s.Contributors.Latency
+		in.SleepRemSleep = s.Contributors.RemSleep
+		in.SleepRestfulness = s.Contributors.Restfulness
+		in.SleepTiming = s.Contributors.Timing
+		in.SleepTotalSleep = s.Contributors.TotalSleep
+	}
+
+	applyReadiness := func(in *OuraDailyInput, score *int, temp *float64, c ouraReadinessContributors) {
+		in.ReadinessScore = score
+		in.TemperatureDeviation = temp
+		in.ReadinessActivityBalance = c.ActivityBalance
+		in.ReadinessBodyTemperature = c.BodyTemperature
+		in.ReadinessHrvBalance = c.HrvBalance
+		in.ReadinessPreviousDayActivity = c.PreviousDayActivity
+		in.ReadinessPreviousNight = c.PreviousNight
+		in.ReadinessRecoveryIndex = c.RecoveryIndex
+		in.ReadinessRestingHeartRate = c.RestingHeartRate
+		in.ReadinessSleepBalance = c.SleepBalance
+		in.ReadinessSleepRegularity = c.SleepRegularity
+	}
+
+	for _, rd := range readiness {
+		applyReadiness(day(rd.Day), rd.Score, rd.TemperatureDeviation, rd.Contributors)
+	}
+
+	for _, s := range sessions {
+		if s.Type != "long_sleep" || s.Readiness == nil {
+			continue
+		}
+		in := day(s.Day)
+		if in.ReadinessScore == nil {
+			applyReadiness(in, s.Readiness.Score, s.Readiness.TemperatureDeviation, s.Readiness.Contributors)
+		}
+	}
+
+	for _, a := range activity {
+		in := day(a.Day)
+		in.ActivityScore = a.Score
+		in.ActivityActiveCalories = a.ActiveCalories
+		in.ActivitySteps = a.Steps
+		in.ActivityTotalCalories = a.TotalCalories
+		in.ActivityMeetDailyTargets = a.Contributors.MeetDailyTargets
+		in.ActivityMoveEveryHour = a.Contributors.MoveEveryHour
+		in.ActivityRecoveryTime = a.Contributors.RecoveryTime
+		in.ActivityStayActive = a.Contributors.StayActive
+		in.ActivityTrainingFrequency = a.Contributors.TrainingFrequency
+		in.ActivityTrainingVolume = a.Contributors.TrainingVolume
+	}
+
+	result := make([]OuraDailyInput, 0, len(days))
+	for _, in := range days {
+		result = append(result, *in)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Day < result[j].Day })
+	return result
+}
+
+// ouraSleepSessionInput maps an Oura sleep period onto sleep_sessions.
+// Anything but the main (long) sleep of a day counts as a nap.
+func ouraSleepSessionInput(s OuraSleepSession) SleepSessionInput {
+	id := s.ID
+	return SleepSessionInput{
+		Day:               s.Day,
+		BedtimeStart:      s.BedtimeStart.Format(time.RFC3339),
+		BedtimeEnd:        s.BedtimeEnd.Format(time.RFC3339),
+		TotalSleepSeconds: s.TotalSleepDuration,
+		DeepSleepSeconds:  s.DeepSleepDuration,
+		RemSleepSeconds:   s.RemSleepDuration,
+		LightSleepSeconds: s.LightSleepDuration,
+		AwakeSeconds:      s.AwakeTime,
+		AverageHeartRate:  s.AverageHeartRate,
+		LowestHeartRate:   s.LowestHeartRate,
+		AverageHrv:        s.AverageHrv,
+		IsNap:             s.Type != "long_sleep",
+		Source:            "oura",
+		ExternalID:        &id,
+	}
+}
+
+// ouraStrengthActivities are Oura activity types stored as strength workouts;
+// everything else is cardio
+var ouraStrengthActivities = map[string]bool{
+	"strength_training":   true,
+	"weight_training":     true,
+	"functional_training": true,
+	"crossfit":            true,
+	"bodyweight":          true,
+}
+
+// ouraWorkoutInput maps an Oura workout onto the workouts table
+func ouraWorkoutInput(wo OuraWorkout) WorkoutInput {
+	workoutType := "cardio"
+	if ouraStrengthActivities[wo.Activity] {
+		workoutType = "strength"
+	}
+
+	name := strings.ReplaceAll(wo.Activity, "_", " ")
+	if wo.Label != nil && *wo.Label != "" {
+		name = *wo.Label
+	}
+	notes := name
+	if mins := int(wo.EndDatetime.Sub(wo.StartDatetime).Minutes()); mins > 0 {
+		notes += fmt.Sprintf(", %d min", mins)
+	}
+	if wo.Distance != nil && *wo.Distance > 0 {
+		notes += fmt.Sprintf(", %.1f km", *wo.Distance/1000)
+	}
+	if wo.Calories != nil && *wo.Calories > 0 {
+		notes += fmt.Sprintf(", %d kcal", int(*wo.Calories))
+	}
+
+	return WorkoutInput{
+		Date:  wo.Day,
+		Type:  workoutType,
+		Notes: notes,
+	}
+}
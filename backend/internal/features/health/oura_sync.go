@@ -0,0 +1,617 @@
+package health
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// ouraBackfillDays is how far back missing days are looked for
+	ouraBackfillDays = 90
+	// ouraResyncDays re-fetches the days before the last synced day, since
+	// Oura keeps revising scores once the next night has been recorded
+	ouraResyncDays = 2
+	// ouraTokenLeeway refreshes OAuth access tokens this long before expiry
+	ouraTokenLeeway = 5 * time.Minute
+	// ouraSyncTimeout bounds a single background sync run
+	ouraSyncTimeout = 30 * time.Minute
+)
+
+var (
+	errOuraKeyMissing   = errors.New("OURA_TOKEN_KEY is not configured")
+	errOuraNotConnected = errors.New("Oura is not connected")
+	errOuraSyncRunning  = errors.New("an Oura sync is already running")
+)
+
+// OuraSyncer pulls data from the Oura API into oura_daily, sleep_sessions
+// and workouts
+type OuraSyncer struct {
+	db     *pgxpool.Pool
+	client *http.Client
+
+	mu         sync.Mutex
+	running    bool
+	lastResult *OuraSyncResult
+}
+
+// NewOuraSyncer creates a new Oura syncer
+func NewOuraSyncer(db *pgxpool.Pool) *OuraSyncer {
+	return &OuraSyncer{
+		db: db,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// --- Token storage ---
+
+// ouraTokenCipher derives an AES-256-GCM cipher from OURA_TOKEN_KEY
+func ouraTokenCipher() (cipher.AEAD, error) {
+	secret := os.Getenv("OURA_TOKEN_KEY")
+	if secret == "" {
+		return nil, errOuraKeyMissing
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealOuraToken encrypts a token; the nonce is stored in front of the ciphertext
+func sealOuraToken(token string) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	aead, err := ouraTokenCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, []byte(token), nil), nil
+}
+
+// openOuraToken decrypts a token sealed by sealOuraToken
+func openOuraToken(sealed []byte) (string, error) {
+	if len(sealed) == 0 {
+		return "", nil
+	}
+	aead, err := ouraTokenCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("stored Oura token is corrupt")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("stored Oura token could not be decrypted; was OURA_TOKEN_KEY changed?")
+	}
+	return string(plain), nil
+}
+
+// ouraCredentials is the decrypted content of oura_connection
+type ouraCredentials struct {
+	tokenType    string
+	accessToken  string
+	refreshToken string
+	expiresAt    *time.Time
+}
+
+// loadCredentials reads and decrypts the stored connection
+func (s *OuraSyncer) loadCredentials(ctx context.Context) (*ouraCredentials, error) {
+	var creds ouraCredentials
+	var access, refresh []byte
+	err := s.db.QueryRow(ctx, `
+		SELECT token_type, access_token, refresh_token, expires_at
+		FROM oura_connection WHERE id = 1
+	`).Scan(&creds.tokenType, &access, &refresh, &creds.expiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, errOuraNotConnected
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if creds.accessToken, err = openOuraToken(access); err != nil {
+		return nil, err
+	}
+	if creds.refreshToken, err = openOuraToken(refresh); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// storeCredentials encrypts and saves the connection, keeping sync bookkeeping
+func (s *OuraSyncer) storeCredentials(ctx context.Context, creds *ouraCredentials) error {
+	access, err := sealOuraToken(creds.accessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := sealOuraToken(creds.refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO oura_connection (id, token_type, access_token, refresh_token, expires_at)
+		VALUES (1, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			token_type = EXCLUDED.token_type,
+			access_token = EXCLUDED.access_token,
+			refresh_token = EXCLUDED.refresh_token,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = NOW()
+	`, creds.tokenType, access, refresh, creds.expiresAt)
+	return err
+}
+
+// accessToken returns a usable bearer token, refreshing OAuth tokens when
+// they are about to expire (or always, when force is set)
+func (s *OuraSyncer) accessToken(ctx context.Context, force bool) (string, error) {
+	creds, err := s.loadCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if creds.tokenType == "personal" {
+		if force {
+			return "", errOuraUnauthorized
+		}
+		return creds.accessToken, nil
+	}
+
+	fresh := creds.accessToken != "" && creds.expiresAt != nil &&
+		time.Until(*creds.expiresAt) > ouraTokenLeeway
+	if fresh && !force {
+		return creds.accessToken, nil
+	}
+
+	token, err := refreshOuraToken(ctx, s.client, creds.refreshToken)
+	if err != nil {
+		return "", err
+	}
+	creds.accessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		creds.refreshToken = token.RefreshToken
+	}
+	creds.expiresAt = nil
+	if token.ExpiresIn > 0 {
+		expires := time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+		creds.expiresAt = &expires
+	}
+	if err := s.storeCredentials(ctx, creds); err != nil {
+		return "", err
+	}
+	return creds.accessToken, nil
+}
+
+// --- Sync ---
+
+// syncRanges works out which days to fetch. An explicit start backfills
+// start..end as a whole; otherwise the days from shortly before the last
+// synced day up to end are fetched, plus any days within the backfill window
+// that have no Oura scores (missing, or only filled from another source).
+func (s *OuraSyncer) syncRanges(ctx context.Context, start *time.Time, end time.Time) ([]OuraSyncRange, error) {
+	if start != nil {
+		return []OuraSyncRange{{Start: formatDate(*start), End: formatDate(end)}}, nil
+	}
+
+	windowStart := end.AddDate(0, 0, -ouraBackfillDays)
+	recentStart := windowStart
+
+	var lastSynced *time.Time
+	err := s.db.QueryRow(ctx, `SELECT last_synced_day FROM oura_connection WHERE id = 1`).Scan(&lastSynced)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+	if lastSynced != nil {
+		if resync := lastSynced.AddDate(0, 0, -ouraResyncDays); resync.After(windowStart) {
+			recentStart = resync
+		}
+	}
+	if recentStart.After(end) {
+		recentStart = end
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT d::date
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') d
+		LEFT JOIN oura_daily o ON o.day = d::date
+		WHERE o.id IS NULL
+			OR (o.sleep_score IS NULL AND o.readiness_score IS NULL AND o.activity_score IS NULL)
+		ORDER BY d
+	`, formatDate(windowStart), formatDate(recentStart.AddDate(0, 0, -1)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ranges []OuraSyncRange
+	var prev time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		if len(ranges) > 0 && day.Equal(prev.AddDate(0, 0, 1)) {
+			ranges[len(ranges)-1].End = formatDate(day)
+		} else {
+			ranges = append(ranges, OuraSyncRange{Start: formatDate(day), End: formatDate(day)})
+		}
+		prev = day
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A gap ending the day before the recent range simply extends it
+	if n := len(ranges); n > 0 && ranges[n-1].End == formatDate(recentStart.AddDate(0, 0, -1)) {
+		ranges[n-1].End = formatDate(end)
+	} else {
+		ranges = append(ranges, OuraSyncRange{Start: formatDate(recentStart), End: formatDate(end)})
+	}
+	return ranges, nil
+}
+
+// Sync fetches all configured ranges and stores them. start may be nil to
+// sync incrementally.
+func (s *OuraSyncer) Sync(ctx context.Context, start *time.Time, end time.Time) (*OuraSyncResult, error) {
+	result := &OuraSyncResult{StartedAt: time.Now()}
+
+	token, err := s.accessToken(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges, err := s.syncRanges(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	result.Ranges = ranges
+
+	client := NewOuraClient(token)
+	for _, rng := range ranges {
+		err := s.syncRange(ctx, client, rng, result)
+		if err == errOuraUnauthorized {
+			// The access token may have been revoked early; refresh once
+			if client.token, err = s.accessToken(ctx, true); err != nil {
+				return nil, err
+			}
+			err = s.syncRange(ctx, client, rng, result)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Re-detect illness episodes from the first synced day
+	first := end
+	for _, rng := range ranges {
+		if day, _ := time.Parse("2006-01-02", rng.Start); day.Before(first) {
+			first = day
+		}
+	}
+	if err := detectAnomalies(ctx, s.db, first, end); err != nil {
+		return nil, err
+	}
+
+	result.LastSyncedDay = formatDate(end)
+	result.FinishedAt = time.Now()
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE oura_connection SET
+			last_synced_day = GREATEST(COALESCE(last_synced_day, $1::date), $1::date),
+			last_sync_at = NOW(),
+			last_error = '',
+			updated_at = NOW()
+		WHERE id = 1
+	`, result.LastSyncedDay)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// syncRange fetches one range from every endpoint and upserts the result
+func (s *OuraSyncer) syncRange(ctx context.Context, client *OuraClient, rng OuraSyncRange, result *OuraSyncResult) error {
+	start, _ := time.Parse("2006-01-02", rng.Start)
+	end, _ := time.Parse("2006-01-02", rng.End)
+
+	sleep, err := client.DailySleep(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	readiness, err := client.DailyReadiness(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	activity, err := client.DailyActivity(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	sessions, err := client.SleepSessions(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	workouts, err := client.Workouts(ctx, start, end)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	inRange := func(day string) bool { return day >= rng.Start && day <= rng.End }
+	var skipped []IngestionRowError
+	var skippedSessions []interface{}
+
+	for _, input := range mergeOuraDays(sleep, readiness, activity, sessions) {
+		if !inRange(input.Day) {
+			continue
+		}
+		if _, err := upsertOuraDaily(ctx, tx, input); err != nil {
+			return err
+		}
+		result.DaysUpserted++
+	}
+
+	for _, session := range sessions {
+		if !inRange(session.Day) || session.Type == "rest" {
+			continue
+		}
+		input := ouraSleepSessionInput(session)
+		start, end, err := validateSleepSession(&input)
+		if err != nil {
+			skipped = append(skipped, IngestionRowError{Index: -1, Day: session.Day, Error: "sleep session: " + err.Error()})
+			skippedSessions = append(skippedSessions, session)
+			continue
+		}
+		if _, err := upsertSleepSession(ctx, tx, input, start, end); err != nil {
+			return err
+		}
+		result.SessionsUpserted++
+	}
+
+	for _, wo := range workouts {
+		if !inRange(wo.Day) {
+			continue
+		}
+		input := ouraWorkoutInput(wo)
+		var calories *int
+		if wo.Calories != nil {
+			kcal := int(*wo.Calories + 0.5)
+			calories = &kcal
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO workouts (date, type, notes, source, external_id, started_at, duration_seconds, distance_meters, calories)
+			VALUES ($1, $2, $3, 'oura', $4, $5, $6, $7, $8)
+			ON CONFLICT (source, external_id) DO UPDATE SET
+				date = EXCLUDED.date,
+				type = EXCLUDED.type,
+				notes = EXCLUDED.notes,
+				started_at = EXCLUDED.started_at,
+				duration_seconds = EXCLUDED.duration_seconds,
+				distance_meters = EXCLUDED.distance_meters,
+				calories = EXCLUDED.calories
+		`, input.Date, input.Type, input.Notes, wo.ID, wo.StartDatetime,
+			int(wo.EndDatetime.Sub(wo.StartDatetime).Seconds()), wo.Distance, calories)
+		if err != nil {
+			return err
+		}
+		result.WorkoutsUpserted++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	return recordIngestionErrors(ctx, s.db, ingestionSourceOuraSync, skipped, skippedSessions)
+}
+
+// Start runs a sync in the background; only one sync runs at a time
+func (s *OuraSyncer) Start(start *time.Time, end time.Time) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errOuraSyncRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ouraSyncTimeout)
+		defer cancel()
+
+		result, err := s.Sync(ctx, start, end)
+		if err != nil {
+			s.db.Exec(ctx, `UPDATE oura_connection SET last_error = $1, updated_at = NOW() WHERE id = 1`, err.Error())
+			recordIngestionErrors(ctx, s.db, ingestionSourceOuraSync, []IngestionRowError{{Index: -1, Error: err.Error()}}, nil)
+		}
+
+		s.mu.Lock()
+		s.running = false
+		if result != nil {
+			s.lastResult = result
+		}
+		s.mu.Unlock()
+	}()
+	return nil
+}
+
+// status describes the connection, whether a sync is running and its last result
+func (s *OuraSyncer) status(ctx context.Context) (*OuraConnection, error) {
+	var conn OuraConnection
+	var lastSynced *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT token_type, expires_at, last_synced_day, last_sync_at, last_error
+		FROM oura_connection WHERE id = 1
+	`).Scan(&conn.TokenType, &conn.ExpiresAt, &lastSynced, &conn.LastSyncAt, &conn.LastError)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+	conn.Connected = err == nil
+	if lastSynced != nil {
+		day := formatDate(*lastSynced)
+		conn.LastSyncedDay = &day
+	}
+
+	s.mu.Lock()
+	conn.Syncing = s.running
+	conn.LastResult = s.lastResult
+	s.mu.Unlock()
+
+	return &conn, nil
+}
+
+// --- Handlers ---
+
+// writeOuraError maps Oura sync errors onto HTTP statuses
+func writeOuraError(w http.ResponseWriter, err error) {
+	switch err {
+	case errOuraKeyMissing:
+		core.WriteError(w, http.StatusServiceUnavailable, err.Error())
+	case errOuraNotConnected:
+		core.WriteError(w, http.StatusNotFound, err.Error())
+	case errOuraSyncRunning:
+		core.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// GetOuraConnection returns the Oura connection and sync status
+func (h *Handler) GetOuraConnection(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.oura.status(r.Context())
+	if err != nil {
+		writeOuraError(w, err)
+		return
+	}
+	core.WriteJSON(w, http.StatusOK, conn)
+}
+
+// PutOuraConnection stores a personal access token or OAuth tokens.
+// Tokens are encrypted with OURA_TOKEN_KEY and never returned.
+func (h *Handler) PutOuraConnection(w http.ResponseWriter, r *http.Request) {
+	var input OuraConnectionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	var creds ouraCredentials
+	switch {
+	case input.PersonalAccessToken != "" && input.RefreshToken != "":
+		core.WriteError(w, http.StatusBadRequest, "provide either personal_access_token or refresh_token, not both")
+		return
+	case input.PersonalAccessToken != "":
+		creds = ouraCredentials{tokenType: "personal", accessToken: input.PersonalAccessToken}
+	case input.RefreshToken != "":
+		creds = ouraCredentials{tokenType: "oauth", accessToken: input.AccessToken, refreshToken: input.RefreshToken}
+		if input.AccessToken != "" && input.ExpiresIn > 0 {
+			expires := time.Now().Add(time.Duration(input.ExpiresIn) * time.Second)
+			creds.expiresAt = &expires
+		}
+	default:
+		core.WriteError(w, http.StatusBadRequest, "personal_access_token or refresh_token is required")
+		return
+	}
+
+	if err := h.oura.storeCredentials(r.Context(), &creds); err != nil {
+		writeOuraError(w, err)
+		return
+	}
+
+	conn, err := h.oura.status(r.Context())
+	if err != nil {
+		writeOuraError(w, err)
+		return
+	}
+	core.WriteJSON(w, http.StatusOK, conn)
+}
+
+// DeleteOuraConnection forgets the stored Oura credentials and sync state
+func (h *Handler) DeleteOuraConnection(w http.ResponseWriter, r *http.Request) {
+	result, err := h.db.Exec(r.Context(), `DELETE FROM oura_connection WHERE id = 1`)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, errOuraNotConnected.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SyncOura starts a background sync. Without parameters it syncs
+// incrementally from the last synced day and fills gaps; ?start=&end=
+// (YYYY-MM-DD) backfill an explicit range.
+func (h *Handler) SyncOura(w http.ResponseWriter, r *http.Request) {
+	today := h.today(r.Context())
+	end := today
+	if e := r.URL.Query().Get("end"); e != "" {
+		parsed, err := time.Parse("2006-01-02", e)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "end must be YYYY-MM-DD")
+			return
+		}
+		if parsed.After(today) {
+			parsed = today
+		}
+		end = parsed
+	}
+
+	var start *time.Time
+	if s := r.URL.Query().Get("start"); s != "" {
+		parsed, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "start must be YYYY-MM-DD")
+			return
+		}
+		if parsed.After(end) {
+			core.WriteError(w, http.StatusBadRequest, "start must not be after end")
+			return
+		}
+		start = &parsed
+	}
+
+	// Fail fast when there is nothing to sync with
+	if _, err := h.oura.loadCredentials(r.Context()); err != nil {
+		writeOuraError(w, err)
+		return
+	}
+
+	if err := h.oura.Start(start, end); err != nil {
+		writeOuraError(w, err)
+		return
+	}
+
+	conn, err := h.oura.status(r.Context())
+	if err != nil {
+		writeOuraError(w, err)
+		return
+	}
+	core.WriteJSON(w, http.StatusAccepted, conn)
+}
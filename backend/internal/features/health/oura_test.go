@@ -0,0 +1,333 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ouraStandIn serves canned Oura responses and records the requests made
+type ouraStandIn struct {
+	handler func(w http.ResponseWriter, r *http.Request, call int)
+
+	mu    sync.Mutex
+	calls []*http.Request
+}
+
+func (s *ouraStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls = append(s.calls, r)
+	call := len(s.calls)
+	s.mu.Unlock()
+	s.handler(w, r, call)
+}
+
+// newTestOuraClient points a client at a stand-in server and records the
+// waits instead of sleeping
+func newTestOuraClient(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, call int)) (*OuraClient, *ouraStandIn, *[]time.Duration) {
+	t.Helper()
+	standIn := &ouraStandIn{handler: handler}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("OURA_API_URL", srv.URL+"/")
+	c := NewOuraClient("test-token")
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return c, standIn, &waits
+}
+
+func TestOuraClientFollowsNextToken(t *testing.T) {
+	c, standIn, _ := newTestOuraClient(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		if r.URL.Path != "/daily_sleep" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("next_token") {
+		case "":
+			fmt.Fprint(w, `{"data":[{"id":"a","day":"2024-03-01","score":80}],"next_token":"page2"}`)
+		case "page2":
+			fmt.Fprint(w, `{"data":[{"id":"b","day":"2024-03-02","score":81}],"next_token":"page3"}`)
+		case "page3":
+			fmt.Fprint(w, `{"data":[{"id":"c","day":"2024-03-03","score":82}],"next_token":null}`)
+		default:
+			http.Error(w, "unknown token", http.StatusBadRequest)
+		}
+	})
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	docs, err := c.DailySleep(context.Background(), start, end)
+	if err != nil {
+		t.Fatalf("DailySleep: %v", err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("got %d documents, want 3", len(docs))
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if docs[i].ID != want {
+			t.Errorf("document %d: id %q, want %q", i, docs[i].ID, want)
+		}
+	}
+
+	if len(standIn.calls) != 3 {
+		t.Fatalf("made %d requests, want 3", len(standIn.calls))
+	}
+	for i, r := range standIn.calls {
+		q := r.URL.Query()
+		if got := q.Get("start_date"); got != "2024-03-01" {
+			t.Errorf("request %d: start_date %q", i, got)
+		}
+		// end_date is widened by a day
+		if got := q.Get("end_date"); got != "2024-03-04" {
+			t.Errorf("request %d: end_date %q", i, got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("request %d: Authorization %q", i, got)
+		}
+	}
+}
+
+func TestOuraClientRetriesRateLimit(t *testing.T) {
+	c, standIn, waits := newTestOuraClient(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		if call <= 2 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"w1","day":"2024-03-01","activity":"running","intensity":"moderate","source":"manual",
+			"start_datetime":"2024-03-01T07:00:00+01:00","end_datetime":"2024-03-01T07:45:00+01:00"}]}`)
+	})
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	docs, err := c.Workouts(context.Background(), day, day)
+	if err != nil {
+		t.Fatalf("Workouts: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "w1" {
+		t.Fatalf("got %+v, want the one workout", docs)
+	}
+	if len(standIn.calls) != 3 {
+		t.Errorf("made %d requests, want 3", len(standIn.calls))
+	}
+	if len(*waits) != 2 {
+		t.Fatalf("waited %d times, want 2", len(*waits))
+	}
+	for i, d := range *waits {
+		if d != 7*time.Second {
+			t.Errorf("wait %d: %v, want 7s from Retry-After", i, d)
+		}
+	}
+}
+
+func TestOuraClientGivesUpAfterMaxRetries(t *testing.T) {
+	c, standIn, waits := newTestOuraClient(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := c.DailyActivity(context.Background(), day, day); err == nil {
+		t.Fatal("expected an error once the retries are used up")
+	}
+	if len(standIn.calls) != ouraMaxRetries {
+		t.Errorf("made %d requests, want %d", len(standIn.calls), ouraMaxRetries)
+	}
+	// Without Retry-After the backoff doubles from one second
+	for i, d := range *waits {
+		if want := time.Second << i; d != want {
+			t.Errorf("wait %d: %v, want %v", i, d, want)
+		}
+	}
+}
+
+func TestOuraClientUnauthorized(t *testing.T) {
+	c, standIn, waits := newTestOuraClient(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	_, err := c.DailyReadiness(context.Background(), day, day)
+	if !errors.Is(err, errOuraUnauthorized) {
+		t.Fatalf("got error %v, want errOuraUnauthorized", err)
+	}
+	if len(standIn.calls) != 1 || len(*waits) != 0 {
+		t.Errorf("a 401 must not be retried: %d requests, %d waits", len(standIn.calls), len(*waits))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{"", 0, time.Second},
+		{"", 3, 8 * time.Second},
+		{"12", 0, 12 * time.Second},
+		{"0", 2, 0},
+		{"-5", 1, 2 * time.Second},
+		{"3600", 0, ouraMaxRetryWait},
+		{"soon", 1, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, tt.attempt); got != tt.want {
+			t.Errorf("retryAfter(%q, %d) = %v, want %v", tt.header, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestMergeOuraDays(t *testing.T) {
+	c, _, _ := newTestOuraClient(t, func(w http.ResponseWriter, r *http.Request, call int) {
+		switch r.URL.Path {
+		case "/daily_sleep":
+			fmt.Fprint(w, `{"data":[{"id":"s1","day":"2024-03-01","score":78,
+				"contributors":{"deep_sleep":70,"efficiency":90,"latency":80,"rem_sleep":65,"restfulness":60,"timing":95,"total_sleep":75}}]}`)
+		case "/daily_readiness":
+			fmt.Fprint(w, `{"data":[{"id":"r1","day":"2024-03-01","score":84,"temperature_deviation":-0.2,
+				"contributors":{"hrv_balance":88,"resting_heart_rate":91}}]}`)
+		case "/daily_activity":
+			fmt.Fprint(w, `{"data":[{"id":"a1","day":"2024-03-02","score":90,"active_calories":540,"steps":11234,"total_calories":2700,
+				"contributors":{"stay_active":85,"training_volume":97}}]}`)
+		case "/sleep":
+			fmt.Fprint(w, `{"data":[
+				{"id":"p1","day":"2024-03-01","type":"long_sleep","bedtime_start":"2024-02-29T23:10:00+01:00","bedtime_end":"2024-03-01T07:00:00+01:00",
+					"readiness":{"score":50,"contributors":{}}},
+				{"id":"p2","day":"2024-03-02","type":"long_sleep","bedtime_start":"2024-03-01T23:30:00+01:00","bedtime_end":"2024-03-02T06:45:00+01:00",
+					"readiness":{"score":72,"temperature_deviation":0.4,"contributors":{"hrv_balance":70}}},
+				{"id":"p3","day":"2024-03-03","type":"late_nap","bedtime_start":"2024-03-03T14:00:00+01:00","bedtime_end":"2024-03-03T14:30:00+01:00",
+					"readiness":{"score":99,"contributors":{}}}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	sleep, err := c.DailySleep(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readiness, err := c.DailyReadiness(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activity, err := c.DailyActivity(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := c.SleepSessions(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	days := mergeOuraDays(sleep, readiness, activity, sessions)
+	if len(days) != 2 {
+		t.Fatalf("got %d days, want 2 (the nap adds no day)", len(days))
+	}
+
+	first, second := days[0], days[1]
+	if first.Day != "2024-03-01" || second.Day != "2024-03-02" {
+		t.Fatalf("days %q, %q not sorted", first.Day, second.Day)
+	}
+
+	checkInt := func(name string, got *int, want int) {
+		t.Helper()
+		if got == nil {
+			t.Errorf("%s: nil, want %d", name, want)
+		} else if *got != want {
+			t.Errorf("%s: %d, want %d", name, *got, want)
+		}
+	}
+	checkNil := func(name string, got *int) {
+		t.Helper()
+		if got != nil {
+			t.Errorf("%s: %d, want nil", name, *got)
+		}
+	}
+
+	// Day one: daily_sleep plus daily_readiness, which wins over the session
+	checkInt("sleep score", first.SleepScore, 78)
+	checkInt("deep sleep", first.SleepDeepSleep, 70)
+	checkInt("timing", first.SleepTiming, 95)
+	checkInt("readiness score", first.ReadinessScore, 84)
+	checkInt("hrv balance", first.ReadinessHrvBalance, 88)
+	checkInt("resting heart rate", first.ReadinessRestingHeartRate, 91)
+	if first.TemperatureDeviation == nil || *first.TemperatureDeviation != -0.2 {
+		t.Errorf("temperature deviation: %v, want -0.2", first.TemperatureDeviation)
+	}
+	checkNil("activity score", first.ActivityScore)
+
+	// Day two: activity, and readiness from the main sleep session
+	checkInt("activity score", second.ActivityScore, 90)
+	checkInt("steps", second.ActivitySteps, 11234)
+	checkInt("active calories", second.ActivityActiveCalories, 540)
+	checkInt("total calories", second.ActivityTotalCalories, 2700)
+	checkInt("stay active", second.ActivityStayActive, 85)
+	checkInt("training volume", second.ActivityTrainingVolume, 97)
+	checkInt("readiness score", second.ReadinessScore, 72)
+	checkInt("hrv balance", second.ReadinessHrvBalance, 70)
+	if second.TemperatureDeviation == nil || *second.TemperatureDeviation != 0.4 {
+		t.Errorf("temperature deviation: %v, want 0.4", second.TemperatureDeviation)
+	}
+	checkNil("sleep score", second.SleepScore)
+}
+
+func TestOuraTokenRoundTrip(t *testing.T) {
+	t.Setenv("OURA_TOKEN_KEY", "first key")
+
+	sealed, err := sealOuraToken("access-token-123")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if string(sealed) == "access-token-123" {
+		t.Fatal("token stored in plain text")
+	}
+
+	again, err := sealOuraToken("access-token-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) == string(sealed) {
+		t.Error("sealing twice gave the same bytes; the nonce is not random")
+	}
+
+	plain, err := openOuraToken(sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if plain != "access-token-123" {
+		t.Errorf("opened %q, want the original token", plain)
+	}
+
+	// An empty token is stored as nothing and reads back empty
+	if empty, err := sealOuraToken(""); err != nil || empty != nil {
+		t.Errorf("seal empty: %v, %v", empty, err)
+	}
+	if plain, err := openOuraToken(nil); err != nil || plain != "" {
+		t.Errorf("open empty: %q, %v", plain, err)
+	}
+
+	if _, err := openOuraToken(sealed[:4]); err == nil {
+		t.Error("opening a truncated token should fail")
+	}
+
+	t.Setenv("OURA_TOKEN_KEY", "second key")
+	if _, err := openOuraToken(sealed); err == nil {
+		t.Error("opening with the wrong key should fail")
+	}
+
+	t.Setenv("OURA_TOKEN_KEY", "")
+	if _, err := sealOuraToken("x"); !errors.Is(err, errOuraKeyMissing) {
+		t.Errorf("seal without a key: %v, want errOuraKeyMissing", err)
+	}
+}
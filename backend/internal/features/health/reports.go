@@ -0,0 +1,733 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+const (
+	reportTopDays = 5
+
+	// reportMinCoverage is the share of its days a past period needs data
+	// on to be ranked in the percentile
+	reportMinCoverage = 1.0 / 3
+)
+
+// reportMetric is a daily metric summarised in monthly and yearly reports
+type reportMetric struct {
+	key, label string
+	decimals   int
+	notable    float64 // change in the average worth a highlight
+	sql        string  // (day, value) rows up to $1
+}
+
+var reportMetrics = []reportMetric{
+	{"sleep_score", "Sleep score", 1, 3, `SELECT day, sleep_score::double precision FROM oura_daily WHERE day <= $1`},
+	{"readiness_score", "Readiness score", 1, 3, `SELECT day, readiness_score::double precision FROM oura_daily WHERE day <= $1`},
+	{"activity_score", "Activity score", 1, 3, `SELECT day, activity_score::double precision FROM oura_daily WHERE day <= $1`},
+	{"activity_steps", "Steps", 0, 1000, `SELECT day, steps FROM daily_steps WHERE day <= $1`},
+	{"sleep_hours", "Sleep hours", 2, 0.25, `SELECT day, SUM(total_sleep_seconds) / 3600.0 FROM sleep_sessions
+		WHERE NOT is_nap AND total_sleep_seconds IS NOT NULL AND day <= $1 GROUP BY day`},
+	{"hrv", "HRV (ms)", 1, 3, `SELECT day, AVG(average_hrv)::double precision FROM sleep_sessions
+		WHERE NOT is_nap AND average_hrv IS NOT NULL AND day <= $1 GROUP BY day`},
+}
+
+// round rounds a value to the metric's precision
+func (m reportMetric) round(v float64) float64 {
+	p := math.Pow10(m.decimals)
+	return math.Round(v*p) / p
+}
+
+// format writes a value at the metric's precision
+func (m reportMetric) format(v float64) string {
+	return strconv.FormatFloat(v, 'f', m.decimals, 64)
+}
+
+// reportPeriod is the month or year a report covers. end is capped at
+// today while the period is still running.
+type reportPeriod struct {
+	kind       string // month, year
+	start, end time.Time
+	last       time.Time // last day of the full period
+}
+
+// newReportPeriod returns the month or year starting at start
+func newReportPeriod(kind string, start, today time.Time) reportPeriod {
+	p := reportPeriod{kind: kind, start: start}
+	if kind == "year" {
+		p.last = start.AddDate(1, 0, -1)
+	} else {
+		p.last = start.AddDate(0, 1, -1)
+	}
+	p.end = p.last
+	if p.end.After(today) {
+		p.end = today
+	}
+	return p
+}
+
+// shift moves the period by n months or years. A period still running
+// keeps its length, so month to date compares with the same days before.
+func (p reportPeriod) shift(n int) reportPeriod {
+	q := reportPeriod{kind: p.kind}
+	if p.kind == "year" {
+		q.start = p.start.AddDate(n, 0, 0)
+		q.last = q.start.AddDate(1, 0, -1)
+	} else {
+		q.start = p.start.AddDate(0, n, 0)
+		q.last = q.start.AddDate(0, 1, -1)
+	}
+	q.end = q.last
+	if !p.complete() {
+		if end := q.start.AddDate(0, 0, int(p.end.Sub(p.start).Hours()/24)); end.Before(q.last) {
+			q.end = end
+		}
+	}
+	return q
+}
+
+// next returns the full period after p
+func (p reportPeriod) next(today time.Time) reportPeriod {
+	return newReportPeriod(p.kind, p.last.AddDate(0, 0, 1), today)
+}
+
+// complete reports whether the period has ended
+func (p reportPeriod) complete() bool {
+	return p.end.Equal(p.last)
+}
+
+// days is the number of days in the full period
+func (p reportPeriod) days() int {
+	return int(p.last.Sub(p.start).Hours()/24) + 1
+}
+
+// label names the period, e.g. May 2024 or 2024
+func (p reportPeriod) label() string {
+	if p.kind == "year" {
+		return p.start.Format("2006")
+	}
+	return p.start.Format("January 2006")
+}
+
+// parseReportPeriod reads ?month=YYYY-MM or ?year=YYYY. Without a value the
+// last complete period is used; "current" is the period so far.
+func parseReportPeriod(kind, value string, today time.Time) (reportPeriod, error) {
+	thisMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	thisYear := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var start time.Time
+	switch {
+	case value == "current" && kind == "year":
+		start = thisYear
+	case value == "current":
+		start = thisMonth
+	case value == "" && kind == "year":
+		start = thisYear.AddDate(-1, 0, 0)
+	case value == "":
+		start = thisMonth.AddDate(0, -1, 0)
+	case kind == "year":
+		t, err := time.Parse("2006", value)
+		if err != nil {
+			return reportPeriod{}, fmt.Errorf("year must be YYYY or current")
+		}
+		start = t
+	default:
+		t, err := time.Parse("2006-01", value)
+		if err != nil {
+			return reportPeriod{}, fmt.Errorf("month must be YYYY-MM or current")
+		}
+		start = t
+	}
+	if start.After(today) {
+		return reportPeriod{}, fmt.Errorf("%s has not started yet", kind)
+	}
+	return newReportPeriod(kind, start, today), nil
+}
+
+// periodAverage averages a series over a period
+func periodAverage(values map[string]float64, p reportPeriod) (float64, int, bool) {
+	return aggregateGoal(values, p.start, p.end, "avg")
+}
+
+// historyPercentile ranks the period's average among all earlier periods
+// of the same kind with enough data, as the share of them it beats (ties
+// count half). It returns nil with fewer than two periods to compare.
+func historyPercentile(values map[string]float64, p reportPeriod, avg float64, today time.Time) (*float64, int) {
+	first := ""
+	for day := range values {
+		if first == "" || day < first {
+			first = day
+		}
+	}
+	if first == "" {
+		return nil, 0
+	}
+	firstDay, _ := time.Parse("2006-01-02", first)
+	start := time.Date(firstDay.Year(), firstDay.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if p.kind == "year" {
+		start = time.Date(firstDay.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var below, equal float64
+	ranked := 0
+	for q := newReportPeriod(p.kind, start, today); !q.start.After(p.start); q = q.next(today) {
+		v := avg
+		if !q.start.Equal(p.start) {
+			var days int
+			var ok bool
+			if v, days, ok = periodAverage(values, q); !ok || float64(days) < reportMinCoverage*float64(q.days()) {
+				continue
+			}
+		}
+		ranked++
+		switch {
+		case v < avg:
+			below++
+		case v == avg:
+			equal++
+		}
+	}
+	if ranked < 2 {
+		return nil, ranked
+	}
+	pct := round1((below + equal/2) / float64(ranked) * 100)
+	return &pct, ranked
+}
+
+// reportDays ranks the days of a period by the mean of their Oura sleep,
+// readiness and activity scores and returns the best and worst
+func reportDays(history map[string]map[string]float64, p reportPeriod) (top, bottom []ReportDay) {
+	var days []ReportDay
+	for day := p.start; !day.After(p.end); day = day.AddDate(0, 0, 1) {
+		d := ReportDay{Date: formatDate(day)}
+		var sum float64
+		var n int
+		for _, s := range []struct {
+			key string
+			dst **float64
+		}{{"sleep_score", &d.Sleep}, {"readiness_score", &d.Readiness}, {"activity_score", &d.Activity}} {
+			if v, ok := history[s.key][d.Date]; ok {
+				v := v
+				*s.dst = &v
+				sum += v
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		d.Score = round1(sum / float64(n))
+		days = append(days, d)
+	}
+
+	sort.SliceStable(days, func(i, j int) bool { return days[i].Score > days[j].Score })
+	n := reportTopDays
+	if len(days)/2 < n {
+		n = len(days) / 2
+	}
+	top = append([]ReportDay{}, days[:n]...)
+	bottom = []ReportDay{}
+	for i := len(days) - 1; i >= len(days)-n; i-- {
+		bottom = append(bottom, days[i])
+	}
+	return top, bottom
+}
+
+// reportWorkouts totals the workouts logged in a period
+func reportWorkouts(ctx context.Context, q querier, p reportPeriod) (ReportWorkouts, error) {
+	var wo ReportWorkouts
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE type = 'strength'),
+			COUNT(*) FILTER (WHERE type = 'cardio'),
+			COALESCE(SUM(duration_seconds), 0) / 60.0,
+			COALESCE(SUM(distance_meters), 0) / 1000.0,
+			COALESCE(SUM(calories), 0)
+		FROM workouts
+		WHERE date >= $1 AND date <= $2
+	`, formatDate(p.start), formatDate(p.end)).Scan(&wo.Count, &wo.Strength, &wo.Cardio, &wo.Minutes, &wo.DistanceKm, &wo.Calories)
+	wo.Minutes = math.Round(wo.Minutes)
+	wo.DistanceKm = round1(wo.DistanceKm)
+	return wo, err
+}
+
+// reportWeight returns the change of the smoothed weight trend over a
+// period, or nil with fewer than two weigh-ins
+func reportWeight(ctx context.Context, q querier, p reportPeriod) (*ReportWeight, error) {
+	rows, err := q.Query(ctx, `
+		SELECT date, weight_kg FROM weight_entries
+		WHERE date >= $1 AND date <= $2
+		ORDER BY date
+	`, formatDate(p.start.AddDate(0, 0, -weightTrendWarmup)), formatDate(p.end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []WeightEntry
+	for rows.Next() {
+		var e WeightEntry
+		var day time.Time
+		if err := rows.Scan(&day, &e.WeightKg); err != nil {
+			return nil, err
+		}
+		e.Date = formatDate(day)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	smoothWeights(entries)
+
+	from := formatDate(p.start)
+	var weight *ReportWeight
+	for _, e := range entries {
+		if e.Date < from {
+			continue
+		}
+		if weight == nil {
+			weight = &ReportWeight{StartKg: *e.TrendKg, MinKg: e.WeightKg, MaxKg: e.WeightKg}
+		}
+		weight.Entries++
+		weight.EndKg = *e.TrendKg
+		weight.MinKg = math.Min(weight.MinKg, e.WeightKg)
+		weight.MaxKg = math.Max(weight.MaxKg, e.WeightKg)
+	}
+	if weight == nil || weight.Entries < 2 {
+		return nil, nil
+	}
+	weight.ChangeKg = round2(weight.EndKg - weight.StartKg)
+	return weight, nil
+}
+
+// reportGoals evaluates the active goals over every goal period starting
+// in the report period, up to today
+func (h *Handler) reportGoals(ctx context.Context, p reportPeriod, today time.Time) ([]ReportGoal, error) {
+	reports := []ReportGoal{}
+	goals, err := h.loadHealthGoals(ctx, false)
+	if err != nil || len(goals) == 0 {
+		return reports, err
+	}
+
+	// Weekly goals can run up to six days past the period
+	to := p.last.AddDate(0, 0, 6)
+	if to.After(today) {
+		to = today
+	}
+	all, err := h.loadCorrelationSeries(ctx, p.start, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, goal := range goals {
+		values := map[string]float64{}
+		if s, ok := findCorrelationSeries(all, goal.Metric); ok {
+			values = s.values
+		}
+		g := ReportGoal{GoalType: goal.GoalType, Label: goal.Label, Period: goal.Period}
+		start := goalPeriodStart(p.start, goal.Period)
+		if start.Before(p.start) {
+			start = goalPeriodShift(start, goal.Period, 1)
+		}
+		for ; !start.After(p.end); start = goalPeriodShift(start, goal.Period, 1) {
+			end := goalPeriodShift(start, goal.Period, 1).AddDate(0, 0, -1)
+			if end.After(today) {
+				end = today
+			}
+			value, _, ok := aggregateGoal(values, start, end, goal.Aggregation)
+			if !ok {
+				continue
+			}
+			g.Evaluated++
+			if compareGoal(value, goal.Comparator, goal.Target) {
+				g.Met++
+			}
+		}
+		if g.Evaluated > 0 {
+			pct := round1(float64(g.Met) / float64(g.Evaluated) * 100)
+			g.AttainmentPct = &pct
+		}
+		reports = append(reports, g)
+	}
+	return reports, nil
+}
+
+// buildHealthReport summarises a month or a year
+func (h *Handler) buildHealthReport(ctx context.Context, p reportPeriod, today time.Time) (*HealthReport, error) {
+	prev := p.shift(-1)
+	report := &HealthReport{
+		Period:     p.kind,
+		Label:      p.label(),
+		Start:      formatDate(p.start),
+		End:        formatDate(p.end),
+		Complete:   p.complete(),
+		Previous:   prev.label(),
+		Metrics:    []ReportMetric{},
+		Highlights: []string{},
+		Lowlights:  []string{},
+	}
+	// A yearly report's previous period already is last year
+	var lastYear *reportPeriod
+	if p.kind == "month" {
+		ly := p.shift(-12)
+		lastYear = &ly
+		report.LastYear = ly.label()
+	}
+	periodName := p.kind + "s"
+
+	history := make(map[string]map[string]float64, len(reportMetrics))
+	for _, m := range reportMetrics {
+		values, err := loadDaySeries(ctx, h.db, m.sql, formatDate(p.end))
+		if err != nil {
+			return nil, err
+		}
+		history[m.key] = values
+	}
+
+	withData := make(map[string]bool)
+	for _, m := range reportMetrics {
+		values := history[m.key]
+		for day := p.start; !day.After(p.end); day = day.AddDate(0, 0, 1) {
+			if _, ok := values[formatDate(day)]; ok {
+				withData[formatDate(day)] = true
+			}
+		}
+
+		rm := ReportMetric{Key: m.key, Label: m.label}
+		avg, days, ok := periodAverage(values, p)
+		if !ok {
+			report.Metrics = append(report.Metrics, rm)
+			continue
+		}
+		a := m.round(avg)
+		rm.Avg, rm.Days = &a, days
+		if v, _, ok := periodAverage(values, prev); ok {
+			pv, delta := m.round(v), m.round(avg-v)
+			rm.PreviousAvg, rm.PreviousDelta = &pv, &delta
+			if delta >= m.notable {
+				report.Highlights = append(report.Highlights, fmt.Sprintf("%s up %s on %s", m.label, m.format(delta), report.Previous))
+			} else if delta <= -m.notable {
+				report.Lowlights = append(report.Lowlights, fmt.Sprintf("%s down %s on %s", m.label, m.format(-delta), report.Previous))
+			}
+		}
+		if lastYear != nil {
+			if v, _, ok := periodAverage(values, *lastYear); ok {
+				lv, delta := m.round(v), m.round(avg-v)
+				rm.LastYearAvg, rm.LastYearDelta = &lv, &delta
+			}
+		}
+		rm.Percentile, rm.PeriodsRanked = historyPercentile(values, p, avg, today)
+		if rm.Percentile != nil && rm.PeriodsRanked >= 6 {
+			if *rm.Percentile >= 90 {
+				report.Highlights = append(report.Highlights, fmt.Sprintf("%s in the top 10%% of %d %s", m.label, rm.PeriodsRanked, periodName))
+			} else if *rm.Percentile <= 10 {
+				report.Lowlights = append(report.Lowlights, fmt.Sprintf("%s in the bottom 10%% of %d %s", m.label, rm.PeriodsRanked, periodName))
+			}
+		}
+		report.Metrics = append(report.Metrics, rm)
+	}
+	report.DaysWithData = len(withData)
+	report.TopDays, report.BottomDays = reportDays(history, p)
+
+	goals, err := h.reportGoals(ctx, p, today)
+	if err != nil {
+		return nil, err
+	}
+	report.Goals = goals
+	var evaluated, met int
+	for _, g := range goals {
+		evaluated += g.Evaluated
+		met += g.Met
+	}
+	if evaluated > 0 {
+		pct := round1(float64(met) / float64(evaluated) * 100)
+		report.GoalAttainmentPct = &pct
+		if pct >= 80 {
+			report.Highlights = append(report.Highlights, fmt.Sprintf("Met %d of %d goal periods", met, evaluated))
+		} else if pct < 50 {
+			report.Lowlights = append(report.Lowlights, fmt.Sprintf("Met only %d of %d goal periods", met, evaluated))
+		}
+	}
+
+	if report.Workouts, err = reportWorkouts(ctx, h.db, p); err != nil {
+		return nil, err
+	}
+	prevWorkouts, err := reportWorkouts(ctx, h.db, prev)
+	if err != nil {
+		return nil, err
+	}
+	report.Workouts.PreviousCount = &prevWorkouts.Count
+	if lastYear != nil {
+		lastYearWorkouts, err := reportWorkouts(ctx, h.db, *lastYear)
+		if err != nil {
+			return nil, err
+		}
+		report.Workouts.LastYearCount = &lastYearWorkouts.Count
+	}
+	switch {
+	case report.Workouts.Count == 0:
+		report.Lowlights = append(report.Lowlights, "No workouts logged")
+	case report.Workouts.Count > prevWorkouts.Count && report.Complete:
+		report.Highlights = append(report.Highlights, fmt.Sprintf("%d workouts, up from %d", report.Workouts.Count, prevWorkouts.Count))
+	case report.Workouts.Count < prevWorkouts.Count && report.Complete:
+		report.Lowlights = append(report.Lowlights, fmt.Sprintf("%d workouts, down from %d", report.Workouts.Count, prevWorkouts.Count))
+	}
+
+	if report.Weight, err = reportWeight(ctx, h.db, p); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// --- Rendering ---
+
+// reportSection is a titled part of a rendered report: a list, a table or both
+type reportSection struct {
+	Title   string
+	Items   []string
+	Headers []string
+	Rows    [][]string
+}
+
+// signed formats a change with its sign
+func signed(v float64, m reportMetric) string {
+	if v > 0 {
+		return "+" + m.format(v)
+	}
+	return m.format(v)
+}
+
+// optional formats a value, or a dash when missing
+func optional(v *float64, format func(float64) string) string {
+	if v == nil {
+		return "–"
+	}
+	return format(*v)
+}
+
+// reportSections lays out a report for Markdown and HTML alike
+func reportSections(rep *HealthReport) []reportSection {
+	var sections []reportSection
+
+	metrics := reportSection{Title: "Metrics", Headers: []string{"Metric", "Average", "Days", "vs " + rep.Previous}}
+	if rep.LastYear != "" {
+		metrics.Headers = append(metrics.Headers, "vs "+rep.LastYear)
+	}
+	metrics.Headers = append(metrics.Headers, "Percentile")
+	for i, rm := range rep.Metrics {
+		m := reportMetrics[i] // metrics are reported in catalogue order
+		delta := func(v float64) string { return signed(v, m) }
+		row := []string{rm.Label, optional(rm.Avg, m.format), strconv.Itoa(rm.Days), optional(rm.PreviousDelta, delta)}
+		if rep.LastYear != "" {
+			row = append(row, optional(rm.LastYearDelta, delta))
+		}
+		percentile := "–"
+		if rm.Percentile != nil {
+			percentile = fmt.Sprintf("%.0f (of %d)", *rm.Percentile, rm.PeriodsRanked)
+		}
+		metrics.Rows = append(metrics.Rows, append(row, percentile))
+	}
+	sections = append(sections, metrics)
+
+	if len(rep.Highlights) > 0 {
+		sections = append(sections, reportSection{Title: "Highlights", Items: rep.Highlights})
+	}
+	if len(rep.Lowlights) > 0 {
+		sections = append(sections, reportSection{Title: "Lowlights", Items: rep.Lowlights})
+	}
+
+	score := func(v float64) string { return strconv.FormatFloat(v, 'f', 0, 64) }
+	for _, d := range []struct {
+		title string
+		days  []ReportDay
+	}{{"Best days", rep.TopDays}, {"Worst days", rep.BottomDays}} {
+		if len(d.days) == 0 {
+			continue
+		}
+		s := reportSection{Title: d.title, Headers: []string{"Date", "Score", "Sleep", "Readiness", "Activity"}}
+		for _, day := range d.days {
+			s.Rows = append(s.Rows, []string{day.Date, strconv.FormatFloat(day.Score, 'f', 1, 64),
+				optional(day.Sleep, score), optional(day.Readiness, score), optional(day.Activity, score)})
+		}
+		sections = append(sections, s)
+	}
+
+	if len(rep.Goals) > 0 {
+		s := reportSection{Title: "Goals", Headers: []string{"Goal", "Period", "Met", "Attainment"}}
+		if rep.GoalAttainmentPct != nil {
+			s.Items = []string{fmt.Sprintf("%.0f%% of goal periods met", *rep.GoalAttainmentPct)}
+		}
+		for _, g := range rep.Goals {
+			s.Rows = append(s.Rows, []string{g.Label, g.Period, fmt.Sprintf("%d / %d", g.Met, g.Evaluated),
+				optional(g.AttainmentPct, func(v float64) string { return fmt.Sprintf("%.0f%%", v) })})
+		}
+		sections = append(sections, s)
+	}
+
+	wo := rep.Workouts
+	workouts := reportSection{Title: "Workouts", Items: []string{
+		fmt.Sprintf("%d workouts (%d strength, %d cardio)", wo.Count, wo.Strength, wo.Cardio),
+		fmt.Sprintf("%.0f minutes, %.1f km, %d kcal", wo.Minutes, wo.DistanceKm, wo.Calories),
+	}}
+	if wo.PreviousCount != nil {
+		workouts.Items = append(workouts.Items, fmt.Sprintf("%s: %d workouts", rep.Previous, *wo.PreviousCount))
+	}
+	if wo.LastYearCount != nil {
+		workouts.Items = append(workouts.Items, fmt.Sprintf("%s: %d workouts", rep.LastYear, *wo.LastYearCount))
+	}
+	sections = append(sections, workouts)
+
+	if w := rep.Weight; w != nil {
+		sections = append(sections, reportSection{Title: "Weight", Items: []string{
+			fmt.Sprintf("Trend %.1f kg to %.1f kg (%+.1f kg) over %d weigh-ins", w.StartKg, w.EndKg, w.ChangeKg, w.Entries),
+			fmt.Sprintf("Range %.1f–%.1f kg", w.MinKg, w.MaxKg),
+		}})
+	}
+	return sections
+}
+
+// reportSubtitle describes the dates a report covers
+func reportSubtitle(rep *HealthReport) string {
+	status := "complete"
+	if !rep.Complete {
+		status = "to date"
+	}
+	return fmt.Sprintf("%s to %s (%s), %d days with data", rep.Start, rep.End, status, rep.DaysWithData)
+}
+
+// renderReportMarkdown renders a report as a Markdown document
+func renderReportMarkdown(rep *HealthReport) string {
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Health report: %s\n\n%s\n", rep.Label, reportSubtitle(rep))
+	for _, s := range reportSections(rep) {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.Title)
+		for _, item := range s.Items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		if len(s.Headers) == 0 {
+			continue
+		}
+		if len(s.Items) > 0 {
+			b.WriteString("\n")
+		}
+		writeRow := func(row []string) {
+			cells := make([]string, len(row))
+			for i, c := range row {
+				cells[i] = cell.Replace(c)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		writeRow(s.Headers)
+		b.WriteString("|" + strings.Repeat(" --- |", len(s.Headers)) + "\n")
+		for _, row := range s.Rows {
+			writeRow(row)
+		}
+	}
+	return b.String()
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Health report: {{.Report.Label}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 56rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+table { border-collapse: collapse; margin: 0.5rem 0 1rem; }
+th, td { border: 1px solid #ccc; padding: 0.3rem 0.6rem; text-align: left; }
+th { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>Health report: {{.Report.Label}}</h1>
+<p>{{.Subtitle}}</p>
+{{range .Sections}}<h2>{{.Title}}</h2>
+{{if .Items}}<ul>
+{{range .Items}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{if .Headers}}<table>
+<tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// reportFormats are the accepted values of ?format=
+var reportFormats = map[string]bool{"": true, "json": true, "markdown": true, "md": true, "html": true}
+
+// writeHealthReport writes a report as JSON, Markdown or HTML by ?format=
+func writeHealthReport(w http.ResponseWriter, r *http.Request, rep *HealthReport) {
+	name := "health-report-" + strings.ToLower(strings.ReplaceAll(rep.Label, " ", "-"))
+	switch r.URL.Query().Get("format") {
+	case "markdown", "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.md"`, name))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(renderReportMarkdown(rep)))
+	case "html":
+		var b strings.Builder
+		err := reportHTML.Execute(&b, struct {
+			Report   *HealthReport
+			Subtitle string
+			Sections []reportSection
+		}{rep, reportSubtitle(rep), reportSections(rep)})
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.html"`, name))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(b.String()))
+	default:
+		core.WriteJSON(w, http.StatusOK, rep)
+	}
+}
+
+// --- Handlers ---
+
+// getHealthReport serves the monthly or yearly report
+func (h *Handler) getHealthReport(w http.ResponseWriter, r *http.Request, kind string) {
+	ctx := r.Context()
+	today := h.today(ctx)
+
+	p, err := parseReportPeriod(kind, r.URL.Query().Get(kind), today)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !reportFormats[r.URL.Query().Get("format")] {
+		core.WriteError(w, http.StatusBadRequest, "format must be json, markdown or html")
+		return
+	}
+
+	report, err := h.buildHealthReport(ctx, p, today)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeHealthReport(w, r, report)
+}
+
+// GetMonthlyReport returns the monthly health review. Query: ?month=YYYY-MM
+// or current (default last month), ?format=json, markdown or html
+func (h *Handler) GetMonthlyReport(w http.ResponseWriter, r *http.Request) {
+	h.getHealthReport(w, r, "month")
+}
+
+// GetYearlyReport returns the yearly health review. Query: ?year=YYYY or
+// current (default last year), ?format=json, markdown or html
+func (h *Handler) GetYearlyReport(w http.ResponseWriter, r *http.Request) {
+	h.getHealthReport(w, r, "year")
+}
@@ -18,34 +18,161 @@ func RegisterRoutes(r chi.Router, db *pgxpool.Pool) {
 
 		// Bulk upsert for historical import
 		r.Post("/oura/bulk", h.BulkUpsertOuraDaily)
+		r.Delete("/ingestion-errors", h.ClearIngestionErrors)
+
+		// Oura API connection and sync
+		r.Get("/oura/connection", h.GetOuraConnection)
+		r.Put("/oura/connection", h.PutOuraConnection)
+		r.Delete("/oura/connection", h.DeleteOuraConnection)
+		r.Post("/oura/sync", h.SyncOura)
+
+		// Sleep sessions
+		r.Get("/sleep-sessions", h.ListSleepSessions)
+		r.Post("/sleep-sessions", h.CreateSleepSession)
+		r.Post("/sleep-sessions/bulk", h.BulkCreateSleepSessions)
+		r.Get("/sleep-sessions/{id}", h.GetSleepSession)
+		r.Delete("/sleep-sessions/{id}", h.DeleteSleepSession)
 
 		// Workouts
 		r.Get("/workouts", h.ListWorkouts)
 		r.Post("/workouts", h.CreateWorkout)
+		r.Post("/workouts/import", h.ImportWorkoutFile)
 		r.Get("/workouts/{id}", h.GetWorkout)
+		r.Put("/workouts/{id}", h.UpdateWorkout)
+		r.Get("/workouts/{id}/track", h.GetWorkoutTrack)
 		r.Delete("/workouts/{id}", h.DeleteWorkout)
 
+		// Exercise catalogue for strength sets
+		r.Get("/exercises", h.ListExercises)
+		r.Post("/exercises", h.CreateExercise)
+		r.Put("/exercises/{id}", h.UpdateExercise)
+		r.Delete("/exercises/{id}", h.DeleteExercise)
+
+		// Apple Health export import
+		r.Post("/import/apple-health", h.ImportAppleHealth)
+		r.Get("/import/apple-health", h.ListAppleHealthImports)
+		r.Get("/import/apple-health/{id}", h.GetAppleHealthImport)
+
+		// Daily journal and tag catalogue
+		r.Get("/journal", h.ListJournalEntries)
+		r.Post("/journal", h.UpsertJournalEntry)
+		r.Get("/journal/{day}", h.GetJournalEntry)
+		r.Delete("/journal/{day}", h.DeleteJournalEntry)
+		r.Get("/journal-tags", h.ListJournalTags)
+		r.Post("/journal-tags", h.CreateJournalTag)
+		r.Delete("/journal-tags/{id}", h.DeleteJournalTag)
+
+		// Self-experiments
+		r.Get("/experiments", h.ListExperiments)
+		r.Post("/experiments", h.CreateExperiment)
+		r.Get("/experiments/{id}", h.GetExperiment)
+		r.Delete("/experiments/{id}", h.DeleteExperiment)
+		r.Get("/experiments/{id}/compliance", h.ListExperimentCompliance)
+		r.Post("/experiments/{id}/compliance", h.LogExperimentCompliance)
+		r.Get("/experiments/{id}/analysis", h.GetExperimentAnalysis)
+
 		// Weight entries
 		r.Get("/weight", h.ListWeightEntries)
 		r.Post("/weight", h.CreateWeightEntry)
 		r.Get("/weight/{date}", h.GetWeightEntry)
 		r.Delete("/weight/{date}", h.DeleteWeightEntry)
+
+		// Body composition and circumference measurements
+		r.Get("/body-measurements", h.ListBodyMeasurements)
+		r.Post("/body-measurements", h.CreateBodyMeasurements)
+		r.Delete("/body-measurements/{id}", h.DeleteBodyMeasurement)
+
+		// Nutrition: food catalogue, recipes, meals and water
+		r.Get("/foods", h.ListFoods)
+		r.Post("/foods", h.CreateFood)
+		r.Put("/foods/{id}", h.UpdateFood)
+		r.Delete("/foods/{id}", h.DeleteFood)
+		r.Get("/recipes", h.ListRecipes)
+		r.Post("/recipes", h.CreateRecipe)
+		r.Get("/recipes/{id}", h.GetRecipe)
+		r.Delete("/recipes/{id}", h.DeleteRecipe)
+		r.Get("/meals", h.ListMeals)
+		r.Post("/meals", h.CreateMeal)
+		r.Delete("/meals/{id}", h.DeleteMeal)
+		r.Get("/water", h.ListWaterIntake)
+		r.Post("/water", h.CreateWaterEntry)
+		r.Delete("/water/{id}", h.DeleteWaterEntry)
+
+		// Medications and supplements
+		r.Get("/medications", h.ListMedications)
+		r.Post("/medications", h.CreateMedication)
+		r.Get("/medications/{id}", h.GetMedication)
+		r.Put("/medications/{id}", h.UpdateMedication)
+		r.Delete("/medications/{id}", h.DeleteMedication)
+		r.Post("/medications/{id}/refill", h.RefillMedication)
+		r.Get("/medications/{id}/intakes", h.ListMedicationIntakes)
+		r.Post("/medications/{id}/intakes", h.LogMedicationIntake)
+		r.Delete("/medications/{id}/intakes/{intakeId}", h.DeleteMedicationIntake)
+
+		// Lab results and vital signs
+		r.Get("/labs", h.ListLabResults)
+		r.Post("/labs", h.CreateLabResult)
+		r.Post("/labs/import", h.ImportLabResults)
+		r.Get("/labs/markers", h.ListLabMarkers)
+		r.Delete("/labs/{id}", h.DeleteLabResult)
+		r.Get("/vitals", h.ListVitals)
+		r.Post("/vitals", h.CreateVital)
+		r.Post("/vitals/import", h.ImportVitals)
+		r.Delete("/vitals/{id}", h.DeleteVital)
+
+		// Home timezone and travel overrides for day boundaries
+		r.Get("/timezone", h.GetTimezoneSettings)
+		r.Put("/timezone", h.PutTimezoneSettings)
+		r.Get("/timezone/overrides", h.ListTimezoneOverrides)
+		r.Post("/timezone/overrides", h.SetTimezoneOverrides)
+		r.Delete("/timezone/overrides/{day}", h.DeleteTimezoneOverride)
+
+		// Forecast retraining (for cron job)
+		r.Post("/forecast/train", h.TrainForecast)
+
+		// Illness/anomaly detection (for cron job)
+		r.Post("/anomalies/detect", h.DetectAnomalies)
 	})
 
 	// Dashboard endpoints
 	r.Get("/dashboard/health", h.GetDashboard)
 	r.Get("/dashboard/health/history", h.GetHistory)
 	r.Get("/dashboard/health/sleep", h.GetSleepAnalysis)
+	r.Get("/dashboard/health/sleep/timing", h.GetSleepTiming)
+	r.Get("/dashboard/health/sleep/chronotype", h.GetSleepChronotype)
+	r.Get("/dashboard/health/strength/1rm", h.GetOneRepMaxProgress)
+	r.Get("/dashboard/health/strength/volume", h.GetWeeklyVolume)
+	r.Get("/dashboard/health/training-load", h.GetTrainingLoad)
 	r.Get("/dashboard/health/weight", h.GetWeightTrend)
 	r.Get("/dashboard/health/body", h.GetBodyMetrics)
+	r.Get("/dashboard/health/energy-balance", h.GetEnergyBalance)
+	r.Get("/dashboard/health/medications", h.GetMedicationAdherence)
+	r.Get("/dashboard/health/labs/{marker}", h.GetLabMarkerTrend)
+	r.Get("/dashboard/health/vitals/{kind}", h.GetVitalTrend)
+	r.Get("/dashboard/health/data-quality", h.GetDataQuality)
+	r.Get("/dashboard/health/anomalies", h.GetAnomalies)
 	r.Get("/dashboard/health/insights", h.GetInsights)
+	r.Get("/dashboard/health/correlations", h.GetCorrelation)
+	r.Get("/dashboard/health/correlations/series", h.ListCorrelationSeries)
+	r.Get("/dashboard/health/correlations/discover", h.DiscoverCorrelations)
+	r.Get("/dashboard/health/tags/impact", h.GetTagImpact)
 	r.Get("/dashboard/health/goals", h.GetGoalsOverview)
 	r.Get("/dashboard/health/weekly", h.GetWeeklySummary)
+	r.Get("/dashboard/health/monthly", h.GetMonthlyReport)
+	r.Get("/dashboard/health/yearly", h.GetYearlyReport)
+	r.Get("/dashboard/health/forecast", h.GetForecast)
+
+	// Verdict rules
+	r.Get("/health/verdict-rules", h.GetVerdictRules)
+	r.Put("/health/verdict-rules", h.PutVerdictRules)
+	r.Delete("/health/verdict-rules", h.DeleteVerdictRules)
+	r.Post("/health/verdict-rules/test", h.TestVerdictRules)
 
 	// Goals CRUD
 	r.Route("/health/goals", func(r chi.Router) {
 		r.Get("/", h.ListGoals)
 		r.Post("/", h.UpsertGoal)
+		r.Get("/{type}/history", h.GetGoalHistory)
 		r.Delete("/{type}", h.DeleteGoal)
 	})
 }
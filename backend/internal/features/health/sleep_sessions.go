@@ -0,0 +1,633 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxSleepSessionHours rejects sessions that are clearly not one sleep
+	maxSleepSessionHours = 24
+	// minutesPerDay is used for clock arithmetic
+	minutesPerDay = 24 * 60
+)
+
+// sleepSessionColumns is the column list read by scanSleepSession
+const sleepSessionColumns = `id, day, bedtime_start, bedtime_end, utc_offset_minutes,
+	total_sleep_seconds, deep_sleep_seconds, rem_sleep_seconds, light_sleep_seconds, awake_seconds,
+	average_heart_rate, lowest_heart_rate, average_hrv, is_nap, source, external_id, created_at`
+
+// scanSleepSession scans a row selected with sleepSessionColumns
+func scanSleepSession(row pgx.Row) (SleepSession, error) {
+	var s SleepSession
+	var day time.Time
+	err := row.Scan(&s.ID, &day, &s.BedtimeStart, &s.BedtimeEnd, &s.UTCOffsetMinutes,
+		&s.TotalSleepSeconds, &s.DeepSleepSeconds, &s.RemSleepSeconds, &s.LightSleepSeconds, &s.AwakeSeconds,
+		&s.AverageHeartRate, &s.LowestHeartRate, &s.AverageHrv, &s.IsNap, &s.Source, &s.ExternalID, &s.CreatedAt)
+	if err != nil {
+		return s, err
+	}
+
+	zone := time.FixedZone("", s.UTCOffsetMinutes*60)
+	s.Day = formatDate(day)
+	s.BedtimeStart = s.BedtimeStart.In(zone)
+	s.BedtimeEnd = s.BedtimeEnd.In(zone)
+	s.TimeInBedSeconds = int(s.BedtimeEnd.Sub(s.BedtimeStart).Seconds())
+	if s.TotalSleepSeconds != nil && s.TimeInBedSeconds > 0 {
+		eff := float64(*s.TotalSleepSeconds) / float64(s.TimeInBedSeconds) * 100
+		s.Efficiency = &eff
+	}
+	return s, nil
+}
+
+// validateSleepSession parses the bedtimes and fills in defaults
+func validateSleepSession(in *SleepSessionInput) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, in.BedtimeStart)
+	if err != nil {
+		return start, start, errors.New("bedtime_start must be an RFC 3339 timestamp with UTC offset")
+	}
+	end, err := time.Parse(time.RFC3339, in.BedtimeEnd)
+	if err != nil {
+		return start, end, errors.New("bedtime_end must be an RFC 3339 timestamp with UTC offset")
+	}
+	if !end.After(start) {
+		return start, end, errors.New("bedtime_end must be after bedtime_start")
+	}
+	if end.Sub(start) > maxSleepSessionHours*time.Hour {
+		return start, end, fmt.Errorf("sleep sessions cannot be longer than %d hours", maxSleepSessionHours)
+	}
+
+	for name, v := range map[string]*int{
+		"total_sleep_seconds": in.TotalSleepSeconds,
+		"deep_sleep_seconds":  in.DeepSleepSeconds,
+		"rem_sleep_seconds":   in.RemSleepSeconds,
+		"light_sleep_seconds": in.LightSleepSeconds,
+		"awake_seconds":       in.AwakeSeconds,
+	} {
+		if v != nil && (*v < 0 || *v > int(end.Sub(start).Seconds())) {
+			return start, end, fmt.Errorf("%s must be between 0 and the time in bed", name)
+		}
+	}
+
+	if in.Day == "" {
+		in.Day = formatDate(end)
+	} else if _, err := time.Parse("2006-01-02", in.Day); err != nil {
+		return start, end, errors.New("day must be YYYY-MM-DD")
+	}
+	if in.Source == "" {
+		in.Source = "manual"
+	}
+	return start, end, nil
+}
+
+// upsertSleepSession stores a validated session; a session from the same
+// source starting at the same instant is replaced
+func upsertSleepSession(ctx context.Context, q querier, in SleepSessionInput, start, end time.Time) (int64, error) {
+	_, offset := start.Zone()
+
+	var id int64
+	err := q.QueryRow(ctx, `
+		INSERT INTO sleep_sessions (
+			day, bedtime_start, bedtime_end, utc_offset_minutes,
+			total_sleep_seconds, deep_sleep_seconds, rem_sleep_seconds, light_sleep_seconds, awake_seconds,
+			average_heart_rate, lowest_heart_rate, average_hrv, is_nap, source, external_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (source, bedtime_start) DO UPDATE SET
+			day = EXCLUDED.day,
+			bedtime_end = EXCLUDED.bedtime_end,
+			utc_offset_minutes = EXCLUDED.utc_offset_minutes,
+			total_sleep_seconds = EXCLUDED.total_sleep_seconds,
+			deep_sleep_seconds = EXCLUDED.deep_sleep_seconds,
+			rem_sleep_seconds = EXCLUDED.rem_sleep_seconds,
+			light_sleep_seconds = EXCLUDED.light_sleep_seconds,
+			awake_seconds = EXCLUDED.awake_seconds,
+			average_heart_rate = EXCLUDED.average_heart_rate,
+			lowest_heart_rate = EXCLUDED.lowest_heart_rate,
+			average_hrv = EXCLUDED.average_hrv,
+			is_nap = EXCLUDED.is_nap,
+			external_id = EXCLUDED.external_id
+		RETURNING id
+	`,
+		in.Day, start, end, offset/60,
+		in.TotalSleepSeconds, in.DeepSleepSeconds, in.RemSleepSeconds, in.LightSleepSeconds, in.AwakeSeconds,
+		in.AverageHeartRate, in.LowestHeartRate, in.AverageHrv, in.IsNap, in.Source, in.ExternalID,
+	).Scan(&id)
+	return id, err
+}
+
+// --- Sleep session handlers ---
+
+// ListSleepSessions returns sleep sessions, newest first.
+// Optional ?start=&end= (YYYY-MM-DD) filter on day; ?naps=false hides naps.
+func (h *Handler) ListSleepSessions(w http.ResponseWriter, r *http.Request) {
+	limit := 90
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+
+	start := r.URL.Query().Get("start")
+	if start == "" {
+		start = "0001-01-01"
+	}
+	end := r.URL.Query().Get("end")
+	if end == "" {
+		end = "9999-12-31"
+	}
+	includeNaps := r.URL.Query().Get("naps") != "false"
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+sleepSessionColumns+`
+		FROM sleep_sessions
+		WHERE day >= $1 AND day <= $2 AND ($3 OR NOT is_nap)
+		ORDER BY bedtime_start DESC
+		LIMIT $4
+	`, start, end, includeNaps, limit)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	results := []SleepSession{}
+	for rows.Next() {
+		s, err := scanSleepSession(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		results = append(results, s)
+	}
+
+	core.WriteJSON(w, http.StatusOK, results)
+}
+
+// CreateSleepSession ingests a single sleep session
+func (h *Handler) CreateSleepSession(w http.ResponseWriter, r *http.Request) {
+	var input SleepSessionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	start, end, err := validateSleepSession(&input)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id, err := upsertSleepSession(r.Context(), h.db, input, start, end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s, err := scanSleepSession(h.db.QueryRow(r.Context(),
+		`SELECT `+sleepSessionColumns+` FROM sleep_sessions WHERE id = $1`, id))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, s)
+}
+
+// BulkCreateSleepSessions ingests many sleep sessions, e.g. a history
+// import. Rows that fail are reported by index and recorded.
+func (h *Handler) BulkCreateSleepSessions(w http.ResponseWriter, r *http.Request) {
+	var inputs []SleepSessionInput
+	if err := json.NewDecoder(r.Body).Decode(&inputs); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON array")
+		return
+	}
+
+	result := BulkUpsertResult{Total: len(inputs), Errors: []IngestionRowError{}}
+	var failed []interface{}
+	for i, input := range inputs {
+		start, end, err := validateSleepSession(&input)
+		if err == nil {
+			_, err = upsertSleepSession(r.Context(), h.db, input, start, end)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, IngestionRowError{Index: i, Day: input.Day, Error: err.Error()})
+			failed = append(failed, input)
+			continue
+		}
+		result.Inserted++
+	}
+	result.Failed = len(result.Errors)
+
+	if err := recordIngestionErrors(r.Context(), h.db, ingestionSourceSleepBulk, result.Errors, failed); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
+
+// GetSleepSession returns a single sleep session by ID
+func (h *Handler) GetSleepSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	s, err := scanSleepSession(h.db.QueryRow(r.Context(),
+		`SELECT `+sleepSessionColumns+` FROM sleep_sessions WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Sleep session not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, s)
+}
+
+// DeleteSleepSession removes a sleep session
+func (h *Handler) DeleteSleepSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM sleep_sessions WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Sleep session not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Sleep timing analysis ---
+
+// loadSleepSessions returns all sessions from the last n days, oldest first
+func (h *Handler) loadSleepSessions(ctx context.Context, days int) ([]SleepSession, error) {
+	since := formatDate(h.today(ctx).AddDate(0, 0, -days))
+	rows, err := h.db.Query(ctx, `
+		SELECT `+sleepSessionColumns+`
+		FROM sleep_sessions
+		WHERE day > $1
+		ORDER BY bedtime_start ASC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []SleepSession
+	for rows.Next() {
+		s, err := scanSleepSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// mainSleeps keeps the longest non-nap session per day, in day order
+func mainSleeps(sessions []SleepSession) []SleepSession {
+	byDay := make(map[string]int)
+	var result []SleepSession
+	for _, s := range sessions {
+		if s.IsNap {
+			continue
+		}
+		if i, ok := byDay[s.Day]; ok {
+			if s.TimeInBedSeconds > result[i].TimeInBedSeconds {
+				result[i] = s
+			}
+			continue
+		}
+		byDay[s.Day] = len(result)
+		result = append(result, s)
+	}
+	return result
+}
+
+// bedtimeMinutes is the local clock time in minutes after the previous
+// midnight: 23:30 is 1410 and 00:30 is 1470, so bedtimes either side of
+// midnight average sensibly
+func bedtimeMinutes(t time.Time) float64 {
+	m := float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60
+	if m < minutesPerDay/2 {
+		m += minutesPerDay
+	}
+	return m
+}
+
+// clockMinutes is the local clock time in minutes after midnight
+func clockMinutes(t time.Time) float64 {
+	return float64(t.Hour()*60+t.Minute()) + float64(t.Second())/60
+}
+
+// formatClock renders minutes (on any day offset) as HH:MM
+func formatClock(minutes float64) string {
+	m := int(math.Round(minutes)) % minutesPerDay
+	if m < 0 {
+		m += minutesPerDay
+	}
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+// meanStdDev returns the mean and population standard deviation
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// sleepNight is a main sleep reduced to the numbers the analyses need
+type sleepNight struct {
+	day        string
+	bedtime    float64 // bedtimeMinutes
+	wake       float64 // clockMinutes
+	midsleep   float64 // on the bedtime scale
+	sleepHours float64
+	freeDay    bool
+}
+
+// toSleepNight converts a session into local clock figures. The midsleep
+// point is halfway through time in bed.
+func toSleepNight(s SleepSession) sleepNight {
+	bed := bedtimeMinutes(s.BedtimeStart)
+	inBed := s.BedtimeEnd.Sub(s.BedtimeStart).Minutes()
+	asleep := inBed
+	if s.TotalSleepSeconds != nil {
+		asleep = float64(*s.TotalSleepSeconds) / 60
+	}
+
+	day, _ := time.Parse("2006-01-02", s.Day)
+	weekday := day.Weekday()
+
+	return sleepNight{
+		day:        s.Day,
+		bedtime:    bed,
+		wake:       clockMinutes(s.BedtimeEnd),
+		midsleep:   bed + inBed/2,
+		sleepHours: asleep / 60,
+		freeDay:    weekday == time.Saturday || weekday == time.Sunday,
+	}
+}
+
+// sleepConsistency rates the standard deviation of bedtimes
+func sleepConsistency(sdMinutes float64) string {
+	switch {
+	case sdMinutes <= 30:
+		return "very consistent"
+	case sdMinutes <= 60:
+		return "consistent"
+	case sdMinutes <= 90:
+		return "variable"
+	default:
+		return "irregular"
+	}
+}
+
+// GetSleepTiming returns real bedtime/wake time consistency from sleep sessions
+func (h *Handler) GetSleepTiming(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+
+	sessions, err := h.loadSleepSessions(r.Context(), days)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	analysis := SleepTimingAnalysis{
+		Days:       days,
+		StageShare: make(map[string]float64),
+		Detail:     []SleepTimingNight{},
+	}
+	for _, s := range sessions {
+		if s.IsNap {
+			analysis.Naps++
+		}
+	}
+
+	mains := mainSleeps(sessions)
+	analysis.Nights = len(mains)
+	if len(mains) == 0 {
+		analysis.Consistency = "unknown"
+		core.WriteJSON(w, http.StatusOK, analysis)
+		return
+	}
+
+	var bedtimes, wakes, midsleeps, sleepHours, efficiencies []float64
+	var inBedTotal, deepTotal, remTotal, lightTotal, awakeTotal float64
+	var hrSum, lowestSum, hrvSum float64
+	var hrCount, lowestCount, hrvCount int
+	var shiftSum float64
+
+	for i, s := range mains {
+		n := toSleepNight(s)
+		bedtimes = append(bedtimes, n.bedtime)
+		wakes = append(wakes, n.wake)
+		midsleeps = append(midsleeps, n.midsleep)
+		sleepHours = append(sleepHours, n.sleepHours)
+		if s.Efficiency != nil {
+			efficiencies = append(efficiencies, *s.Efficiency)
+		}
+		if i > 0 {
+			shiftSum += math.Abs(n.midsleep - midsleeps[i-1])
+		}
+
+		// Stage shares only over sessions that report all stages
+		if s.DeepSleepSeconds != nil && s.RemSleepSeconds != nil && s.LightSleepSeconds != nil && s.AwakeSeconds != nil {
+			inBedTotal += float64(s.TimeInBedSeconds)
+			deepTotal += float64(*s.DeepSleepSeconds)
+			remTotal += float64(*s.RemSleepSeconds)
+			lightTotal += float64(*s.LightSleepSeconds)
+			awakeTotal += float64(*s.AwakeSeconds)
+		}
+		if s.AverageHeartRate != nil {
+			hrSum += *s.AverageHeartRate
+			hrCount++
+		}
+		if s.LowestHeartRate != nil {
+			lowestSum += float64(*s.LowestHeartRate)
+			lowestCount++
+		}
+		if s.AverageHrv != nil {
+			hrvSum += float64(*s.AverageHrv)
+			hrvCount++
+		}
+
+		analysis.Detail = append(analysis.Detail, SleepTimingNight{
+			Day:             s.Day,
+			Bedtime:         formatClock(n.bedtime),
+			WakeTime:        formatClock(n.wake),
+			Midsleep:        formatClock(n.midsleep),
+			TotalSleepHours: n.sleepHours,
+			TimeInBedHours:  float64(s.TimeInBedSeconds) / 3600,
+			FreeDay:         n.freeDay,
+		})
+	}
+
+	avgBed, sdBed := meanStdDev(bedtimes)
+	avgWake, sdWake := meanStdDev(wakes)
+	avgMid, sdMid := meanStdDev(midsleeps)
+	analysis.AvgBedtime = formatClock(avgBed)
+	analysis.AvgWakeTime = formatClock(avgWake)
+	analysis.AvgMidsleep = formatClock(avgMid)
+	analysis.BedtimeSDMinutes = sdBed
+	analysis.WakeTimeSDMinutes = sdWake
+	analysis.MidsleepSDMinutes = sdMid
+	if len(mains) > 1 {
+		analysis.NightToNightShiftMinutes = shiftSum / float64(len(mains)-1)
+	}
+	analysis.Consistency = sleepConsistency(sdBed)
+
+	analysis.AvgTotalSleepHours, _ = meanStdDev(sleepHours)
+	analysis.AvgEfficiency, _ = meanStdDev(efficiencies)
+	if inBedTotal > 0 {
+		analysis.StageShare["deep"] = deepTotal / inBedTotal * 100
+		analysis.StageShare["rem"] = remTotal / inBedTotal * 100
+		analysis.StageShare["light"] = lightTotal / inBedTotal * 100
+		analysis.StageShare["awake"] = awakeTotal / inBedTotal * 100
+	}
+	if hrCount > 0 {
+		avg := hrSum / float64(hrCount)
+		analysis.AvgHeartRate = &avg
+	}
+	if lowestCount > 0 {
+		avg := lowestSum / float64(lowestCount)
+		analysis.AvgLowestHeartRate = &avg
+	}
+	if hrvCount > 0 {
+		avg := hrvSum / float64(hrvCount)
+		analysis.AvgHrv = &avg
+	}
+
+	core.WriteJSON(w, http.StatusOK, analysis)
+}
+
+// chronotypeLabel classifies corrected free-day mid-sleep (hours after midnight)
+func chronotypeLabel(msfHours float64) string {
+	switch {
+	case msfHours < 2:
+		return "extreme early"
+	case msfHours < 3:
+		return "early"
+	case msfHours <= 5:
+		return "intermediate"
+	case msfHours <= 6:
+		return "late"
+	default:
+		return "extreme late"
+	}
+}
+
+// GetSleepChronotype estimates social jet lag and chronotype by comparing
+// sleep before work days with sleep before free (weekend) days
+func (h *Handler) GetSleepChronotype(w http.ResponseWriter, r *http.Request) {
+	days := 56 // eight weekends by default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+
+	sessions, err := h.loadSleepSessions(r.Context(), days)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var workMid, freeMid, workSleep, freeSleep []float64
+	for _, s := range mainSleeps(sessions) {
+		n := toSleepNight(s)
+		if n.freeDay {
+			freeMid = append(freeMid, n.midsleep)
+			freeSleep = append(freeSleep, n.sleepHours)
+		} else {
+			workMid = append(workMid, n.midsleep)
+			workSleep = append(workSleep, n.sleepHours)
+		}
+	}
+
+	result := SleepChronotype{
+		Days:       days,
+		WorkNights: len(workMid),
+		FreeNights: len(freeMid),
+		Chronotype: "unknown",
+	}
+	if len(workMid) < 2 || len(freeMid) < 2 {
+		result.SocialJetLagRating = "unknown"
+		result.Message = "At least two work-day and two free-day nights are needed"
+		core.WriteJSON(w, http.StatusOK, result)
+		return
+	}
+
+	msw, _ := meanStdDev(workMid)
+	msf, _ := meanStdDev(freeMid)
+	sdw, _ := meanStdDev(workSleep)
+	sdf, _ := meanStdDev(freeSleep)
+
+	result.MidsleepWork = formatClock(msw)
+	result.MidsleepFree = formatClock(msf)
+	result.SleepHoursWork = sdw
+	result.SleepHoursFree = sdf
+	result.SocialJetLagHours = math.Abs(msf-msw) / 60
+
+	switch {
+	case result.SocialJetLagHours < 1:
+		result.SocialJetLagRating = "minimal"
+	case result.SocialJetLagHours < 2:
+		result.SocialJetLagRating = "moderate"
+	default:
+		result.SocialJetLagRating = "high"
+	}
+
+	// Oversleeping on free days to repay work-day debt pushes mid-sleep
+	// later; correct for it against the weekly average sleep duration
+	msfsc := msf
+	if sdf > sdw {
+		weekAvg := (5*sdw + 2*sdf) / 7
+		msfsc = msf - (sdf-weekAvg)*60/2
+	}
+	result.CorrectedMidsleepFree = formatClock(msfsc)
+	result.Chronotype = chronotypeLabel((msfsc - minutesPerDay) / 60)
+
+	result.Message = fmt.Sprintf("Free-day mid-sleep corrected for sleep debt is %s (%s chronotype). Weekend sleep is shifted %.1f h from work days (%s social jet lag).",
+		result.CorrectedMidsleepFree, result.Chronotype, result.SocialJetLagHours, result.SocialJetLagRating)
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,232 @@
+package health
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Statistical helpers for the correlation code
+
+// ranks returns the 1-based ranks of values, averaging ties
+func ranks(values []float64) []float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	r := make([]float64, len(values))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && values[idx[j+1]] == values[idx[i]] {
+			j++
+		}
+		avg := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			r[idx[k]] = avg
+		}
+		i = j + 1
+	}
+	return r
+}
+
+// spearmanCorrelation is Pearson's r on the ranks of x and y
+func spearmanCorrelation(x, y []float64) float64 {
+	return pearsonCorrelation(ranks(x), ranks(y))
+}
+
+// correlationPValue is the two-sided p-value of a correlation coefficient r
+// over n pairs, from Student's t with n-2 degrees of freedom
+func correlationPValue(r float64, n int) float64 {
+	if n < 3 {
+		return 1
+	}
+	if math.Abs(r) >= 1 {
+		return 0
+	}
+	df := float64(n - 2)
+	t := r * math.Sqrt(df/(1-r*r))
+	return studentTTwoSided(t, df)
+}
+
+// studentTTwoSided returns P(|T| >= |t|) for Student's t with df degrees of freedom
+func studentTTwoSided(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// correlationCI is the 95% confidence interval of r via the Fisher transform
+func correlationCI(r float64, n int) (float64, float64) {
+	if n < 4 {
+		return -1, 1
+	}
+	z := math.Atanh(math.Max(-0.999999, math.Min(0.999999, r)))
+	se := 1 / math.Sqrt(float64(n-3))
+	return math.Tanh(z - 1.96*se), math.Tanh(z + 1.96*se)
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with the continued fraction
+// from Numerical Recipes
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges fastest for x < (a+1)/(a+b+2)
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction for I_x(a, b)
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+// benjaminiHochberg converts p-values into false discovery rate q-values
+func benjaminiHochberg(pValues []float64) []float64 {
+	n := len(pValues)
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return pValues[idx[a]] < pValues[idx[b]] })
+
+	q := make([]float64, n)
+	running := 1.0
+	for k := n - 1; k >= 0; k-- {
+		i := idx[k]
+		running = math.Min(running, pValues[i]*float64(n)/float64(k+1))
+		q[i] = running
+	}
+	return q
+}
+
+// sampleMeanStdDev returns the mean and the n-1 sample standard deviation
+func sampleMeanStdDev(values []float64) (float64, float64) {
+	mean, sd := meanStdDev(values)
+	n := float64(len(values))
+	if n < 2 {
+		return mean, 0
+	}
+	return mean, sd * math.Sqrt(n/(n-1))
+}
+
+// cohensD is the standardised mean difference (b - a) using the pooled
+// standard deviation
+func cohensD(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	meanA, sdA := sampleMeanStdDev(a)
+	meanB, sdB := sampleMeanStdDev(b)
+	na, nb := float64(len(a)), float64(len(b))
+	pooled := math.Sqrt(((na-1)*sdA*sdA + (nb-1)*sdB*sdB) / (na + nb - 2))
+	if pooled == 0 {
+		return 0
+	}
+	return (meanB - meanA) / pooled
+}
+
+// bootstrapMeanDiffCI returns the 95% percentile bootstrap interval of
+// mean(b) - mean(a), resampling each group independently
+func bootstrapMeanDiffCI(a, b []float64, iterations int, rng *rand.Rand) (float64, float64) {
+	if len(a) == 0 || len(b) == 0 {
+		return 0, 0
+	}
+	resampledMean := func(values []float64) float64 {
+		var sum float64
+		for range values {
+			sum += values[rng.Intn(len(values))]
+		}
+		return sum / float64(len(values))
+	}
+	diffs := make([]float64, iterations)
+	for i := range diffs {
+		diffs[i] = resampledMean(b) - resampledMean(a)
+	}
+	sort.Float64s(diffs)
+	return percentileSorted(diffs, 0.025), percentileSorted(diffs, 0.975)
+}
+
+// percentileSorted interpolates the p-th quantile (0-1) of sorted values
+func percentileSorted(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// linearFit returns the least-squares slope and intercept of y on x
+func linearFit(xs, ys []float64) (float64, float64) {
+	n := float64(len(xs))
+	if n == 0 {
+		return 0, 0
+	}
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, sy / n
+	}
+	slope := (n*sxy - sx*sy) / den
+	return slope, (sy - slope*sx) / n
+}
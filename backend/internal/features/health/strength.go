@@ -0,0 +1,448 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// muscleGroups are the valid Exercise.MuscleGroup values
+var muscleGroups = map[string]bool{
+	"chest": true, "back": true, "shoulders": true, "biceps": true, "triceps": true,
+	"quads": true, "hamstrings": true, "glutes": true, "calves": true, "core": true, "full_body": true,
+}
+
+// exerciseEquipment are the valid Exercise.Equipment values
+var exerciseEquipment = map[string]bool{
+	"barbell": true, "dumbbell": true, "machine": true, "cable": true,
+	"bodyweight": true, "kettlebell": true, "other": true,
+}
+
+// maxOneRepMaxReps is the highest rep count used for 1RM estimates; the
+// Epley formula overestimates badly beyond it
+const maxOneRepMaxReps = 12
+
+// errUnknownExercise is returned when a set references a missing exercise
+var errUnknownExercise = errors.New("unknown exercise")
+
+// estimatedOneRepMax estimates a one-rep max with the Epley formula
+func estimatedOneRepMax(weightKg float64, reps int) float64 {
+	if reps <= 1 {
+		return weightKg
+	}
+	return weightKg * (1 + float64(reps)/30)
+}
+
+// validateWorkoutInput checks the recorded metrics and sets of a workout
+func validateWorkoutInput(input WorkoutInput) error {
+	if input.DurationSeconds != nil && *input.DurationSeconds < 0 {
+		return errors.New("duration_seconds must not be negative")
+	}
+	if input.RPE != nil && (*input.RPE < 1 || *input.RPE > 10) {
+		return errors.New("rpe must be between 1 and 10")
+	}
+	if input.AvgHeartRate != nil && (*input.AvgHeartRate < 20 || *input.AvgHeartRate > 250) {
+		return errors.New("avg_heart_rate must be between 20 and 250")
+	}
+	if input.DistanceMeters != nil && *input.DistanceMeters < 0 {
+		return errors.New("distance_meters must not be negative")
+	}
+	for i, set := range input.Sets {
+		if set.ExerciseID == 0 && strings.TrimSpace(set.Exercise) == "" {
+			return fmt.Errorf("sets[%d]: exercise_id or exercise is required", i)
+		}
+		if set.Sets <= 0 || set.Reps <= 0 {
+			return fmt.Errorf("sets[%d]: sets and reps must be positive", i)
+		}
+		if set.WeightKg < 0 {
+			return fmt.Errorf("sets[%d]: weight_kg must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// replaceWorkoutSets replaces all sets of a workout, resolving exercises by
+// ID or case-insensitive name
+func replaceWorkoutSets(ctx context.Context, q querier, workoutID int64, inputs []WorkoutSetInput) error {
+	if _, err := q.Exec(ctx, `DELETE FROM workout_sets WHERE workout_id = $1`, workoutID); err != nil {
+		return err
+	}
+
+	for i, set := range inputs {
+		var exerciseID int64
+		err := q.QueryRow(ctx, `
+			SELECT id FROM exercises
+			WHERE ($1 <> 0 AND id = $1) OR ($1 = 0 AND LOWER(name) = LOWER($2))
+		`, set.ExerciseID, strings.TrimSpace(set.Exercise)).Scan(&exerciseID)
+		if err == pgx.ErrNoRows {
+			if set.ExerciseID != 0 {
+				return fmt.Errorf("%w: id %d", errUnknownExercise, set.ExerciseID)
+			}
+			return fmt.Errorf("%w: %q", errUnknownExercise, set.Exercise)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.Exec(ctx, `
+			INSERT INTO workout_sets (workout_id, exercise_id, position, sets, reps, weight_kg)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, workoutID, exerciseID, i, set.Sets, set.Reps, set.WeightKg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadWorkoutSets returns the sets of a workout in logged order
+func loadWorkoutSets(ctx context.Context, q querier, workoutID int64) ([]WorkoutSet, error) {
+	rows, err := q.Query(ctx, `
+		SELECT ws.id, ws.exercise_id, e.name, e.muscle_group, ws.sets, ws.reps, ws.weight_kg
+		FROM workout_sets ws
+		JOIN exercises e ON e.id = ws.exercise_id
+		WHERE ws.workout_id = $1
+		ORDER BY ws.position, ws.id
+	`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sets []WorkoutSet
+	for rows.Next() {
+		var s WorkoutSet
+		if err := rows.Scan(&s.ID, &s.ExerciseID, &s.Exercise, &s.MuscleGroup, &s.Sets, &s.Reps, &s.WeightKg); err != nil {
+			return nil, err
+		}
+		s.Volume = float64(s.Sets*s.Reps) * s.WeightKg
+		s.Estimated1RM = math.Round(estimatedOneRepMax(s.WeightKg, s.Reps)*10) / 10
+		sets = append(sets, s)
+	}
+	return sets, rows.Err()
+}
+
+// writeWorkoutSetError reports a replaceWorkoutSets failure
+func writeWorkoutSetError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnknownExercise) {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	core.WriteError(w, http.StatusInternalServerError, err.Error())
+}
+
+// --- Exercise catalogue handlers ---
+
+// validateExercise normalizes and checks an exercise body
+func validateExercise(input *ExerciseInput) error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	if !muscleGroups[input.MuscleGroup] {
+		return errors.New("muscle_group must be one of chest, back, shoulders, biceps, triceps, quads, hamstrings, glutes, calves, core, full_body")
+	}
+	if input.Equipment == "" {
+		input.Equipment = "other"
+	}
+	if !exerciseEquipment[input.Equipment] {
+		return errors.New("equipment must be one of barbell, dumbbell, machine, cable, bodyweight, kettlebell, other")
+	}
+	return nil
+}
+
+// isConstraintViolation reports whether err is the given Postgres error code
+func isConstraintViolation(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// ListExercises returns the exercise catalogue, optionally for one muscle group
+func (h *Handler) ListExercises(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Query(r.Context(), `
+		SELECT id, name, muscle_group, equipment, created_at
+		FROM exercises
+		WHERE $1 = '' OR muscle_group = $1
+		ORDER BY muscle_group, name
+	`, r.URL.Query().Get("muscle_group"))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	exercises := []Exercise{}
+	for rows.Next() {
+		var e Exercise
+		if err := rows.Scan(&e.ID, &e.Name, &e.MuscleGroup, &e.Equipment, &e.CreatedAt); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		exercises = append(exercises, e)
+	}
+
+	core.WriteJSON(w, http.StatusOK, exercises)
+}
+
+// CreateExercise adds an exercise to the catalogue
+func (h *Handler) CreateExercise(w http.ResponseWriter, r *http.Request) {
+	var input ExerciseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateExercise(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e := Exercise{Name: input.Name, MuscleGroup: input.MuscleGroup, Equipment: input.Equipment}
+	err := h.db.QueryRow(r.Context(), `
+		INSERT INTO exercises (name, muscle_group, equipment)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, e.Name, e.MuscleGroup, e.Equipment).Scan(&e.ID, &e.CreatedAt)
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Exercise already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, e)
+}
+
+// UpdateExercise renames or reclassifies an exercise
+func (h *Handler) UpdateExercise(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var input ExerciseInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateExercise(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	e := Exercise{ID: id, Name: input.Name, MuscleGroup: input.MuscleGroup, Equipment: input.Equipment}
+	err = h.db.QueryRow(r.Context(), `
+		UPDATE exercises SET name = $2, muscle_group = $3, equipment = $4
+		WHERE id = $1
+		RETURNING created_at
+	`, id, e.Name, e.MuscleGroup, e.Equipment).Scan(&e.CreatedAt)
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Exercise not found")
+		return
+	}
+	if isConstraintViolation(err, "23505") {
+		core.WriteError(w, http.StatusConflict, "Exercise already exists")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, e)
+}
+
+// DeleteExercise removes an exercise that is not used by any workout
+func (h *Handler) DeleteExercise(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM exercises WHERE id = $1`, id)
+	if isConstraintViolation(err, "23503") {
+		core.WriteError(w, http.StatusConflict, "Exercise is used by workouts")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Exercise not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Strength progress handlers ---
+
+// GetOneRepMaxProgress returns the best estimated 1RM per training day for
+// one exercise, given as ?exercise_id= or ?exercise=<name>
+func (h *Handler) GetOneRepMaxProgress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	days := 180 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 730 {
+			days = n
+		}
+	}
+
+	exerciseID, _ := strconv.ParseInt(r.URL.Query().Get("exercise_id"), 10, 64)
+	name := strings.TrimSpace(r.URL.Query().Get("exercise"))
+	if exerciseID == 0 && name == "" {
+		core.WriteError(w, http.StatusBadRequest, "exercise_id or exercise is required")
+		return
+	}
+
+	var progress OneRepMaxProgress
+	e := &progress.Exercise
+	err := h.db.QueryRow(ctx, `
+		SELECT id, name, muscle_group, equipment, created_at FROM exercises
+		WHERE ($1 <> 0 AND id = $1) OR ($1 = 0 AND LOWER(name) = LOWER($2))
+	`, exerciseID, name).Scan(&e.ID, &e.Name, &e.MuscleGroup, &e.Equipment, &e.CreatedAt)
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Exercise not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	startDate := h.today(ctx).AddDate(0, 0, -days)
+	rows, err := h.db.Query(ctx, `
+		SELECT w.date, ws.weight_kg, ws.reps
+		FROM workout_sets ws
+		JOIN workouts w ON w.id = ws.workout_id
+		WHERE ws.exercise_id = $1 AND w.date >= $2 AND ws.weight_kg > 0 AND ws.reps <= $3
+		ORDER BY w.date ASC
+	`, e.ID, formatDate(startDate), maxOneRepMaxReps)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	progress.Points = []OneRepMaxPoint{}
+	for rows.Next() {
+		var day time.Time
+		var p OneRepMaxPoint
+		if err := rows.Scan(&day, &p.WeightKg, &p.Reps); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		p.Date = formatDate(day)
+		p.Estimated1RM = math.Round(estimatedOneRepMax(p.WeightKg, p.Reps)*10) / 10
+
+		// Keep the best set of each day
+		last := len(progress.Points) - 1
+		if last >= 0 && progress.Points[last].Date == p.Date {
+			if p.Estimated1RM > progress.Points[last].Estimated1RM {
+				progress.Points[last] = p
+			}
+			continue
+		}
+		progress.Points = append(progress.Points, p)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for i := range progress.Points {
+		if progress.Best == nil || progress.Points[i].Estimated1RM > progress.Best.Estimated1RM {
+			progress.Best = &progress.Points[i]
+		}
+	}
+	if n := len(progress.Points); n >= 2 {
+		change := math.Round((progress.Points[n-1].Estimated1RM-progress.Points[0].Estimated1RM)*10) / 10
+		progress.Change = &change
+	}
+
+	core.WriteJSON(w, http.StatusOK, progress)
+}
+
+// GetWeeklyVolume returns sets, reps and volume per muscle group for each of
+// the last ?weeks= weeks (Monday start), oldest first
+func (h *Handler) GetWeeklyVolume(w http.ResponseWriter, r *http.Request) {
+	weeks := 12 // default
+	if wk := r.URL.Query().Get("weeks"); wk != "" {
+		if n, err := strconv.Atoi(wk); err == nil && n > 0 && n <= 52 {
+			weeks = n
+		}
+	}
+
+	now := h.today(r.Context())
+	weekday := int(now.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	currentWeek := time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, time.UTC)
+	firstWeek := currentWeek.AddDate(0, 0, -7*(weeks-1))
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT date_trunc('week', w.date)::date AS week, e.muscle_group,
+			SUM(ws.sets), SUM(ws.sets * ws.reps), SUM(ws.sets * ws.reps * ws.weight_kg)
+		FROM workout_sets ws
+		JOIN workouts w ON w.id = ws.workout_id
+		JOIN exercises e ON e.id = ws.exercise_id
+		WHERE w.date >= $1
+		GROUP BY week, e.muscle_group
+	`, formatDate(firstWeek))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	byWeek := make(map[string][]MuscleGroupVolume)
+	for rows.Next() {
+		var week time.Time
+		var v MuscleGroupVolume
+		if err := rows.Scan(&week, &v.MuscleGroup, &v.Sets, &v.Reps, &v.Volume); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		byWeek[formatDate(week)] = append(byWeek[formatDate(week)], v)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Emit every week, including ones without training, for charting
+	result := make([]WeeklyVolume, 0, weeks)
+	for week := firstWeek; !week.After(currentWeek); week = week.AddDate(0, 0, 7) {
+		wv := WeeklyVolume{WeekStart: formatDate(week), MuscleGroups: byWeek[formatDate(week)]}
+		if wv.MuscleGroups == nil {
+			wv.MuscleGroups = []MuscleGroupVolume{}
+		}
+		sort.Slice(wv.MuscleGroups, func(i, j int) bool {
+			return wv.MuscleGroups[i].Volume > wv.MuscleGroups[j].Volume
+		})
+		for _, v := range wv.MuscleGroups {
+			wv.TotalVolume += v.Volume
+		}
+		result = append(result, wv)
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
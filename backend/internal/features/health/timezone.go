@@ -0,0 +1,406 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // zone names must resolve on hosts without a zoneinfo database
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// dayZonesTTL bounds how long zones inferred from sleep sessions are
+	// cached; settings and manual overrides reset the cache when changed
+	dayZonesTTL = 5 * time.Minute
+
+	maxTimezoneOverrideDays = 366
+
+	overrideSourceManual = "manual"
+	overrideSourceSleep  = "sleep"
+)
+
+// utcOffsetPattern matches fixed offsets such as +02:00, -0530 or UTC+2
+var utcOffsetPattern = regexp.MustCompile(`^(?:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// parseZone resolves an IANA zone name or a fixed UTC offset
+func parseZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "local") {
+		return nil, fmt.Errorf("timezone is required")
+	}
+	if m := utcOffsetPattern.FindStringSubmatch(strings.ToUpper(name)); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes := 0
+		if m[3] != "" {
+			minutes, _ = strconv.Atoi(m[3])
+		}
+		if hours > 14 || minutes >= 60 {
+			return nil, fmt.Errorf("invalid UTC offset %q", name)
+		}
+		secs := hours*3600 + minutes*60
+		if m[1] == "-" {
+			secs = -secs
+		}
+		return time.FixedZone(offsetName(secs), secs), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// offsetName formats a UTC offset in seconds as UTC+02:00
+func offsetName(secs int) string {
+	sign := "+"
+	if secs < 0 {
+		sign, secs = "-", -secs
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, secs/3600, secs%3600/60)
+}
+
+// dayOverride is the zone in force on one day away from home
+type dayOverride struct {
+	loc    *time.Location
+	source string // manual, sleep
+	note   string
+}
+
+// dayZones maps instants to calendar days: the home zone, except on days
+// with an override
+type dayZones struct {
+	home      *time.Location
+	overrides map[string]dayOverride // by YYYY-MM-DD
+}
+
+// dayOf returns the calendar day an instant belongs to. An override
+// applies when the instant falls on the overridden day in that zone, so a
+// trip changes the day boundaries only while it lasts.
+func (z *dayZones) dayOf(t time.Time) string {
+	homeDay := t.In(z.home)
+	for _, delta := range []int{0, -1, 1} {
+		day := formatDate(homeDay.AddDate(0, 0, delta))
+		if o, ok := z.overrides[day]; ok && formatDate(t.In(o.loc)) == day {
+			return day
+		}
+	}
+	return formatDate(homeDay)
+}
+
+// location returns the zone in force on a day
+func (z *dayZones) location(day string) *time.Location {
+	if o, ok := z.overrides[day]; ok {
+		return o.loc
+	}
+	return z.home
+}
+
+// today returns the current day at midnight UTC, matching parsed day keys
+func (z *dayZones) today(now time.Time) time.Time {
+	day, _ := time.Parse("2006-01-02", z.dayOf(now))
+	return day
+}
+
+// dayClock loads and caches the day zones from the database
+type dayClock struct {
+	db *pgxpool.Pool
+
+	mu       sync.Mutex
+	zones    *dayZones
+	loadedAt time.Time
+}
+
+// newDayClock creates a clock reading its settings from db
+func newDayClock(db *pgxpool.Pool) *dayClock {
+	return &dayClock{db: db}
+}
+
+// invalidate drops the cache after settings or overrides change
+func (c *dayClock) invalidate() {
+	c.mu.Lock()
+	c.zones = nil
+	c.mu.Unlock()
+}
+
+// get returns the cached zones, loading them when stale. Without stored
+// settings, or when they cannot be read, days follow the server's zone.
+func (c *dayClock) get(ctx context.Context) *dayZones {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.zones != nil && time.Since(c.loadedAt) < dayZonesTTL {
+		return c.zones
+	}
+	zones, err := loadDayZones(ctx, c.db)
+	if err != nil {
+		return &dayZones{home: time.Local, overrides: map[string]dayOverride{}}
+	}
+	c.zones, c.loadedAt = zones, time.Now()
+	return zones
+}
+
+// sleepOverride returns the zone of a main sleep session that ended at end
+// with the given UTC offset in minutes. It is false when the offset is the
+// home zone's own at that instant, so DST changes at home are not trips.
+func sleepOverride(home *time.Location, end time.Time, offsetMinutes int) (dayOverride, bool) {
+	_, homeOffset := end.In(home).Zone()
+	if offsetMinutes*60 == homeOffset {
+		return dayOverride{}, false
+	}
+	return dayOverride{
+		loc:    time.FixedZone(offsetName(offsetMinutes*60), offsetMinutes*60),
+		source: overrideSourceSleep,
+	}, true
+}
+
+// loadDayZones reads the home zone, the manual overrides and the zones of
+// main sleep sessions recorded away from the home offset
+func loadDayZones(ctx context.Context, q querier) (*dayZones, error) {
+	zones := &dayZones{home: time.Local, overrides: map[string]dayOverride{}}
+
+	var homeName string
+	err := q.QueryRow(ctx, `SELECT home_timezone FROM health_settings WHERE id = 1`).Scan(&homeName)
+	if err == nil {
+		if loc, err := parseZone(homeName); err == nil {
+			zones.home = loc
+		}
+	}
+
+	// Sleep sessions first, so manual overrides replace them
+	rows, err := q.Query(ctx, `
+		SELECT DISTINCT ON (day) day, bedtime_end, utc_offset_minutes
+		FROM sleep_sessions
+		WHERE NOT is_nap
+		ORDER BY day, total_sleep_seconds DESC NULLS LAST
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var day, end time.Time
+		var offset int
+		if err := rows.Scan(&day, &end, &offset); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if o, ok := sleepOverride(zones.home, end, offset); ok {
+			zones.overrides[formatDate(day)] = o
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(ctx, `SELECT day, timezone, note FROM timezone_overrides`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		var name, note string
+		if err := rows.Scan(&day, &name, &note); err != nil {
+			return nil, err
+		}
+		loc, err := parseZone(name)
+		if err != nil {
+			continue // stored before the zone was removed from tzdata
+		}
+		zones.overrides[formatDate(day)] = dayOverride{loc: loc, source: overrideSourceManual, note: note}
+	}
+	return zones, rows.Err()
+}
+
+// today is the current day in the configured zones, at midnight UTC
+func (h *Handler) today(ctx context.Context) time.Time {
+	return h.clock.get(ctx).today(time.Now())
+}
+
+// todayKey is today as YYYY-MM-DD
+func (h *Handler) todayKey(ctx context.Context) string {
+	return formatDate(h.today(ctx))
+}
+
+// dayOf is the calendar day of an instant in the configured zones
+func (h *Handler) dayOf(ctx context.Context, t time.Time) string {
+	return h.clock.get(ctx).dayOf(t)
+}
+
+// --- Handlers ---
+
+// GetTimezoneSettings returns the home zone, today's zone and date, and
+// the overrides for the coming and past month
+func (h *Handler) GetTimezoneSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var settings TimezoneSettings
+	err := h.db.QueryRow(ctx, `SELECT home_timezone FROM health_settings WHERE id = 1`).Scan(&settings.HomeTimezone)
+	if err != nil {
+		settings.HomeTimezone = "" // not configured, the server's zone is used
+	}
+
+	zones := h.clock.get(ctx)
+	now := time.Now()
+	settings.Today = zones.dayOf(now)
+	settings.CurrentTimezone = zones.location(settings.Today).String()
+	settings.LocalTime = now.In(zones.location(settings.Today)).Format(time.RFC3339)
+
+	today, _ := time.Parse("2006-01-02", settings.Today)
+	settings.Overrides = listTimezoneOverrides(zones, formatDate(today.AddDate(0, 0, -30)), formatDate(today.AddDate(0, 0, 30)))
+
+	core.WriteJSON(w, http.StatusOK, settings)
+}
+
+// PutTimezoneSettings sets the home zone, an IANA name such as Europe/Oslo
+func (h *Handler) PutTimezoneSettings(w http.ResponseWriter, r *http.Request) {
+	var input TimezoneSettingsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	input.HomeTimezone = strings.TrimSpace(input.HomeTimezone)
+	if _, err := parseZone(input.HomeTimezone); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err := h.db.Exec(r.Context(), `
+		INSERT INTO health_settings (id, home_timezone) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET home_timezone = EXCLUDED.home_timezone, updated_at = NOW()
+	`, input.HomeTimezone)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.clock.invalidate()
+
+	h.GetTimezoneSettings(w, r)
+}
+
+// listTimezoneOverrides returns the overrides between two days, in order
+func listTimezoneOverrides(zones *dayZones, from, to string) []TimezoneOverride {
+	overrides := []TimezoneOverride{}
+	start, _ := time.Parse("2006-01-02", from)
+	end, _ := time.Parse("2006-01-02", to)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		d := formatDate(day)
+		if o, ok := zones.overrides[d]; ok {
+			overrides = append(overrides, TimezoneOverride{Day: d, Timezone: o.loc.String(), Source: o.source, Note: o.note})
+		}
+	}
+	return overrides
+}
+
+// ListTimezoneOverrides returns manual and sleep-derived overrides.
+// Query: ?from=, ?to= (default the last 90 days and the next 30)
+func (h *Handler) ListTimezoneOverrides(w http.ResponseWriter, r *http.Request) {
+	today := h.today(r.Context())
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		from = formatDate(today.AddDate(0, 0, -90))
+	}
+	if to == "" {
+		to = formatDate(today.AddDate(0, 0, 30))
+	}
+	start, err1 := time.Parse("2006-01-02", from)
+	end, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		core.WriteError(w, http.StatusBadRequest, "dates must be YYYY-MM-DD")
+		return
+	}
+	if end.Sub(start).Hours()/24 > 3660 {
+		core.WriteError(w, http.StatusBadRequest, "range must be at most 10 years")
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, listTimezoneOverrides(h.clock.get(r.Context()), from, to))
+}
+
+// SetTimezoneOverrides sets the zone for a day or a trip of consecutive days
+func (h *Handler) SetTimezoneOverrides(w http.ResponseWriter, r *http.Request) {
+	var input TimezoneOverrideInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	loc, err := parseZone(input.Timezone)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.End == "" {
+		input.End = input.Start
+	}
+	start, err1 := time.Parse("2006-01-02", input.Start)
+	end, err2 := time.Parse("2006-01-02", input.End)
+	if err1 != nil || err2 != nil {
+		core.WriteError(w, http.StatusBadRequest, "start and end must be YYYY-MM-DD")
+		return
+	}
+	days := int(end.Sub(start).Hours()/24) + 1
+	if days < 1 || days > maxTimezoneOverrideDays {
+		core.WriteError(w, http.StatusBadRequest, fmt.Sprintf("end must be within %d days after start", maxTimezoneOverrideDays))
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	overrides := []TimezoneOverride{}
+	note := strings.TrimSpace(input.Note)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		_, err := tx.Exec(r.Context(), `
+			INSERT INTO timezone_overrides (day, timezone, note) VALUES ($1, $2, $3)
+			ON CONFLICT (day) DO UPDATE SET timezone = EXCLUDED.timezone, note = EXCLUDED.note
+		`, formatDate(day), loc.String(), note)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		overrides = append(overrides, TimezoneOverride{Day: formatDate(day), Timezone: loc.String(), Source: overrideSourceManual, Note: note})
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.clock.invalidate()
+
+	core.WriteJSON(w, http.StatusOK, overrides)
+}
+
+// DeleteTimezoneOverride removes the manual override of a day
+func (h *Handler) DeleteTimezoneOverride(w http.ResponseWriter, r *http.Request) {
+	day := chi.URLParam(r, "day")
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "day must be YYYY-MM-DD")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM timezone_overrides WHERE day = $1`, day)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Override not found")
+		return
+	}
+	h.clock.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
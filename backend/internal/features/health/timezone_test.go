@@ -0,0 +1,205 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := parseZone(name)
+	if err != nil {
+		t.Fatalf("parseZone(%q): %v", name, err)
+	}
+	return loc
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDayOfAcrossDST(t *testing.T) {
+	zones := &dayZones{home: mustZone(t, "Europe/Stockholm"), overrides: map[string]dayOverride{}}
+
+	// Stockholm moves to CEST at 01:00 UTC on 31 March 2024 and back to
+	// CET at 01:00 UTC on 27 October 2024
+	tests := []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"last minute before spring-forward day", "2024-03-30T22:59:00Z", "2024-03-30"},
+		{"midnight CET on spring-forward day", "2024-03-30T23:00:00Z", "2024-03-31"},
+		{"01:59 CET, just before the change", "2024-03-31T00:59:59Z", "2024-03-31"},
+		{"03:00 CEST, just after the change", "2024-03-31T01:00:00Z", "2024-03-31"},
+		{"23:59 CEST, the 23-hour day ends", "2024-03-31T21:59:00Z", "2024-03-31"},
+		{"midnight CEST after spring-forward", "2024-03-31T22:00:00Z", "2024-04-01"},
+		{"23:59 CEST before fall-back day", "2024-10-26T21:59:00Z", "2024-10-26"},
+		{"midnight CEST on fall-back day", "2024-10-26T22:00:00Z", "2024-10-27"},
+		{"first 02:30, in CEST", "2024-10-27T00:30:00Z", "2024-10-27"},
+		{"second 02:30, in CET", "2024-10-27T01:30:00Z", "2024-10-27"},
+		{"23:59 CET, the 25-hour day ends", "2024-10-27T22:59:00Z", "2024-10-27"},
+		{"midnight CET after fall-back", "2024-10-27T23:00:00Z", "2024-10-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zones.dayOf(utc(tt.at)); got != tt.want {
+				t.Errorf("dayOf(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDayOfTrip(t *testing.T) {
+	newYork := mustZone(t, "America/New_York")
+	zones := &dayZones{home: mustZone(t, "Europe/Stockholm"), overrides: map[string]dayOverride{}}
+	for _, day := range []string{"2024-06-10", "2024-06-11", "2024-06-12", "2024-06-13", "2024-06-14"} {
+		zones.overrides[day] = dayOverride{loc: newYork, source: overrideSourceManual}
+	}
+
+	// New York is on EDT (UTC-4) and Stockholm on CEST (UTC+2)
+	tests := []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"home day before the trip", "2024-06-09T12:00:00Z", "2024-06-09"},
+		{"evening before the trip in New York is already the first day at home", "2024-06-10T03:30:00Z", "2024-06-10"},
+		{"00:30 on the first day in New York", "2024-06-10T04:30:00Z", "2024-06-10"},
+		{"22:00 in New York is the next day at home", "2024-06-13T02:00:00Z", "2024-06-12"},
+		{"noon on the last day", "2024-06-14T16:00:00Z", "2024-06-14"},
+		{"23:00 on the last day in New York", "2024-06-15T03:00:00Z", "2024-06-14"},
+		{"23:59 on the last day in New York", "2024-06-15T03:59:00Z", "2024-06-14"},
+		{"00:30 after the trip in New York falls back to home", "2024-06-15T04:30:00Z", "2024-06-15"},
+		{"home day after the trip", "2024-06-15T10:00:00Z", "2024-06-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zones.dayOf(utc(tt.at)); got != tt.want {
+				t.Errorf("dayOf(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+		})
+	}
+
+	if got := zones.location("2024-06-12"); got != newYork {
+		t.Errorf("location on a trip day = %v, want America/New_York", got)
+	}
+	if got := zones.location("2024-06-15"); got != zones.home {
+		t.Errorf("location after the trip = %v, want the home zone", got)
+	}
+
+	want := time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)
+	if got := zones.today(utc("2024-06-15T03:00:00Z")); !got.Equal(want) {
+		t.Errorf("today = %v, want %v", got, want)
+	}
+}
+
+func TestWorkoutFileDay(t *testing.T) {
+	zones := &dayZones{home: mustZone(t, "Europe/Stockholm"), overrides: map[string]dayOverride{
+		"2024-06-12": {loc: mustZone(t, "America/New_York"), source: overrideSourceManual},
+	}}
+
+	tests := []struct {
+		name  string
+		start string
+		want  string
+	}{
+		{"late run at home, after midnight UTC+2", "2024-06-05T22:30:00Z", "2024-06-06"},
+		{"late run at home, before midnight UTC+2", "2024-06-05T21:30:00Z", "2024-06-05"},
+		{"late run in New York, already the next day at home", "2024-06-13T02:30:00Z", "2024-06-12"},
+		{"00:30 on fall-back day, still CEST", "2024-10-26T22:30:00Z", "2024-10-27"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &parsedWorkoutFile{summary: workoutSummary{start: utc(tt.start)}}
+			if got := f.day(zones); got != tt.want {
+				t.Errorf("day(%s) = %s, want %s", tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleepOverride(t *testing.T) {
+	home := mustZone(t, "Europe/Stockholm")
+
+	tests := []struct {
+		name   string
+		end    string
+		offset int // minutes
+		want   bool
+	}{
+		{"home in winter", "2024-03-30T06:00:00Z", 60, false},
+		{"home the morning after spring-forward", "2024-04-01T05:00:00Z", 120, false},
+		{"winter offset after spring-forward", "2024-04-01T05:00:00Z", 60, true},
+		{"summer offset before spring-forward", "2024-03-30T06:00:00Z", 120, true},
+		{"home the morning after fall-back", "2024-10-28T06:00:00Z", 60, false},
+		{"summer offset after fall-back", "2024-10-28T06:00:00Z", 120, true},
+		{"New York", "2024-06-12T11:00:00Z", -240, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, ok := sleepOverride(home, utc(tt.end), tt.offset)
+			if ok != tt.want {
+				t.Fatalf("sleepOverride = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if o.source != overrideSourceSleep {
+				t.Errorf("source %q, want %q", o.source, overrideSourceSleep)
+			}
+			if _, secs := utc(tt.end).In(o.loc).Zone(); secs != tt.offset*60 {
+				t.Errorf("zone offset %ds, want %ds", secs, tt.offset*60)
+			}
+		})
+	}
+}
+
+func TestParseZone(t *testing.T) {
+	tests := []struct {
+		in      string
+		name    string
+		offset  int // seconds, checked for fixed offsets
+		wantErr bool
+	}{
+		{in: "Europe/Stockholm", name: "Europe/Stockholm"},
+		{in: " America/New_York ", name: "America/New_York"},
+		{in: "+0530", name: "UTC+05:30", offset: 19800},
+		{in: "UTC-3", name: "UTC-03:00", offset: -10800},
+		{in: "+02:00", name: "UTC+02:00", offset: 7200},
+		{in: "gmt+2", name: "UTC+02:00", offset: 7200},
+		{in: "-09:30", name: "UTC-09:30", offset: -34200},
+		{in: "UTC+14", name: "UTC+14:00", offset: 50400},
+		{in: "+15", wantErr: true},
+		{in: "+05:60", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "Local", wantErr: true},
+		{in: "Mars/Olympus_Mons", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			loc, err := parseZone(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseZone(%q) = %v, want an error", tt.in, loc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseZone(%q): %v", tt.in, err)
+			}
+			if loc.String() != tt.name {
+				t.Errorf("name %q, want %q", loc.String(), tt.name)
+			}
+			if tt.offset != 0 {
+				if _, secs := time.Now().In(loc).Zone(); secs != tt.offset {
+					t.Errorf("offset %ds, want %ds", secs, tt.offset)
+				}
+			}
+		})
+	}
+}
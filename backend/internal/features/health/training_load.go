@@ -0,0 +1,360 @@
+package health
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+// Training load methods
+const (
+	trainingLoadSRPE  = "srpe"  // session RPE: minutes x RPE (Foster)
+	trainingLoadTRIMP = "trimp" // Banister TRIMP from heart rate reserve
+)
+
+const (
+	acuteLoadDays   = 7
+	chronicLoadDays = 28
+
+	// Banister impulse-response time constants, in days
+	fitnessTimeConstant = 42.0
+	fatigueTimeConstant = 7.0
+
+	// trainingLoadWarmupDays of history seed the fitness/fatigue curves
+	// before the requested window starts
+	trainingLoadWarmupDays = 3 * int(fitnessTimeConstant)
+
+	defaultMaxHeartRate     = 190
+	defaultRestingHeartRate = 60
+	defaultSessionRPE       = 5
+)
+
+// trainingLoadOptions selects the load method and heart rate anchors
+type trainingLoadOptions struct {
+	method string
+	female bool
+	maxHR  int
+	restHR int
+}
+
+// heartRateReserve is the fraction of heart rate reserve used at avgHR
+func heartRateReserve(avgHR, maxHR, restHR int) float64 {
+	if maxHR <= restHR {
+		return 0
+	}
+	hrr := float64(avgHR-restHR) / float64(maxHR-restHR)
+	return math.Max(0, math.Min(1, hrr))
+}
+
+// banisterTRIMP is Banister's training impulse for a session
+func banisterTRIMP(minutes, hrr float64, female bool) float64 {
+	if female {
+		return minutes * hrr * 0.86 * math.Exp(1.67*hrr)
+	}
+	return minutes * hrr * 0.64 * math.Exp(1.92*hrr)
+}
+
+// workoutLoad computes the load of one workout. Intensity that was not
+// recorded is estimated: RPE from heart rate reserve (RPE ~ 10 x HRR) and
+// vice versa, falling back to a moderate RPE. estimated reports whether
+// either fallback was used.
+func workoutLoad(opts trainingLoadOptions, durationSeconds int, rpe, avgHR *int) (load float64, estimated bool) {
+	minutes := float64(durationSeconds) / 60
+
+	switch opts.method {
+	case trainingLoadTRIMP:
+		var hrr float64
+		if avgHR != nil {
+			hrr = heartRateReserve(*avgHR, opts.maxHR, opts.restHR)
+		} else if rpe != nil {
+			hrr, estimated = float64(*rpe)/10, true
+		} else {
+			hrr, estimated = float64(defaultSessionRPE)/10, true
+		}
+		return banisterTRIMP(minutes, hrr, opts.female), estimated
+	default:
+		var intensity float64
+		if rpe != nil {
+			intensity = float64(*rpe)
+		} else if avgHR != nil {
+			intensity = math.Max(1, math.Round(10*heartRateReserve(*avgHR, opts.maxHR, opts.restHR)))
+			estimated = true
+		} else {
+			intensity, estimated = defaultSessionRPE, true
+		}
+		return minutes * intensity, estimated
+	}
+}
+
+// acwrRiskBand classifies an acute:chronic workload ratio using the
+// commonly cited 0.8-1.3 "sweet spot"
+func acwrRiskBand(acwr *float64) string {
+	switch {
+	case acwr == nil:
+		return "unknown"
+	case *acwr < 0.8:
+		return "low" // undertraining; a later spike carries more risk
+	case *acwr <= 1.3:
+		return "optimal"
+	case *acwr <= 1.5:
+		return "elevated"
+	default:
+		return "high"
+	}
+}
+
+// formBand classifies form relative to fitness, so the bands do not depend
+// on the load method's units
+func formBand(form, fitness float64) string {
+	if fitness < 1 {
+		return "unknown"
+	}
+	switch ratio := form / fitness; {
+	case ratio >= 0.1:
+		return "fresh"
+	case ratio >= -0.1:
+		return "neutral"
+	case ratio >= -0.3:
+		return "productive"
+	default:
+		return "overreaching"
+	}
+}
+
+// trainingHeartRates returns the max and resting heart rate used for TRIMP:
+// the highest recorded workout max HR and the median resting HR of the last
+// 30 days, with defaults when there is no data
+func (h *Handler) trainingHeartRates(ctx context.Context) (int, int, error) {
+	maxHR, restHR := defaultMaxHeartRate, defaultRestingHeartRate
+
+	var recordedMax *int
+	if err := h.db.QueryRow(ctx, `SELECT MAX(max_heart_rate) FROM workouts`).Scan(&recordedMax); err != nil {
+		return 0, 0, err
+	}
+	if recordedMax != nil && *recordedMax > restHR {
+		maxHR = *recordedMax
+	}
+
+	var recordedRest *float64
+	err := h.db.QueryRow(ctx, `
+		SELECT COALESCE(
+			(SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY value) FROM daily_metrics
+			 WHERE metric = 'resting_heart_rate' AND day >= $1::date - 30),
+			(SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY lowest_heart_rate) FROM sleep_sessions
+			 WHERE NOT is_nap AND lowest_heart_rate IS NOT NULL AND day >= $1::date - 30)
+		)
+	`, h.todayKey(ctx)).Scan(&recordedRest)
+	if err != nil {
+		return 0, 0, err
+	}
+	if recordedRest != nil && int(math.Round(*recordedRest)) < maxHR {
+		restHR = int(math.Round(*recordedRest))
+	}
+
+	return maxHR, restHR, nil
+}
+
+// computeTrainingLoad builds daily load, ACWR and the fitness/fatigue/form
+// curves for the `days` days ending on end
+func (h *Handler) computeTrainingLoad(ctx context.Context, opts trainingLoadOptions, days int, end time.Time) (*TrainingLoad, error) {
+	if opts.maxHR == 0 || opts.restHR == 0 {
+		maxHR, restHR, err := h.trainingHeartRates(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if opts.maxHR == 0 {
+			opts.maxHR = maxHR
+		}
+		if opts.restHR == 0 {
+			opts.restHR = restHR
+		}
+	}
+
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -(days - 1))
+	warmupStart := start.AddDate(0, 0, -trainingLoadWarmupDays)
+
+	rows, err := h.db.Query(ctx, `
+		SELECT date, duration_seconds, rpe, avg_heart_rate
+		FROM workouts
+		WHERE date >= $1 AND date <= $2
+	`, formatDate(warmupStart), formatDate(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &TrainingLoad{
+		Method:           opts.method,
+		MaxHeartRate:     opts.maxHR,
+		RestingHeartRate: opts.restHR,
+		Days:             []TrainingLoadDay{},
+	}
+
+	type dayLoad struct {
+		load                float64
+		workouts, estimated int
+	}
+	loads := make(map[string]*dayLoad)
+	for rows.Next() {
+		var day time.Time
+		var duration, rpe, avgHR *int
+		if err := rows.Scan(&day, &duration, &rpe, &avgHR); err != nil {
+			return nil, err
+		}
+		if duration == nil || *duration <= 0 {
+			if !day.Before(start) {
+				result.SkippedWorkouts++
+			}
+			continue
+		}
+		load, estimated := workoutLoad(opts, *duration, rpe, avgHR)
+		dl := loads[formatDate(day)]
+		if dl == nil {
+			dl = &dayLoad{}
+			loads[formatDate(day)] = dl
+		}
+		dl.load += load
+		dl.workouts++
+		if estimated {
+			dl.estimated++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Walk every day from the warmup start so rolling sums and the
+	// exponentially weighted curves are seeded
+	var series []float64
+	var fitness, fatigue float64
+	fitnessDecay := 1 - math.Exp(-1/fitnessTimeConstant)
+	fatigueDecay := 1 - math.Exp(-1/fatigueTimeConstant)
+
+	for day := warmupStart; !day.After(end); day = day.AddDate(0, 0, 1) {
+		p := TrainingLoadDay{Date: formatDate(day)}
+		if dl := loads[p.Date]; dl != nil {
+			p.Load = round1(dl.load)
+			p.Workouts = dl.workouts
+			p.Estimated = dl.estimated
+		}
+		series = append(series, p.Load)
+
+		// Form is the freshness going into the day, before its training
+		form := fitness - fatigue
+		fitness += (p.Load - fitness) * fitnessDecay
+		fatigue += (p.Load - fatigue) * fatigueDecay
+
+		if day.Before(start) {
+			continue
+		}
+
+		p.AcuteLoad = round1(sumLast(series, acuteLoadDays))
+		p.ChronicLoad = round1(sumLast(series, chronicLoadDays) / (chronicLoadDays / acuteLoadDays))
+		if p.ChronicLoad > 0 {
+			acwr := math.Round(p.AcuteLoad/p.ChronicLoad*100) / 100
+			p.ACWR = &acwr
+		}
+		p.Fitness = round1(fitness)
+		p.Fatigue = round1(fatigue)
+		p.Form = round1(form)
+		result.Days = append(result.Days, p)
+	}
+
+	if n := len(result.Days); n > 0 {
+		result.Current = trainingLoadStatus(result.Days[n-1])
+	}
+
+	return result, nil
+}
+
+// trainingLoadStatus classifies one day of the training load series
+func trainingLoadStatus(day TrainingLoadDay) TrainingLoadStatus {
+	return TrainingLoadStatus{
+		Date:        day.Date,
+		AcuteLoad:   day.AcuteLoad,
+		ChronicLoad: day.ChronicLoad,
+		ACWR:        day.ACWR,
+		RiskBand:    acwrRiskBand(day.ACWR),
+		Fitness:     day.Fitness,
+		Fatigue:     day.Fatigue,
+		Form:        day.Form,
+		FormBand:    formBand(day.Form, day.Fitness),
+	}
+}
+
+// sumLast sums the last n values of series
+func sumLast(series []float64, n int) float64 {
+	if len(series) < n {
+		n = len(series)
+	}
+	var sum float64
+	for _, v := range series[len(series)-n:] {
+		sum += v
+	}
+	return sum
+}
+
+// round1 rounds to one decimal
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// round2 rounds to two decimals
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// GetTrainingLoad returns daily training load with acute (7d) and chronic
+// (28d) load, ACWR and the Banister fitness/fatigue/form curves.
+// Query: ?days= (default 90), ?method=srpe|trimp, ?sex=female for the
+// female TRIMP weighting, ?max_hr= and ?rest_hr= to override the
+// heart rates derived from recorded data.
+func (h *Handler) GetTrainingLoad(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	days := 90 // default
+	if d := query.Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+	}
+
+	opts := trainingLoadOptions{method: trainingLoadSRPE, female: query.Get("sex") == "female"}
+	switch m := query.Get("method"); m {
+	case "", trainingLoadSRPE:
+	case trainingLoadTRIMP:
+		opts.method = m
+	default:
+		core.WriteError(w, http.StatusBadRequest, "method must be 'srpe' or 'trimp'")
+		return
+	}
+	if v := query.Get("max_hr"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 100 || n > 250 {
+			core.WriteError(w, http.StatusBadRequest, "max_hr must be between 100 and 250")
+			return
+		}
+		opts.maxHR = n
+	}
+	if v := query.Get("rest_hr"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 25 || n > 120 {
+			core.WriteError(w, http.StatusBadRequest, "rest_hr must be between 25 and 120")
+			return
+		}
+		opts.restHR = n
+	}
+
+	load, err := h.computeTrainingLoad(r.Context(), opts, days, h.today(r.Context()))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, load)
+}
@@ -0,0 +1,558 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+)
+
+// ouraMetricColumns are the numeric oura_daily columns usable in verdict
+// rules; each is also available as <name>_avg7d and <name>_avg30d
+var ouraMetricColumns = []string{
+	"sleep_score", "sleep_deep_sleep", "sleep_efficiency", "sleep_latency", "sleep_rem_sleep",
+	"sleep_restfulness", "sleep_timing", "sleep_total_sleep",
+	"readiness_score", "readiness_activity_balance", "readiness_body_temperature", "readiness_hrv_balance",
+	"readiness_previous_day_activity", "readiness_previous_night", "readiness_recovery_index",
+	"readiness_resting_heart_rate", "readiness_sleep_balance", "readiness_sleep_regularity",
+	"temperature_deviation",
+	"activity_score", "activity_active_calories", "activity_steps", "activity_total_calories",
+	"activity_meet_daily_targets", "activity_move_every_hour", "activity_recovery_time",
+	"activity_stay_active", "activity_training_frequency", "activity_training_volume",
+}
+
+// verdictDerivedMetrics are the numeric metrics computed per day besides
+// the oura_daily columns and their rolling averages
+var verdictDerivedMetrics = []string{
+	"sleep_debt", "sleep_debt_days",
+	"acute_load", "chronic_load", "acwr", "fitness", "fatigue", "form", "form_ratio",
+}
+
+// verdictLabelMetrics are string-valued metrics, compared with == and !=
+var verdictLabelMetrics = map[string]string{
+	"form_band": "fresh, neutral, productive, overreaching, unknown",
+	"acwr_band": "low, optimal, elevated, high, unknown",
+}
+
+// verdictTypes are the valid VerdictRule.VerdictType values
+var verdictTypes = map[string]bool{"push": true, "normal": true, "recovery": true, "unknown": true}
+
+// verdictSleepDebtDays is the window sleep debt is accumulated over
+const verdictSleepDebtDays = 14
+
+// defaultVerdictRules are used while no custom rules are stored. They keep
+// the original 80/70/60/50 score thresholds, adjusted by training form.
+var defaultVerdictRules = []VerdictRule{
+	{Name: "no_data", VerdictType: "unknown", Message: "No data available", Conditions: []VerdictCondition{
+		{Metric: "sleep_score", Op: "missing"}, {Metric: "readiness_score", Op: "missing"},
+	}},
+	{Name: "rested_but_fatigued", VerdictType: "normal", Message: "Well rested, but training fatigue is high — keep intensity moderate", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: ">=", Value: 80.0}, {Metric: "sleep_score", Op: ">=", Value: 80.0},
+		{Metric: "form_band", Op: "==", Value: "overreaching"},
+	}},
+	{Name: "well_rested", VerdictType: "push", Message: "Well rested — good day for intensity", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: ">=", Value: 80.0}, {Metric: "sleep_score", Op: ">=", Value: 80.0},
+	}},
+	{Name: "low_readiness", VerdictType: "recovery", Message: "Recovery needed — take it easy today", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: "<", Value: 50.0},
+	}},
+	{Name: "poor_sleep", VerdictType: "recovery", Message: "Recovery needed — take it easy today", Conditions: []VerdictCondition{
+		{Metric: "sleep_score", Op: "<", Value: 50.0},
+	}},
+	{Name: "below_baseline_readiness", VerdictType: "recovery", Message: "Below baseline — keep activity light", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: "<", Value: 60.0},
+	}},
+	{Name: "below_baseline_sleep", VerdictType: "recovery", Message: "Below baseline — keep activity light", Conditions: []VerdictCondition{
+		{Metric: "sleep_score", Op: "<", Value: 60.0},
+	}},
+	{Name: "training_fatigue", VerdictType: "recovery", Message: "Training fatigue is high — make today an easy day", Conditions: []VerdictCondition{
+		{Metric: "form_band", Op: "==", Value: "overreaching"},
+	}},
+	{Name: "solid_and_fresh", VerdictType: "push", Message: "Solid day and fresh legs — room for a harder session", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: ">=", Value: 70.0}, {Metric: "sleep_score", Op: ">=", Value: 70.0},
+		{Metric: "form_band", Op: "==", Value: "fresh"},
+	}},
+	{Name: "solid", VerdictType: "normal", Message: "Solid day — normal activity is fine", Conditions: []VerdictCondition{
+		{Metric: "readiness_score", Op: ">=", Value: 70.0}, {Metric: "sleep_score", Op: ">=", Value: 70.0},
+	}},
+	{Name: "average", VerdictType: "normal", Message: "Average day — listen to your body"},
+}
+
+// verdictContext holds the metrics of one day that rules are evaluated on
+type verdictContext struct {
+	day    string
+	values map[string]float64
+	labels map[string]string
+	load   *TrainingLoadStatus
+}
+
+// verdictMetricNames lists every metric a condition may reference
+func verdictMetricNames() []string {
+	names := make([]string, 0, 3*len(ouraMetricColumns)+len(verdictDerivedMetrics)+len(verdictLabelMetrics))
+	for _, c := range ouraMetricColumns {
+		names = append(names, c, c+"_avg7d", c+"_avg30d")
+	}
+	names = append(names, verdictDerivedMetrics...)
+	for label := range verdictLabelMetrics {
+		names = append(names, label)
+	}
+	return names
+}
+
+// isNumericVerdictMetric reports whether metric is a known numeric metric
+func isNumericVerdictMetric(metric string) bool {
+	base := strings.TrimSuffix(strings.TrimSuffix(metric, "_avg7d"), "_avg30d")
+	for _, c := range ouraMetricColumns {
+		if c == base {
+			return true
+		}
+	}
+	for _, m := range verdictDerivedMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVerdictRules checks a rule set before it is stored or tested
+func validateVerdictRules(rules []VerdictRule) error {
+	if len(rules) == 0 {
+		return errors.New("at least one rule is required")
+	}
+	names := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return fmt.Errorf("rules[%d]: name is required", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rules[%d]: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		if !verdictTypes[rule.VerdictType] {
+			return fmt.Errorf("rule %q: verdict_type must be push, normal, recovery or unknown", rule.Name)
+		}
+		if strings.TrimSpace(rule.Message) == "" {
+			return fmt.Errorf("rule %q: message is required", rule.Name)
+		}
+		for _, c := range rule.Conditions {
+			if err := validateVerdictCondition(c); err != nil {
+				return fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVerdictCondition checks the metric, operator and value of a condition
+func validateVerdictCondition(c VerdictCondition) error {
+	_, isLabel := verdictLabelMetrics[c.Metric]
+	if !isLabel && !isNumericVerdictMetric(c.Metric) {
+		return fmt.Errorf("unknown metric %q", c.Metric)
+	}
+
+	switch c.Op {
+	case "exists", "missing":
+		return nil
+	case "==", "!=":
+		if isLabel {
+			if _, ok := c.Value.(string); !ok {
+				return fmt.Errorf("%s must be compared with a string (%s)", c.Metric, verdictLabelMetrics[c.Metric])
+			}
+			return nil
+		}
+	case ">", ">=", "<", "<=":
+		if isLabel {
+			return fmt.Errorf("%s only supports ==, !=, exists and missing", c.Metric)
+		}
+	default:
+		return fmt.Errorf("op must be one of >, >=, <, <=, ==, !=, exists, missing")
+	}
+
+	if _, ok := c.Value.(float64); !ok {
+		return fmt.Errorf("%s %s needs a numeric value", c.Metric, c.Op)
+	}
+	return nil
+}
+
+// matches reports whether a condition holds for the day. Comparisons on a
+// metric without data are false.
+func (c VerdictCondition) matches(vc verdictContext) bool {
+	if label, isLabel := vc.labels[c.Metric]; isLabel || verdictLabelMetrics[c.Metric] != "" {
+		switch c.Op {
+		case "exists":
+			return isLabel
+		case "missing":
+			return !isLabel
+		case "==":
+			return isLabel && label == c.Value
+		case "!=":
+			return isLabel && label != c.Value
+		}
+		return false
+	}
+
+	value, ok := vc.values[c.Metric]
+	switch c.Op {
+	case "exists":
+		return ok
+	case "missing":
+		return !ok
+	}
+	target, isNumber := c.Value.(float64)
+	if !ok || !isNumber {
+		return false
+	}
+	switch c.Op {
+	case ">":
+		return value > target
+	case ">=":
+		return value >= target
+	case "<":
+		return value < target
+	case "<=":
+		return value <= target
+	case "==":
+		return value == target
+	case "!=":
+		return value != target
+	}
+	return false
+}
+
+// evaluateVerdictRules returns the first rule whose conditions all hold
+func evaluateVerdictRules(rules []VerdictRule, vc verdictContext) *VerdictRule {
+	for i := range rules {
+		matched := true
+		for _, c := range rules[i].Conditions {
+			if !c.matches(vc) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// verdictRules returns the stored rule set, or the defaults when none is stored
+func (h *Handler) verdictRules(ctx context.Context) ([]VerdictRule, bool, error) {
+	rows, err := h.db.Query(ctx, `
+		SELECT id, name, conditions, verdict_type, message
+		FROM verdict_rules
+		ORDER BY position, id
+	`)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var rules []VerdictRule
+	for rows.Next() {
+		var rule VerdictRule
+		var conditions []byte
+		if err := rows.Scan(&rule.ID, &rule.Name, &conditions, &rule.VerdictType, &rule.Message); err != nil {
+			return nil, false, err
+		}
+		if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+			return nil, false, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(rules) == 0 {
+		return defaultVerdictRules, false, nil
+	}
+	return rules, true, nil
+}
+
+// loadVerdictContexts builds the rule inputs for every day with Oura data
+// between start and end, oldest first
+func (h *Handler) loadVerdictContexts(ctx context.Context, start, end time.Time) ([]verdictContext, error) {
+	// Rolling averages and sleep debt need history before the window
+	historyStart := start.AddDate(0, 0, -30)
+
+	columns := make([]string, len(ouraMetricColumns))
+	for i, c := range ouraMetricColumns {
+		columns[i] = c + "::double precision"
+	}
+	rows, err := h.db.Query(ctx, `
+		SELECT day, `+strings.Join(columns, ", ")+`
+		FROM oura_daily
+		WHERE day >= $1 AND day <= $2
+		ORDER BY day ASC
+	`, formatDate(historyStart), formatDate(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type ouraRow struct {
+		day    time.Time
+		values []*float64
+	}
+	var history []ouraRow
+	for rows.Next() {
+		row := ouraRow{values: make([]*float64, len(ouraMetricColumns))}
+		dest := make([]interface{}, 0, len(ouraMetricColumns)+1)
+		dest = append(dest, &row.day)
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		history = append(history, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Training load for the whole window, keyed by day
+	loads := make(map[string]TrainingLoadDay)
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(endDay.Sub(startDay).Hours()/24) + 1
+	load, err := h.computeTrainingLoad(ctx, trainingLoadOptions{method: trainingLoadSRPE}, days, endDay)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range load.Days {
+		loads[d.Date] = d
+	}
+
+	var contexts []verdictContext
+	sleepIdx := -1
+	for i, col := range ouraMetricColumns {
+		if col == "sleep_total_sleep" {
+			sleepIdx = i
+		}
+	}
+	for i, row := range history {
+		if row.day.Before(startDay) {
+			continue
+		}
+		vc := verdictContext{
+			day:    formatDate(row.day),
+			values: make(map[string]float64),
+			labels: make(map[string]string),
+		}
+
+		for j, col := range ouraMetricColumns {
+			if v := row.values[j]; v != nil {
+				vc.values[col] = *v
+			}
+			for _, window := range []struct {
+				suffix string
+				days   int
+			}{{"_avg7d", 7}, {"_avg30d", 30}} {
+				var sum float64
+				var n int
+				from := row.day.AddDate(0, 0, -(window.days - 1))
+				for k := i; k >= 0 && !history[k].day.Before(from); k-- {
+					if v := history[k].values[j]; v != nil {
+						sum += *v
+						n++
+					}
+				}
+				if n > 0 {
+					vc.values[col+window.suffix] = sum / float64(n)
+				}
+			}
+		}
+
+		// Sleep debt over the trailing window, same model as the sleep analysis
+		var points []SleepBreakdownPoint
+		from := row.day.AddDate(0, 0, -(verdictSleepDebtDays - 1))
+		for k := 0; k <= i; k++ {
+			if history[k].day.Before(from) {
+				continue
+			}
+			p := SleepBreakdownPoint{Day: formatDate(history[k].day)}
+			if v := history[k].values[sleepIdx]; v != nil {
+				total := int(*v)
+				p.TotalSleep = &total
+			}
+			points = append(points, p)
+		}
+		debt := calculateSleepDebt(points)
+		vc.values["sleep_debt"] = debt.CurrentDebt
+		vc.values["sleep_debt_days"] = float64(debt.DaysInDebt)
+
+		if d, ok := loads[vc.day]; ok {
+			status := trainingLoadStatus(d)
+			vc.load = &status
+			vc.values["acute_load"] = d.AcuteLoad
+			vc.values["chronic_load"] = d.ChronicLoad
+			vc.values["fitness"] = d.Fitness
+			vc.values["fatigue"] = d.Fatigue
+			vc.values["form"] = d.Form
+			if d.ACWR != nil {
+				vc.values["acwr"] = *d.ACWR
+			}
+			if d.Fitness > 0 {
+				vc.values["form_ratio"] = d.Form / d.Fitness
+			}
+			vc.labels["form_band"] = status.FormBand
+			vc.labels["acwr_band"] = status.RiskBand
+		}
+
+		contexts = append(contexts, vc)
+	}
+	return contexts, nil
+}
+
+// --- Verdict rule handlers ---
+
+// GetVerdictRules returns the active rule set and the metrics rules can use
+func (h *Handler) GetVerdictRules(w http.ResponseWriter, r *http.Request) {
+	rules, custom, err := h.verdictRules(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	set := VerdictRuleSet{Source: "default", Rules: rules, Metrics: verdictMetricNames()}
+	if custom {
+		set.Source = "custom"
+	}
+	core.WriteJSON(w, http.StatusOK, set)
+}
+
+// PutVerdictRules replaces the rule set; rules are evaluated in the given order
+func (h *Handler) PutVerdictRules(w http.ResponseWriter, r *http.Request) {
+	var input VerdictRuleSet
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := validateVerdictRules(input.Rules); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	if _, err := tx.Exec(r.Context(), `DELETE FROM verdict_rules`); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range input.Rules {
+		rule := &input.Rules[i]
+		if rule.Conditions == nil {
+			rule.Conditions = []VerdictCondition{}
+		}
+		conditions, err := json.Marshal(rule.Conditions)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		err = tx.QueryRow(r.Context(), `
+			INSERT INTO verdict_rules (position, name, conditions, verdict_type, message)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, i, rule.Name, conditions, rule.VerdictType, rule.Message).Scan(&rule.ID)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, VerdictRuleSet{Source: "custom", Rules: input.Rules, Metrics: verdictMetricNames()})
+}
+
+// DeleteVerdictRules removes the custom rules, restoring the defaults
+func (h *Handler) DeleteVerdictRules(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.db.Exec(r.Context(), `DELETE FROM verdict_rules`); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestVerdictRules replays a rule set (the active one when none is given)
+// over the last `days` days and reports what each day's verdict would be
+func (h *Handler) TestVerdictRules(w http.ResponseWriter, r *http.Request) {
+	var input VerdictRuleTestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if input.Days == 0 {
+		input.Days = 90
+	}
+	if input.Days < 1 || input.Days > 365 {
+		core.WriteError(w, http.StatusBadRequest, "days must be between 1 and 365")
+		return
+	}
+
+	rules := input.Rules
+	if rules == nil {
+		var err error
+		if rules, _, err = h.verdictRules(r.Context()); err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else if err := validateVerdictRules(rules); err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	end := h.today(r.Context())
+	contexts, err := h.loadVerdictContexts(r.Context(), end.AddDate(0, 0, -(input.Days-1)), end)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := VerdictRuleTest{
+		Days:    len(contexts),
+		Counts:  map[string]int{"push": 0, "normal": 0, "recovery": 0, "unknown": 0},
+		Rules:   make([]VerdictRuleStat, len(rules)),
+		Results: []VerdictTestDay{},
+	}
+	for i, rule := range rules {
+		result.Rules[i] = VerdictRuleStat{Rule: rule.Name, VerdictType: rule.VerdictType}
+	}
+
+	for _, vc := range contexts {
+		day := VerdictTestDay{Day: vc.day, VerdictType: "unknown"}
+		if rule := evaluateVerdictRules(rules, vc); rule != nil {
+			day.Verdict, day.VerdictType, day.Rule = rule.Message, rule.VerdictType, rule.Name
+			for i := range rules {
+				if &rules[i] == rule {
+					result.Rules[i].Count++
+				}
+			}
+		} else {
+			result.Unmatched++
+		}
+		result.Counts[day.VerdictType]++
+		result.Results = append(result.Results, day)
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
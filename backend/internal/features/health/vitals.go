@@ -0,0 +1,512 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	vitalBloodPressure = "blood_pressure"
+	vitalSpO2          = "spo2"
+	vitalGlucose       = "glucose"
+)
+
+// vitalContexts are the accepted glucose reading contexts
+var vitalContexts = map[string]bool{"": true, "fasting": true, "post_meal": true, "random": true}
+
+// bloodPressureCategory classifies a reading with the ACC/AHA categories
+func bloodPressureCategory(systolic, diastolic int) string {
+	switch {
+	case systolic > 180 || diastolic > 120:
+		return "crisis"
+	case systolic >= 140 || diastolic >= 90:
+		return "stage_2"
+	case systolic >= 130 || diastolic >= 80:
+		return "stage_1"
+	case systolic >= 120:
+		return "elevated"
+	case systolic < 90 || diastolic < 60:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// spO2Flag is low below 95% and critical below 90%
+func spO2Flag(pct float64) string {
+	switch {
+	case pct < 90:
+		return "critical"
+	case pct < 95:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// glucoseFlag classifies a glucose reading in mmol/L for its context
+func glucoseFlag(mmol float64, readingContext string) string {
+	fasting := readingContext == "fasting"
+	switch {
+	case mmol < 3.9:
+		return "low"
+	case fasting && mmol >= 7.0:
+		return "high"
+	case fasting && mmol >= 5.6:
+		return "elevated"
+	case !fasting && mmol >= 11.1:
+		return "high"
+	case !fasting && mmol >= 7.8:
+		return "elevated"
+	default:
+		return "normal"
+	}
+}
+
+// vitalFlag classifies a stored reading
+func vitalFlag(v Vital) string {
+	switch {
+	case v.Kind == vitalBloodPressure && v.Systolic != nil && v.Diastolic != nil:
+		return bloodPressureCategory(*v.Systolic, *v.Diastolic)
+	case v.Kind == vitalSpO2 && v.Value != nil:
+		return spO2Flag(*v.Value)
+	case v.Kind == vitalGlucose && v.Value != nil:
+		return glucoseFlag(*v.Value, v.Context)
+	}
+	return ""
+}
+
+// prepareVital validates a reading and normalises SpO2 to percent and
+// glucose to mmol/L. The reading's day follows the configured zones.
+func prepareVital(input VitalInput, zones *dayZones) (Vital, error) {
+	v := Vital{
+		Kind:      input.Kind,
+		Systolic:  input.Systolic,
+		Diastolic: input.Diastolic,
+		Pulse:     input.Pulse,
+		Context:   strings.TrimSpace(input.Context),
+		Notes:     strings.TrimSpace(input.Notes),
+	}
+
+	v.MeasuredAt = time.Now()
+	v.Date = zones.dayOf(v.MeasuredAt)
+	if input.MeasuredAt != "" {
+		t, err := time.Parse(time.RFC3339, input.MeasuredAt)
+		if err == nil {
+			v.MeasuredAt = t
+			v.Date = zones.dayOf(t)
+		} else {
+			// A bare date is taken as noon UTC on that day
+			d, derr := time.Parse("2006-01-02", input.MeasuredAt)
+			if derr != nil {
+				return v, fmt.Errorf("measured_at must be RFC 3339 or YYYY-MM-DD")
+			}
+			v.MeasuredAt = d.Add(12 * time.Hour)
+			v.Date = input.MeasuredAt
+		}
+	}
+
+	if v.Pulse != nil && (*v.Pulse < 20 || *v.Pulse > 250) {
+		return v, fmt.Errorf("pulse must be between 20 and 250")
+	}
+
+	switch v.Kind {
+	case vitalBloodPressure:
+		if v.Systolic == nil || v.Diastolic == nil {
+			return v, fmt.Errorf("systolic and diastolic are required for blood pressure")
+		}
+		if *v.Systolic < 50 || *v.Systolic > 300 || *v.Diastolic < 30 || *v.Diastolic > 200 {
+			return v, fmt.Errorf("blood pressure is out of range")
+		}
+		if *v.Diastolic >= *v.Systolic {
+			return v, fmt.Errorf("diastolic must be below systolic")
+		}
+		v.Unit = "mmHg"
+
+	case vitalSpO2:
+		if input.Value == nil {
+			return v, fmt.Errorf("value is required for spo2")
+		}
+		pct := *input.Value
+		if pct > 0 && pct <= 1 {
+			pct *= 100 // given as a fraction
+		}
+		if pct < 50 || pct > 100 {
+			return v, fmt.Errorf("spo2 must be between 50 and 100 percent")
+		}
+		pct = round1(pct)
+		v.Value, v.Unit = &pct, "%"
+
+	case vitalGlucose:
+		if input.Value == nil {
+			return v, fmt.Errorf("value is required for glucose")
+		}
+		mmol, unit, err := normaliseLabValue("glucose", *input.Value, input.Unit)
+		if err != nil {
+			return v, err
+		}
+		if mmol <= 0 || mmol > 50 {
+			return v, fmt.Errorf("glucose is out of range")
+		}
+		v.Value, v.Unit = &mmol, unit
+		if !vitalContexts[v.Context] {
+			return v, fmt.Errorf("context must be fasting, post_meal or random")
+		}
+
+	default:
+		return v, fmt.Errorf("kind must be blood_pressure, spo2 or glucose")
+	}
+	if v.Kind != vitalBloodPressure {
+		v.Systolic, v.Diastolic = nil, nil
+	}
+	if v.Kind != vitalGlucose {
+		v.Context = ""
+	}
+	v.Flag = vitalFlag(v)
+	return v, nil
+}
+
+// vitalColumns is the column list matching scanVital
+const vitalColumns = `id, kind, measured_at, date, systolic, diastolic, pulse, value, unit, context, notes, source`
+
+// scanVital scans a row selected with vitalColumns
+func scanVital(row pgx.Row) (Vital, error) {
+	var v Vital
+	var day time.Time
+	err := row.Scan(&v.ID, &v.Kind, &v.MeasuredAt, &day, &v.Systolic, &v.Diastolic, &v.Pulse,
+		&v.Value, &v.Unit, &v.Context, &v.Notes, &v.Source)
+	if err != nil {
+		return v, err
+	}
+	v.Date = formatDate(day)
+	v.Flag = vitalFlag(v)
+	return v, nil
+}
+
+// insertVital stores a reading. A reading of the same kind at the same
+// time already stored is left alone and reported as not inserted.
+func insertVital(ctx context.Context, q querier, v Vital, source string) (Vital, bool, error) {
+	stored, err := scanVital(q.QueryRow(ctx, `
+		INSERT INTO vitals (kind, measured_at, date, systolic, diastolic, pulse, value, unit, context, notes, source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (kind, measured_at) DO NOTHING
+		RETURNING `+vitalColumns,
+		v.Kind, v.MeasuredAt, v.Date, v.Systolic, v.Diastolic, v.Pulse, v.Value, v.Unit, v.Context, v.Notes, source))
+	if err == pgx.ErrNoRows {
+		return v, false, nil
+	}
+	return stored, err == nil, err
+}
+
+// ListVitals returns readings, newest first. Query: ?kind=, ?days= (default 30)
+func (h *Handler) ListVitals(w http.ResponseWriter, r *http.Request) {
+	days := 30 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 3650 {
+			days = n
+		}
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+vitalColumns+`
+		FROM vitals
+		WHERE ($1 = '' OR kind = $1) AND date > $2
+		ORDER BY measured_at DESC
+	`, r.URL.Query().Get("kind"), formatDate(h.today(r.Context()).AddDate(0, 0, -days)))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	vitals := []Vital{}
+	for rows.Next() {
+		v, err := scanVital(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		vitals = append(vitals, v)
+	}
+
+	core.WriteJSON(w, http.StatusOK, vitals)
+}
+
+// CreateVital records a blood pressure, SpO2 or glucose reading
+func (h *Handler) CreateVital(w http.ResponseWriter, r *http.Request) {
+	var input VitalInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	v, err := prepareVital(input, h.clock.get(r.Context()))
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v, inserted, err := insertVital(r.Context(), h.db, v, "manual")
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !inserted {
+		core.WriteError(w, http.StatusConflict, "A reading of this kind already exists at that time")
+		return
+	}
+
+	core.WriteJSON(w, http.StatusCreated, v)
+}
+
+// DeleteVital removes a reading
+func (h *Handler) DeleteVital(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	result, err := h.db.Exec(r.Context(), `DELETE FROM vitals WHERE id = $1`, id)
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if result.RowsAffected() == 0 {
+		core.WriteError(w, http.StatusNotFound, "Vital not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportVitals loads readings from CSV with the columns kind, measured_at
+// (or date), and systolic/diastolic/pulse or value/unit, plus optional
+// context and notes. Readings already stored are skipped without error.
+func (h *Handler) ImportVitals(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	reader, cols, err := readCSVUpload(w, r)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := cols["kind"]; !ok {
+		core.WriteError(w, http.StatusBadRequest, "CSV needs a kind column")
+		return
+	}
+	zones := h.clock.get(ctx)
+
+	result := CSVImportResult{Errors: []CSVRowError{}}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		skip := func(err error) {
+			result.Skipped++
+			result.Errors = append(result.Errors, CSVRowError{Row: row, Error: err.Error()})
+		}
+		if err != nil {
+			skip(err)
+			continue
+		}
+
+		input := VitalInput{
+			Kind:       strings.ToLower(csvValue(record, cols, "kind", "type")),
+			MeasuredAt: csvValue(record, cols, "measured_at", "date"),
+			Unit:       csvValue(record, cols, "unit"),
+			Context:    strings.ToLower(csvValue(record, cols, "context")),
+			Notes:      csvValue(record, cols, "notes"),
+		}
+		if input.Value, err = csvFloat(record, cols, "value"); err != nil {
+			skip(err)
+			continue
+		}
+		ints := []struct {
+			name string
+			dst  **int
+		}{{"systolic", &input.Systolic}, {"diastolic", &input.Diastolic}, {"pulse", &input.Pulse}}
+		for _, field := range ints {
+			f, ferr := csvFloat(record, cols, field.name)
+			if ferr != nil {
+				err = ferr
+				break
+			}
+			if f != nil {
+				n := int(math.Round(*f))
+				*field.dst = &n
+			}
+		}
+		if err != nil {
+			skip(err)
+			continue
+		}
+
+		v, err := prepareVital(input, zones)
+		if err != nil {
+			skip(err)
+			continue
+		}
+		_, inserted, err := insertVital(ctx, h.db, v, "csv")
+		if err != nil {
+			skip(err)
+			continue
+		}
+		if inserted {
+			result.Imported++
+		} else {
+			result.Duplicates++
+		}
+	}
+
+	core.WriteJSON(w, http.StatusOK, result)
+}
+
+// GetVitalTrend returns daily averages and flag counts for one kind of
+// reading. Query: ?days= (default 90)
+func (h *Handler) GetVitalTrend(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	if kind != vitalBloodPressure && kind != vitalSpO2 && kind != vitalGlucose {
+		core.WriteError(w, http.StatusBadRequest, "kind must be blood_pressure, spo2 or glucose")
+		return
+	}
+
+	days := 90 // default
+	if d := r.URL.Query().Get("days"); d != "" {
+		if n, err := strconv.Atoi(d); err == nil && n > 0 && n <= 3650 {
+			days = n
+		}
+	}
+
+	rows, err := h.db.Query(r.Context(), `
+		SELECT `+vitalColumns+`
+		FROM vitals
+		WHERE kind = $1 AND date > $2
+		ORDER BY measured_at
+	`, kind, formatDate(h.today(r.Context()).AddDate(0, 0, -days)))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var readings []Vital
+	for rows.Next() {
+		v, err := scanVital(rows)
+		if err != nil {
+			core.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		readings = append(readings, v)
+	}
+	if err := rows.Err(); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, vitalTrend(kind, days, readings))
+}
+
+// vitalTrend averages readings per day, oldest first, and fits the weekly
+// change of the main value (systolic for blood pressure)
+func vitalTrend(kind string, days int, readings []Vital) VitalTrend {
+	trend := VitalTrend{Kind: kind, Days: days, Readings: len(readings), Flags: map[string]int{}, Daily: []VitalDay{}}
+
+	type sums struct{ main, diastolic, pulse, pulses float64 }
+	var totals sums
+	var current *VitalDay
+	var day sums
+	finish := func() {
+		if current == nil {
+			return
+		}
+		n := float64(current.Readings)
+		main := round1(day.main / n)
+		if kind == vitalBloodPressure {
+			dia := round1(day.diastolic / n)
+			current.Systolic, current.Diastolic = &main, &dia
+		} else {
+			current.Value = &main
+		}
+		if day.pulses > 0 {
+			p := round1(day.pulse / day.pulses)
+			current.Pulse = &p
+		}
+		trend.Daily = append(trend.Daily, *current)
+	}
+
+	for _, v := range readings {
+		if v.Flag != "" {
+			trend.Flags[v.Flag]++
+		}
+		if current == nil || current.Date != v.Date {
+			finish()
+			current = &VitalDay{Date: v.Date}
+			day = sums{}
+		}
+		current.Readings++
+		var main float64
+		if kind == vitalBloodPressure {
+			main = float64(*v.Systolic)
+			day.diastolic += float64(*v.Diastolic)
+			totals.diastolic += float64(*v.Diastolic)
+		} else {
+			main = *v.Value
+		}
+		day.main += main
+		totals.main += main
+		if v.Pulse != nil {
+			day.pulse += float64(*v.Pulse)
+			day.pulses++
+		}
+	}
+	finish()
+	if len(readings) == 0 {
+		return trend
+	}
+
+	latest := readings[len(readings)-1]
+	trend.Latest = &latest
+	n := float64(len(readings))
+	avg := round1(totals.main / n)
+	if kind == vitalBloodPressure {
+		dia := round1(totals.diastolic / n)
+		trend.AvgSystolic, trend.AvgDiastolic = &avg, &dia
+		trend.Category = bloodPressureCategory(int(math.Round(avg)), int(math.Round(dia)))
+	} else {
+		trend.AvgValue = &avg
+	}
+
+	if len(trend.Daily) >= 3 {
+		first, _ := time.Parse("2006-01-02", trend.Daily[0].Date)
+		xs := make([]float64, len(trend.Daily))
+		ys := make([]float64, len(trend.Daily))
+		for i, d := range trend.Daily {
+			t, _ := time.Parse("2006-01-02", d.Date)
+			xs[i] = t.Sub(first).Hours() / 24 / 7
+			if d.Systolic != nil {
+				ys[i] = *d.Systolic
+			} else {
+				ys[i] = *d.Value
+			}
+		}
+		if xs[len(xs)-1] > 0 {
+			slope, _ := linearFit(xs, ys)
+			perWeek := round2(slope)
+			trend.ChangePerWeek = &perWeek
+		}
+	}
+	return trend
+}
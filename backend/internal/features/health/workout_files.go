@@ -0,0 +1,462 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EsbenHerman/HermanAdmin/backend/internal/core"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxWorkoutFileBytes caps FIT/GPX uploads
+	maxWorkoutFileBytes = 64 << 20
+	// splitMeters is the length of automatic splits when a file has no laps
+	splitMeters = 1000
+	// elevationThreshold filters GPS/barometer noise out of elevation gain
+	elevationThreshold = 2.0
+	// earthRadiusMeters is used for haversine distances
+	earthRadiusMeters = 6371000
+)
+
+// workoutSummary holds the totals derived from a workout file
+type workoutSummary struct {
+	start           time.Time
+	durationSeconds *int
+	distanceMeters  *float64
+	elevationGain   *float64
+	avgHeartRate    *int
+	maxHeartRate    *int
+	calories        *int
+}
+
+// haversine returns the distance in meters between two coordinates
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// cumulativeDistances returns the distance covered at each point, preferring
+// the device's own distance field over GPS
+func cumulativeDistances(points []TrackPoint) []float64 {
+	dist := make([]float64, len(points))
+	var total float64
+	for i, p := range points {
+		switch {
+		case p.Distance != nil:
+			total = *p.Distance
+		case i > 0 && p.Lat != nil && points[i-1].Lat != nil:
+			total += haversine(*points[i-1].Lat, *points[i-1].Lon, *p.Lat, *p.Lon)
+		}
+		dist[i] = total
+	}
+	return dist
+}
+
+// elevationGain sums climbs larger than elevationThreshold
+func elevationGain(points []TrackPoint) (float64, bool) {
+	var gain float64
+	var ref *float64
+	for _, p := range points {
+		if p.Elevation == nil {
+			continue
+		}
+		switch {
+		case ref == nil:
+			ref = p.Elevation
+		case *p.Elevation-*ref >= elevationThreshold:
+			gain += *p.Elevation - *ref
+			ref = p.Elevation
+		case *ref-*p.Elevation >= elevationThreshold:
+			ref = p.Elevation
+		}
+	}
+	return gain, ref != nil
+}
+
+// heartRateStats returns average and max heart rate of the points
+func heartRateStats(points []TrackPoint) (*int, *int) {
+	var sum, count, max int
+	for _, p := range points {
+		if p.HeartRate == nil {
+			continue
+		}
+		sum += *p.HeartRate
+		count++
+		if *p.HeartRate > max {
+			max = *p.HeartRate
+		}
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	avg := int(math.Round(float64(sum) / float64(count)))
+	return &avg, &max
+}
+
+// summarizeTrack derives workout totals from the track alone
+func summarizeTrack(points []TrackPoint) workoutSummary {
+	var s workoutSummary
+	if len(points) == 0 {
+		return s
+	}
+	s.start = points[0].Time
+
+	duration := int(points[len(points)-1].Time.Sub(points[0].Time).Seconds())
+	s.durationSeconds = &duration
+
+	dist := cumulativeDistances(points)
+	if total := dist[len(dist)-1]; total > 0 {
+		s.distanceMeters = &total
+	}
+	if gain, ok := elevationGain(points); ok {
+		s.elevationGain = &gain
+	}
+	s.avgHeartRate, s.maxHeartRate = heartRateStats(points)
+	return s
+}
+
+// setLapPace fills the pace of a lap from its distance and duration
+func setLapPace(lap *WorkoutLap) {
+	if lap.DistanceMeters > 0 && lap.DurationSeconds > 0 {
+		pace := lap.DurationSeconds / (lap.DistanceMeters / 1000)
+		lap.PaceSecondsPerKm = &pace
+	}
+}
+
+// autoSplits cuts the track into splitMeters splits (the last one partial)
+func autoSplits(points []TrackPoint) []WorkoutLap {
+	dist := cumulativeDistances(points)
+	var laps []WorkoutLap
+	startIdx := 0
+	for i := 1; i < len(points); i++ {
+		last := i == len(points)-1
+		if dist[i]-dist[startIdx] < splitMeters && !last {
+			continue
+		}
+		segment := points[startIdx : i+1]
+		lap := WorkoutLap{
+			Index:           len(laps) + 1,
+			StartTime:       points[startIdx].Time,
+			DurationSeconds: points[i].Time.Sub(points[startIdx].Time).Seconds(),
+			DistanceMeters:  dist[i] - dist[startIdx],
+		}
+		if gain, ok := elevationGain(segment); ok {
+			lap.ElevationGainMeters = &gain
+		}
+		lap.AvgHeartRate, lap.MaxHeartRate = heartRateStats(segment)
+		setLapPace(&lap)
+		if lap.DistanceMeters > 0 || lap.DurationSeconds > 0 {
+			laps = append(laps, lap)
+		}
+		startIdx = i
+	}
+	return laps
+}
+
+// trackGeoJSON renders the positioned points as a GeoJSON LineString
+func trackGeoJSON(workoutID int64, points []TrackPoint) GeoJSONFeature {
+	coords := [][]float64{}
+	for _, p := range points {
+		if p.Lat == nil || p.Lon == nil {
+			continue
+		}
+		c := []float64{*p.Lon, *p.Lat}
+		if p.Elevation != nil {
+			c = append(c, *p.Elevation)
+		}
+		coords = append(coords, c)
+	}
+
+	props := map[string]interface{}{
+		"workout_id": workoutID,
+		"points":     len(points),
+	}
+	if len(points) > 0 {
+		props["start_time"] = points[0].Time
+		props["end_time"] = points[len(points)-1].Time
+	}
+
+	return GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   GeoJSONLineString{Type: "LineString", Coordinates: coords},
+		Properties: props,
+	}
+}
+
+// parsedWorkoutFile is a decoded FIT or GPX upload
+type parsedWorkoutFile struct {
+	format   string
+	activity string
+	points   []TrackPoint
+	laps     []WorkoutLap
+	summary  workoutSummary
+}
+
+// parseWorkoutFile detects and decodes a FIT or GPX file
+func parseWorkoutFile(data []byte) (*parsedWorkoutFile, error) {
+	switch {
+	case isFIT(data):
+		act, err := decodeFIT(data)
+		if err != nil {
+			return nil, err
+		}
+		points := act.trackPoints()
+		f := &parsedWorkoutFile{
+			format:   "fit",
+			activity: act.sport,
+			points:   points,
+			laps:     act.workoutLaps(),
+			summary:  act.summary(points),
+		}
+		if act.subSport != "" {
+			f.activity = act.subSport
+		}
+		if len(f.laps) <= 1 {
+			f.laps = autoSplits(points)
+		}
+		return f, nil
+
+	case isGPX(data):
+		points, name, activity, err := decodeGPX(data)
+		if err != nil {
+			return nil, err
+		}
+		if activity == "" {
+			activity = strings.ToLower(name)
+		}
+		return &parsedWorkoutFile{
+			format:   "gpx",
+			activity: activity,
+			points:   points,
+			laps:     autoSplits(points),
+			summary:  summarizeTrack(points),
+		}, nil
+	}
+	return nil, errors.New("unsupported file: expected a .fit or .gpx workout")
+}
+
+// workoutType maps an activity name onto strength or cardio
+func (f *parsedWorkoutFile) workoutType() string {
+	switch f.activity {
+	case "strength_training", "training", "hiit":
+		return "strength"
+	}
+	return "cardio"
+}
+
+// notes describes the workout in the same style as synced workouts
+func (f *parsedWorkoutFile) notes() string {
+	notes := strings.ReplaceAll(f.activity, "_", " ")
+	if notes == "" || notes == "generic" {
+		notes = "workout"
+	}
+	if f.summary.durationSeconds != nil && *f.summary.durationSeconds > 0 {
+		notes += fmt.Sprintf(", %d min", (*f.summary.durationSeconds+30)/60)
+	}
+	if f.summary.distanceMeters != nil && *f.summary.distanceMeters > 0 {
+		notes += fmt.Sprintf(", %.1f km", *f.summary.distanceMeters/1000)
+	}
+	return notes
+}
+
+// day is the calendar day the workout started on in the configured zones
+func (f *parsedWorkoutFile) day(zones *dayZones) string {
+	return zones.dayOf(f.summary.start)
+}
+
+// storeWorkoutFile saves the workout and its track. A workout from another
+// source starting within workoutDuplicateWindow gets the file attached instead.
+func storeWorkoutFile(ctx context.Context, q querier, f *parsedWorkoutFile, zones *dayZones) (int64, bool, error) {
+	s := f.summary
+
+	var id int64
+	err := q.QueryRow(ctx, `
+		SELECT id FROM workouts
+		WHERE source <> $1
+			AND started_at BETWEEN $2::timestamptz - $3 * INTERVAL '1 minute' AND $2::timestamptz + $3 * INTERVAL '1 minute'
+		ORDER BY ABS(EXTRACT(EPOCH FROM started_at - $2::timestamptz))
+		LIMIT 1
+	`, f.format, s.start, int(workoutDuplicateWindow.Minutes())).Scan(&id)
+	merged := err == nil
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, false, err
+	}
+
+	if merged {
+		_, err = q.Exec(ctx, `
+			UPDATE workouts SET
+				duration_seconds = COALESCE(duration_seconds, $2),
+				distance_meters = COALESCE(distance_meters, $3),
+				elevation_gain_meters = COALESCE(elevation_gain_meters, $4),
+				avg_heart_rate = COALESCE(avg_heart_rate, $5),
+				max_heart_rate = COALESCE(max_heart_rate, $6),
+				calories = COALESCE(calories, $7)
+			WHERE id = $1
+		`, id, s.durationSeconds, s.distanceMeters, s.elevationGain, s.avgHeartRate, s.maxHeartRate, s.calories)
+	} else {
+		err = q.QueryRow(ctx, `
+			INSERT INTO workouts (
+				date, type, notes, source, external_id, started_at, duration_seconds,
+				distance_meters, elevation_gain_meters, avg_heart_rate, max_heart_rate, calories
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (source, external_id) DO UPDATE SET
+				duration_seconds = EXCLUDED.duration_seconds,
+				distance_meters = EXCLUDED.distance_meters,
+				elevation_gain_meters = EXCLUDED.elevation_gain_meters,
+				avg_heart_rate = EXCLUDED.avg_heart_rate,
+				max_heart_rate = EXCLUDED.max_heart_rate,
+				calories = EXCLUDED.calories
+			RETURNING id
+		`, f.day(zones), f.workoutType(), f.notes(), f.format, s.start.UTC().Format(time.RFC3339),
+			s.start, s.durationSeconds, s.distanceMeters, s.elevationGain, s.avgHeartRate, s.maxHeartRate, s.calories,
+		).Scan(&id)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	points, err := json.Marshal(f.points)
+	if err != nil {
+		return 0, false, err
+	}
+	laps, err := json.Marshal(f.laps)
+	if err != nil {
+		return 0, false, err
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO workout_tracks (workout_id, format, points, laps)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workout_id) DO UPDATE SET
+			format = EXCLUDED.format,
+			points = EXCLUDED.points,
+			laps = EXCLUDED.laps,
+			created_at = NOW()
+	`, id, f.format, points, laps)
+	if err != nil {
+		return 0, false, err
+	}
+	return id, merged, nil
+}
+
+// --- Workout file handlers ---
+
+// ImportWorkoutFile creates a workout from an uploaded .fit or .gpx file,
+// sent as the raw body or as the "file" field of a multipart form
+func (h *Handler) ImportWorkoutFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxWorkoutFileBytes)
+	body := io.Reader(r.Body)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			core.WriteError(w, http.StatusBadRequest, "file field is required")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "upload failed: "+err.Error())
+		return
+	}
+
+	f, err := parseWorkoutFile(data)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if f.summary.start.IsZero() {
+		core.WriteError(w, http.StatusBadRequest, "workout file has no start time")
+		return
+	}
+
+	tx, err := h.db.Begin(r.Context())
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	id, merged, err := storeWorkoutFile(r.Context(), tx, f, h.clock.get(r.Context()))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	wo, err := scanWorkout(tx.QueryRow(r.Context(), `SELECT `+workoutColumns+` FROM workouts WHERE id = $1`, id))
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusCreated
+	if merged {
+		status = http.StatusOK
+	}
+	core.WriteJSON(w, status, WorkoutImportResult{
+		Workout: wo,
+		Points:  len(f.points),
+		Laps:    len(f.laps),
+		Merged:  merged,
+	})
+}
+
+// GetWorkoutTrack returns a workout's track as GeoJSON with per-lap splits
+func (h *Handler) GetWorkoutTrack(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		core.WriteError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var format string
+	var pointsJSON, lapsJSON []byte
+	err = h.db.QueryRow(r.Context(), `
+		SELECT format, points, laps FROM workout_tracks WHERE workout_id = $1
+	`, id).Scan(&format, &pointsJSON, &lapsJSON)
+	if err == pgx.ErrNoRows {
+		core.WriteError(w, http.StatusNotFound, "Track not found")
+		return
+	}
+	if err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var points []TrackPoint
+	if err := json.Unmarshal(pointsJSON, &points); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	laps := []WorkoutLap{}
+	if err := json.Unmarshal(lapsJSON, &laps); err != nil {
+		core.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	core.WriteJSON(w, http.StatusOK, WorkoutTrack{
+		WorkoutID: id,
+		Format:    format,
+		GeoJSON:   trackGeoJSON(id, points),
+		Laps:      laps,
+	})
+}
@@ -0,0 +1,21 @@
+#!/bin/bash
+# Re-run illness/anomaly detection over the last 90 days and print the
+# episodes found. Oura uploads and syncs detect on arrival; this catches
+# changes from other sources (sleep sessions, Apple Health, deletions).
+# Run daily via cron, after the morning Oura sync
+
+set -e
+
+API_BASE="${HERMANADMIN_API:-http://localhost:8080/api/v1}"
+DAYS="${DAYS:-90}"
+
+echo "[$(date -Iseconds)] Detecting anomalies over the last ${DAYS} days..."
+
+if ! response=$(curl -sf -X POST "${API_BASE}/health/anomalies/detect?days=${DAYS}"); then
+    echo "  ✗ Detection failed"
+    exit 1
+fi
+
+echo "$response" | jq -r 'if length == 0 then "  ✓ No episodes" else .[] | "  ✓ \(.start_day) to \(.end_day): \(.severity)\(if .active then " (ongoing)" else "" end)" end'
+
+echo "[$(date -Iseconds)] Done"
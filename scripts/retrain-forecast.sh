@@ -0,0 +1,19 @@
+#!/bin/bash
+# Retrain the readiness and sleep score forecast models on the latest
+# Oura history and print their hold-out accuracy
+# Run daily via cron, after the morning Oura sync
+
+set -e
+
+API_BASE="${HERMANADMIN_API:-http://localhost:8080/api/v1}"
+
+echo "[$(date -Iseconds)] Retraining forecast models..."
+
+if ! response=$(curl -sf -X POST "${API_BASE}/health/forecast/train"); then
+    echo "  ✗ Training failed"
+    exit 1
+fi
+
+echo "$response" | jq -r '.[] | "  ✓ \(.target): \(.samples) days, hold-out MAE \(.accuracy.mae) (naive \(.accuracy.baseline_mae)), trust \(.accuracy.trust)"'
+
+echo "[$(date -Iseconds)] Done"